@@ -13,6 +13,7 @@ import (
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/dnfjson"
+	"github.com/osbuild/images/internal/testrepos"
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/distro"
@@ -35,22 +36,6 @@ func check(err error) {
 	}
 }
 
-type repository struct {
-	Name           string   `json:"name"`
-	Id             string   `json:"id,omitempty"`
-	BaseURL        string   `json:"baseurl,omitempty"`
-	Metalink       string   `json:"metalink,omitempty"`
-	MirrorList     string   `json:"mirrorlist,omitempty"`
-	GPGKey         string   `json:"gpgkey,omitempty"`
-	CheckGPG       bool     `json:"check_gpg,omitempty"`
-	CheckRepoGPG   bool     `json:"check_repo_gpg,omitempty"`
-	IgnoreSSL      bool     `json:"ignore_ssl,omitempty"`
-	RHSM           bool     `json:"rhsm,omitempty"`
-	MetadataExpire string   `json:"metadata_expire,omitempty"`
-	ImageTypeTags  []string `json:"image_type_tags,omitempty"`
-	PackageSets    []string `json:"package-sets,omitempty"`
-}
-
 type ostreeOptions struct {
 	Ref    string `json:"ref"`
 	URL    string `json:"url"`
@@ -58,22 +43,31 @@ type ostreeOptions struct {
 	RHSM   bool   `json:"rhsm"`
 }
 
+type compressionOptions struct {
+	Type  string `json:"type"`
+	Level *uint  `json:"level,omitempty"`
+}
+
 type crBlueprint struct {
-	Name           string                    `json:"name,omitempty"`
-	Description    string                    `json:"description,omitempty"`
-	Version        string                    `json:"version,omitempty"`
-	Packages       []blueprint.Package       `json:"packages,omitempty"`
-	Modules        []blueprint.Package       `json:"modules,omitempty"`
-	Groups         []blueprint.Group         `json:"groups,omitempty"`
-	Containers     []blueprint.Container     `json:"containers,omitempty"`
-	Customizations *blueprint.Customizations `json:"customizations,omitempty"`
-	Distro         string                    `json:"distro,omitempty"`
+	Name            string                    `json:"name,omitempty"`
+	Description     string                    `json:"description,omitempty"`
+	Version         string                    `json:"version,omitempty"`
+	Packages        []blueprint.Package       `json:"packages,omitempty"`
+	ExcludePackages []string                  `json:"exclude_packages,omitempty"`
+	Modules         []blueprint.Package       `json:"modules,omitempty"`
+	Groups          []blueprint.Group         `json:"groups,omitempty"`
+	Containers      []blueprint.Container     `json:"containers,omitempty"`
+	Customizations  *blueprint.Customizations `json:"customizations,omitempty"`
+	Distro          string                    `json:"distro,omitempty"`
 }
 
 type buildConfig struct {
 	Name      string         `json:"name"`
 	OSTree    *ostreeOptions `json:"ostree,omitempty"`
 	Blueprint *crBlueprint   `json:"blueprint,omitempty"`
+
+	// Overrides the default compression of the image type
+	Compression *compressionOptions `json:"compression,omitempty"`
 }
 
 func loadConfig(filepath string) buildConfig {
@@ -96,9 +90,8 @@ func loadConfig(filepath string) buildConfig {
 	return config
 }
 
-func makeManifest(imgType distro.ImageType, config buildConfig, distribution distro.Distro, repos []rpmmd.RepoConfig, archName string, seedArg int64, cacheRoot string) (manifest.OSBuildManifest, error) {
-	cacheDir := filepath.Join(cacheRoot, archName+distribution.Name())
-
+// makeImageOptions returns the image options of the build config
+func makeImageOptions(config buildConfig) distro.ImageOptions {
 	options := distro.ImageOptions{Size: 0}
 	if config.OSTree != nil {
 		options.OSTree = &ostree.ImageOptions{
@@ -109,109 +102,95 @@ func makeManifest(imgType distro.ImageType, config buildConfig, distribution dis
 		}
 	}
 
+	if config.Compression != nil {
+		options.Compression = &distro.CompressionOptions{
+			Type:  distro.CompressionType(config.Compression.Type),
+			Level: config.Compression.Level,
+		}
+	}
+
 	// add RHSM fact to detect changes
 	options.Facts = &facts.ImageOptions{
 		APIType: facts.TEST_APITYPE,
 	}
+	return options
+}
+
+func makeManifest(imgType distro.ImageType, config buildConfig, distribution distro.Distro, repos []rpmmd.RepoConfig, archName string, seedArg int64, cacheRoot string, caCertPath string, persistentResults bool, lock *manifest.LockFile) (manifest.OSBuildManifest, *manifest.LockFile, error) {
+	cacheDir := filepath.Join(cacheRoot, archName+distribution.Name())
+
+	options := makeImageOptions(config)
 
 	var bp blueprint.Blueprint
 	if config.Blueprint != nil {
 		bp = blueprint.Blueprint(*config.Blueprint)
 	}
 
-	manifest, warnings, err := imgType.Manifest(&bp, options, repos, seedArg)
+	m, warnings, err := imgType.Manifest(&bp, options, repos, seedArg)
 	if err != nil {
-		return nil, fmt.Errorf("[ERROR] manifest generation failed: %s", err.Error())
+		return nil, nil, fmt.Errorf("[ERROR] manifest generation failed: %s", err.Error())
 	}
 	if len(warnings) > 0 {
 		fmt.Fprintf(os.Stderr, "[WARNING]\n%s", strings.Join(warnings, "\n"))
 	}
 
-	packageSpecs, err := depsolve(cacheDir, manifest.GetPackageSetChains(), distribution, archName)
+	if lock != nil {
+		// reproduce the manifest from the lock file without resolving any content
+		mf, err := m.SerializeLocked(lock)
+		if err != nil {
+			return nil, nil, fmt.Errorf("[ERROR] manifest serialization failed: %s", err.Error())
+		}
+		return mf, lock, nil
+	}
+
+	packageSpecs, moduleStates, err := depsolve(cacheDir, caCertPath, persistentResults, m.GetPackageSetChains(), distribution, archName)
 	if err != nil {
-		return nil, fmt.Errorf("[ERROR] depsolve failed: %s", err.Error())
+		return nil, nil, fmt.Errorf("[ERROR] depsolve failed: %s", err.Error())
 	}
 	if packageSpecs == nil {
-		return nil, fmt.Errorf("[ERROR] depsolve did not return any packages")
+		return nil, nil, fmt.Errorf("[ERROR] depsolve did not return any packages")
 	}
 
-	if config.Blueprint != nil {
-		bp = blueprint.Blueprint(*config.Blueprint)
-	}
-
-	containerSpecs, err := resolvePipelineContainers(manifest.GetContainerSourceSpecs(), archName)
+	containerSpecs, err := resolvePipelineContainers(m.GetContainerSourceSpecs(), archName, caCertPath)
 	if err != nil {
-		return nil, fmt.Errorf("[ERROR] container resolution failed: %s", err.Error())
+		return nil, nil, fmt.Errorf("[ERROR] container resolution failed: %s", err.Error())
 	}
 
-	commitSpecs := resolvePipelineCommits(manifest.GetOSTreeSourceSpecs())
+	commitSpecs := resolvePipelineCommits(m.GetOSTreeSourceSpecs())
 
-	mf, err := manifest.Serialize(packageSpecs, containerSpecs, commitSpecs)
+	mf, err := m.SerializeWithModules(packageSpecs, moduleStates, containerSpecs, commitSpecs)
 	if err != nil {
-		return nil, fmt.Errorf("[ERROR] manifest serialization failed: %s", err.Error())
+		return nil, nil, fmt.Errorf("[ERROR] manifest serialization failed: %s", err.Error())
 	}
 
-	return mf, nil
-}
-
-type DistroArchRepoMap map[string]map[string][]repository
-
-func convertRepo(r repository) rpmmd.RepoConfig {
-	var urls []string
-	if r.BaseURL != "" {
-		urls = []string{r.BaseURL}
-	}
-
-	var keys []string
-	if r.GPGKey != "" {
-		keys = []string{r.GPGKey}
+	lock, err = manifest.NewLockFile(*m, packageSpecs, moduleStates, containerSpecs, commitSpecs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[ERROR] lock file generation failed: %s", err.Error())
 	}
 
-	return rpmmd.RepoConfig{
-		Id:             r.Id,
-		Name:           r.Name,
-		BaseURLs:       urls,
-		Metalink:       r.Metalink,
-		MirrorList:     r.MirrorList,
-		GPGKeys:        keys,
-		CheckGPG:       &r.CheckGPG,
-		CheckRepoGPG:   &r.CheckRepoGPG,
-		IgnoreSSL:      &r.IgnoreSSL,
-		MetadataExpire: r.MetadataExpire,
-		RHSM:           r.RHSM,
-		ImageTypeTags:  r.ImageTypeTags,
-		PackageSets:    r.PackageSets,
-	}
+	return mf, lock, nil
 }
 
-func convertRepos(rr []repository) []rpmmd.RepoConfig {
-	cr := make([]rpmmd.RepoConfig, len(rr))
-	for idx, r := range rr {
-		cr[idx] = convertRepo(r)
-	}
-	return cr
-}
-
-func readRepos() DistroArchRepoMap {
-	file := "./tools/test-case-generators/repos.json"
-	var darm DistroArchRepoMap
-	fp, err := os.Open(file)
+func readLockFile(fpath string) *manifest.LockFile {
+	fp, err := os.Open(fpath)
 	if err != nil {
-		check(err)
+		fail(fmt.Sprintf("failed to open lock file %q: %s", fpath, err.Error()))
 	}
 	defer fp.Close()
-	data, err := io.ReadAll(fp)
+	lock, err := manifest.ReadLockFile(fp)
 	if err != nil {
-		check(err)
-	}
-	if err := json.Unmarshal(data, &darm); err != nil {
-		check(err)
+		fail(fmt.Sprintf("failed to read lock file %q: %s", fpath, err.Error()))
 	}
-	return darm
+	return lock
 }
 
-func resolveContainers(containers []container.SourceSpec, archName string) ([]container.Spec, error) {
+func resolveContainers(containers []container.SourceSpec, archName string, caCertPath string) ([]container.Spec, error) {
 	resolver := container.NewResolver(archName)
+	if caCertPath != "" {
+		if err := resolver.SetCACertPath(caCertPath); err != nil {
+			return nil, err
+		}
+	}
 
 	for _, c := range containers {
 		resolver.Add(c)
@@ -220,10 +199,10 @@ func resolveContainers(containers []container.SourceSpec, archName string) ([]co
 	return resolver.Finish()
 }
 
-func resolvePipelineContainers(containerSources map[string][]container.SourceSpec, archName string) (map[string][]container.Spec, error) {
+func resolvePipelineContainers(containerSources map[string][]container.SourceSpec, archName string, caCertPath string) (map[string][]container.Spec, error) {
 	containerSpecs := make(map[string][]container.Spec, len(containerSources))
 	for plName, sourceSpecs := range containerSources {
-		specs, err := resolveContainers(sourceSpecs, archName)
+		specs, err := resolveContainers(sourceSpecs, archName, caCertPath)
 		if err != nil {
 			return nil, err
 		}
@@ -259,22 +238,26 @@ func resolvePipelineCommits(commitSources map[string][]ostree.SourceSpec) map[st
 	return commits
 }
 
-func depsolve(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string][]rpmmd.PackageSpec, error) {
+func depsolve(cacheDir string, caCertPath string, persistentResults bool, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string][]rpmmd.PackageSpec, map[string][]rpmmd.ModuleState, error) {
 	solver := dnfjson.NewSolver(d.ModulePlatformID(), d.Releasever(), arch, d.Name(), cacheDir)
 	solver.SetDNFJSONPath("./dnf-json")
+	solver.SetCACertPath(caCertPath)
+	solver.SetPersistentResults(persistentResults)
 	depsolvedSets := make(map[string][]rpmmd.PackageSpec)
+	moduleStates := make(map[string][]rpmmd.ModuleState)
 	for name, pkgSet := range packageSets {
-		res, err := solver.Depsolve(pkgSet)
+		res, modules, err := solver.DepsolveModules(pkgSet)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		depsolvedSets[name] = res
+		moduleStates[name] = modules
 	}
-	return depsolvedSets, nil
+	return depsolvedSets, moduleStates, nil
 }
 
-func save(ms manifest.OSBuildManifest, fpath string) error {
-	b, err := json.MarshalIndent(ms, "", "  ")
+func save(data interface{}, fpath string) error {
+	b, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal data for %q: %s\n", fpath, err.Error())
 	}
@@ -294,29 +277,17 @@ func u(s string) string {
 	return strings.Replace(s, "-", "_", -1)
 }
 
-func filterRepos(repos []repository, typeName string) []repository {
-	filtered := make([]repository, 0)
-	for _, repo := range repos {
-		if len(repo.ImageTypeTags) == 0 {
-			filtered = append(filtered, repo)
-		} else {
-			for _, tt := range repo.ImageTypeTags {
-				if tt == typeName {
-					filtered = append(filtered, repo)
-					break
-				}
-			}
-		}
-	}
-	return filtered
-}
-
 func main() {
 	// common args
 	var outputDir, osbuildStore, rpmCacheRoot string
 	flag.StringVar(&outputDir, "output", ".", "artifact output directory")
 	flag.StringVar(&osbuildStore, "store", ".osbuild", "osbuild store for intermediate pipeline trees")
 	flag.StringVar(&rpmCacheRoot, "rpmmd", "/tmp/rpmmd", "rpm metadata cache directory")
+	var persistentResults bool
+	flag.BoolVar(&persistentResults, "persistent-results", false, "store depsolve results in the rpm metadata cache directory and reuse them until a repository changes")
+
+	var caCertPath string
+	flag.StringVar(&caCertPath, "cacert", "", "PEM bundle of CA certificates used to verify repositories and container registries")
 
 	// image selection args
 	var distroName, imgTypeName, configFile string
@@ -324,15 +295,22 @@ func main() {
 	flag.StringVar(&imgTypeName, "image", "", "image type name (required)")
 	flag.StringVar(&configFile, "config", "", "build config file (required)")
 
+	// lock file args
+	var lockFile string
+	var frozen bool
+	flag.StringVar(&lockFile, "lock", "", "lock file of the resolved content, written unless -frozen is set")
+	flag.BoolVar(&frozen, "frozen", false, "build from the content of the lock file without resolving it again")
+
 	flag.Parse()
 
-	if distroName == "" || imgTypeName == "" || configFile == "" {
+	if distroName == "" || imgTypeName == "" || configFile == "" || (frozen && lockFile == "") {
 		flag.Usage()
 		os.Exit(1)
 	}
 
 	seedArg := int64(0)
-	darm := readRepos()
+	darm, err := testrepos.Read(testrepos.DefaultPath)
+	check(err)
 	distroReg := distroregistry.NewDefault()
 
 	config := loadConfig(configFile)
@@ -364,19 +342,29 @@ func main() {
 	}
 
 	// get repositories
-	repos := filterRepos(darm[distroName][archName], imgTypeName)
-	rpmmdRepos := convertRepos(repos)
+	repos := testrepos.FilterByImageType(darm[distroName][archName], imgTypeName)
+	rpmmdRepos := testrepos.RepoConfigs(repos)
 	if len(repos) == 0 {
 		fail(fmt.Sprintf("no repositories defined for %s/%s\n", distroName, archName))
 	}
 
 	fmt.Printf("Generating manifest for %s: ", config.Name)
-	mf, err := makeManifest(imgType, config, distribution, rpmmdRepos, archName, seedArg, rpmCacheRoot)
+	var lock *manifest.LockFile
+	if frozen {
+		lock = readLockFile(lockFile)
+	}
+	mf, lock, err := makeManifest(imgType, config, distribution, rpmmdRepos, archName, seedArg, rpmCacheRoot, caCertPath, persistentResults, lock)
 	if err != nil {
 		check(err)
 	}
 	fmt.Print("DONE\n")
 
+	if lockFile != "" && !frozen {
+		if err := save(lock, lockFile); err != nil {
+			check(err)
+		}
+	}
+
 	manifestPath := filepath.Join(buildDir, "manifest.json")
 	if err := save(mf, manifestPath); err != nil {
 		check(err)
@@ -385,7 +373,7 @@ func main() {
 	fmt.Printf("Building manifest: %s\n", manifestPath)
 
 	jobOutput := filepath.Join(outputDir, buildName)
-	if _, err := osbuild.RunOSBuild(mf, osbuildStore, jobOutput, imgType.Exports(), nil, nil, false, os.Stderr); err != nil {
+	if _, err := osbuild.RunOSBuild(mf, osbuildStore, jobOutput, distro.ExportsFor(imgType, makeImageOptions(config)), nil, nil, false, os.Stderr); err != nil {
 		check(err)
 	}
 
@@ -0,0 +1,172 @@
+// Tool to explain why packages are part of an image. It depsolves the package
+// sets of a pipeline of a distro x arch x image type for a blueprint and
+// either prints the dependency chain that pulls in a package or exports the
+// whole dependency graph as DOT or JSON.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/dnfjson"
+	"github.com/osbuild/images/internal/testrepos"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distroregistry"
+	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+func fail(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(1)
+}
+
+func check(err error) {
+	if err != nil {
+		fail(err.Error())
+	}
+}
+
+// readBlueprint reads a blueprint in TOML or, if the file name ends in .json,
+// in JSON format
+func readBlueprint(fpath string) blueprint.Blueprint {
+	var bp blueprint.Blueprint
+	if fpath == "" {
+		return bp
+	}
+	data, err := os.ReadFile(fpath)
+	check(err)
+	if filepath.Ext(fpath) == ".json" {
+		err = json.Unmarshal(data, &bp)
+	} else {
+		err = toml.Unmarshal(data, &bp)
+	}
+	if err != nil {
+		fail(fmt.Sprintf("failed to parse blueprint %q: %s", fpath, err.Error()))
+	}
+	return bp
+}
+
+// printWhy prints the chain of dependencies from a requested package to the
+// package with the given name
+func printWhy(w io.Writer, graph *rpmmd.DependencyGraph, pkgs []rpmmd.PackageSpec, name string) error {
+	var nevras []string
+	for idx := range pkgs {
+		if pkgs[idx].Name == name || pkgs[idx].GetNEVRA() == name {
+			nevras = append(nevras, pkgs[idx].GetNEVRA())
+		}
+	}
+	if len(nevras) == 0 {
+		return fmt.Errorf("package %q is not part of the depsolved packages", name)
+	}
+
+	for _, nevra := range nevras {
+		chain, ok := graph.Why(nevra)
+		switch {
+		case !ok:
+			fmt.Fprintf(w, "%s is not required by any requested package, it is pulled in by a rich dependency or supplements another package\n", nevra)
+		case len(chain) == 0:
+			fmt.Fprintf(w, "%s is requested\n", nevra)
+		default:
+			fmt.Fprintf(w, "%s is requested\n", chain[0].Package)
+			for _, dep := range chain {
+				fmt.Fprintln(w, dep.String())
+			}
+		}
+	}
+	return nil
+}
+
+func main() {
+	var distroName, archName, imgTypeName, bpFile, reposFile, cacheRoot, pipeline, why, format string
+	flag.StringVar(&distroName, "distro", "", "distribution (required)")
+	flag.StringVar(&archName, "arch", common.CurrentArch(), "architecture")
+	flag.StringVar(&imgTypeName, "image", "", "image type name (required)")
+	flag.StringVar(&bpFile, "blueprint", "", "blueprint file in TOML or JSON (.json) format")
+	flag.StringVar(&reposFile, "repos", testrepos.DefaultPath, "repositories per distro and arch")
+	flag.StringVar(&cacheRoot, "rpmmd", "/tmp/rpmmd", "rpm metadata cache directory")
+	flag.StringVar(&pipeline, "pipeline", "", "pipeline whose packages are depsolved (default: the first payload pipeline)")
+	flag.StringVar(&why, "why", "", "print why the package with this name or NEVRA is installed")
+	flag.StringVar(&format, "format", "dot", "format of the dependency graph: dot or json")
+	flag.Parse()
+
+	if distroName == "" || imgTypeName == "" || (format != "dot" && format != "json") {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	d := distroregistry.NewDefault().GetDistro(distroName)
+	if d == nil {
+		fail(fmt.Sprintf("invalid or unsupported distribution: %q", distroName))
+	}
+	arch, err := d.GetArch(archName)
+	if err != nil {
+		fail(fmt.Sprintf("invalid arch name %q for distro %q: %s", archName, distroName, err.Error()))
+	}
+	imgType, err := arch.GetImageType(imgTypeName)
+	if err != nil {
+		fail(fmt.Sprintf("invalid image type %q for distro %q and arch %q: %s", imgTypeName, distroName, archName, err.Error()))
+	}
+
+	darm, err := testrepos.Read(reposFile)
+	check(err)
+	repos := testrepos.RepoConfigs(testrepos.FilterByImageType(darm[distroName][archName], imgTypeName))
+	if len(repos) == 0 {
+		fail(fmt.Sprintf("no repositories defined for %s/%s", distroName, archName))
+	}
+
+	bp := readBlueprint(bpFile)
+	options := distro.ImageOptions{
+		OSTree: &ostree.ImageOptions{
+			URL: "https://example.com", // required by some image types
+		},
+	}
+	manifest, warnings, err := imgType.Manifest(&bp, options, repos, 0)
+	if err != nil {
+		fail(fmt.Sprintf("manifest generation failed: %s", err.Error()))
+	}
+	if len(warnings) > 0 {
+		fmt.Fprintf(os.Stderr, "[WARNING]\n%s\n", strings.Join(warnings, "\n"))
+	}
+
+	chains := manifest.GetPackageSetChains()
+	if pipeline == "" {
+		for _, name := range imgType.PayloadPipelines() {
+			if _, ok := chains[name]; ok {
+				pipeline = name
+				break
+			}
+		}
+	}
+	chain, ok := chains[pipeline]
+	if !ok {
+		fail(fmt.Sprintf("pipeline %q of image type %q has no packages", pipeline, imgTypeName))
+	}
+
+	solver := dnfjson.NewSolver(d.ModulePlatformID(), d.Releasever(), archName, d.Name(), filepath.Join(cacheRoot, archName+d.Name()))
+	solver.SetDNFJSONPath("./dnf-json")
+	pkgs, graph, err := solver.DepsolveGraph(chain)
+	if err != nil {
+		fail(fmt.Sprintf("depsolve failed: %s", err.Error()))
+	}
+
+	switch {
+	case why != "":
+		check(printWhy(os.Stdout, graph, pkgs, why))
+	case format == "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		check(encoder.Encode(graph))
+	default:
+		check(graph.WriteDOT(os.Stdout))
+	}
+}
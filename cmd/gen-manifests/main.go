@@ -19,6 +19,7 @@ import (
 
 	"github.com/gobwas/glob"
 	"github.com/osbuild/images/internal/dnfjson"
+	"github.com/osbuild/images/internal/testrepos"
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/distro"
@@ -41,22 +42,6 @@ func (mv *multiValue) Set(v string) error {
 	return nil
 }
 
-type repository struct {
-	Name           string   `json:"name"`
-	Id             string   `json:"id,omitempty"`
-	BaseURL        string   `json:"baseurl,omitempty"`
-	Metalink       string   `json:"metalink,omitempty"`
-	MirrorList     string   `json:"mirrorlist,omitempty"`
-	GPGKey         string   `json:"gpgkey,omitempty"`
-	CheckGPG       bool     `json:"check_gpg,omitempty"`
-	CheckRepoGPG   bool     `json:"check_repo_gpg,omitempty"`
-	IgnoreSSL      bool     `json:"ignore_ssl,omitempty"`
-	RHSM           bool     `json:"rhsm,omitempty"`
-	MetadataExpire string   `json:"metadata_expire,omitempty"`
-	ImageTypeTags  []string `json:"image_type_tags,omitempty"`
-	PackageSets    []string `json:"package-sets,omitempty"`
-}
-
 type ostreeOptions struct {
 	Ref    string `json:"ref"`
 	URL    string `json:"url"`
@@ -65,23 +50,24 @@ type ostreeOptions struct {
 }
 
 type crBlueprint struct {
-	Name           string                    `json:"name,omitempty"`
-	Description    string                    `json:"description,omitempty"`
-	Version        string                    `json:"version,omitempty"`
-	Packages       []blueprint.Package       `json:"packages,omitempty"`
-	Modules        []blueprint.Package       `json:"modules,omitempty"`
-	Groups         []blueprint.Group         `json:"groups,omitempty"`
-	Containers     []blueprint.Container     `json:"containers,omitempty"`
-	Customizations *blueprint.Customizations `json:"customizations,omitempty"`
-	Distro         string                    `json:"distro,omitempty"`
+	Name            string                    `json:"name,omitempty"`
+	Description     string                    `json:"description,omitempty"`
+	Version         string                    `json:"version,omitempty"`
+	Packages        []blueprint.Package       `json:"packages,omitempty"`
+	ExcludePackages []string                  `json:"exclude_packages,omitempty"`
+	Modules         []blueprint.Package       `json:"modules,omitempty"`
+	Groups          []blueprint.Group         `json:"groups,omitempty"`
+	Containers      []blueprint.Container     `json:"containers,omitempty"`
+	Customizations  *blueprint.Customizations `json:"customizations,omitempty"`
+	Distro          string                    `json:"distro,omitempty"`
 }
 
 type buildRequest struct {
-	Distro       string       `json:"distro,omitempty"`
-	Arch         string       `json:"arch,omitempty"`
-	ImageType    string       `json:"image-type,omitempty"`
-	Repositories []repository `json:"repositories,omitempty"`
-	Config       *buildConfig `json:"config"`
+	Distro       string                 `json:"distro,omitempty"`
+	Arch         string                 `json:"arch,omitempty"`
+	ImageType    string                 `json:"image-type,omitempty"`
+	Repositories []testrepos.Repository `json:"repositories,omitempty"`
+	Config       *buildConfig           `json:"config"`
 }
 
 type buildConfig struct {
@@ -140,14 +126,13 @@ func loadConfigMap() configMap {
 
 type manifestJob func(chan string) error
 
-func makeManifestJob(name string, imgType distro.ImageType, bc buildConfig, distribution distro.Distro, repos []repository, archName string, seedArg int64, path string, cacheRoot string, metadata bool) manifestJob {
+func makeManifestJob(name string, imgType distro.ImageType, bc buildConfig, distribution distro.Distro, repos []testrepos.Repository, archName string, seedArg int64, path string, solver *dnfjson.BaseSolver, metadata bool, lockDir string, frozen bool) manifestJob {
 	distroName := distribution.Name()
 	u := func(s string) string {
 		return strings.Replace(s, "-", "_", -1)
 	}
 	filename := fmt.Sprintf("%s-%s-%s-%s-boot.json", u(distroName), u(archName), u(imgType.Name()), u(name))
-	cacheDir := filepath.Join(cacheRoot, archName+distribution.Name())
-
+	lockFilename := fmt.Sprintf("%s-%s-%s-%s.lock.json", u(distroName), u(archName), u(imgType.Name()), u(name))
 	options := distro.ImageOptions{Size: 0}
 	if bc.OSTree != nil {
 		options.OSTree = &ostree.ImageOptions{
@@ -172,19 +157,41 @@ func makeManifestJob(name string, imgType distro.ImageType, bc buildConfig, dist
 			msgq <- msg
 		}()
 		msgq <- fmt.Sprintf("Starting job %s", filename)
-		rpmrepos := convertRepos(repos)
+		rpmrepos := testrepos.RepoConfigs(repos)
 		var bp blueprint.Blueprint
 		if bc.Blueprint != nil {
 			bp = blueprint.Blueprint(*bc.Blueprint)
 		}
 
-		manifest, _, err := imgType.Manifest(&bp, options, rpmrepos, seedArg)
+		m, _, err := imgType.Manifest(&bp, options, rpmrepos, seedArg)
 		if err != nil {
 			err = fmt.Errorf("[%s] failed: %s", filename, err)
 			return
 		}
 
-		packageSpecs, err := depsolve(cacheDir, manifest.GetPackageSetChains(), distribution, archName)
+		request := buildRequest{
+			Distro:       distribution.Name(),
+			Arch:         archName,
+			ImageType:    imgType.Name(),
+			Repositories: repos,
+			Config:       &bc,
+		}
+
+		if frozen {
+			// reproduce the manifest from its lock file without resolving any content
+			lock, err := readLockFile(filepath.Join(lockDir, lockFilename))
+			if err != nil {
+				return fmt.Errorf("[%s] %s", filename, err.Error())
+			}
+			mf, err := m.SerializeLocked(lock)
+			if err != nil {
+				return fmt.Errorf("[%s] manifest serialization failed: %s", filename, err.Error())
+			}
+			packageSpecs, containerSpecs, commitSpecs := lockedContent(lock)
+			return save(mf, packageSpecs, containerSpecs, commitSpecs, request, path, filename, metadata)
+		}
+
+		packageSpecs, moduleStates, err := depsolve(solver, m.GetPackageSetChains(), distribution, archName)
 		if err != nil {
 			err = fmt.Errorf("[%s] depsolve failed: %s", filename, err.Error())
 			return
@@ -198,87 +205,34 @@ func makeManifestJob(name string, imgType distro.ImageType, bc buildConfig, dist
 			bp = blueprint.Blueprint(*bc.Blueprint)
 		}
 
-		containerSpecs, err := resolvePipelineContainers(manifest.GetContainerSourceSpecs(), archName)
+		containerSpecs, err := resolvePipelineContainers(m.GetContainerSourceSpecs(), archName)
 		if err != nil {
 			return fmt.Errorf("[%s] container resolution failed: %s", filename, err.Error())
 		}
 
-		commitSpecs := resolvePipelineCommits(manifest.GetOSTreeSourceSpecs())
+		commitSpecs := resolvePipelineCommits(m.GetOSTreeSourceSpecs())
 
-		mf, err := manifest.Serialize(packageSpecs, containerSpecs, commitSpecs)
+		mf, err := m.SerializeWithModules(packageSpecs, moduleStates, containerSpecs, commitSpecs)
 		if err != nil {
 			return fmt.Errorf("[%s] manifest serialization failed: %s", filename, err.Error())
 		}
 
-		request := buildRequest{
-			Distro:       distribution.Name(),
-			Arch:         archName,
-			ImageType:    imgType.Name(),
-			Repositories: repos,
-			Config:       &bc,
+		if lockDir != "" {
+			lock, err := manifest.NewLockFile(*m, packageSpecs, moduleStates, containerSpecs, commitSpecs)
+			if err != nil {
+				return fmt.Errorf("[%s] lock file generation failed: %s", filename, err.Error())
+			}
+			if err := saveLockFile(lock, filepath.Join(lockDir, lockFilename)); err != nil {
+				return fmt.Errorf("[%s] %s", filename, err.Error())
+			}
 		}
+
 		err = save(mf, packageSpecs, containerSpecs, commitSpecs, request, path, filename, metadata)
 		return
 	}
 	return job
 }
 
-type DistroArchRepoMap map[string]map[string][]repository
-
-func convertRepo(r repository) rpmmd.RepoConfig {
-	var urls []string
-	if r.BaseURL != "" {
-		urls = []string{r.BaseURL}
-	}
-
-	var keys []string
-	if r.GPGKey != "" {
-		keys = []string{r.GPGKey}
-	}
-
-	return rpmmd.RepoConfig{
-		Id:             r.Id,
-		Name:           r.Name,
-		BaseURLs:       urls,
-		Metalink:       r.Metalink,
-		MirrorList:     r.MirrorList,
-		GPGKeys:        keys,
-		CheckGPG:       &r.CheckGPG,
-		CheckRepoGPG:   &r.CheckRepoGPG,
-		IgnoreSSL:      &r.IgnoreSSL,
-		MetadataExpire: r.MetadataExpire,
-		RHSM:           r.RHSM,
-		ImageTypeTags:  r.ImageTypeTags,
-		PackageSets:    r.PackageSets,
-	}
-}
-
-func convertRepos(rr []repository) []rpmmd.RepoConfig {
-	cr := make([]rpmmd.RepoConfig, len(rr))
-	for idx, r := range rr {
-		cr[idx] = convertRepo(r)
-	}
-	return cr
-}
-
-func readRepos() DistroArchRepoMap {
-	file := "./tools/test-case-generators/repos.json"
-	var darm DistroArchRepoMap
-	fp, err := os.Open(file)
-	if err != nil {
-		panic(err)
-	}
-	defer fp.Close()
-	data, err := io.ReadAll(fp)
-	if err != nil {
-		panic(err)
-	}
-	if err := json.Unmarshal(data, &darm); err != nil {
-		panic(err)
-	}
-	return darm
-}
-
 func resolveContainers(containers []container.SourceSpec, archName string) ([]container.Spec, error) {
 	resolver := container.NewResolver(archName)
 
@@ -328,18 +282,19 @@ func resolvePipelineCommits(commitSources map[string][]ostree.SourceSpec) map[st
 	return commits
 }
 
-func depsolve(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string][]rpmmd.PackageSpec, error) {
-	solver := dnfjson.NewSolver(d.ModulePlatformID(), d.Releasever(), arch, d.Name(), cacheDir)
-	solver.SetDNFJSONPath("./dnf-json")
+func depsolve(bs *dnfjson.BaseSolver, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string][]rpmmd.PackageSpec, map[string][]rpmmd.ModuleState, error) {
+	solver := bs.NewWithConfig(d.ModulePlatformID(), d.Releasever(), arch, d.Name())
 	depsolvedSets := make(map[string][]rpmmd.PackageSpec)
+	moduleStates := make(map[string][]rpmmd.ModuleState)
 	for name, pkgSet := range packageSets {
-		res, err := solver.Depsolve(pkgSet)
+		res, modules, err := solver.DepsolveModules(pkgSet)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		depsolvedSets[name] = res
+		moduleStates[name] = modules
 	}
-	return depsolvedSets, nil
+	return depsolvedSets, moduleStates, nil
 }
 
 func save(ms manifest.OSBuildManifest, pkgs map[string][]rpmmd.PackageSpec, containers map[string][]container.Spec, commits map[string][]ostree.CommitSpec, cr buildRequest, path, filename string, metadata bool) error {
@@ -375,21 +330,49 @@ func save(ms manifest.OSBuildManifest, pkgs map[string][]rpmmd.PackageSpec, cont
 	return nil
 }
 
-func filterRepos(repos []repository, typeName string) []repository {
-	filtered := make([]repository, 0)
-	for _, repo := range repos {
-		if len(repo.ImageTypeTags) == 0 {
-			filtered = append(filtered, repo)
-		} else {
-			for _, tt := range repo.ImageTypeTags {
-				if tt == typeName {
-					filtered = append(filtered, repo)
-					break
-				}
-			}
+func readLockFile(fpath string) (*manifest.LockFile, error) {
+	fp, err := os.Open(fpath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %q: %s", fpath, err.Error())
+	}
+	defer fp.Close()
+	lock, err := manifest.ReadLockFile(fp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock file %q: %s", fpath, err.Error())
+	}
+	return lock, nil
+}
+
+func saveLockFile(lock *manifest.LockFile, fpath string) error {
+	b, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock file %q: %s", fpath, err.Error())
+	}
+	b = append(b, '\n') // add new line at end of file
+	if err := os.WriteFile(fpath, b, 0600); err != nil {
+		return fmt.Errorf("failed to write lock file %q: %s", fpath, err.Error())
+	}
+	return nil
+}
+
+// lockedContent returns the resolved content recorded in the lock file in the
+// form it is stored in the manifest metadata.
+func lockedContent(lock *manifest.LockFile) (map[string][]rpmmd.PackageSpec, map[string][]container.Spec, map[string][]ostree.CommitSpec) {
+	packageSpecs := make(map[string][]rpmmd.PackageSpec)
+	containerSpecs := make(map[string][]container.Spec)
+	commitSpecs := make(map[string][]ostree.CommitSpec)
+	for name, pl := range lock.Pipelines {
+		if pl.PackageSetChain != "" {
+			packageSpecs[name] = pl.Packages
+		}
+		if len(pl.ContainerSources) > 0 {
+			containerSpecs[name] = pl.Containers
+		}
+		if len(pl.OSTreeSources) > 0 {
+			commitSpecs[name] = pl.OSTreeCommits
 		}
 	}
-	return filtered
+	return packageSpecs, containerSpecs, commitSpecs
 }
 
 // resolveArgValues returns a list of valid values from the list of values on the
@@ -420,13 +403,22 @@ func resolveArgValues(args multiValue, valueList []string) ([]string, []string)
 func main() {
 	// common args
 	var outputDir, cacheRoot string
-	var nWorkers int
+	var nWorkers, nDNFWorkers int
 	var metadata bool
 	flag.StringVar(&outputDir, "output", "test/data/manifests/", "manifest store directory")
 	flag.IntVar(&nWorkers, "workers", 16, "number of workers to run concurrently")
 	flag.StringVar(&cacheRoot, "cache", "/tmp/rpmmd", "rpm metadata cache directory")
+	var persistentResults bool
+	flag.BoolVar(&persistentResults, "persistent-results", false, "store depsolve results in the rpm metadata cache directory and reuse them until a repository changes")
+	flag.IntVar(&nDNFWorkers, "dnf-json-workers", 2, "number of dnf-json processes kept running per distro and arch (0 runs dnf-json for every depsolve)")
 	flag.BoolVar(&metadata, "metadata", true, "store metadata in the file")
 
+	// lock file args
+	var lockDir string
+	var frozen bool
+	flag.StringVar(&lockDir, "lock", "", "directory of the lock files of the resolved content, one per manifest, written unless -frozen is set")
+	flag.BoolVar(&frozen, "frozen", false, "generate manifests from the content of their lock files without resolving it again")
+
 	// manifest selection args
 	var arches, distros, imgTypes multiValue
 	flag.Var(&arches, "arches", "comma-separated list of architectures (globs supported)")
@@ -435,16 +427,34 @@ func main() {
 
 	flag.Parse()
 
+	if frozen && lockDir == "" {
+		fmt.Fprintln(os.Stderr, "-frozen requires -lock")
+		os.Exit(1)
+	}
+
 	seedArg := int64(0)
-	darm := readRepos()
+	darm, err := testrepos.Read(testrepos.DefaultPath)
+	if err != nil {
+		panic(err)
+	}
 	distroReg := distroregistry.NewDefault()
 	jobs := make([]manifestJob, 0)
 
 	configs := loadConfigMap()
 
+	solver := dnfjson.NewBaseSolver(cacheRoot)
+	solver.SetDNFJSONPath("./dnf-json")
+	solver.SetWorkers(nDNFWorkers)
+	solver.SetPersistentResults(persistentResults)
+
 	if err := os.MkdirAll(outputDir, 0770); err != nil {
 		panic(fmt.Sprintf("failed to create target directory: %s", err.Error()))
 	}
+	if lockDir != "" && !frozen {
+		if err := os.MkdirAll(lockDir, 0770); err != nil {
+			panic(fmt.Sprintf("failed to create lock file directory: %s", err.Error()))
+		}
+	}
 
 	fmt.Println("Collecting jobs")
 	distros, invalidDistros := resolveArgValues(distros, distroReg.List())
@@ -478,7 +488,7 @@ func main() {
 
 				// get repositories
 				repos := darm[distroName][archName]
-				repos = filterRepos(repos, imgTypeName)
+				repos = testrepos.FilterByImageType(repos, imgTypeName)
 				if len(repos) == 0 {
 					fmt.Printf("no repositories defined for %s/%s/%s\n", distroName, archName, imgTypeName)
 					fmt.Println("Skipping")
@@ -496,7 +506,7 @@ func main() {
 				}
 
 				for _, itConfig := range imgTypeConfigs {
-					job := makeManifestJob(itConfig.Name, imgType, itConfig, distribution, repos, archName, seedArg, outputDir, cacheRoot, metadata)
+					job := makeManifestJob(itConfig.Name, imgType, itConfig, distribution, repos, archName, seedArg, outputDir, solver, metadata, lockDir, frozen)
 					jobs = append(jobs, job)
 				}
 			}
@@ -514,6 +524,7 @@ func main() {
 	}
 	fmt.Println("done")
 	errs := wq.wait()
+	solver.Close()
 	exit := 0
 	if len(errs) > 0 {
 		fmt.Fprintf(os.Stderr, "Encountered %d errors:\n", len(errs))
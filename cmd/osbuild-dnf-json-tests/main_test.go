@@ -5,6 +5,7 @@
 package main
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -118,3 +119,36 @@ func TestDepsolvePackageSets(t *testing.T) {
 		assert.True(t, ok)
 	}
 }
+
+// This test depsolves a module profile with its stream enabled and checks that
+// the packages of the stream are selected.
+func TestDepsolveEnabledModules(t *testing.T) {
+	// Load repositories from the definition we provide in the RPM package
+	repoDir := "/usr/share/tests/osbuild-composer"
+
+	cs9 := rhel.NewCentos()
+	solver := dnfjson.NewSolver(cs9.ModulePlatformID(), cs9.Releasever(), platform.ARCH_X86_64.String(), cs9.Name(), t.TempDir())
+
+	repos, err := rpmmd.LoadRepositories([]string{repoDir}, cs9.Name())
+	require.NoErrorf(t, err, "Failed to LoadRepositories %v", cs9.Name())
+	x86Repos, ok := repos[platform.ARCH_X86_64.String()]
+	require.Truef(t, ok, "failed to get %q repos for %q", platform.ARCH_X86_64.String(), cs9.Name())
+
+	res, err := solver.Depsolve([]rpmmd.PackageSet{
+		{
+			Include:        []string{"@nodejs:18/minimal"},
+			Repositories:   x86Repos,
+			EnabledModules: []rpmmd.ModuleSpec{{Name: "nodejs", Stream: "18", Profile: "minimal"}},
+		},
+	})
+	require.NoError(t, err)
+
+	found := false
+	for _, pkg := range res {
+		if pkg.Name == "nodejs" {
+			found = true
+			assert.Truef(t, strings.HasPrefix(pkg.Version, "18."), "nodejs %s is not from stream 18", pkg.Version)
+		}
+	}
+	assert.True(t, found, "nodejs is not part of the depsolved packages")
+}
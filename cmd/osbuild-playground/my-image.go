@@ -4,6 +4,7 @@ import (
 	"math/rand"
 
 	"github.com/osbuild/images/pkg/artifact"
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/platform"
@@ -39,7 +40,7 @@ func (img *MyImage) InstantiateManifest(m *manifest.Manifest,
 	}
 
 	// TODO: add helper
-	pt, err := disk.NewPartitionTable(&basePT, nil, 0, false, nil, rng)
+	pt, err := disk.NewPartitionTable(&basePT, nil, 0, blueprint.RawPartitioningMode, nil, nil, nil, rng)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,216 @@
+package dnfjson
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// resultFileSuffix is appended to the key of a result in the name of its
+// file. Keys are sha256 sums (64 hex chars) like repository IDs, so the
+// results are handled by the rpmCache like the metadata of a repository: they
+// are touched when used and removed by the size policy of CleanCache().
+const resultFileSuffix = "-dnfjson-result.json"
+
+// Time to fetch the repomd.xml of a repository
+const repomdTimeout = 30 * time.Second
+
+// diskResult is the content of a result file. The results of dnf-json
+// requests are stored in the cache directory of the solver if
+// persistent results are enabled (see BaseSolver.SetPersistentResults()), so
+// that they are shared between processes. Results are keyed by the request
+// and the current repomd.xml at every base URL of each of its repositories,
+// so a result is used until a repository changes.
+type diskResult struct {
+	Request string              `json:"request"`
+	Repomds map[string][]string `json:"repomds"`
+	Result  json.RawMessage     `json:"result"`
+}
+
+// resultKey returns the key of the result of the request and the checksums of
+// the repomd.xml at the base URLs of its repositories. Returns an error if
+// the metadata revision of a repository cannot be determined, the result of
+// the request must not be stored then. caCertPath is used for repositories
+// without their own CA certificate, empty for the system trust store.
+func resultKey(req *Request, caCertPath string) (string, map[string][]string, error) {
+	repomds := make(map[string][]string, len(req.Arguments.Repos))
+	// Request.Hash() doesn't cover the transactions of depsolve requests,
+	// the key covers the complete request instead
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", nil, err
+	}
+	h := sha256.New()
+	h.Write(data)
+	for idx := range req.Arguments.Repos {
+		repo := &req.Arguments.Repos[idx]
+		checksums, err := repomdChecksums(repo, caCertPath)
+		if err != nil {
+			return "", nil, err
+		}
+		repomds[repo.ID] = checksums
+		fmt.Fprintln(h, repo.ID, strings.Join(checksums, " "))
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), repomds, nil
+}
+
+// repomdChecksums fetches the repomd.xml at every base URL of the repository
+// and returns their checksums, which change with every update of the
+// repository metadata. dnf can use any of the base URLs, so all of them are
+// part of the key. Repositories with a metalink or mirrorlist are not
+// supported, dnf can use mirrors with other metadata than the ones checked
+// here.
+func repomdChecksums(repo *repoConfig, caCertPath string) ([]string, error) {
+	if repo.Metalink != "" || repo.MirrorList != "" {
+		return nil, fmt.Errorf("metadata revision of repository %s with a metalink or mirrorlist cannot be determined", repo.ID)
+	}
+	if len(repo.BaseURLs) == 0 {
+		return nil, fmt.Errorf("repository %s has no base URL", repo.ID)
+	}
+
+	client, err := repomdClient(repo, caCertPath)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make([]string, len(repo.BaseURLs))
+	for idx, baseURL := range repo.BaseURLs {
+		checksum, err := repomdChecksum(client, repo, baseURL)
+		if err != nil {
+			return nil, err
+		}
+		checksums[idx] = checksum
+	}
+	return checksums, nil
+}
+
+// repomdChecksum fetches the repomd.xml at the base URL and returns its
+// checksum
+func repomdChecksum(client *http.Client, repo *repoConfig, baseURL string) (string, error) {
+	repomdURL := strings.TrimSuffix(baseURL, "/") + "/repodata/repomd.xml"
+	httpReq, err := http.NewRequest(http.MethodGet, repomdURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if repo.Username != "" {
+		httpReq.SetBasicAuth(repo.Username, repo.Password)
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cannot fetch %s: %s", repomdURL, resp.Status)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
+
+// repomdClient returns an HTTP client with the TLS and proxy settings of the
+// repository
+func repomdClient(repo *repoConfig, caCertPath string) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		// #nosec G402
+		InsecureSkipVerify: repo.IgnoreSSL,
+	}
+
+	if repo.SSLCACert != "" {
+		caCertPath = repo.SSLCACert
+	}
+	if caCertPath != "" {
+		caCerts, err := os.ReadFile(caCertPath)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCerts) {
+			return nil, fmt.Errorf("no CA certificates found in %s", caCertPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if repo.SSLClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(repo.SSLClientCert, repo.SSLClientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := &http.Transport{TLSClientConfig: tlsConfig}
+	if repo.Proxy != "" {
+		proxyURL, err := url.Parse(repo.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Transport: transport, Timeout: repomdTimeout}, nil
+}
+
+func resultPath(cacheDir, key string) string {
+	return filepath.Join(cacheDir, key+resultFileSuffix)
+}
+
+// getResult returns the result with the key from the cache directory. The
+// result file is locked for reading, so that it isn't read while another
+// process writes it.
+func getResult(cacheDir, key string) ([]byte, bool) {
+	f, err := os.Open(resultPath(cacheDir, key))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH); err != nil {
+		return nil, false
+	}
+	// closing the file releases the lock
+	var result diskResult
+	if err := json.NewDecoder(f).Decode(&result); err != nil {
+		// incomplete or corrupted result, it is replaced by the next store
+		return nil, false
+	}
+	return result.Result, true
+}
+
+// storeResult writes the result with the key to the cache directory. The
+// result file is locked for writing, so that no other process reads or writes
+// it at the same time.
+func storeResult(cacheDir, key string, req *Request, repomds map[string][]string, result []byte) error {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(resultPath(cacheDir, key), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	return json.NewEncoder(f).Encode(diskResult{
+		Request: req.Hash(),
+		Repomds: repomds,
+		Result:  result,
+	})
+}
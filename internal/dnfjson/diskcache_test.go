@@ -0,0 +1,142 @@
+package dnfjson
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRepomdServer serves the repomd.xml set by the returned function for
+// the repositories "repo" and "mirror"
+func newTestRepomdServer(t *testing.T) (*httptest.Server, func(string)) {
+	repomd := "<repomd><revision>1</revision></repomd>"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repo/repodata/repomd.xml" && r.URL.Path != "/mirror/repodata/repomd.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(repomd))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func(content string) { repomd = content }
+}
+
+func TestResultKey(t *testing.T) {
+	srv, setRepomd := newTestRepomdServer(t)
+
+	req := &Request{
+		Command:   "dump",
+		Arguments: arguments{Repos: []repoConfig{{ID: "repo1", BaseURLs: []string{srv.URL + "/repo/"}}}},
+	}
+	key, repomds, err := resultKey(req, "")
+	require.NoError(t, err)
+	assert.Len(t, key, 64)
+	checksum := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("<repomd><revision>1</revision></repomd>")))
+	assert.Equal(t, map[string][]string{"repo1": {checksum}}, repomds)
+
+	sameKey, _, err := resultKey(req, "")
+	require.NoError(t, err)
+	assert.Equal(t, key, sameKey)
+
+	// a changed repository changes the key
+	setRepomd("<repomd><revision>2</revision></repomd>")
+	newKey, _, err := resultKey(req, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, key, newKey)
+
+	// results of other requests have other keys
+	req.Command = "search"
+	searchKey, _, err := resultKey(req, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, newKey, searchKey)
+
+	// depsolve requests are keyed by their transactions
+	depsolve := &Request{
+		Command: "depsolve",
+		Arguments: arguments{
+			Repos:        []repoConfig{{ID: "repo1", BaseURLs: []string{srv.URL + "/repo/"}}},
+			Transactions: []transactionArgs{{PackageSpecs: []string{"bash"}, RepoIDs: []string{"repo1"}}},
+		},
+	}
+	depsolveKey, _, err := resultKey(depsolve, "")
+	require.NoError(t, err)
+	depsolve.Arguments.Transactions[0].PackageSpecs = []string{"zsh"}
+	otherDepsolveKey, _, err := resultKey(depsolve, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, depsolveKey, otherDepsolveKey)
+
+	// every base URL is checked
+	setRepomd("<repomd><revision>1</revision></repomd>")
+	req.Arguments.Repos[0].BaseURLs = []string{srv.URL + "/repo/", srv.URL + "/mirror"}
+	mirrorKey, repomds, err := resultKey(req, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, searchKey, mirrorKey)
+	assert.Equal(t, map[string][]string{"repo1": {checksum, checksum}}, repomds)
+
+	req.Arguments.Repos[0].BaseURLs = []string{srv.URL + "/repo/", srv.URL + "/missing"}
+	_, _, err = resultKey(req, "")
+	assert.EqualError(t, err, "cannot fetch "+srv.URL+"/missing/repodata/repomd.xml: 404 Not Found")
+
+	req.Arguments.Repos[0].BaseURLs = nil
+	_, _, err = resultKey(req, "")
+	assert.EqualError(t, err, "repository repo1 has no base URL")
+
+	// the mirrors of metalink and mirrorlist repositories are unknown
+	req.Arguments.Repos[0].BaseURLs = []string{srv.URL + "/repo/"}
+	req.Arguments.Repos[0].Metalink = srv.URL + "/metalink"
+	_, _, err = resultKey(req, "")
+	assert.EqualError(t, err, "metadata revision of repository repo1 with a metalink or mirrorlist cannot be determined")
+	req.Arguments.Repos[0].Metalink = ""
+	req.Arguments.Repos[0].MirrorList = srv.URL + "/mirrorlist"
+	_, _, err = resultKey(req, "")
+	assert.EqualError(t, err, "metadata revision of repository repo1 with a metalink or mirrorlist cannot be determined")
+}
+
+func TestPersistentResults(t *testing.T) {
+	srv, setRepomd := newTestRepomdServer(t)
+	t.Setenv("DNFJSON_TEST_WORKER", "oneshot")
+	cacheDir := t.TempDir()
+	repos := []rpmmd.RepoConfig{{Name: "repo", BaseURLs: []string{srv.URL + "/repo"}}}
+
+	// every solver has its own in-memory results, like separate processes
+	fetch := func(persistent bool) string {
+		bs := NewBaseSolver(cacheDir)
+		bs.SetDNFJSONPath(os.Args[0], "-test.run=TestWorkerHelperProcess", "--")
+		bs.SetPersistentResults(persistent)
+		s := bs.NewWithConfig("platform:f38", "38", "x86_64", "fedora-38")
+		pkgs, err := s.FetchMetadata(repos)
+		require.NoError(t, err)
+		require.Len(t, pkgs, 1)
+		return pkgs[0].Version
+	}
+
+	pid := fetch(true)
+	assert.Equal(t, pid, fetch(true))
+	assert.NotEqual(t, pid, fetch(false))
+
+	results, err := filepath.Glob(filepath.Join(cacheDir, "fedora-38", "*"+resultFileSuffix))
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	// the stored result is not used after the repository changed
+	setRepomd("<repomd><revision>2</revision></repomd>")
+	newPID := fetch(true)
+	assert.NotEqual(t, pid, newPID)
+	assert.Equal(t, newPID, fetch(true))
+
+	// stored results are removed by the size policy of the cache
+	bs := NewBaseSolver(cacheDir)
+	bs.SetMaxCacheSize(0)
+	require.NoError(t, bs.CleanCache())
+	results, err = filepath.Glob(filepath.Join(cacheDir, "fedora-38", "*"+resultFileSuffix))
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
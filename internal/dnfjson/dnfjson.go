@@ -9,6 +9,10 @@
 // Solver. This type can't be used for depsolving, but can be used to create
 // configured Solver instances sharing the same cache directory.
 //
+// By default every request runs a new dnf-json process. With
+// BaseSolver.SetWorkers(), long-lived dnf-json processes in server mode handle
+// the requests instead and keep the repository metadata loaded between them.
+//
 // This package relies on the types defined in rpmmd to describe RPM package
 // metadata.
 package dnfjson
@@ -17,16 +21,22 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
+	"unicode"
+
+	"github.com/gobwas/glob"
 
 	"github.com/osbuild/images/pkg/rhsm"
 	"github.com/osbuild/images/pkg/rpmmd"
+	"golang.org/x/exp/slices"
 )
 
 // BaseSolver defines the basic solver configuration without platform
@@ -41,6 +51,17 @@ type BaseSolver struct {
 	dnfJsonCmd []string
 
 	resultCache *dnfCache
+
+	// Path to a PEM bundle of CA certificates used to verify the repository
+	// servers (default: the system trust store)
+	caCertPath string
+
+	// Persistent dnf-json workers, nil if every request runs its own
+	// dnf-json process
+	workers *workerPool
+
+	// Store the results of metadata requests in the cache directory
+	persistResults bool
 }
 
 // Create a new unconfigured BaseSolver (without platform information). It can
@@ -66,6 +87,48 @@ func (s *BaseSolver) SetDNFJSONPath(cmd string, args ...string) {
 	s.dnfJsonCmd = append([]string{cmd}, args...)
 }
 
+// SetCACertPath sets the path to a PEM bundle of CA certificates that is used
+// to verify the servers of all repositories, instead of the system trust
+// store. Repositories that come with their own CA certificate (e.g. RHSM
+// repositories) are not affected.
+func (s *BaseSolver) SetCACertPath(path string) {
+	s.caCertPath = path
+}
+
+// SetPersistentResults enables storing the results of Depsolve(),
+// FetchMetadata() and SearchMetadata() in the cache directory. Stored results
+// are shared between processes and are used until the metadata of one of their repositories changes, which is
+// checked by fetching the repomd.xml at every base URL of the repositories.
+// Results of repositories with a metalink or mirrorlist are never stored.
+// Stored results count towards the maximum cache size (see SetMaxCacheSize()).
+func (s *BaseSolver) SetPersistentResults(enabled bool) {
+	s.persistResults = enabled
+}
+
+// SetWorkers enables persistent dnf-json worker processes: up to n workers
+// per distribution and architecture are kept running and handle all
+// requests, so that repository metadata is loaded once instead of for every
+// request. If dnf-json cannot run as a worker, each request runs its own
+// dnf-json process as without workers. A value of 0 disables the workers.
+// Must be called before creating Solver instances with NewWithConfig(), and
+// the workers must be stopped with Close().
+func (s *BaseSolver) SetWorkers(n int) {
+	if s.workers != nil {
+		s.workers.close()
+		s.workers = nil
+	}
+	if n > 0 {
+		s.workers = newWorkerPool(n)
+	}
+}
+
+// Close stops all persistent dnf-json workers (see SetWorkers()).
+func (s *BaseSolver) Close() {
+	if s.workers != nil {
+		s.workers.close()
+	}
+}
+
 // NewWithConfig initialises a Solver with the platform information and the
 // BaseSolver's subscription info, cache directory, and dnf-json path.
 // Also loads system subscription information.
@@ -83,9 +146,14 @@ func (bs *BaseSolver) NewWithConfig(modulePlatformID, releaseVer, arch, distro s
 
 // CleanCache deletes the least recently used repository metadata caches until
 // the total size of the cache falls below the configured maximum size (see
-// SetMaxCacheSize()).
+// SetMaxCacheSize()). The persistent dnf-json workers are stopped as well, the
+// next requests start new ones.
 func (bs *BaseSolver) CleanCache() error {
 	bs.resultCache.CleanCache()
+	if bs.workers != nil {
+		// the workers would keep using the metadata of removed caches
+		bs.workers.reset()
+	}
 	return bs.cache.shrink()
 }
 
@@ -133,19 +201,64 @@ func (s *Solver) GetCacheDir() string {
 // their associated repositories.  Each package set is depsolved as a separate
 // transactions in a chain.  It returns a list of all packages (with solved
 // dependencies) that will be installed into the system.
+//
+// The Pins of the package sets are passed to dnf-json, which excludes all
+// other versions of the pinned packages, and are checked against the result.
+// If a pinned package is excluded, cannot be installed or is depsolved to a
+// different version, a PinError is returned that lists all failed pins.
 func (s *Solver) Depsolve(pkgSets []rpmmd.PackageSet) ([]rpmmd.PackageSpec, error) {
+	pkgs, _, _, err := s.depsolve(pkgSets, false)
+	return pkgs, err
+}
+
+// DepsolveGraph depsolves like Depsolve() and also returns the graph of the
+// requirements between the packages, which explains why each package is part
+// of the result.
+func (s *Solver) DepsolveGraph(pkgSets []rpmmd.PackageSet) ([]rpmmd.PackageSpec, *rpmmd.DependencyGraph, error) {
+	pkgs, graph, _, err := s.depsolve(pkgSets, true)
+	return pkgs, graph, err
+}
+
+// DepsolveModules depsolves like Depsolve() and also returns the state of the
+// module streams enabled by the package sets, including the profiles that are
+// installed by default, which DNF records in /etc/dnf/modules.d. The state is
+// only reported if any package set enables module streams.
+func (s *Solver) DepsolveModules(pkgSets []rpmmd.PackageSet) ([]rpmmd.PackageSpec, []rpmmd.ModuleState, error) {
+	pkgs, _, modules, err := s.depsolve(pkgSets, false)
+	return pkgs, modules, err
+}
+
+func (s *Solver) depsolve(pkgSets []rpmmd.PackageSet, withGraph bool) ([]rpmmd.PackageSpec, *rpmmd.DependencyGraph, []rpmmd.ModuleState, error) {
 	req, repoMap, err := s.makeDepsolveRequest(pkgSets)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
+	}
+	req.Arguments.Graph = withGraph
+	for _, ps := range pkgSets {
+		if len(ps.EnabledModules) > 0 {
+			req.Arguments.Modules = true
+		}
+	}
+
+	if err := checkExcludedPins(pkgSets); err != nil {
+		return nil, nil, nil, err
 	}
 
 	// get non-exclusive read lock
 	s.cache.locker.RLock()
 	defer s.cache.locker.RUnlock()
 
-	output, err := run(s.dnfJsonCmd, req)
+	output, err := s.runPersistent(req)
 	if err != nil {
-		return nil, err
+		if dnfErr, ok := err.(Error); ok {
+			if pinErr := explainPinsError(pkgSets, dnfErr); pinErr != nil {
+				return nil, nil, nil, pinErr
+			}
+			if modulesErr := explainModulesError(pkgSets, dnfErr); modulesErr != nil {
+				return nil, nil, nil, modulesErr
+			}
+		}
+		return nil, nil, nil, err
 	}
 	// touch repos to now
 	now := time.Now().Local()
@@ -155,59 +268,215 @@ func (s *Solver) Depsolve(pkgSets []rpmmd.PackageSet) ([]rpmmd.PackageSpec, erro
 	}
 	s.cache.updateInfo()
 
-	var result packageSpecs
-	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, err
+	var result depsolveResult
+	if req.Arguments.Graph || req.Arguments.Modules {
+		err = json.Unmarshal(output, &result)
+	} else {
+		err = json.Unmarshal(output, &result.Packages)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	pkgs := result.Packages.toRPMMD(repoMap)
+	if err := checkPins(pkgSets, pkgs); err != nil {
+		return nil, nil, nil, err
 	}
 
-	return result.toRPMMD(repoMap), nil
+	return pkgs, result.Graph, result.Modules, nil
 }
 
-// FetchMetadata returns the list of all the available packages in repos and
-// their info.
-func (s *Solver) FetchMetadata(repos []rpmmd.RepoConfig) (rpmmd.PackageList, error) {
-	req, err := s.makeDumpRequest(repos)
-	if err != nil {
-		return nil, err
+// PinFailure describes why a package pin could not be satisfied
+type PinFailure struct {
+	Pin    rpmmd.PackagePin
+	Reason string
+}
+
+// PinError is returned by Depsolve if any of the package pins could not be
+// satisfied
+type PinError struct {
+	Failures []PinFailure
+
+	// the error of dnf-json, if any
+	Err error
+}
+
+func (e PinError) Error() string {
+	failures := make([]string, len(e.Failures))
+	for idx, f := range e.Failures {
+		failures[idx] = fmt.Sprintf("%s: %s", f.Pin, f.Reason)
+	}
+	msg := fmt.Sprintf("package pins cannot be satisfied: %s", strings.Join(failures, "; "))
+	if e.Err != nil {
+		msg += fmt.Sprintf(" (%s)", e.Err)
 	}
+	return msg
+}
 
-	// get non-exclusive read lock
-	s.cache.locker.RLock()
-	defer s.cache.locker.RUnlock()
+func (e PinError) Unwrap() error {
+	return e.Err
+}
 
-	// Is this cached?
-	if pkgs, ok := s.resultCache.Get(req.Hash()); ok {
-		return pkgs, nil
+// checkExcludedPins returns a PinError for all pins that are excluded by
+// their own or a later package set of the chain, since dnf would only report
+// them as missing.
+func checkExcludedPins(pkgSets []rpmmd.PackageSet) error {
+	var failures []PinFailure
+	for idx, ps := range pkgSets {
+		for _, pin := range ps.Pins {
+			if exclude := findExclude(pkgSets[idx:], pin); exclude != "" {
+				failures = append(failures, PinFailure{Pin: pin, Reason: fmt.Sprintf("excluded by %q", exclude)})
+			}
+		}
+	}
+	if len(failures) > 0 {
+		return PinError{Failures: failures}
 	}
+	return nil
+}
 
-	result, err := run(s.dnfJsonCmd, req)
-	if err != nil {
-		return nil, err
+func findExclude(pkgSets []rpmmd.PackageSet, pin rpmmd.PackagePin) string {
+	for _, ps := range pkgSets {
+		for _, exclude := range ps.Exclude {
+			g, err := glob.Compile(exclude)
+			if err != nil {
+				continue
+			}
+			if g.Match(pin.Name) || g.Match(pin.String()) {
+				return exclude
+			}
+		}
 	}
+	return ""
+}
 
-	// touch repos to now
-	now := time.Now().Local()
-	for _, r := range repos {
-		// ignore errors
-		_ = s.cache.touchRepo(r.Hash(), now)
+// explainPinsError returns a PinError for the pins that are named by the
+// dnf-json error, or nil if the error is not related to any pin.
+func explainPinsError(pkgSets []rpmmd.PackageSet, dnfErr Error) error {
+	var failures []PinFailure
+	for _, ps := range pkgSets {
+		for _, pin := range ps.Pins {
+			switch {
+			case dnfErr.Kind == "MarkingErrors" && reasonMentions(dnfErr.Reason, func(token string) bool { return tokenIsPin(token, pin) }):
+				failures = append(failures, PinFailure{Pin: pin, Reason: "not available in the enabled repositories"})
+			case dnfErr.Kind == "DepsolveError" && reasonMentions(dnfErr.Reason, func(token string) bool { return tokenIsPackage(token, pin.Name) }):
+				failures = append(failures, PinFailure{Pin: pin, Reason: "conflicts with the dependencies of other packages"})
+			}
+		}
 	}
-	s.cache.updateInfo()
+	if len(failures) > 0 {
+		return PinError{Failures: failures, Err: dnfErr}
+	}
+	return nil
+}
 
-	var pkgs rpmmd.PackageList
-	if err := json.Unmarshal(result, &pkgs); err != nil {
-		return nil, err
+// reasonMentions returns true if match returns true for any of the words of
+// a dnf-json error reason
+func reasonMentions(reason string, match func(token string) bool) bool {
+	tokens := strings.FieldsFunc(reason, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`,;()[]{}'"`, r)
+	})
+	for _, token := range tokens {
+		// the token may end a sentence
+		if match(strings.TrimSuffix(token, ".")) {
+			return true
+		}
 	}
+	return false
+}
 
-	sortID := func(pkg rpmmd.Package) string {
-		return fmt.Sprintf("%s-%s-%s", pkg.Name, pkg.Version, pkg.Release)
+// tokenIsPackage returns true if the token is the name of the package or a
+// "name-[epoch:]version-release[.arch]" of it. Other packages whose names
+// start with the name, e.g. "kernel-core" for "kernel", do not match.
+func tokenIsPackage(token, name string) bool {
+	if token == name {
+		return true
 	}
-	sort.Slice(pkgs, func(i, j int) bool {
-		return sortID(pkgs[i]) < sortID(pkgs[j])
-	})
+	if !strings.HasPrefix(token, name+"-") {
+		return false
+	}
+	evra := strings.TrimPrefix(token, name+"-")
+	idx := strings.Index(evra, "-")
+	return idx > 0 && idx < len(evra)-1 && strings.Count(evra, "-") == 1 && evra[0] >= '0' && evra[0] <= '9'
+}
 
-	// Cache the results
-	s.resultCache.Store(req.Hash(), pkgs)
-	return pkgs, nil
+// tokenIsPin returns true if the token is the package spec of the pin or, if
+// the pin has no architecture, the package spec followed by an architecture.
+func tokenIsPin(token string, pin rpmmd.PackagePin) bool {
+	spec := pin.String()
+	if token == spec {
+		return true
+	}
+	if pin.Arch != "" || !strings.HasPrefix(token, spec+".") {
+		return false
+	}
+	arch := strings.TrimPrefix(token, spec+".")
+	return arch != "" && arch[0] >= 'a' && arch[0] <= 'z' && !strings.Contains(arch, ".")
+}
+
+// explainModulesError returns an error that names the enabled module streams
+// if the module streams cannot be enabled or the dnf-json error is otherwise
+// caused by modularity, or nil if the error is not related to modules.
+func explainModulesError(pkgSets []rpmmd.PackageSet, dnfErr Error) error {
+	var streams []string
+	related := dnfErr.Kind == "ModuleError" || strings.Contains(strings.ToLower(dnfErr.Reason), "modul")
+	for _, ps := range pkgSets {
+		for _, module := range ps.EnabledModules {
+			stream := module.Name + ":" + module.Stream
+			if !slices.Contains(streams, stream) {
+				streams = append(streams, stream)
+			}
+			related = related || strings.Contains(dnfErr.Reason, module.Name+":")
+		}
+	}
+	if len(streams) == 0 || !related {
+		return nil
+	}
+	return fmt.Errorf("module streams %s cannot be enabled together with the requested packages: %w", strings.Join(streams, ", "), dnfErr)
+}
+
+// checkPins returns a PinError for all pins that are not satisfied by the
+// depsolved packages
+func checkPins(pkgSets []rpmmd.PackageSet, pkgs []rpmmd.PackageSpec) error {
+	var failures []PinFailure
+	for _, ps := range pkgSets {
+		for _, pin := range ps.Pins {
+			var candidates []string
+			found := false
+			for _, pkg := range pkgs {
+				if pkg.Name != pin.Name {
+					continue
+				}
+				if pin.Matches(pkg) {
+					found = true
+					break
+				}
+				candidates = append(candidates, pkg.GetNEVRA())
+			}
+			if found {
+				continue
+			}
+			reason := "not part of the depsolved packages"
+			if len(candidates) > 0 {
+				reason = fmt.Sprintf("depsolved to %s instead", strings.Join(candidates, ", "))
+			}
+			failures = append(failures, PinFailure{Pin: pin, Reason: reason})
+		}
+	}
+	if len(failures) > 0 {
+		return PinError{Failures: failures}
+	}
+	return nil
+}
+
+// FetchMetadata returns the list of all the available packages in repos and
+// their info.
+func (s *Solver) FetchMetadata(repos []rpmmd.RepoConfig) (rpmmd.PackageList, error) {
+	req, err := s.makeDumpRequest(repos)
+	if err != nil {
+		return nil, err
+	}
+	return s.queryMetadata(req)
 }
 
 // SearchMetadata searches for packages and returns a list of the info for matches.
@@ -216,7 +485,12 @@ func (s *Solver) SearchMetadata(repos []rpmmd.RepoConfig, packages []string) (rp
 	if err != nil {
 		return nil, err
 	}
+	return s.queryMetadata(req)
+}
 
+// queryMetadata returns the result of a metadata request from the result
+// caches, or runs the request and caches its result
+func (s *Solver) queryMetadata(req *Request) (rpmmd.PackageList, error) {
 	// get non-exclusive read lock
 	s.cache.locker.RLock()
 	defer s.cache.locker.RUnlock()
@@ -226,19 +500,11 @@ func (s *Solver) SearchMetadata(repos []rpmmd.RepoConfig, packages []string) (rp
 		return pkgs, nil
 	}
 
-	result, err := run(s.dnfJsonCmd, req)
+	result, err := s.runPersistent(req)
 	if err != nil {
 		return nil, err
 	}
 
-	// touch repos to now
-	now := time.Now().Local()
-	for _, r := range repos {
-		// ignore errors
-		_ = s.cache.touchRepo(r.Hash(), now)
-	}
-	s.cache.updateInfo()
-
 	var pkgs rpmmd.PackageList
 	if err := json.Unmarshal(result, &pkgs); err != nil {
 		return nil, err
@@ -253,9 +519,55 @@ func (s *Solver) SearchMetadata(repos []rpmmd.RepoConfig, packages []string) (rp
 
 	// Cache the results
 	s.resultCache.Store(req.Hash(), pkgs)
+
+	// touch repos to now
+	now := time.Now().Local()
+	for _, r := range req.Arguments.Repos {
+		// ignore errors
+		_ = s.cache.touchRepo(r.ID, now)
+	}
+	s.cache.updateInfo()
+
 	return pkgs, nil
 }
 
+// runPersistent runs the request like run(), but returns the stored result of
+// the request instead if persistent results are enabled and the repositories
+// didn't change since it was stored
+func (s *Solver) runPersistent(req *Request) ([]byte, error) {
+	var key string
+	var repomds map[string][]string
+	if s.persistResults {
+		var err error
+		// the result isn't stored if the repositories can't be checked for
+		// changes
+		key, repomds, err = resultKey(req, s.caCertPath)
+		if err != nil {
+			key = ""
+		}
+	}
+
+	if key != "" {
+		if result, ok := getResult(s.GetCacheDir(), key); ok {
+			// ignore errors
+			_ = s.cache.touchRepo(key, time.Now().Local())
+			return result, nil
+		}
+	}
+
+	result, err := s.run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if key != "" {
+		// a result that can't be stored is run again next time
+		_ = storeResult(s.GetCacheDir(), key, req, repomds, result)
+		_ = s.cache.touchRepo(key, time.Now().Local())
+	}
+	return result, nil
+}
+
 func (s *Solver) reposFromRPMMD(rpmRepos []rpmmd.RepoConfig) ([]repoConfig, error) {
 	dnfRepos := make([]repoConfig, len(rpmRepos))
 	for idx, rr := range rpmRepos {
@@ -282,6 +594,10 @@ func (s *Solver) reposFromRPMMD(rpmRepos []rpmmd.RepoConfig) ([]repoConfig, erro
 			dr.IgnoreSSL = *rr.IgnoreSSL
 		}
 
+		if rr.RHSM && (rr.UsesCustomSecrets() || rr.SSLCACert != "") {
+			return nil, fmt.Errorf("RHSM repositories cannot use custom certificates or credentials: %s", rr.BaseURLs)
+		}
+
 		if rr.RHSM {
 			if s.subscriptions == nil {
 				return nil, fmt.Errorf("This system does not have any valid subscriptions. Subscribe it before specifying rhsm: true in sources.")
@@ -294,6 +610,45 @@ func (s *Solver) reposFromRPMMD(rpmRepos []rpmmd.RepoConfig) ([]repoConfig, erro
 			dr.SSLClientKey = secrets.SSLClientKey
 			dr.SSLClientCert = secrets.SSLClientCert
 		}
+
+		if rr.SSLClientCert != "" || rr.SSLClientKey != "" {
+			if rr.SSLClientCert == "" || rr.SSLClientKey == "" {
+				return nil, fmt.Errorf("repository client certificate and key must be set together: %s", rr.BaseURLs)
+			}
+			dr.SSLClientCert = rr.SSLClientCert
+			dr.SSLClientKey = rr.SSLClientKey
+		}
+
+		if rr.Username != "" || rr.Password != "" {
+			if rr.Username == "" || rr.Password == "" {
+				return nil, fmt.Errorf("repository username and password must be set together: %s", rr.BaseURLs)
+			}
+			var err error
+			if dr.Username, err = rpmmd.ResolveSecretRef(rr.Username); err != nil {
+				return nil, fmt.Errorf("cannot resolve repository username: %w", err)
+			}
+			if dr.Password, err = rpmmd.ResolveSecretRef(rr.Password); err != nil {
+				return nil, fmt.Errorf("cannot resolve repository password: %w", err)
+			}
+		}
+
+		if rr.Proxy != "" {
+			proxy, err := url.Parse(rr.Proxy)
+			if err != nil || proxy.Scheme == "" || proxy.Host == "" {
+				return nil, fmt.Errorf("repository proxy %q is not a valid URL", rr.Proxy)
+			}
+			// credentials are only accepted as secret references
+			if proxy.User != nil {
+				return nil, fmt.Errorf("repository proxy %q must not contain credentials", proxy.Redacted())
+			}
+			dr.Proxy = rr.Proxy
+		}
+
+		if rr.SSLCACert != "" {
+			dr.SSLCACert = rr.SSLCACert
+		} else if dr.SSLCACert == "" {
+			dr.SSLCACert = s.caCertPath
+		}
 		dnfRepos[idx] = dr
 	}
 	return dnfRepos, nil
@@ -314,6 +669,9 @@ type repoConfig struct {
 	SSLCACert      string   `json:"sslcacert,omitempty"`
 	SSLClientKey   string   `json:"sslclientkey,omitempty"`
 	SSLClientCert  string   `json:"sslclientcert,omitempty"`
+	Username       string   `json:"username,omitempty"`
+	Password       string   `json:"password,omitempty"`
+	Proxy          string   `json:"proxy,omitempty"`
 	MetadataExpire string   `json:"metadata_expire,omitempty"`
 	// set the repo hass from `rpmmd.RepoConfig.Hash()` function
 	// rather than re-calculating it
@@ -356,6 +714,7 @@ func (s *Solver) makeDepsolveRequest(pkgSets []rpmmd.PackageSet) (*Request, map[
 	}
 
 	transactions := make([]transactionArgs, len(pkgSets))
+	moduleStreams := make(map[string]string)
 	for dsIdx, pkgSet := range pkgSets {
 		transactions[dsIdx] = transactionArgs{
 			PackageSpecs:    pkgSet.Include,
@@ -363,6 +722,29 @@ func (s *Solver) makeDepsolveRequest(pkgSets []rpmmd.PackageSet) (*Request, map[
 			InstallWeakDeps: pkgSet.InstallWeakDeps,
 		}
 
+		for _, module := range pkgSet.EnabledModules {
+			if stream, ok := moduleStreams[module.Name]; ok && stream != module.Stream {
+				return nil, nil, fmt.Errorf("conflicting streams for module %q: %s and %s", module.Name, stream, module.Stream)
+			}
+			moduleStreams[module.Name] = module.Stream
+			transactions[dsIdx].ModuleEnableSpecs = append(transactions[dsIdx].ModuleEnableSpecs, module.Name+":"+module.Stream)
+		}
+
+		// the pins of a transaction also constrain the following ones, like
+		// the packages it installs
+		if dsIdx > 0 {
+			transactions[dsIdx].Pins = append(transactions[dsIdx].Pins, transactions[dsIdx-1].Pins...)
+		}
+		for _, pin := range pkgSet.Pins {
+			transactions[dsIdx].Pins = append(transactions[dsIdx].Pins, pinArgs{
+				Name:    pin.Name,
+				Epoch:   pin.Epoch,
+				Version: pin.Version,
+				Release: pin.Release,
+				Arch:    pin.Arch,
+			})
+		}
+
 		for _, jobRepo := range pkgSet.Repositories {
 			transactions[dsIdx].RepoIDs = append(transactions[dsIdx].RepoIDs, jobRepo.Hash())
 		}
@@ -518,6 +900,12 @@ type arguments struct {
 
 	// Depsolve package sets and repository mappings for this request
 	Transactions []transactionArgs `json:"transactions"`
+
+	// Return the dependency graph of the depsolved packages as well
+	Graph bool `json:"graph,omitempty"`
+
+	// Return the state of the enabled module streams as well
+	Modules bool `json:"modules,omitempty"`
 }
 
 type searchArgs struct {
@@ -542,10 +930,33 @@ type transactionArgs struct {
 
 	// If we want weak deps for this depsolve
 	InstallWeakDeps bool `json:"install_weak_deps"`
+
+	// Module streams to enable before depsolving, as name:stream
+	ModuleEnableSpecs []string `json:"module-enable-specs,omitempty"`
+
+	// Pinned packages, all other versions of them are excluded so that the
+	// pins also apply to packages that are only installed as dependencies
+	Pins []pinArgs `json:"pins,omitempty"`
+}
+
+type pinArgs struct {
+	Name    string `json:"name"`
+	Epoch   uint   `json:"epoch"`
+	Version string `json:"version"`
+	Release string `json:"release"`
+	Arch    string `json:"arch,omitempty"`
 }
 
 type packageSpecs []PackageSpec
 
+// depsolveResult is the result of a depsolve request with a graph or module
+// states
+type depsolveResult struct {
+	Packages packageSpecs           `json:"packages"`
+	Graph    *rpmmd.DependencyGraph `json:"graph"`
+	Modules  []rpmmd.ModuleState    `json:"modules"`
+}
+
 // Package specification
 type PackageSpec struct {
 	Name           string `json:"name"`
@@ -615,6 +1026,18 @@ func ParseError(data []byte) Error {
 	return e
 }
 
+// run handles the request with a persistent worker if workers are enabled and
+// supported, and otherwise with a new dnf-json process
+func (s *Solver) run(req *Request) ([]byte, error) {
+	if s.workers != nil {
+		output, err := s.workers.run(s.distro+"/"+s.arch, s.dnfJsonCmd, req)
+		if !errors.Is(err, errServerModeUnsupported) {
+			return output, err
+		}
+	}
+	return run(s.dnfJsonCmd, req)
+}
+
 func run(dnfJsonCmd []string, req *Request) ([]byte, error) {
 	if len(dnfJsonCmd) == 0 {
 		return nil, fmt.Errorf("dnf-json command undefined")
@@ -0,0 +1,440 @@
+package dnfjson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	// Time a new worker has to answer the first ping. A worker that doesn't
+	// answer in time is assumed to not support the server mode.
+	workerStartTimeout = 30 * time.Second
+
+	// Idle time after which a worker is pinged before it is used again
+	workerHealthCheckInterval = 30 * time.Second
+
+	// Time a worker has to answer a health check ping
+	workerHealthCheckTimeout = 10 * time.Second
+
+	// Time after which starting a worker is attempted again when the first
+	// worker for a distro and architecture failed to start. The backoff
+	// doubles with every consecutive failure.
+	workerStartMinBackoff = 1 * time.Minute
+	workerStartMaxBackoff = 30 * time.Minute
+
+	// Number of times a request is retried with a new worker when its worker
+	// dies while handling it
+	workerRetries = 1
+)
+
+// errServerModeUnsupported is returned by the worker pool when the dnf-json
+// command cannot be run in server mode
+var errServerModeUnsupported = errors.New("dnf-json does not support the server mode")
+
+// errWorkerPoolClosed is returned by the worker pool after it was closed
+var errWorkerPoolClosed = errors.New("dnf-json worker pool closed")
+
+// workerRequest is a request line sent to a dnf-json worker in server mode
+type workerRequest struct {
+	ID      uint64   `json:"id"`
+	Request *Request `json:"request"`
+}
+
+// workerResponse is a response line of a dnf-json worker in server mode,
+// with either a Result or an Error
+type workerResponse struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  json.RawMessage `json:"error,omitempty"`
+}
+
+// worker is a long-lived dnf-json process in server mode. It reads one
+// request per line from stdin and writes one response per line to stdout.
+// Requests are handled one after the other, but any number of them can be
+// sent at the same time; responses are dispatched to the callers by request
+// ID.
+type worker struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	enc   *json.Encoder
+
+	// guards the fields below and writes to stdin
+	mu       sync.Mutex
+	nextID   uint64
+	pending  map[uint64]chan workerResponse
+	lastUsed time.Time
+
+	// set when the worker died, all further calls fail with it
+	err error
+}
+
+// startWorker starts a dnf-json worker in server mode and waits for it to
+// answer a ping
+func startWorker(dnfJsonCmd []string) (*worker, error) {
+	if len(dnfJsonCmd) == 0 {
+		return nil, fmt.Errorf("dnf-json command undefined")
+	}
+	args := append(append([]string{}, dnfJsonCmd[1:]...), "--server")
+	cmd := exec.Command(dnfJsonCmd[0], args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	w := &worker{
+		cmd:      cmd,
+		stdin:    stdin,
+		enc:      json.NewEncoder(stdin),
+		pending:  make(map[uint64]chan workerResponse),
+		lastUsed: time.Now(),
+	}
+	go w.readResponses(stdout)
+
+	if err := w.ping(workerStartTimeout); err != nil {
+		w.close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// readResponses dispatches the responses of the worker until its stdout is
+// closed, i.e. until the worker exits
+func (w *worker) readResponses(stdout io.Reader) {
+	dec := json.NewDecoder(stdout)
+	for {
+		var resp workerResponse
+		if err := dec.Decode(&resp); err != nil {
+			if err == io.EOF {
+				err = fmt.Errorf("dnf-json worker exited")
+			}
+			w.fail(err)
+			// reap the process
+			_ = w.cmd.Wait()
+			return
+		}
+
+		w.mu.Lock()
+		ch, ok := w.pending[resp.ID]
+		delete(w.pending, resp.ID)
+		w.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+}
+
+// fail marks the worker as dead and fails all pending calls
+func (w *worker) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+	for id, ch := range w.pending {
+		close(ch)
+		delete(w.pending, id)
+	}
+}
+
+// call sends the request to the worker and waits for its response. A timeout
+// of 0 waits forever. Returns an error only if the worker failed, errors of
+// dnf-json are part of the response.
+func (w *worker) call(req *Request, timeout time.Duration) (workerResponse, error) {
+	ch := make(chan workerResponse, 1)
+
+	w.mu.Lock()
+	if w.err != nil {
+		w.mu.Unlock()
+		return workerResponse{}, w.err
+	}
+	w.nextID++
+	id := w.nextID
+	w.pending[id] = ch
+	w.lastUsed = time.Now()
+	err := w.enc.Encode(workerRequest{ID: id, Request: req})
+	w.mu.Unlock()
+	if err != nil {
+		w.kill(fmt.Errorf("cannot send request to dnf-json worker: %w", err))
+		return workerResponse{}, w.error()
+	}
+
+	var timer <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return workerResponse{}, w.error()
+		}
+		return resp, nil
+	case <-timer:
+		w.kill(fmt.Errorf("dnf-json worker did not respond within %s", timeout))
+		return workerResponse{}, w.error()
+	}
+}
+
+// ping checks that the worker responds within the timeout
+func (w *worker) ping(timeout time.Duration) error {
+	resp, err := w.call(&Request{Command: "ping"}, timeout)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return ParseError(resp.Error)
+	}
+	return nil
+}
+
+func (w *worker) error() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// alive returns true if the worker hasn't failed yet
+func (w *worker) alive() bool {
+	return w.error() == nil
+}
+
+// load returns the number of requests the worker hasn't answered yet
+func (w *worker) load() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.pending)
+}
+
+// needsHealthCheck returns true if the worker has been idle for so long that
+// it should be pinged before it is used again
+func (w *worker) needsHealthCheck() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.pending) == 0 && time.Since(w.lastUsed) > workerHealthCheckInterval
+}
+
+// kill stops the worker immediately and fails all pending calls with err
+func (w *worker) kill(err error) {
+	w.fail(err)
+	if w.cmd.Process != nil {
+		_ = w.cmd.Process.Kill()
+	}
+}
+
+// close asks the worker to exit by closing its stdin
+func (w *worker) close() {
+	w.fail(fmt.Errorf("dnf-json worker closed"))
+	_ = w.stdin.Close()
+}
+
+// drain asks the worker to exit after it answered the pending requests, new
+// requests fail
+func (w *worker) drain() {
+	w.mu.Lock()
+	if w.err == nil {
+		w.err = fmt.Errorf("dnf-json worker closed")
+	}
+	w.mu.Unlock()
+	// nothing is sent to the worker anymore once err is set
+	_ = w.stdin.Close()
+}
+
+// workerPool manages the dnf-json workers of a BaseSolver. It keeps up to
+// size workers for each distro and architecture, so that the repository
+// metadata loaded by a worker is likely to be reused by the next request.
+type workerPool struct {
+	size int
+
+	// guards keys and the fields of all workerSets, but is not held while a
+	// worker starts
+	mu sync.Mutex
+	// signalled whenever a worker finished starting
+	started *sync.Cond
+	keys    map[string]*workerSet
+	closed  bool
+}
+
+// workerSet is the state of the workers of a single key
+type workerSet struct {
+	workers []*worker
+
+	// number of workers that are currently starting
+	starting int
+
+	// number of consecutive failures to start the first worker; until
+	// retryAt, requests fall back to the one-shot mode
+	failures int
+	retryAt  time.Time
+}
+
+func newWorkerPool(size int) *workerPool {
+	p := &workerPool{
+		size: size,
+		keys: make(map[string]*workerSet),
+	}
+	p.started = sync.NewCond(&p.mu)
+	return p
+}
+
+// get returns a worker for the key, starting a new one if the pool isn't full
+// yet and otherwise choosing the worker with the fewest pending requests
+func (p *workerPool) get(key string, dnfJsonCmd []string) (*worker, error) {
+	p.mu.Lock()
+	set := p.keys[key]
+	if set == nil {
+		set = &workerSet{}
+		p.keys[key] = set
+	}
+
+	for {
+		if p.closed {
+			p.mu.Unlock()
+			return nil, errWorkerPoolClosed
+		}
+		if time.Now().Before(set.retryAt) {
+			p.mu.Unlock()
+			return nil, errServerModeUnsupported
+		}
+
+		// drop dead workers, they are replaced below
+		workers := set.workers[:0]
+		for _, w := range set.workers {
+			if w.alive() {
+				workers = append(workers, w)
+			}
+		}
+		set.workers = workers
+
+		var best *worker
+		for _, w := range workers {
+			if best == nil || w.load() < best.load() {
+				best = w
+			}
+		}
+		full := len(workers)+set.starting >= p.size
+		if best != nil && (best.load() == 0 || full) {
+			p.mu.Unlock()
+			return best, nil
+		}
+		if !full {
+			break
+		}
+		// all remaining slots are taken by workers that are still starting
+		p.started.Wait()
+	}
+
+	set.starting++
+	p.mu.Unlock()
+
+	w, err := startWorker(dnfJsonCmd)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.started.Broadcast()
+	set.starting--
+	if p.closed {
+		if w != nil {
+			w.close()
+		}
+		return nil, errWorkerPoolClosed
+	}
+	if err != nil {
+		if len(set.workers) == 0 {
+			// dnf-json probably doesn't support the server mode, don't try
+			// again for this key until the backoff expired
+			set.failures++
+			set.retryAt = time.Now().Add(workerStartBackoff(set.failures))
+			return nil, fmt.Errorf("%w: %s", errServerModeUnsupported, err.Error())
+		}
+		return nil, err
+	}
+	set.failures = 0
+	set.workers = append(set.workers, w)
+	return w, nil
+}
+
+// workerStartBackoff returns the time to wait before starting a worker again
+// after the given number of consecutive failures
+func workerStartBackoff(failures int) time.Duration {
+	backoff := workerStartMinBackoff
+	for i := 1; i < failures && backoff < workerStartMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > workerStartMaxBackoff {
+		backoff = workerStartMaxBackoff
+	}
+	return backoff
+}
+
+// run handles the request with a worker for the key. Workers that fail a
+// health check or die while handling the request are replaced by new ones.
+func (p *workerPool) run(key string, dnfJsonCmd []string, req *Request) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		w, err := p.get(key, dnfJsonCmd)
+		if err != nil {
+			return nil, err
+		}
+
+		if w.needsHealthCheck() {
+			if err := w.ping(workerHealthCheckTimeout); err != nil {
+				w.kill(err)
+				continue
+			}
+		}
+
+		resp, err := w.call(req, 0)
+		if err != nil {
+			if attempt < workerRetries {
+				continue
+			}
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, parseError(resp.Error, req.Arguments.Repos)
+		}
+		return resp.Result, nil
+	}
+}
+
+// reset stops all workers of the pool, the next requests start new ones.
+// Requests that were already sent to a worker are still answered by it.
+func (p *workerPool) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, set := range p.keys {
+		for _, w := range set.workers {
+			w.drain()
+		}
+		set.workers = nil
+	}
+}
+
+// close stops all workers of the pool
+func (p *workerPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, set := range p.keys {
+		for _, w := range set.workers {
+			w.close()
+		}
+		delete(p.keys, key)
+	}
+	p.closed = true
+	p.started.Broadcast()
+}
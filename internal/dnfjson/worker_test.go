@@ -0,0 +1,280 @@
+package dnfjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWorkerHelperProcess is not a real test, it is run as a fake dnf-json by
+// the worker tests. Without --server it handles a single request, with
+// --server it serves requests until stdin is closed. Every result is the PID
+// of the process, so that the tests can tell which process handled a
+// request. Metadata requests and depsolve requests with transactions return a
+// package with the PID as its version, and, if requested, the enabled module
+// streams with a "default" profile.
+// The command "crash" makes the process exit.
+func TestWorkerHelperProcess(t *testing.T) {
+	mode := os.Getenv("DNFJSON_TEST_WORKER")
+	if mode == "" {
+		return
+	}
+	defer os.Exit(0)
+
+	server := os.Args[len(os.Args)-1] == "--server"
+	if server && mode == "oneshot" {
+		os.Exit(1)
+	}
+
+	handle := func(req *Request) (interface{}, *Error) {
+		switch req.Command {
+		case "ping":
+			return "pong", nil
+		case "crash":
+			os.Exit(2)
+		case "fail":
+			return nil, &Error{Kind: "DepsolveError", Reason: fmt.Sprintf("cannot depsolve in repo '%s'", req.Arguments.Repos[0].ID)}
+		case "depsolve":
+			if len(req.Arguments.Transactions) > 0 {
+				pkgs := packageSpecs{{Name: "pkg", Version: strconv.Itoa(os.Getpid()), Release: "1", RepoID: req.Arguments.Repos[0].ID}}
+				if !req.Arguments.Modules {
+					return pkgs, nil
+				}
+				result := depsolveResult{Packages: pkgs}
+				for _, transaction := range req.Arguments.Transactions {
+					for _, spec := range transaction.ModuleEnableSpecs {
+						module, err := rpmmd.ParseModuleSpec(spec)
+						if err != nil {
+							os.Exit(1)
+						}
+						result.Modules = append(result.Modules, rpmmd.ModuleState{Name: module.Name, Stream: module.Stream, Profiles: []string{"default"}})
+					}
+				}
+				return result, nil
+			}
+		case "dump", "search":
+			return rpmmd.PackageList{{Name: "pkg", Version: strconv.Itoa(os.Getpid()), Release: "1"}}, nil
+		}
+		return os.Getpid(), nil
+	}
+
+	if !server {
+		var req Request
+		if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+			os.Exit(1)
+		}
+		result, dnfErr := handle(&req)
+		if dnfErr != nil {
+			_ = json.NewEncoder(os.Stdout).Encode(dnfErr)
+			os.Exit(1)
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(result)
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	enc := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var wreq workerRequest
+		if err := json.Unmarshal(scanner.Bytes(), &wreq); err != nil {
+			os.Exit(1)
+		}
+		resp := map[string]interface{}{"id": wreq.ID}
+		result, dnfErr := handle(wreq.Request)
+		if dnfErr != nil {
+			resp["error"] = dnfErr
+		} else {
+			resp["result"] = result
+		}
+		_ = enc.Encode(resp)
+	}
+}
+
+func newTestWorkerSolver(t *testing.T, mode string, workers int) *Solver {
+	t.Setenv("DNFJSON_TEST_WORKER", mode)
+	bs := NewBaseSolver(t.TempDir())
+	bs.SetDNFJSONPath(os.Args[0], "-test.run=TestWorkerHelperProcess", "--")
+	bs.SetWorkers(workers)
+	t.Cleanup(bs.Close)
+	return bs.NewWithConfig("platform:f38", "38", "x86_64", "fedora-38")
+}
+
+func runPID(t *testing.T, s *Solver, command string) int {
+	output, err := s.run(&Request{Command: command})
+	require.NoError(t, err)
+	var pid int
+	require.NoError(t, json.Unmarshal(output, &pid))
+	return pid
+}
+
+// runMetadataPID runs a metadata request and returns the PID reported as the
+// version of its package
+func runMetadataPID(t *testing.T, s *Solver, command string) int {
+	output, err := s.run(&Request{Command: command})
+	require.NoError(t, err)
+	var pkgs rpmmd.PackageList
+	require.NoError(t, json.Unmarshal(output, &pkgs))
+	require.Len(t, pkgs, 1)
+	pid, err := strconv.Atoi(pkgs[0].Version)
+	require.NoError(t, err)
+	return pid
+}
+
+func TestWorkerReuse(t *testing.T) {
+	s := newTestWorkerSolver(t, "server", 1)
+
+	pid := runPID(t, s, "depsolve")
+	assert.NotEqual(t, os.Getpid(), pid)
+	assert.Equal(t, pid, runMetadataPID(t, s, "dump"))
+	assert.Equal(t, pid, runMetadataPID(t, s, "search"))
+
+	// solvers for another architecture use another worker
+	other := s.BaseSolver.NewWithConfig("platform:f38", "38", "aarch64", "fedora-38")
+	assert.NotEqual(t, pid, runPID(t, other, "depsolve"))
+}
+
+func TestWorkerMultiplexing(t *testing.T) {
+	s := newTestWorkerSolver(t, "server", 2)
+
+	var wg sync.WaitGroup
+	pids := make([]int, 20)
+	for idx := range pids {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			output, err := s.run(&Request{Command: "depsolve"})
+			assert.NoError(t, err)
+			assert.NoError(t, json.Unmarshal(output, &pids[idx]))
+		}(idx)
+	}
+	wg.Wait()
+
+	workers := make(map[int]bool)
+	for _, pid := range pids {
+		workers[pid] = true
+	}
+	assert.LessOrEqual(t, len(workers), 2)
+	assert.NotContains(t, workers, 0)
+}
+
+func TestWorkerRestart(t *testing.T) {
+	s := newTestWorkerSolver(t, "server", 1)
+	pid := runPID(t, s, "depsolve")
+
+	_, err := s.run(&Request{Command: "crash"})
+	assert.EqualError(t, err, "dnf-json worker exited")
+
+	// a new worker replaces the crashed one
+	newPID := runPID(t, s, "depsolve")
+	assert.NotEqual(t, pid, newPID)
+	assert.Equal(t, newPID, runPID(t, s, "depsolve"))
+}
+
+func TestWorkerError(t *testing.T) {
+	s := newTestWorkerSolver(t, "server", 1)
+	req := &Request{
+		Command:   "fail",
+		Arguments: arguments{Repos: []repoConfig{{ID: "abc", Name: "fedora", BaseURLs: []string{"https://example.com/fedora"}}}},
+	}
+	_, err := s.run(req)
+	assert.Equal(t, Error{Kind: "DepsolveError", Reason: "cannot depsolve in repo 'abc' [fedora: https://example.com/fedora]"}, err)
+
+	// errors of requests don't affect the worker
+	runPID(t, s, "depsolve")
+}
+
+func TestWorkerFallback(t *testing.T) {
+	// dnf-json without server mode: every request runs its own process
+	s := newTestWorkerSolver(t, "oneshot", 1)
+	pid := runPID(t, s, "depsolve")
+	assert.NotEqual(t, pid, runPID(t, s, "depsolve"))
+
+	// workers disabled
+	s = newTestWorkerSolver(t, "server", 0)
+	pid = runPID(t, s, "depsolve")
+	assert.NotEqual(t, pid, runPID(t, s, "depsolve"))
+}
+
+func TestWorkerFallbackPerKey(t *testing.T) {
+	// the first worker fails to start, only this distro and arch falls back
+	// to the one-shot mode
+	s := newTestWorkerSolver(t, "oneshot", 1)
+	pid := runPID(t, s, "depsolve")
+	assert.NotEqual(t, pid, runPID(t, s, "depsolve"))
+
+	t.Setenv("DNFJSON_TEST_WORKER", "server")
+	other := s.BaseSolver.NewWithConfig("platform:f38", "38", "aarch64", "fedora-38")
+	otherPID := runPID(t, other, "depsolve")
+	assert.Equal(t, otherPID, runPID(t, other, "depsolve"))
+
+	// starting a worker is retried once the backoff expired
+	pid = runPID(t, s, "depsolve")
+	assert.NotEqual(t, pid, runPID(t, s, "depsolve"))
+	s.workers.mu.Lock()
+	s.workers.keys[s.distro+"/"+s.arch].retryAt = time.Now()
+	s.workers.mu.Unlock()
+	pid = runPID(t, s, "depsolve")
+	assert.Equal(t, pid, runPID(t, s, "depsolve"))
+}
+
+func TestWorkerCleanCache(t *testing.T) {
+	s := newTestWorkerSolver(t, "server", 1)
+	pkgSets := []rpmmd.PackageSet{
+		{
+			Include:      []string{"pkg"},
+			Repositories: []rpmmd.RepoConfig{{Name: "fedora", BaseURLs: []string{"https://example.com/fedora"}}},
+		},
+	}
+	depsolvePID := func() string {
+		pkgs, err := s.Depsolve(pkgSets)
+		require.NoError(t, err)
+		require.Len(t, pkgs, 1)
+		return pkgs[0].Version
+	}
+
+	pid := depsolvePID()
+	assert.Equal(t, pid, depsolvePID())
+
+	// cleaning the cache stops the workers, but the solver remains usable
+	// with new ones
+	require.NoError(t, s.CleanCache())
+	newPID := depsolvePID()
+	assert.NotEqual(t, pid, newPID)
+	assert.Equal(t, newPID, depsolvePID())
+}
+
+func TestWorkerDepsolveModules(t *testing.T) {
+	s := newTestWorkerSolver(t, "server", 1)
+	repos := []rpmmd.RepoConfig{{Name: "fedora", BaseURLs: []string{"https://example.com/fedora"}}}
+
+	pkgs, modules, err := s.DepsolveModules([]rpmmd.PackageSet{{Include: []string{"pkg"}, Repositories: repos}})
+	require.NoError(t, err)
+	assert.Len(t, pkgs, 1)
+	assert.Empty(t, modules)
+
+	pkgs, modules, err = s.DepsolveModules([]rpmmd.PackageSet{
+		{
+			Include:        []string{"pkg", "@nodejs:18"},
+			Repositories:   repos,
+			EnabledModules: []rpmmd.ModuleSpec{{Name: "nodejs", Stream: "18"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Len(t, pkgs, 1)
+	assert.Equal(t, []rpmmd.ModuleState{{Name: "nodejs", Stream: "18", Profiles: []string{"default"}}}, modules)
+}
+
+func TestWorkerStartBackoff(t *testing.T) {
+	assert.Equal(t, workerStartMinBackoff, workerStartBackoff(1))
+	assert.Equal(t, 2*workerStartMinBackoff, workerStartBackoff(2))
+	assert.Equal(t, workerStartMaxBackoff, workerStartBackoff(100))
+}
@@ -1,11 +1,19 @@
 package environment
 
-import "github.com/osbuild/images/pkg/rpmmd"
+import (
+	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/internal/users"
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
 
 type Environment interface {
 	GetPackages() []string
 	GetRepos() []rpmmd.RepoConfig
 	GetServices() []string
+	GetUsers() []users.User
+	GetFiles() []*fsnode.File
+	GetSshdConfig() *osbuild.SshdConfigStageOptions
 }
 
 type BaseEnvironment struct {
@@ -23,3 +31,15 @@ func (p BaseEnvironment) GetRepos() []rpmmd.RepoConfig {
 func (p BaseEnvironment) GetServices() []string {
 	return []string{}
 }
+
+func (p BaseEnvironment) GetUsers() []users.User {
+	return nil
+}
+
+func (p BaseEnvironment) GetFiles() []*fsnode.File {
+	return nil
+}
+
+func (p BaseEnvironment) GetSshdConfig() *osbuild.SshdConfigStageOptions {
+	return nil
+}
@@ -0,0 +1,31 @@
+package environment
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/internal/fsnode"
+)
+
+// KubeVirt is the environment of VMs booted from a containerDisk by KubeVirt,
+// which passes the cloud-init user data on a NoCloud or ConfigDrive volume
+type KubeVirt struct {
+	BaseEnvironment
+}
+
+func (p *KubeVirt) GetPackages() []string {
+	return []string{"cloud-init", "qemu-guest-agent"}
+}
+
+func (p *KubeVirt) GetFiles() []*fsnode.File {
+	datasources, err := fsnode.NewFile(
+		"/etc/cloud/cloud.cfg.d/10-kubevirt-datasources.cfg",
+		nil,
+		nil,
+		nil,
+		[]byte("datasource_list: [ NoCloud, ConfigDrive ]\n"),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create the cloud-init datasource config for kubevirt: %v", err))
+	}
+	return []*fsnode.File{datasources}
+}
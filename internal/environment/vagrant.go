@@ -0,0 +1,69 @@
+package environment
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/internal/users"
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+// VagrantUser is the user Vagrant logs in as
+const VagrantUser = "vagrant"
+
+// VagrantInsecureKey is the well-known public key Vagrant uses to log in to
+// a new box for the first time, after which it replaces the key with a
+// generated one
+const VagrantInsecureKey = "ssh-rsa AAAAB3NzaC1yc2EAAAABIwAAAQEA6NF8iallvQVp22WDkTkyrtvp9eWW6A8YVr+kz4TjGYe7gHzIw+niNltGEFHzD8+v1I2YJ6oXevct1YeS0o9HZyN1Q9qgCgzUFtdOKLv6IedplqoPkcmF0aYet2PkEDo3MlTBckFXPITAMzF8dJSIFo9D8HfdOV0IAdx4O7PtixWKn5y2hMNG0zQPyUecp4pzC6kivAIhyfHilFR61RGL+GPXQ2MWZWFYbAGjyiYJnAmCP3NOTd0jMZEnDkbUvxhMmBYSdETk1rRgm+R4LOzFUGaHqHDLKLX+FIPKcF96hrucXzcWyLbIbEgE98OHlnVYCzRdK8jlqm8tehUc9c9WhQ== vagrant insecure public key"
+
+// Vagrant is the environment of Vagrant boxes: the vagrant user logs in
+// with the insecure key of Vagrant and can use sudo without a password
+type Vagrant struct {
+	BaseEnvironment
+}
+
+func (p *Vagrant) GetPackages() []string {
+	// rsync is used by the default synced folder of the boxes
+	return []string{"openssh-server", "rsync", "sudo"}
+}
+
+func (p *Vagrant) GetServices() []string {
+	return []string{"sshd.service"}
+}
+
+func (p *Vagrant) GetUsers() []users.User {
+	return []users.User{
+		{
+			Name:        VagrantUser,
+			Description: common.ToPtr("Vagrant"),
+			Key:         common.ToPtr(VagrantInsecureKey),
+			Groups:      []string{"wheel"},
+		},
+	}
+}
+
+func (p *Vagrant) GetFiles() []*fsnode.File {
+	sudoers, err := fsnode.NewFile(
+		"/etc/sudoers.d/"+VagrantUser,
+		common.ToPtr(os.FileMode(0440)),
+		"root",
+		"root",
+		[]byte(fmt.Sprintf("%s ALL=(ALL) NOPASSWD: ALL\n", VagrantUser)),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create the sudoers file for vagrant: %v", err))
+	}
+	return []*fsnode.File{sudoers}
+}
+
+func (p *Vagrant) GetSshdConfig() *osbuild.SshdConfigStageOptions {
+	return &osbuild.SshdConfigStageOptions{
+		Config: osbuild.SshdConfigConfig{
+			// Vagrant logs in with keys only
+			PasswordAuthentication: common.ToPtr(false),
+			PermitRootLogin:        osbuild.PermitRootLoginValueNo,
+		},
+	}
+}
@@ -0,0 +1,107 @@
+// Package testrepos loads the repository configurations of the development
+// tools, which define the repositories of every distro and architecture in a
+// single JSON file, like tools/test-case-generators/repos.json.
+package testrepos
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// DefaultPath is the path of the repository configurations relative to the
+// root of the repository
+const DefaultPath = "./tools/test-case-generators/repos.json"
+
+// Repository is the configuration of a single repository
+type Repository struct {
+	Name           string   `json:"name"`
+	Id             string   `json:"id,omitempty"`
+	BaseURL        string   `json:"baseurl,omitempty"`
+	Metalink       string   `json:"metalink,omitempty"`
+	MirrorList     string   `json:"mirrorlist,omitempty"`
+	GPGKey         string   `json:"gpgkey,omitempty"`
+	CheckGPG       bool     `json:"check_gpg,omitempty"`
+	CheckRepoGPG   bool     `json:"check_repo_gpg,omitempty"`
+	IgnoreSSL      bool     `json:"ignore_ssl,omitempty"`
+	RHSM           bool     `json:"rhsm,omitempty"`
+	MetadataExpire string   `json:"metadata_expire,omitempty"`
+	ImageTypeTags  []string `json:"image_type_tags,omitempty"`
+	PackageSets    []string `json:"package-sets,omitempty"`
+}
+
+// DistroArchRepoMap maps distro names to architecture names to their
+// repositories
+type DistroArchRepoMap map[string]map[string][]Repository
+
+// Read reads the repository configurations at fpath
+func Read(fpath string) (DistroArchRepoMap, error) {
+	data, err := os.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	var darm DistroArchRepoMap
+	if err := json.Unmarshal(data, &darm); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal repositories %q: %w", fpath, err)
+	}
+	return darm, nil
+}
+
+// RepoConfig returns the rpmmd.RepoConfig of the repository
+func (r Repository) RepoConfig() rpmmd.RepoConfig {
+	var urls []string
+	if r.BaseURL != "" {
+		urls = []string{r.BaseURL}
+	}
+
+	var keys []string
+	if r.GPGKey != "" {
+		keys = []string{r.GPGKey}
+	}
+
+	return rpmmd.RepoConfig{
+		Id:             r.Id,
+		Name:           r.Name,
+		BaseURLs:       urls,
+		Metalink:       r.Metalink,
+		MirrorList:     r.MirrorList,
+		GPGKeys:        keys,
+		CheckGPG:       &r.CheckGPG,
+		CheckRepoGPG:   &r.CheckRepoGPG,
+		IgnoreSSL:      &r.IgnoreSSL,
+		MetadataExpire: r.MetadataExpire,
+		RHSM:           r.RHSM,
+		ImageTypeTags:  r.ImageTypeTags,
+		PackageSets:    r.PackageSets,
+	}
+}
+
+// RepoConfigs returns the rpmmd.RepoConfig of every repository
+func RepoConfigs(repos []Repository) []rpmmd.RepoConfig {
+	cr := make([]rpmmd.RepoConfig, len(repos))
+	for idx, r := range repos {
+		cr[idx] = r.RepoConfig()
+	}
+	return cr
+}
+
+// FilterByImageType returns the repositories without image type tags and the
+// ones tagged with the given image type name
+func FilterByImageType(repos []Repository, imgTypeName string) []Repository {
+	filtered := make([]Repository, 0)
+	for _, repo := range repos {
+		if len(repo.ImageTypeTags) == 0 {
+			filtered = append(filtered, repo)
+			continue
+		}
+		for _, tt := range repo.ImageTypeTags {
+			if tt == imgTypeName {
+				filtered = append(filtered, repo)
+				break
+			}
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,42 @@
+package testrepos
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+)
+
+func TestRead(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "repos.json")
+	data := `{"fedora-38": {"x86_64": [
+		{"name": "fedora", "baseurl": "https://example.com/fedora", "gpgkey": "KEY", "check_gpg": true},
+		{"name": "ami", "metalink": "https://example.com/metalink", "image_type_tags": ["ami"]}
+	]}}`
+	require.NoError(t, os.WriteFile(fpath, []byte(data), 0644))
+
+	darm, err := Read(fpath)
+	require.NoError(t, err)
+	repos := darm["fedora-38"]["x86_64"]
+	require.Len(t, repos, 2)
+
+	assert.Equal(t, repos[:1], FilterByImageType(repos, "qcow2"))
+	assert.Equal(t, repos, FilterByImageType(repos, "ami"))
+
+	configs := RepoConfigs(repos)
+	require.Len(t, configs, 2)
+	assert.Equal(t, []string{"https://example.com/fedora"}, configs[0].BaseURLs)
+	assert.Equal(t, []string{"KEY"}, configs[0].GPGKeys)
+	assert.Equal(t, common.ToPtr(true), configs[0].CheckGPG)
+	assert.Nil(t, configs[1].BaseURLs)
+	assert.Equal(t, common.ToPtr(false), configs[1].CheckGPG)
+	assert.Equal(t, []string{"ami"}, configs[1].ImageTypeTags)
+
+	require.NoError(t, os.WriteFile(fpath, []byte("{"), 0644))
+	_, err = Read(fpath)
+	assert.ErrorContains(t, err, "failed to unmarshal repositories")
+}
@@ -1,8 +1,13 @@
 package workload
 
+import "github.com/osbuild/images/pkg/rpmmd"
+
 type Custom struct {
 	BaseWorkload
 	Packages         []string
+	ExcludePackages  []string
+	PackagePins      []rpmmd.PackagePin
+	EnabledModules   []rpmmd.ModuleSpec
 	Services         []string
 	DisabledServices []string
 }
@@ -11,6 +16,26 @@ func (p *Custom) GetPackages() []string {
 	return p.Packages
 }
 
+// GetExcludePackages returns the packages that are excluded from all package
+// sets of the image, not only from the workload.
+func (p *Custom) GetExcludePackages() []string {
+	return p.ExcludePackages
+}
+
+// GetPackagePins returns the pinned packages of the workload. The pins apply
+// to all package sets of the image, so that pinned packages that are also
+// part of the base packages are not installed in a different version first.
+func (p *Custom) GetPackagePins() []rpmmd.PackagePin {
+	return p.PackagePins
+}
+
+// GetEnabledModules returns the module streams of the workload. The streams
+// are enabled for all package sets of the image, so that the base packages
+// are not installed from a different stream.
+func (p *Custom) GetEnabledModules() []rpmmd.ModuleSpec {
+	return p.EnabledModules
+}
+
 func (p *Custom) GetServices() []string {
 	return p.Services
 }
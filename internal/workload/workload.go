@@ -4,6 +4,9 @@ import "github.com/osbuild/images/pkg/rpmmd"
 
 type Workload interface {
 	GetPackages() []string
+	GetExcludePackages() []string
+	GetPackagePins() []rpmmd.PackagePin
+	GetEnabledModules() []rpmmd.ModuleSpec
 	GetRepos() []rpmmd.RepoConfig
 	GetServices() []string
 	GetDisabledServices() []string
@@ -17,6 +20,18 @@ func (p BaseWorkload) GetPackages() []string {
 	return []string{}
 }
 
+func (p BaseWorkload) GetExcludePackages() []string {
+	return []string{}
+}
+
+func (p BaseWorkload) GetPackagePins() []rpmmd.PackagePin {
+	return nil
+}
+
+func (p BaseWorkload) GetEnabledModules() []rpmmd.ModuleSpec {
+	return nil
+}
+
 func (p BaseWorkload) GetRepos() []rpmmd.RepoConfig {
 	return p.Repos
 }
@@ -1,17 +1,25 @@
 // Package blueprint contains primitives for representing weldr blueprints
 package blueprint
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
 // A Blueprint is a high-level description of an image.
 type Blueprint struct {
-	Name           string          `json:"name" toml:"name"`
-	Description    string          `json:"description" toml:"description"`
-	Version        string          `json:"version,omitempty" toml:"version,omitempty"`
-	Packages       []Package       `json:"packages" toml:"packages"`
-	Modules        []Package       `json:"modules" toml:"modules"`
-	Groups         []Group         `json:"groups" toml:"groups"`
-	Containers     []Container     `json:"containers,omitempty" toml:"containers,omitempty"`
-	Customizations *Customizations `json:"customizations,omitempty" toml:"customizations"`
-	Distro         string          `json:"distro" toml:"distro"`
+	Name            string          `json:"name" toml:"name"`
+	Description     string          `json:"description" toml:"description"`
+	Version         string          `json:"version,omitempty" toml:"version,omitempty"`
+	Packages        []Package       `json:"packages" toml:"packages"`
+	ExcludePackages []string        `json:"exclude_packages,omitempty" toml:"exclude_packages,omitempty"`
+	Modules         []Package       `json:"modules" toml:"modules"`
+	Groups          []Group         `json:"groups" toml:"groups"`
+	Containers      []Container     `json:"containers,omitempty" toml:"containers,omitempty"`
+	Customizations  *Customizations `json:"customizations,omitempty" toml:"customizations"`
+	Distro          string          `json:"distro" toml:"distro"`
 }
 
 type Change struct {
@@ -22,10 +30,12 @@ type Change struct {
 	Blueprint Blueprint `json:"-" toml:"-"`
 }
 
-// A Package specifies an RPM package.
+// A Package specifies an RPM package. The Version is either a glob or an
+// exact "[epoch:]version-release", which pins the package to that version.
 type Package struct {
 	Name    string `json:"name" toml:"name"`
 	Version string `json:"version,omitempty" toml:"version,omitempty"`
+	Arch    string `json:"arch,omitempty" toml:"arch,omitempty"`
 }
 
 // A group specifies an package group.
@@ -52,6 +62,11 @@ func (b *Blueprint) GetPackagesEx(bootable bool) []string {
 		packages = append(packages, pkg.ToNameVersion())
 	}
 	for _, pkg := range b.Modules {
+		if pkg.IsModule() {
+			// installs the given or the default profile of the module
+			packages = append(packages, "@"+pkg.Name)
+			continue
+		}
 		packages = append(packages, pkg.ToNameVersion())
 	}
 	for _, group := range b.Groups {
@@ -67,11 +82,85 @@ func (b *Blueprint) GetPackagesEx(bootable bool) []string {
 	return packages
 }
 
+// ToNameVersion returns the package spec of the package. Invalid pins are
+// returned as given, GetPackagePins() returns their error.
 func (p Package) ToNameVersion() string {
+	if p.IsPinned() {
+		if pin, err := p.GetPin(); err == nil {
+			return pin.String()
+		}
+	}
+
+	spec := p.Name
 	// Omit version to prevent all packages with prefix of name to be installed
-	if p.Version == "*" || p.Version == "" {
-		return p.Name
+	if p.Version != "*" && p.Version != "" {
+		spec += "-" + p.Version
+	}
+	if p.Arch != "" {
+		spec += "." + p.Arch
+	}
+
+	return spec
+}
+
+// IsPinned returns true if the package version is an exact
+// "[epoch:]version-release" instead of a glob or a version without release.
+// The pin of the package can still be invalid, see GetPin.
+func (p Package) IsPinned() bool {
+	return strings.Contains(p.Version, "-") && !strings.ContainsAny(p.Version, "*?[")
+}
+
+// GetPin returns the pin of a pinned package, see IsPinned.
+func (p Package) GetPin() (rpmmd.PackagePin, error) {
+	return rpmmd.NewPackagePin(p.Name, p.Version, p.Arch)
+}
+
+// GetPackagePins returns the pins of all pinned packages and an error if the
+// version of a pinned package is invalid.
+func (b *Blueprint) GetPackagePins() ([]rpmmd.PackagePin, error) {
+	var pins []rpmmd.PackagePin
+	for _, pkg := range append(append([]Package{}, b.Packages...), b.Modules...) {
+		if pkg.IsModule() || !pkg.IsPinned() {
+			continue
+		}
+		pin, err := pkg.GetPin()
+		if err != nil {
+			return nil, err
+		}
+		pins = append(pins, pin)
 	}
+	return pins, nil
+}
 
-	return p.Name + "-" + p.Version
+// IsModule returns true if the package is a DNF module stream, whose name has
+// the form "name:stream[/profile]". Other entries of Blueprint.Modules are
+// installed as plain packages.
+func (p Package) IsModule() bool {
+	return strings.Contains(p.Name, ":")
+}
+
+// GetEnabledModules returns the module streams of all modules and an error if
+// a module is invalid or if two modules select different streams of the same
+// module.
+func (b *Blueprint) GetEnabledModules() ([]rpmmd.ModuleSpec, error) {
+	var modules []rpmmd.ModuleSpec
+	streams := map[string]string{}
+	for _, pkg := range b.Modules {
+		if !pkg.IsModule() {
+			continue
+		}
+		if pkg.Version != "" && pkg.Version != "*" {
+			return nil, fmt.Errorf("module %q must not have a version, the stream is part of its name", pkg.Name)
+		}
+		module, err := rpmmd.ParseModuleSpec(pkg.Name)
+		if err != nil {
+			return nil, err
+		}
+		if stream, ok := streams[module.Name]; ok && stream != module.Stream {
+			return nil, fmt.Errorf("module %q cannot be enabled in multiple streams: %s and %s", module.Name, stream, module.Stream)
+		}
+		streams[module.Name] = module.Stream
+		modules = append(modules, module)
+	}
+	return modules, nil
 }
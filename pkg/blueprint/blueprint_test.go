@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/BurntSushi/toml"
+	"github.com/osbuild/images/pkg/rpmmd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -70,6 +71,103 @@ func TestGetPackages(t *testing.T) {
 	assert.ElementsMatch(t, []string{"tmux-1.2", "openssh-server", "@anaconda-tools", "kernel"}, Received_packages)
 }
 
+func TestPackagePins(t *testing.T) {
+	blueprint := `
+name = "test"
+exclude_packages = ["kernel-5.14.0-400*", "firewalld"]
+
+[[packages]]
+name = "kernel"
+version = "5.14.0-362.el9"
+
+[[packages]]
+name = "grub2-pc"
+version = "1:2.06-94.el9"
+arch = "x86_64"
+
+[[packages]]
+name = "tmux"
+version = "3.*"
+`
+	var bp Blueprint
+	err := toml.Unmarshal([]byte(blueprint), &bp)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kernel-5.14.0-400*", "firewalld"}, bp.ExcludePackages)
+	assert.Equal(t, []string{"kernel-5.14.0-362.el9", "grub2-pc-1:2.06-94.el9.x86_64", "tmux-3.*"}, bp.GetPackagesEx(false))
+
+	pins, err := bp.GetPackagePins()
+	require.NoError(t, err)
+	assert.Equal(t, []rpmmd.PackagePin{
+		{Name: "kernel", Version: "5.14.0", Release: "362.el9"},
+		{Name: "grub2-pc", Epoch: 1, Version: "2.06", Release: "94.el9", Arch: "x86_64"},
+	}, pins)
+
+	assert.Equal(t, "tmux.aarch64", Package{Name: "tmux", Arch: "aarch64"}.ToNameVersion())
+
+	// versions without release and globs are installed as given instead of
+	// being pinned
+	for _, version := range []string{"5.14.0", "5.14.0-362.el9*"} {
+		pkg := Package{Name: "kernel", Version: version}
+		assert.False(t, pkg.IsPinned(), version)
+		assert.Equal(t, "kernel-"+version, pkg.ToNameVersion())
+
+		bp.Packages = []Package{pkg}
+		pins, err = bp.GetPackagePins()
+		assert.NoError(t, err)
+		assert.Empty(t, pins)
+	}
+
+	bp.Packages = []Package{{Name: "kernel", Version: "x:5.14.0-362.el9"}}
+	_, err = bp.GetPackagePins()
+	assert.EqualError(t, err, `package pin "kernel-x:5.14.0-362.el9" has an invalid epoch "x"`)
+
+	bp.Packages = []Package{{Name: "kernel", Version: "5.14.0-362-el9"}}
+	_, err = bp.GetPackagePins()
+	assert.EqualError(t, err, `package pin "kernel-5.14.0-362-el9" must have the form [epoch:]version-release`)
+
+	// plain packages of the modules are pinned as well
+	bp.Packages = nil
+	bp.Modules = []Package{{Name: "tmux", Version: "3.2a-4.el9"}}
+	pins, err = bp.GetPackagePins()
+	require.NoError(t, err)
+	assert.Equal(t, []rpmmd.PackagePin{{Name: "tmux", Version: "3.2a", Release: "4.el9"}}, pins)
+}
+
+func TestEnabledModules(t *testing.T) {
+	blueprint := `
+name = "test"
+
+[[modules]]
+name = "nodejs:18/minimal"
+
+[[modules]]
+name = "postgresql:15"
+
+[[modules]]
+name = "tmux"
+version = "3.*"
+`
+	var bp Blueprint
+	err := toml.Unmarshal([]byte(blueprint), &bp)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"@nodejs:18/minimal", "@postgresql:15", "tmux-3.*"}, bp.GetPackagesEx(false))
+
+	modules, err := bp.GetEnabledModules()
+	require.NoError(t, err)
+	assert.Equal(t, []rpmmd.ModuleSpec{
+		{Name: "nodejs", Stream: "18", Profile: "minimal"},
+		{Name: "postgresql", Stream: "15"},
+	}, modules)
+
+	bp.Modules = append(bp.Modules, Package{Name: "nodejs:20"})
+	_, err = bp.GetEnabledModules()
+	assert.EqualError(t, err, `module "nodejs" cannot be enabled in multiple streams: 18 and 20`)
+
+	bp.Modules = []Package{{Name: "nodejs:18", Version: "1.0"}}
+	_, err = bp.GetEnabledModules()
+	assert.EqualError(t, err, `module "nodejs:18" must not have a version, the stream is part of its name`)
+}
+
 func TestKernelNameCustomization(t *testing.T) {
 	kernels := []string{"kernel", "kernel-debug", "kernel-rt"}
 
@@ -0,0 +1,93 @@
+package blueprint
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+)
+
+// CACertAnchorsDir is the directory of the trust anchors generated from the
+// CA certificate customizations
+const CACertAnchorsDir = "/etc/pki/ca-trust/source/anchors"
+
+// ParseCACertBundle parses a PEM bundle and returns the certificates it
+// contains. The bundle must contain at least one certificate and no other
+// PEM blocks.
+func ParseCACertBundle(bundle string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("unexpected PEM block of type %q", block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+
+	return certs, nil
+}
+
+func validateCACerts(bundles []string) error {
+	for idx, bundle := range bundles {
+		if _, err := ParseCACertBundle(bundle); err != nil {
+			return fmt.Errorf("CA certificate bundle %d is invalid: %v", idx, err)
+		}
+	}
+
+	return nil
+}
+
+// CACertsToFsNodeFiles converts the given PEM bundles to trust anchors. Every
+// certificate is written to its own file, named after the SHA-256 fingerprint
+// of the certificate, so the same certificate is only installed once.
+func CACertsToFsNodeFiles(bundles []string) ([]*fsnode.File, error) {
+	if len(bundles) == 0 {
+		return nil, nil
+	}
+
+	var files []*fsnode.File
+	seen := make(map[string]bool)
+	for _, bundle := range bundles {
+		certs, err := ParseCACertBundle(bundle)
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range certs {
+			fingerprint := sha256.Sum256(cert.Raw)
+			name := hex.EncodeToString(fingerprint[:])
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+			file, err := fsnode.NewFile(path.Join(CACertAnchorsDir, name+".pem"), common.ToPtr(os.FileMode(0644)), "root", "root", data)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
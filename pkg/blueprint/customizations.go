@@ -24,6 +24,19 @@ type Customizations struct {
 	Directories        []DirectoryCustomization  `json:"directories,omitempty" toml:"directories,omitempty"`
 	Files              []FileCustomization       `json:"files,omitempty" toml:"files,omitempty"`
 	Repositories       []RepositoryCustomization `json:"repositories,omitempty" toml:"repositories,omitempty"`
+	Disk               *DiskCustomization        `json:"disk,omitempty" toml:"disk,omitempty"`
+	PartitioningMode   PartitioningMode          `json:"partitioning_mode,omitempty" toml:"partitioning_mode,omitempty"`
+	Network            *NetworkCustomization     `json:"network,omitempty" toml:"network,omitempty"`
+	Sudoers            []SudoersCustomization    `json:"sudoers,omitempty" toml:"sudoers,omitempty"`
+	CACerts            []string                  `json:"cacerts,omitempty" toml:"cacerts,omitempty"`
+	Sysctl             map[string]string         `json:"sysctl,omitempty" toml:"sysctl,omitempty"`
+	Modprobe           *ModprobeCustomization    `json:"modprobe,omitempty" toml:"modprobe,omitempty"`
+	Tuned              *TunedCustomization       `json:"tuned,omitempty" toml:"tuned,omitempty"`
+	Dracut             *DracutCustomization      `json:"dracut,omitempty" toml:"dracut,omitempty"`
+	SELinux            *SELinuxCustomization     `json:"selinux,omitempty" toml:"selinux,omitempty"`
+	FIPS               *bool                     `json:"fips,omitempty" toml:"fips,omitempty"`
+	Systemd            *SystemdCustomization     `json:"systemd,omitempty" toml:"systemd,omitempty"`
+	Installer          *InstallerCustomization   `json:"installer,omitempty" toml:"installer,omitempty"`
 }
 
 type IgnitionCustomization struct {
@@ -104,6 +117,7 @@ type FirewallServicesCustomization struct {
 type ServicesCustomization struct {
 	Enabled  []string `json:"enabled,omitempty" toml:"enabled,omitempty"`
 	Disabled []string `json:"disabled,omitempty" toml:"disabled,omitempty"`
+	Masked   []string `json:"masked,omitempty" toml:"masked,omitempty"`
 }
 
 type OpenSCAPCustomization struct {
@@ -145,7 +159,7 @@ func (c *Customizations) CheckAllowed(allowed ...string) error {
 			if field.String() == "" {
 				empty = true
 			}
-		case reflect.Array, reflect.Slice:
+		case reflect.Array, reflect.Slice, reflect.Map:
 			if field.Len() == 0 {
 				empty = true
 			}
@@ -349,3 +363,185 @@ func (c *Customizations) GetRepositories() ([]RepositoryCustomization, error) {
 
 	return c.Repositories, nil
 }
+
+func (c *Customizations) GetDiskEncryption() (*EncryptionCustomization, error) {
+	if c == nil || c.Disk == nil || c.Disk.Encryption == nil {
+		return nil, nil
+	}
+
+	if err := validateEncryptionCustomization(c.Disk.Encryption); err != nil {
+		return nil, err
+	}
+
+	return c.Disk.Encryption, nil
+}
+
+// GetSwap returns the validated swap customization
+func (c *Customizations) GetSwap() (*SwapCustomization, error) {
+	if c == nil || c.Disk == nil || c.Disk.Swap == nil {
+		return nil, nil
+	}
+
+	if err := validateSwapCustomization(c.Disk.Swap); err != nil {
+		return nil, err
+	}
+
+	return c.Disk.Swap, nil
+}
+
+// GetPartitioningMode returns the requested partitioning mode or
+// DefaultPartitioningMode if the image type default should be used
+func (c *Customizations) GetPartitioningMode() (PartitioningMode, error) {
+	if c == nil {
+		return DefaultPartitioningMode, nil
+	}
+
+	switch c.PartitioningMode {
+	case DefaultPartitioningMode, RawPartitioningMode, LVMPartitioningMode, AutoLVMPartitioningMode, BtrfsPartitioningMode:
+		return c.PartitioningMode, nil
+	default:
+		return DefaultPartitioningMode, fmt.Errorf("Partitioning mode %q is not supported", c.PartitioningMode)
+	}
+}
+
+// GetNetworkConnections returns the validated network connection
+// customizations
+func (c *Customizations) GetNetworkConnections() ([]NetworkConnectionCustomization, error) {
+	if c == nil || c.Network == nil {
+		return nil, nil
+	}
+
+	if err := validateNetworkConnections(c.Network.Connections); err != nil {
+		return nil, err
+	}
+
+	return c.Network.Connections, nil
+}
+
+// GetSudoers returns the sudoers customizations, validated against the users
+// and groups of the blueprint
+func (c *Customizations) GetSudoers() ([]SudoersCustomization, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	if err := validateSudoersCustomizations(c.Sudoers, c.GetUsers(), c.GetGroups()); err != nil {
+		return nil, err
+	}
+
+	return c.Sudoers, nil
+}
+
+// GetCACerts returns the validated PEM bundles of the CA certificate
+// customizations
+func (c *Customizations) GetCACerts() ([]string, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	if err := validateCACerts(c.CACerts); err != nil {
+		return nil, err
+	}
+
+	return c.CACerts, nil
+}
+
+// GetSysctl returns the validated kernel parameters of the sysctl
+// customization
+func (c *Customizations) GetSysctl() (map[string]string, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	if err := validateSysctl(c.Sysctl); err != nil {
+		return nil, err
+	}
+
+	return c.Sysctl, nil
+}
+
+// GetModprobe returns the validated modprobe customization
+func (c *Customizations) GetModprobe() (*ModprobeCustomization, error) {
+	if c == nil || c.Modprobe == nil {
+		return nil, nil
+	}
+
+	if err := validateModprobe(c.Modprobe); err != nil {
+		return nil, err
+	}
+
+	return c.Modprobe, nil
+}
+
+// GetTuned returns the validated TuneD customization
+func (c *Customizations) GetTuned() (*TunedCustomization, error) {
+	if c == nil || c.Tuned == nil {
+		return nil, nil
+	}
+
+	if err := validateTuned(c.Tuned); err != nil {
+		return nil, err
+	}
+
+	return c.Tuned, nil
+}
+
+// GetDracut returns the validated dracut customization
+func (c *Customizations) GetDracut() (*DracutCustomization, error) {
+	if c == nil || c.Dracut == nil {
+		return nil, nil
+	}
+
+	if err := validateDracut(c.Dracut); err != nil {
+		return nil, err
+	}
+
+	return c.Dracut, nil
+}
+
+// GetSELinux returns the validated SELinux customization
+func (c *Customizations) GetSELinux() (*SELinuxCustomization, error) {
+	if c == nil || c.SELinux == nil {
+		return nil, nil
+	}
+
+	if err := validateSELinux(c.SELinux); err != nil {
+		return nil, err
+	}
+
+	return c.SELinux, nil
+}
+
+// GetFIPS returns true if the image is built with FIPS mode enabled
+func (c *Customizations) GetFIPS() bool {
+	if c == nil || c.FIPS == nil {
+		return false
+	}
+	return *c.FIPS
+}
+
+// GetSystemdUnits returns the validated systemd unit customizations
+func (c *Customizations) GetSystemdUnits() ([]SystemdUnitCustomization, error) {
+	if c == nil || c.Systemd == nil {
+		return nil, nil
+	}
+
+	if err := validateSystemdUnits(c.Systemd.Units, c.Services); err != nil {
+		return nil, err
+	}
+
+	return c.Systemd.Units, nil
+}
+
+// GetInstaller returns the validated installer customization
+func (c *Customizations) GetInstaller() (*InstallerCustomization, error) {
+	if c == nil || c.Installer == nil {
+		return nil, nil
+	}
+
+	if err := validateInstaller(c.Installer); err != nil {
+		return nil, err
+	}
+
+	return c.Installer, nil
+}
@@ -3,6 +3,7 @@ package blueprint
 import (
 	"testing"
 
+	"github.com/osbuild/images/internal/common"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -46,6 +47,13 @@ func TestCheckAllowed(t *testing.T) {
 	// "Hostname" not allowed anymore
 	err = x.CheckAllowed("User")
 	assert.Error(t, err)
+
+	// map fields are checked as well
+	x.Sysctl = map[string]string{"vm.swappiness": "10"}
+	err = x.CheckAllowed("Hostname", "User")
+	assert.Error(t, err)
+	err = x.CheckAllowed("Hostname", "User", "Sysctl")
+	assert.NoError(t, err)
 }
 
 func TestGetHostname(t *testing.T) {
@@ -387,3 +395,11 @@ func TestGetOpenSCAPConfig(t *testing.T) {
 
 	assert.EqualValues(t, expectedOscap, *retOpenSCAPCustomiztions)
 }
+
+func TestGetFIPS(t *testing.T) {
+	var c *Customizations
+	assert.False(t, c.GetFIPS())
+	assert.False(t, (&Customizations{}).GetFIPS())
+	assert.False(t, (&Customizations{FIPS: common.ToPtr(false)}).GetFIPS())
+	assert.True(t, (&Customizations{FIPS: common.ToPtr(true)}).GetFIPS())
+}
@@ -0,0 +1,116 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+)
+
+// PartitioningMode defines how custom mountpoints are added to the base
+// partition table of an image.
+type PartitioningMode string
+
+const (
+	// DefaultPartitioningMode is the same as AutoLVMPartitioningMode
+	DefaultPartitioningMode PartitioningMode = ""
+
+	// RawPartitioningMode never converts the partition table to LVM; custom
+	// mountpoints are created as plain partitions
+	RawPartitioningMode PartitioningMode = "raw"
+
+	// LVMPartitioningMode always converts the root partition to LVM, even if
+	// no custom mountpoints are requested
+	LVMPartitioningMode PartitioningMode = "lvm"
+
+	// AutoLVMPartitioningMode converts the root partition to LVM only if
+	// custom mountpoints that do not exist in the base table are requested
+	AutoLVMPartitioningMode PartitioningMode = "auto"
+
+	// BtrfsPartitioningMode requires the root filesystem to be a btrfs
+	// subvolume; custom mountpoints are created as additional subvolumes
+	BtrfsPartitioningMode PartitioningMode = "btrfs"
+)
+
+type DiskCustomization struct {
+	Encryption *EncryptionCustomization `json:"encryption,omitempty" toml:"encryption,omitempty"`
+	Swap       *SwapCustomization       `json:"swap,omitempty" toml:"swap,omitempty"`
+}
+
+// EncryptionCustomization describes the LUKS2 encryption of the root
+// filesystem and, optionally, of additional mountpoints.
+type EncryptionCustomization struct {
+	Passphrase string `json:"passphrase" toml:"passphrase"`
+	Cipher     string `json:"cipher,omitempty" toml:"cipher,omitempty"`
+
+	// Clevis pin to bind the LUKS2 containers to, so that they can be
+	// unlocked automatically at boot.
+	Clevis *ClevisCustomization `json:"clevis,omitempty" toml:"clevis,omitempty"`
+
+	// Remove the passphrase from the containers once they are bound via
+	// clevis. Requires Clevis to be set.
+	RemovePassphrase bool `json:"remove_passphrase,omitempty" toml:"remove_passphrase,omitempty"`
+
+	// Additional mountpoints to encrypt; the root filesystem is always
+	// encrypted.
+	Mountpoints []string `json:"mountpoints,omitempty" toml:"mountpoints,omitempty"`
+}
+
+type ClevisCustomization struct {
+	Pin    string `json:"pin" toml:"pin"`
+	Policy string `json:"policy,omitempty" toml:"policy,omitempty"`
+}
+
+var cipherRegex = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func validateEncryptionCustomization(enc *EncryptionCustomization) error {
+	if enc.Passphrase == "" {
+		return fmt.Errorf("Disk encryption passphrase is required")
+	}
+
+	if enc.Cipher != "" && !cipherRegex.MatchString(enc.Cipher) {
+		return fmt.Errorf("Disk encryption cipher %q is invalid", enc.Cipher)
+	}
+
+	if enc.RemovePassphrase && enc.Clevis == nil {
+		return fmt.Errorf("Disk encryption passphrase can only be removed when a clevis pin is set")
+	}
+
+	if enc.Clevis != nil {
+		if err := validateClevisCustomization(enc.Clevis); err != nil {
+			return err
+		}
+	}
+
+	for _, mountpoint := range enc.Mountpoints {
+		if !filepath.IsAbs(mountpoint) || filepath.Clean(mountpoint) != mountpoint {
+			return fmt.Errorf("Disk encryption mountpoint %q must be a clean absolute path", mountpoint)
+		}
+		if mountpoint == "/boot" || mountpoint == "/boot/efi" {
+			return fmt.Errorf("Disk encryption of %q is not supported", mountpoint)
+		}
+	}
+
+	return nil
+}
+
+func validateClevisCustomization(clevis *ClevisCustomization) error {
+	var policy map[string]interface{}
+	if clevis.Policy != "" {
+		if err := json.Unmarshal([]byte(clevis.Policy), &policy); err != nil {
+			return fmt.Errorf("Clevis policy must be a JSON object: %v", err)
+		}
+	}
+
+	switch clevis.Pin {
+	case "tpm2", "sss":
+	case "tang":
+		if _, ok := policy["url"]; !ok {
+			return fmt.Errorf("Clevis tang pin requires a policy with a \"url\"")
+		}
+	default:
+		return fmt.Errorf("Clevis pin %q is not supported (must be one of tpm2, tang, sss)", clevis.Pin)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package blueprint
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDiskEncryption(t *testing.T) {
+	testCases := []struct {
+		name       string
+		encryption *EncryptionCustomization
+		wantErr    error
+	}{
+		{
+			name: "Test passphrase only",
+			encryption: &EncryptionCustomization{
+				Passphrase: "secret",
+			},
+		},
+		{
+			name: "Test tang pin with passphrase removal",
+			encryption: &EncryptionCustomization{
+				Passphrase:       "secret",
+				Cipher:           "aes-xts-plain64",
+				Clevis:           &ClevisCustomization{Pin: "tang", Policy: `{"url": "http://tang.example.com"}`},
+				RemovePassphrase: true,
+				Mountpoints:      []string{"/var", "/home"},
+			},
+		},
+		{
+			name:       "Test missing passphrase error",
+			encryption: &EncryptionCustomization{},
+			wantErr:    fmt.Errorf("Disk encryption passphrase is required"),
+		},
+		{
+			name: "Test invalid cipher error",
+			encryption: &EncryptionCustomization{
+				Passphrase: "secret",
+				Cipher:     "AES XTS",
+			},
+			wantErr: fmt.Errorf("Disk encryption cipher %q is invalid", "AES XTS"),
+		},
+		{
+			name: "Test passphrase removal without clevis error",
+			encryption: &EncryptionCustomization{
+				Passphrase:       "secret",
+				RemovePassphrase: true,
+			},
+			wantErr: fmt.Errorf("Disk encryption passphrase can only be removed when a clevis pin is set"),
+		},
+		{
+			name: "Test unknown clevis pin error",
+			encryption: &EncryptionCustomization{
+				Passphrase: "secret",
+				Clevis:     &ClevisCustomization{Pin: "yubikey"},
+			},
+			wantErr: fmt.Errorf("Clevis pin %q is not supported (must be one of tpm2, tang, sss)", "yubikey"),
+		},
+		{
+			name: "Test tang pin without url error",
+			encryption: &EncryptionCustomization{
+				Passphrase: "secret",
+				Clevis:     &ClevisCustomization{Pin: "tang", Policy: `{}`},
+			},
+			wantErr: fmt.Errorf("Clevis tang pin requires a policy with a \"url\""),
+		},
+		{
+			name: "Test relative mountpoint error",
+			encryption: &EncryptionCustomization{
+				Passphrase:  "secret",
+				Mountpoints: []string{"var"},
+			},
+			wantErr: fmt.Errorf("Disk encryption mountpoint %q must be a clean absolute path", "var"),
+		},
+		{
+			name: "Test boot mountpoint error",
+			encryption: &EncryptionCustomization{
+				Passphrase:  "secret",
+				Mountpoints: []string{"/boot"},
+			},
+			wantErr: fmt.Errorf("Disk encryption of %q is not supported", "/boot"),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Customizations{Disk: &DiskCustomization{Encryption: tt.encryption}}
+			enc, err := c.GetDiskEncryption()
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.encryption, enc)
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGetDiskEncryptionEmpty(t *testing.T) {
+	var c *Customizations
+	enc, err := c.GetDiskEncryption()
+	assert.NoError(t, err)
+	assert.Nil(t, enc)
+
+	enc, err = (&Customizations{Disk: &DiskCustomization{}}).GetDiskEncryption()
+	assert.NoError(t, err)
+	assert.Nil(t, enc)
+}
+
+func TestGetPartitioningMode(t *testing.T) {
+	var c *Customizations
+	mode, err := c.GetPartitioningMode()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultPartitioningMode, mode)
+
+	for _, m := range []PartitioningMode{RawPartitioningMode, LVMPartitioningMode, AutoLVMPartitioningMode, BtrfsPartitioningMode} {
+		mode, err = (&Customizations{PartitioningMode: m}).GetPartitioningMode()
+		assert.NoError(t, err)
+		assert.Equal(t, m, mode)
+	}
+
+	_, err = (&Customizations{PartitioningMode: "zfs"}).GetPartitioningMode()
+	assert.EqualError(t, err, `Partitioning mode "zfs" is not supported`)
+}
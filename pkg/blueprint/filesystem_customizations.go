@@ -3,6 +3,8 @@ package blueprint
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/pathpolicy"
@@ -11,6 +13,12 @@ import (
 type FilesystemCustomization struct {
 	Mountpoint string `json:"mountpoint,omitempty" toml:"mountpoint,omitempty"`
 	MinSize    uint64 `json:"minsize,omitempty" toml:"size,omitempty"`
+
+	// Optional filesystem type, label and fstab(5) mount options. When not
+	// set, the values of the base partition table are used.
+	FSType       string `json:"fs_type,omitempty" toml:"fs_type,omitempty"`
+	Label        string `json:"label,omitempty" toml:"label,omitempty"`
+	MountOptions string `json:"mount_options,omitempty" toml:"mount_options,omitempty"`
 }
 
 func (fsc *FilesystemCustomization) UnmarshalTOML(data interface{}) error {
@@ -36,6 +44,24 @@ func (fsc *FilesystemCustomization) UnmarshalTOML(data interface{}) error {
 		return fmt.Errorf("TOML unmarshal: size must be integer or string, got %v of type %T", d["size"], d["size"])
 	}
 
+	for _, opt := range []struct {
+		key   string
+		value *string
+	}{
+		{"fs_type", &fsc.FSType},
+		{"label", &fsc.Label},
+		{"mount_options", &fsc.MountOptions},
+	} {
+		key := opt.key
+		switch d[key].(type) {
+		case nil:
+		case string:
+			*opt.value = d[key].(string)
+		default:
+			return fmt.Errorf("TOML unmarshal: %s must be string, got %v of type %T", key, d[key], d[key])
+		}
+	}
+
 	return nil
 }
 
@@ -68,6 +94,24 @@ func (fsc *FilesystemCustomization) UnmarshalJSON(data []byte) error {
 		return fmt.Errorf("JSON unmarshal: minsize must be float64 number or string, got %v of type %T", d["minsize"], d["minsize"])
 	}
 
+	for _, opt := range []struct {
+		key   string
+		value *string
+	}{
+		{"fs_type", &fsc.FSType},
+		{"label", &fsc.Label},
+		{"mount_options", &fsc.MountOptions},
+	} {
+		key := opt.key
+		switch d[key].(type) {
+		case nil:
+		case string:
+			*opt.value = d[key].(string)
+		default:
+			return fmt.Errorf("JSON unmarshal: %s must be string, got %v of type %T", key, d[key], d[key])
+		}
+	}
+
 	return nil
 }
 
@@ -87,3 +131,55 @@ func CheckMountpointsPolicy(mountpoints []FilesystemCustomization, mountpointAll
 
 	return nil
 }
+
+var (
+	fsLabelRegex     = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+	mountOptionRegex = regexp.MustCompile(`^[a-z0-9_-]+(=[^,\s]+)?$`)
+)
+
+// mountpoints that must stay executable for the image to boot
+var execMountpoints = []string{"/", "/usr", "/boot"}
+
+// CheckFilesystemCustomizations checks that the filesystem type, label and
+// mount options of the mountpoints are valid and that the filesystem types are
+// among the ones supported by the image type
+func CheckFilesystemCustomizations(mountpoints []FilesystemCustomization, supportedFSTypes []string) error {
+	for _, m := range mountpoints {
+		if m.FSType != "" {
+			supported := false
+			for _, t := range supportedFSTypes {
+				if m.FSType == t {
+					supported = true
+					break
+				}
+			}
+			if !supported {
+				return fmt.Errorf("Filesystem type %q of mountpoint %q is not supported (must be one of %s)", m.FSType, m.Mountpoint, strings.Join(supportedFSTypes, ", "))
+			}
+			if m.FSType == "vfat" && m.Mountpoint == "/" {
+				return fmt.Errorf("Filesystem type %q cannot be used for the root filesystem", m.FSType)
+			}
+		}
+
+		if m.Label != "" && !fsLabelRegex.MatchString(m.Label) {
+			return fmt.Errorf("Filesystem label %q of mountpoint %q is invalid", m.Label, m.Mountpoint)
+		}
+
+		if m.MountOptions != "" {
+			for _, opt := range strings.Split(m.MountOptions, ",") {
+				if !mountOptionRegex.MatchString(opt) {
+					return fmt.Errorf("Mount options %q of mountpoint %q are invalid", m.MountOptions, m.Mountpoint)
+				}
+				if opt == "noexec" {
+					for _, em := range execMountpoints {
+						if m.Mountpoint == em {
+							return fmt.Errorf("Mountpoint %q cannot be mounted with %q", m.Mountpoint, opt)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesystemCustomizationOptionsParse(t *testing.T) {
+	blueprint := `
+[[customizations.filesystem]]
+mountpoint = "/var/tmp"
+size = "1 GiB"
+fs_type = "ext4"
+label = "vartmp"
+mount_options = "nodev,nosuid,noexec"
+`
+	var bp Blueprint
+	err := toml.Unmarshal([]byte(blueprint), &bp)
+	require.Nil(t, err)
+	assert.Equal(t, FilesystemCustomization{
+		Mountpoint:   "/var/tmp",
+		MinSize:      1024 * 1024 * 1024,
+		FSType:       "ext4",
+		Label:        "vartmp",
+		MountOptions: "nodev,nosuid,noexec",
+	}, bp.Customizations.Filesystem[0])
+
+	blueprint = `{
+		"customizations": {
+		  "filesystem": [{
+			"mountpoint": "/tmp",
+			"minsize": 1073741824,
+			"fs_type": "xfs",
+			"mount_options": "nodev,nosuid,noexec"
+		  }]
+		}
+	  }`
+	bp = Blueprint{}
+	err = json.Unmarshal([]byte(blueprint), &bp)
+	require.Nil(t, err)
+	assert.Equal(t, FilesystemCustomization{
+		Mountpoint:   "/tmp",
+		MinSize:      1024 * 1024 * 1024,
+		FSType:       "xfs",
+		MountOptions: "nodev,nosuid,noexec",
+	}, bp.Customizations.Filesystem[0])
+
+	blueprint = `{"customizations": {"filesystem": [{"mountpoint": "/tmp", "minsize": 1024, "label": 42}]}}`
+	err = json.Unmarshal([]byte(blueprint), &bp)
+	assert.EqualError(t, err, "JSON unmarshal: label must be string, got 42 of type float64")
+}
+
+func TestCheckFilesystemCustomizations(t *testing.T) {
+	fsTypes := []string{"xfs", "ext4", "vfat"}
+
+	testCases := []struct {
+		name       string
+		filesystem FilesystemCustomization
+		wantErr    error
+	}{
+		{
+			name: "Test all options",
+			filesystem: FilesystemCustomization{
+				Mountpoint:   "/tmp",
+				FSType:       "ext4",
+				Label:        "tmp",
+				MountOptions: "nodev,nosuid,noexec,context=system_u:object_r:tmp_t:s0",
+			},
+		},
+		{
+			name: "Test unsupported filesystem type error",
+			filesystem: FilesystemCustomization{
+				Mountpoint: "/var",
+				FSType:     "btrfs",
+			},
+			wantErr: fmt.Errorf("Filesystem type %q of mountpoint %q is not supported (must be one of xfs, ext4, vfat)", "btrfs", "/var"),
+		},
+		{
+			name: "Test vfat root error",
+			filesystem: FilesystemCustomization{
+				Mountpoint: "/",
+				FSType:     "vfat",
+			},
+			wantErr: fmt.Errorf("Filesystem type %q cannot be used for the root filesystem", "vfat"),
+		},
+		{
+			name: "Test invalid label error",
+			filesystem: FilesystemCustomization{
+				Mountpoint: "/var",
+				Label:      "my var",
+			},
+			wantErr: fmt.Errorf("Filesystem label %q of mountpoint %q is invalid", "my var", "/var"),
+		},
+		{
+			name: "Test invalid mount options error",
+			filesystem: FilesystemCustomization{
+				Mountpoint:   "/var",
+				MountOptions: "nodev,,nosuid",
+			},
+			wantErr: fmt.Errorf("Mount options %q of mountpoint %q are invalid", "nodev,,nosuid", "/var"),
+		},
+		{
+			name: "Test noexec usr error",
+			filesystem: FilesystemCustomization{
+				Mountpoint:   "/usr",
+				MountOptions: "nodev,noexec",
+			},
+			wantErr: fmt.Errorf("Mountpoint %q cannot be mounted with %q", "/usr", "noexec"),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckFilesystemCustomizations([]FilesystemCustomization{tt.filesystem}, fsTypes)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package blueprint
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// InstallerCustomization configures the kickstart file of the Anaconda based
+// installer image types
+type InstallerCustomization struct {
+	// Install without any user interaction
+	Unattended bool `json:"unattended,omitempty" toml:"unattended,omitempty"`
+	// Disks the installer is allowed to use, e.g. "vda" or
+	// "/dev/disk/by-id/...", all other disks are ignored
+	TargetDisks []string `json:"target_disks,omitempty" toml:"target_disks,omitempty"`
+	// Partitions removed from the target disks: all, linux or none
+	ClearPart  string                            `json:"clearpart,omitempty" toml:"clearpart,omitempty"`
+	Bootloader *InstallerBootloaderCustomization `json:"bootloader,omitempty" toml:"bootloader,omitempty"`
+	// Action after the installation finished: reboot or poweroff
+	Finish string `json:"finish,omitempty" toml:"finish,omitempty"`
+	// Scripts run after the installation, as %post sections
+	PostScripts []InstallerPostScriptCustomization `json:"post,omitempty" toml:"post,omitempty"`
+	Kickstart   *KickstartCustomization            `json:"kickstart,omitempty" toml:"kickstart,omitempty"`
+}
+
+// InstallerBootloaderCustomization configures the bootloader of the
+// installed system
+type InstallerBootloaderCustomization struct {
+	// Kernel command line arguments added to the installed system
+	Append string `json:"append,omitempty" toml:"append,omitempty"`
+	// Seconds the bootloader menu waits before booting the default entry
+	Timeout *int `json:"timeout,omitempty" toml:"timeout,omitempty"`
+}
+
+// InstallerPostScriptCustomization is a kickstart %post script
+type InstallerPostScriptCustomization struct {
+	// Interpreter of the script, /bin/sh by default
+	Interpreter string `json:"interpreter,omitempty" toml:"interpreter,omitempty"`
+	// Run the script in the installer environment instead of the installed
+	// system, which is mounted at /mnt/sysroot
+	NoChroot bool `json:"nochroot,omitempty" toml:"nochroot,omitempty"`
+	// Abort the installation if the script fails
+	ErrorOnFail bool   `json:"erroronfail,omitempty" toml:"erroronfail,omitempty"`
+	Script      string `json:"script" toml:"script"`
+}
+
+// KickstartCustomization is a raw kickstart fragment
+type KickstartCustomization struct {
+	// Contents appended verbatim to the kickstart file
+	Contents string `json:"contents" toml:"contents"`
+}
+
+var (
+	installerClearPartPolicies = []string{"all", "linux", "none"}
+	installerFinishActions     = []string{"reboot", "poweroff"}
+
+	installerTargetDiskRegex = regexp.MustCompile(`^[A-Za-z0-9_./:@+-]+$`)
+	// a line ending any section of a kickstart file
+	kickstartEndRegex = regexp.MustCompile(`(?m)^\s*%end\s*$`)
+)
+
+func validateInstaller(installer *InstallerCustomization) error {
+	for _, disk := range installer.TargetDisks {
+		if !installerTargetDiskRegex.MatchString(disk) {
+			return fmt.Errorf("Installer target disk %q is invalid", disk)
+		}
+	}
+
+	if installer.ClearPart != "" && !slices.Contains(installerClearPartPolicies, installer.ClearPart) {
+		return fmt.Errorf("Installer clearpart policy %q is not supported (must be one of %s)", installer.ClearPart, strings.Join(installerClearPartPolicies, ", "))
+	}
+
+	if installer.Finish != "" && !slices.Contains(installerFinishActions, installer.Finish) {
+		return fmt.Errorf("Installer finish action %q is not supported (must be one of %s)", installer.Finish, strings.Join(installerFinishActions, ", "))
+	}
+
+	if bl := installer.Bootloader; bl != nil {
+		if strings.ContainsAny(bl.Append, "\n\"") {
+			return fmt.Errorf("Installer bootloader append %q is invalid", bl.Append)
+		}
+		if bl.Timeout != nil && *bl.Timeout < 0 {
+			return fmt.Errorf("Installer bootloader timeout must not be negative")
+		}
+	}
+
+	for idx, script := range installer.PostScripts {
+		if strings.TrimSpace(script.Script) == "" {
+			return fmt.Errorf("Installer post script #%d is empty", idx)
+		}
+		if kickstartEndRegex.MatchString(script.Script) {
+			return fmt.Errorf("Installer post script #%d must not contain %%end", idx)
+		}
+		if script.Interpreter != "" && (!filepath.IsAbs(script.Interpreter) || strings.ContainsAny(script.Interpreter, " \t\n")) {
+			return fmt.Errorf("Installer post script #%d: interpreter %q must be an absolute path", idx, script.Interpreter)
+		}
+	}
+
+	if installer.Kickstart != nil && strings.TrimSpace(installer.Kickstart.Contents) == "" {
+		return fmt.Errorf("Installer kickstart contents must not be empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package blueprint
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/osbuild/images/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallerCustomizationParse(t *testing.T) {
+	blueprint := `
+[customizations.installer]
+unattended = true
+target_disks = ["vda", "/dev/disk/by-id/virtio-disk0"]
+clearpart = "linux"
+finish = "poweroff"
+
+[customizations.installer.bootloader]
+append = "console=ttyS0"
+timeout = 3
+
+[[customizations.installer.post]]
+script = "echo hello > /etc/motd"
+
+[[customizations.installer.post]]
+interpreter = "/usr/bin/python3"
+nochroot = true
+erroronfail = true
+script = "print('hello')"
+
+[customizations.installer.kickstart]
+contents = "sshkey --username root \"ssh-ed25519 AAAA\""
+`
+	var bp Blueprint
+	err := toml.Unmarshal([]byte(blueprint), &bp)
+	require.NoError(t, err)
+
+	installer, err := bp.Customizations.GetInstaller()
+	require.NoError(t, err)
+	assert.Equal(t, &InstallerCustomization{
+		Unattended:  true,
+		TargetDisks: []string{"vda", "/dev/disk/by-id/virtio-disk0"},
+		ClearPart:   "linux",
+		Finish:      "poweroff",
+		Bootloader: &InstallerBootloaderCustomization{
+			Append:  "console=ttyS0",
+			Timeout: common.ToPtr(3),
+		},
+		PostScripts: []InstallerPostScriptCustomization{
+			{Script: "echo hello > /etc/motd"},
+			{Interpreter: "/usr/bin/python3", NoChroot: true, ErrorOnFail: true, Script: "print('hello')"},
+		},
+		Kickstart: &KickstartCustomization{Contents: `sshkey --username root "ssh-ed25519 AAAA"`},
+	}, installer)
+}
+
+func TestGetInstaller(t *testing.T) {
+	testCases := []struct {
+		name      string
+		installer *InstallerCustomization
+		wantErr   error
+	}{
+		{
+			name:      "Test empty",
+			installer: &InstallerCustomization{},
+		},
+		{
+			name: "Test unattended",
+			installer: &InstallerCustomization{
+				Unattended:  true,
+				TargetDisks: []string{"nvme0n1"},
+				ClearPart:   "none",
+				Finish:      "reboot",
+				Bootloader:  &InstallerBootloaderCustomization{Timeout: common.ToPtr(0)},
+			},
+		},
+		{
+			name:      "Test target disk error",
+			installer: &InstallerCustomization{TargetDisks: []string{"vda --only-use=vdb"}},
+			wantErr:   fmt.Errorf("Installer target disk %q is invalid", "vda --only-use=vdb"),
+		},
+		{
+			name:      "Test clearpart error",
+			installer: &InstallerCustomization{ClearPart: "list"},
+			wantErr:   fmt.Errorf("Installer clearpart policy %q is not supported (must be one of %s)", "list", "all, linux, none"),
+		},
+		{
+			name:      "Test finish error",
+			installer: &InstallerCustomization{Finish: "halt"},
+			wantErr:   fmt.Errorf("Installer finish action %q is not supported (must be one of %s)", "halt", "reboot, poweroff"),
+		},
+		{
+			name:      "Test bootloader append error",
+			installer: &InstallerCustomization{Bootloader: &InstallerBootloaderCustomization{Append: `quiet" --password="x`}},
+			wantErr:   fmt.Errorf("Installer bootloader append %q is invalid", `quiet" --password="x`),
+		},
+		{
+			name:      "Test bootloader timeout error",
+			installer: &InstallerCustomization{Bootloader: &InstallerBootloaderCustomization{Timeout: common.ToPtr(-1)}},
+			wantErr:   fmt.Errorf("Installer bootloader timeout must not be negative"),
+		},
+		{
+			name:      "Test empty post script error",
+			installer: &InstallerCustomization{PostScripts: []InstallerPostScriptCustomization{{Script: " \n"}}},
+			wantErr:   fmt.Errorf("Installer post script #%d is empty", 0),
+		},
+		{
+			name:      "Test post script end error",
+			installer: &InstallerCustomization{PostScripts: []InstallerPostScriptCustomization{{Script: "true"}, {Script: "true\n%end\n%pre\nfalse"}}},
+			wantErr:   fmt.Errorf("Installer post script #%d must not contain %%end", 1),
+		},
+		{
+			name:      "Test post script interpreter error",
+			installer: &InstallerCustomization{PostScripts: []InstallerPostScriptCustomization{{Interpreter: "python3", Script: "pass"}}},
+			wantErr:   fmt.Errorf("Installer post script #%d: interpreter %q must be an absolute path", 0, "python3"),
+		},
+		{
+			name:      "Test kickstart error",
+			installer: &InstallerCustomization{Kickstart: &KickstartCustomization{}},
+			wantErr:   fmt.Errorf("Installer kickstart contents must not be empty"),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Customizations{Installer: tt.installer}
+			installer, err := c.GetInstaller()
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.installer, installer)
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+
+	var c *Customizations
+	installer, err := c.GetInstaller()
+	assert.NoError(t, err)
+	assert.Nil(t, installer)
+}
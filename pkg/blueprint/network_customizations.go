@@ -0,0 +1,393 @@
+package blueprint
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+	"golang.org/x/exp/slices"
+)
+
+// NetworkConnectionsDir is the directory of the NetworkManager keyfiles
+// generated from the network connection customizations
+const NetworkConnectionsDir = "/etc/NetworkManager/system-connections"
+
+type NetworkCustomization struct {
+	Connections []NetworkConnectionCustomization `json:"connection,omitempty" toml:"connection,omitempty"`
+}
+
+// NetworkConnectionCustomization describes a NetworkManager connection
+// profile.
+type NetworkConnectionCustomization struct {
+	// ID of the connection, also used as the name of the keyfile
+	ID string `json:"id" toml:"id"`
+	// Type of the connection: ethernet (default), bond, bridge or vlan
+	Type string `json:"type,omitempty" toml:"type,omitempty"`
+	// Name of the network interface the connection is bound to
+	InterfaceName string `json:"interface_name,omitempty" toml:"interface_name,omitempty"`
+	// Whether the connection is activated automatically (default true)
+	Autoconnect *bool `json:"autoconnect,omitempty" toml:"autoconnect,omitempty"`
+	// ID of the bond or bridge connection this connection is a port of
+	Controller string `json:"controller,omitempty" toml:"controller,omitempty"`
+
+	IPv4 *IPConfigCustomization `json:"ipv4,omitempty" toml:"ipv4,omitempty"`
+	IPv6 *IPConfigCustomization `json:"ipv6,omitempty" toml:"ipv6,omitempty"`
+
+	Bond   *BondCustomization   `json:"bond,omitempty" toml:"bond,omitempty"`
+	Bridge *BridgeCustomization `json:"bridge,omitempty" toml:"bridge,omitempty"`
+	VLAN   *VLANCustomization   `json:"vlan,omitempty" toml:"vlan,omitempty"`
+}
+
+// IPConfigCustomization describes the IPv4 or IPv6 configuration of a
+// connection.
+type IPConfigCustomization struct {
+	// Method is one of auto (default), manual or disabled
+	Method string `json:"method,omitempty" toml:"method,omitempty"`
+	// Static addresses in CIDR notation
+	Addresses []string             `json:"addresses,omitempty" toml:"addresses,omitempty"`
+	Gateway   string               `json:"gateway,omitempty" toml:"gateway,omitempty"`
+	DNS       []string             `json:"dns,omitempty" toml:"dns,omitempty"`
+	DNSSearch []string             `json:"dns_search,omitempty" toml:"dns_search,omitempty"`
+	Routes    []RouteCustomization `json:"routes,omitempty" toml:"routes,omitempty"`
+}
+
+type RouteCustomization struct {
+	// Destination network in CIDR notation
+	Destination string  `json:"destination" toml:"destination"`
+	NextHop     string  `json:"next_hop,omitempty" toml:"next_hop,omitempty"`
+	Metric      *uint32 `json:"metric,omitempty" toml:"metric,omitempty"`
+}
+
+type BondCustomization struct {
+	// Bonding mode, e.g. active-backup or 802.3ad
+	Mode string `json:"mode,omitempty" toml:"mode,omitempty"`
+	// Additional bonding options, e.g. miimon
+	Options map[string]string `json:"options,omitempty" toml:"options,omitempty"`
+}
+
+type BridgeCustomization struct {
+	STP *bool `json:"stp,omitempty" toml:"stp,omitempty"`
+}
+
+type VLANCustomization struct {
+	ID uint16 `json:"id" toml:"id"`
+	// Interface name of the parent device
+	Parent string `json:"parent" toml:"parent"`
+}
+
+var (
+	connectionIDRegex    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+	interfaceNameRegex   = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,15}$`)
+	dnsSearchDomainRegex = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*\.?$`)
+	bondOptionRegex      = regexp.MustCompile(`^[a-z_]+$`)
+	bondOptionValueRegex = regexp.MustCompile(`^[A-Za-z0-9_.:,-]+$`)
+)
+
+var bondModes = []string{"balance-rr", "active-backup", "balance-xor", "broadcast", "802.3ad", "balance-tlb", "balance-alb"}
+
+func (conn NetworkConnectionCustomization) connectionType() string {
+	if conn.Type == "" {
+		return "ethernet"
+	}
+	return conn.Type
+}
+
+func validateNetworkConnections(conns []NetworkConnectionCustomization) error {
+	byID := make(map[string]NetworkConnectionCustomization, len(conns))
+	for _, conn := range conns {
+		if !connectionIDRegex.MatchString(conn.ID) {
+			return fmt.Errorf("Network connection ID %q is invalid", conn.ID)
+		}
+		if _, exists := byID[conn.ID]; exists {
+			return fmt.Errorf("Network connection ID %q is used more than once", conn.ID)
+		}
+		byID[conn.ID] = conn
+	}
+
+	for _, conn := range conns {
+		if err := validateNetworkConnection(conn, byID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateNetworkConnection(conn NetworkConnectionCustomization, byID map[string]NetworkConnectionCustomization) error {
+	connType := conn.connectionType()
+	switch connType {
+	case "ethernet", "vlan":
+	case "bond", "bridge":
+		// ports reference their controller by its interface name
+		if conn.InterfaceName == "" {
+			return fmt.Errorf("Network connection %q: %s connections require an interface name", conn.ID, connType)
+		}
+	default:
+		return fmt.Errorf("Network connection %q: type %q is not supported (must be one of ethernet, bond, bridge, vlan)", conn.ID, connType)
+	}
+
+	if conn.InterfaceName != "" && !interfaceNameRegex.MatchString(conn.InterfaceName) {
+		return fmt.Errorf("Network connection %q: interface name %q is invalid", conn.ID, conn.InterfaceName)
+	}
+
+	if conn.Bond != nil && connType != "bond" {
+		return fmt.Errorf("Network connection %q: bond settings require the connection type to be bond", conn.ID)
+	}
+	if conn.Bridge != nil && connType != "bridge" {
+		return fmt.Errorf("Network connection %q: bridge settings require the connection type to be bridge", conn.ID)
+	}
+	if conn.VLAN != nil && connType != "vlan" {
+		return fmt.Errorf("Network connection %q: vlan settings require the connection type to be vlan", conn.ID)
+	}
+
+	if conn.Bond != nil {
+		if conn.Bond.Mode != "" && !slices.Contains(bondModes, conn.Bond.Mode) {
+			return fmt.Errorf("Network connection %q: bond mode %q is not supported (must be one of %s)", conn.ID, conn.Bond.Mode, strings.Join(bondModes, ", "))
+		}
+		for key, value := range conn.Bond.Options {
+			if key == "mode" || !bondOptionRegex.MatchString(key) || !bondOptionValueRegex.MatchString(value) {
+				return fmt.Errorf("Network connection %q: bond option %q=%q is invalid", conn.ID, key, value)
+			}
+		}
+	}
+
+	if connType == "vlan" {
+		if conn.VLAN == nil {
+			return fmt.Errorf("Network connection %q: vlan connections require vlan settings", conn.ID)
+		}
+		if conn.VLAN.ID < 1 || conn.VLAN.ID > 4094 {
+			return fmt.Errorf("Network connection %q: vlan ID %d is out of range (1-4094)", conn.ID, conn.VLAN.ID)
+		}
+		if !interfaceNameRegex.MatchString(conn.VLAN.Parent) {
+			return fmt.Errorf("Network connection %q: vlan parent %q is invalid", conn.ID, conn.VLAN.Parent)
+		}
+	}
+
+	if conn.Controller != "" {
+		controller, exists := byID[conn.Controller]
+		if !exists {
+			return fmt.Errorf("Network connection %q: controller %q does not exist", conn.ID, conn.Controller)
+		}
+		if t := controller.connectionType(); t != "bond" && t != "bridge" {
+			return fmt.Errorf("Network connection %q: controller %q must be a bond or bridge connection", conn.ID, conn.Controller)
+		}
+		if conn.IPv4 != nil || conn.IPv6 != nil {
+			return fmt.Errorf("Network connection %q: ports of a bond or bridge cannot have an IP configuration", conn.ID)
+		}
+	}
+
+	if conn.IPv4 != nil {
+		if err := validateIPConfig(conn.IPv4, false); err != nil {
+			return fmt.Errorf("Network connection %q: ipv4 %v", conn.ID, err)
+		}
+	}
+	if conn.IPv6 != nil {
+		if err := validateIPConfig(conn.IPv6, true); err != nil {
+			return fmt.Errorf("Network connection %q: ipv6 %v", conn.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// parseIP returns the given address if it belongs to the requested family
+func parseIP(addr string, ipv6 bool) net.IP {
+	ip := net.ParseIP(addr)
+	if ip == nil || (ip.To4() == nil) != ipv6 {
+		return nil
+	}
+	return ip
+}
+
+// parseCIDR returns the given network if it belongs to the requested family
+func parseCIDR(addr string, ipv6 bool) *net.IPNet {
+	ip, ipNet, err := net.ParseCIDR(addr)
+	if err != nil || (ip.To4() == nil) != ipv6 {
+		return nil
+	}
+	return ipNet
+}
+
+func validateIPConfig(ipc *IPConfigCustomization, ipv6 bool) error {
+	switch ipc.Method {
+	case "", "auto":
+	case "manual":
+		if len(ipc.Addresses) == 0 {
+			return fmt.Errorf("method manual requires at least one address")
+		}
+	case "disabled":
+		if len(ipc.Addresses) > 0 || ipc.Gateway != "" || len(ipc.DNS) > 0 || len(ipc.DNSSearch) > 0 || len(ipc.Routes) > 0 {
+			return fmt.Errorf("method disabled does not allow any other settings")
+		}
+	default:
+		return fmt.Errorf("method %q is not supported (must be one of auto, manual, disabled)", ipc.Method)
+	}
+
+	for _, addr := range ipc.Addresses {
+		if parseCIDR(addr, ipv6) == nil {
+			return fmt.Errorf("address %q is invalid", addr)
+		}
+	}
+
+	if ipc.Gateway != "" && parseIP(ipc.Gateway, ipv6) == nil {
+		return fmt.Errorf("gateway %q is invalid", ipc.Gateway)
+	}
+
+	for _, dns := range ipc.DNS {
+		if parseIP(dns, ipv6) == nil {
+			return fmt.Errorf("DNS server %q is invalid", dns)
+		}
+	}
+
+	for _, domain := range ipc.DNSSearch {
+		if !dnsSearchDomainRegex.MatchString(domain) {
+			return fmt.Errorf("DNS search domain %q is invalid", domain)
+		}
+	}
+
+	for _, route := range ipc.Routes {
+		if parseCIDR(route.Destination, ipv6) == nil {
+			return fmt.Errorf("route destination %q is invalid", route.Destination)
+		}
+		if route.NextHop != "" && parseIP(route.NextHop, ipv6) == nil {
+			return fmt.Errorf("route next hop %q is invalid", route.NextHop)
+		}
+	}
+
+	return nil
+}
+
+// keyfile renders the connection as a NetworkManager keyfile. The
+// controllers map holds all bond and bridge connections by ID so that ports
+// can reference their controller's interface.
+func (conn NetworkConnectionCustomization) keyfile(controllers map[string]NetworkConnectionCustomization) string {
+	var b strings.Builder
+
+	connType := conn.connectionType()
+	b.WriteString("[connection]\n")
+	fmt.Fprintf(&b, "id=%s\n", conn.ID)
+	fmt.Fprintf(&b, "type=%s\n", connType)
+	if conn.InterfaceName != "" {
+		fmt.Fprintf(&b, "interface-name=%s\n", conn.InterfaceName)
+	}
+	if conn.Autoconnect != nil && !*conn.Autoconnect {
+		b.WriteString("autoconnect=false\n")
+	}
+	if conn.Controller != "" {
+		controller := controllers[conn.Controller]
+		fmt.Fprintf(&b, "master=%s\n", controller.InterfaceName)
+		fmt.Fprintf(&b, "slave-type=%s\n", controller.connectionType())
+	}
+
+	switch connType {
+	case "ethernet":
+		b.WriteString("\n[ethernet]\n")
+	case "bond":
+		b.WriteString("\n[bond]\n")
+		mode := "balance-rr"
+		var options map[string]string
+		if conn.Bond != nil {
+			if conn.Bond.Mode != "" {
+				mode = conn.Bond.Mode
+			}
+			options = conn.Bond.Options
+		}
+		fmt.Fprintf(&b, "mode=%s\n", mode)
+		keys := make([]string, 0, len(options))
+		for key := range options {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, "%s=%s\n", key, options[key])
+		}
+	case "bridge":
+		b.WriteString("\n[bridge]\n")
+		if conn.Bridge != nil && conn.Bridge.STP != nil {
+			fmt.Fprintf(&b, "stp=%t\n", *conn.Bridge.STP)
+		}
+	case "vlan":
+		b.WriteString("\n[vlan]\n")
+		fmt.Fprintf(&b, "id=%d\n", conn.VLAN.ID)
+		fmt.Fprintf(&b, "parent=%s\n", conn.VLAN.Parent)
+	}
+
+	// ports are configured through their controller
+	if conn.Controller == "" {
+		writeIPConfig(&b, "ipv4", conn.IPv4)
+		writeIPConfig(&b, "ipv6", conn.IPv6)
+	}
+
+	return b.String()
+}
+
+func writeIPConfig(b *strings.Builder, section string, ipc *IPConfigCustomization) {
+	fmt.Fprintf(b, "\n[%s]\n", section)
+	if ipc == nil {
+		b.WriteString("method=auto\n")
+		return
+	}
+
+	method := ipc.Method
+	if method == "" {
+		method = "auto"
+	}
+	fmt.Fprintf(b, "method=%s\n", method)
+
+	for idx, addr := range ipc.Addresses {
+		fmt.Fprintf(b, "address%d=%s\n", idx+1, addr)
+	}
+	if ipc.Gateway != "" {
+		fmt.Fprintf(b, "gateway=%s\n", ipc.Gateway)
+	}
+	if len(ipc.DNS) > 0 {
+		fmt.Fprintf(b, "dns=%s;\n", strings.Join(ipc.DNS, ";"))
+	}
+	if len(ipc.DNSSearch) > 0 {
+		fmt.Fprintf(b, "dns-search=%s;\n", strings.Join(ipc.DNSSearch, ";"))
+	}
+	for idx, route := range ipc.Routes {
+		value := route.Destination
+		if route.NextHop != "" || route.Metric != nil {
+			value += "," + route.NextHop
+		}
+		if route.Metric != nil {
+			value += fmt.Sprintf(",%d", *route.Metric)
+		}
+		fmt.Fprintf(b, "route%d=%s\n", idx+1, value)
+	}
+}
+
+// NetworkConnectionsToFsNodeFiles renders the given network connections as
+// NetworkManager keyfiles. The keyfiles are owned by root and readable only
+// by root, as NetworkManager ignores keyfiles with more permissive modes.
+func NetworkConnectionsToFsNodeFiles(conns []NetworkConnectionCustomization) ([]*fsnode.File, error) {
+	if len(conns) == 0 {
+		return nil, nil
+	}
+
+	controllers := make(map[string]NetworkConnectionCustomization)
+	for _, conn := range conns {
+		if t := conn.connectionType(); t == "bond" || t == "bridge" {
+			controllers[conn.ID] = conn
+		}
+	}
+
+	files := make([]*fsnode.File, 0, len(conns))
+	for _, conn := range conns {
+		keyfilePath := path.Join(NetworkConnectionsDir, conn.ID+".nmconnection")
+		file, err := fsnode.NewFile(keyfilePath, common.ToPtr(os.FileMode(0600)), "root", "root", []byte(conn.keyfile(controllers)))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,286 @@
+package blueprint
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/osbuild/images/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkCustomizationParse(t *testing.T) {
+	blueprint := `
+[[customizations.network.connection]]
+id = "bond0"
+type = "bond"
+interface_name = "bond0"
+
+[customizations.network.connection.bond]
+mode = "active-backup"
+options = { miimon = "100" }
+
+[customizations.network.connection.ipv4]
+method = "manual"
+addresses = ["192.168.1.10/24"]
+gateway = "192.168.1.1"
+dns = ["192.168.1.2"]
+dns_search = ["example.com"]
+
+[[customizations.network.connection.ipv4.routes]]
+destination = "10.0.0.0/8"
+next_hop = "192.168.1.254"
+metric = 100
+
+[[customizations.network.connection]]
+id = "bond0-port-eth0"
+interface_name = "eth0"
+controller = "bond0"
+`
+	var bp Blueprint
+	err := toml.Unmarshal([]byte(blueprint), &bp)
+	require.NoError(t, err)
+
+	conns, err := bp.Customizations.GetNetworkConnections()
+	require.NoError(t, err)
+	assert.Equal(t, []NetworkConnectionCustomization{
+		{
+			ID:            "bond0",
+			Type:          "bond",
+			InterfaceName: "bond0",
+			IPv4: &IPConfigCustomization{
+				Method:    "manual",
+				Addresses: []string{"192.168.1.10/24"},
+				Gateway:   "192.168.1.1",
+				DNS:       []string{"192.168.1.2"},
+				DNSSearch: []string{"example.com"},
+				Routes: []RouteCustomization{
+					{
+						Destination: "10.0.0.0/8",
+						NextHop:     "192.168.1.254",
+						Metric:      common.ToPtr(uint32(100)),
+					},
+				},
+			},
+			Bond: &BondCustomization{
+				Mode:    "active-backup",
+				Options: map[string]string{"miimon": "100"},
+			},
+		},
+		{
+			ID:            "bond0-port-eth0",
+			InterfaceName: "eth0",
+			Controller:    "bond0",
+		},
+	}, conns)
+}
+
+func TestGetNetworkConnections(t *testing.T) {
+	bond := NetworkConnectionCustomization{ID: "bond0", Type: "bond", InterfaceName: "bond0"}
+
+	testCases := []struct {
+		name    string
+		conns   []NetworkConnectionCustomization
+		wantErr error
+	}{
+		{
+			name: "Test static ethernet",
+			conns: []NetworkConnectionCustomization{
+				{
+					ID:            "eth0",
+					InterfaceName: "eth0",
+					IPv4: &IPConfigCustomization{
+						Method:    "manual",
+						Addresses: []string{"192.168.1.10/24"},
+						Gateway:   "192.168.1.1",
+					},
+					IPv6: &IPConfigCustomization{
+						Method:    "manual",
+						Addresses: []string{"2001:db8::10/64"},
+						DNS:       []string{"2001:db8::1"},
+					},
+				},
+			},
+		},
+		{
+			name: "Test vlan on bond",
+			conns: []NetworkConnectionCustomization{
+				bond,
+				{ID: "eth0", InterfaceName: "eth0", Controller: "bond0"},
+				{ID: "vlan100", Type: "vlan", VLAN: &VLANCustomization{ID: 100, Parent: "bond0"}},
+			},
+		},
+		{
+			name:    "Test invalid ID error",
+			conns:   []NetworkConnectionCustomization{{ID: "../eth0"}},
+			wantErr: fmt.Errorf("Network connection ID %q is invalid", "../eth0"),
+		},
+		{
+			name:    "Test duplicate ID error",
+			conns:   []NetworkConnectionCustomization{{ID: "eth0"}, {ID: "eth0"}},
+			wantErr: fmt.Errorf("Network connection ID %q is used more than once", "eth0"),
+		},
+		{
+			name:    "Test unsupported type error",
+			conns:   []NetworkConnectionCustomization{{ID: "wifi", Type: "wifi"}},
+			wantErr: fmt.Errorf("Network connection %q: type %q is not supported (must be one of ethernet, bond, bridge, vlan)", "wifi", "wifi"),
+		},
+		{
+			name:    "Test bond without interface name error",
+			conns:   []NetworkConnectionCustomization{{ID: "bond0", Type: "bond"}},
+			wantErr: fmt.Errorf("Network connection %q: bond connections require an interface name", "bond0"),
+		},
+		{
+			name:    "Test invalid interface name error",
+			conns:   []NetworkConnectionCustomization{{ID: "eth0", InterfaceName: "an-interface-name-too-long"}},
+			wantErr: fmt.Errorf("Network connection %q: interface name %q is invalid", "eth0", "an-interface-name-too-long"),
+		},
+		{
+			name:    "Test unknown bond mode error",
+			conns:   []NetworkConnectionCustomization{{ID: "bond0", Type: "bond", InterfaceName: "bond0", Bond: &BondCustomization{Mode: "fast"}}},
+			wantErr: fmt.Errorf("Network connection %q: bond mode %q is not supported (must be one of balance-rr, active-backup, balance-xor, broadcast, 802.3ad, balance-tlb, balance-alb)", "bond0", "fast"),
+		},
+		{
+			name:    "Test vlan settings on ethernet error",
+			conns:   []NetworkConnectionCustomization{{ID: "eth0", VLAN: &VLANCustomization{ID: 100, Parent: "eth1"}}},
+			wantErr: fmt.Errorf("Network connection %q: vlan settings require the connection type to be vlan", "eth0"),
+		},
+		{
+			name:    "Test vlan ID out of range error",
+			conns:   []NetworkConnectionCustomization{{ID: "vlan", Type: "vlan", VLAN: &VLANCustomization{ID: 4095, Parent: "eth0"}}},
+			wantErr: fmt.Errorf("Network connection %q: vlan ID %d is out of range (1-4094)", "vlan", 4095),
+		},
+		{
+			name:    "Test missing controller error",
+			conns:   []NetworkConnectionCustomization{{ID: "eth0", Controller: "bond0"}},
+			wantErr: fmt.Errorf("Network connection %q: controller %q does not exist", "eth0", "bond0"),
+		},
+		{
+			name: "Test port with IP configuration error",
+			conns: []NetworkConnectionCustomization{
+				bond,
+				{ID: "eth0", Controller: "bond0", IPv4: &IPConfigCustomization{}},
+			},
+			wantErr: fmt.Errorf("Network connection %q: ports of a bond or bridge cannot have an IP configuration", "eth0"),
+		},
+		{
+			name:    "Test manual without addresses error",
+			conns:   []NetworkConnectionCustomization{{ID: "eth0", IPv4: &IPConfigCustomization{Method: "manual"}}},
+			wantErr: fmt.Errorf("Network connection %q: ipv4 method manual requires at least one address", "eth0"),
+		},
+		{
+			name:    "Test ipv6 address in ipv4 error",
+			conns:   []NetworkConnectionCustomization{{ID: "eth0", IPv4: &IPConfigCustomization{Method: "manual", Addresses: []string{"2001:db8::10/64"}}}},
+			wantErr: fmt.Errorf("Network connection %q: ipv4 address %q is invalid", "eth0", "2001:db8::10/64"),
+		},
+		{
+			name:    "Test invalid gateway error",
+			conns:   []NetworkConnectionCustomization{{ID: "eth0", IPv6: &IPConfigCustomization{Gateway: "192.168.1.1"}}},
+			wantErr: fmt.Errorf("Network connection %q: ipv6 gateway %q is invalid", "eth0", "192.168.1.1"),
+		},
+		{
+			name:    "Test invalid route error",
+			conns:   []NetworkConnectionCustomization{{ID: "eth0", IPv4: &IPConfigCustomization{Routes: []RouteCustomization{{Destination: "10.0.0.0"}}}}},
+			wantErr: fmt.Errorf("Network connection %q: ipv4 route destination %q is invalid", "eth0", "10.0.0.0"),
+		},
+		{
+			name:    "Test disabled with settings error",
+			conns:   []NetworkConnectionCustomization{{ID: "eth0", IPv6: &IPConfigCustomization{Method: "disabled", DNS: []string{"2001:db8::1"}}}},
+			wantErr: fmt.Errorf("Network connection %q: ipv6 method disabled does not allow any other settings", "eth0"),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Customizations{Network: &NetworkCustomization{Connections: tt.conns}}
+			conns, err := c.GetNetworkConnections()
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.conns, conns)
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNetworkConnectionsToFsNodeFiles(t *testing.T) {
+	conns := []NetworkConnectionCustomization{
+		{
+			ID:            "bond0",
+			Type:          "bond",
+			InterfaceName: "bond0",
+			Bond: &BondCustomization{
+				Mode:    "802.3ad",
+				Options: map[string]string{"miimon": "100", "lacp_rate": "fast"},
+			},
+			IPv4: &IPConfigCustomization{
+				Method:    "manual",
+				Addresses: []string{"192.168.1.10/24"},
+				Gateway:   "192.168.1.1",
+				DNS:       []string{"192.168.1.2", "192.168.1.3"},
+				DNSSearch: []string{"example.com"},
+				Routes: []RouteCustomization{
+					{Destination: "10.0.0.0/8", NextHop: "192.168.1.254", Metric: common.ToPtr(uint32(100))},
+					{Destination: "172.16.0.0/12"},
+				},
+			},
+			IPv6: &IPConfigCustomization{Method: "disabled"},
+		},
+		{
+			ID:            "bond0-port-eth0",
+			InterfaceName: "eth0",
+			Controller:    "bond0",
+			Autoconnect:   common.ToPtr(false),
+		},
+	}
+
+	files, err := NetworkConnectionsToFsNodeFiles(conns)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	assert.Equal(t, "/etc/NetworkManager/system-connections/bond0.nmconnection", files[0].Path())
+	assert.Equal(t, os.FileMode(0600), *files[0].Mode())
+	assert.Equal(t, "root", files[0].User())
+	assert.Equal(t, "root", files[0].Group())
+	assert.Equal(t, `[connection]
+id=bond0
+type=bond
+interface-name=bond0
+
+[bond]
+mode=802.3ad
+lacp_rate=fast
+miimon=100
+
+[ipv4]
+method=manual
+address1=192.168.1.10/24
+gateway=192.168.1.1
+dns=192.168.1.2;192.168.1.3;
+dns-search=example.com;
+route1=10.0.0.0/8,192.168.1.254,100
+route2=172.16.0.0/12
+
+[ipv6]
+method=disabled
+`, string(files[0].Data()))
+
+	assert.Equal(t, "/etc/NetworkManager/system-connections/bond0-port-eth0.nmconnection", files[1].Path())
+	assert.Equal(t, `[connection]
+id=bond0-port-eth0
+type=ethernet
+interface-name=eth0
+autoconnect=false
+master=bond0
+slave-type=bond
+
+[ethernet]
+`, string(files[1].Data()))
+
+	files, err = NetworkConnectionsToFsNodeFiles(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, files)
+}
@@ -0,0 +1,177 @@
+package blueprint
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+	"golang.org/x/exp/slices"
+)
+
+// SELinuxBooleansService is the name of the systemd service that sets the
+// SELinux booleans of the SELinux customization on first boot
+const SELinuxBooleansService = "osbuild-selinux-booleans.service"
+
+// SELinuxCustomization configures SELinux
+type SELinuxCustomization struct {
+	// SELinux mode: enforcing, permissive or disabled
+	Mode string `json:"mode,omitempty" toml:"mode,omitempty"`
+	// SELinux policy type: targeted, minimum or mls
+	Policy string `json:"policy,omitempty" toml:"policy,omitempty"`
+	// SELinux booleans to set persistently, indexed by name
+	Booleans map[string]bool `json:"booleans,omitempty" toml:"booleans,omitempty"`
+	// Custom file context rules, equivalent to 'semanage fcontext -a'
+	FileContexts []SELinuxFileContextCustomization `json:"file_contexts,omitempty" toml:"file_contexts,omitempty"`
+}
+
+// SELinuxFileContextCustomization labels all files matching Path with the
+// SELinux type Type
+type SELinuxFileContextCustomization struct {
+	// Regular expression matching the absolute paths of the files
+	Path string `json:"path" toml:"path"`
+	// SELinux type, e.g. "httpd_sys_content_t"
+	Type string `json:"type" toml:"type"`
+	// Kind of files the rule applies to (default all)
+	FileType string `json:"file_type,omitempty" toml:"file_type,omitempty"`
+}
+
+var (
+	selinuxModes    = []string{"enforcing", "permissive", "disabled"}
+	selinuxPolicies = []string{"targeted", "minimum", "mls"}
+
+	// file type names of 'semanage fcontext' and their file_contexts
+	// specifier
+	selinuxFileTypes = map[string]string{
+		"all":       "",
+		"file":      "--",
+		"directory": "-d",
+		"symlink":   "-l",
+		"socket":    "-s",
+		"pipe":      "-p",
+		"char":      "-c",
+		"block":     "-b",
+	}
+
+	selinuxNameRegex = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	// whitespace separates the fields of a file_contexts entry
+	selinuxFileContextPathRegex = regexp.MustCompile(`^/\S*$`)
+)
+
+func validateSELinux(selinux *SELinuxCustomization) error {
+	if selinux.Mode != "" && !slices.Contains(selinuxModes, selinux.Mode) {
+		return fmt.Errorf("SELinux mode %q is not supported (must be one of %s)", selinux.Mode, strings.Join(selinuxModes, ", "))
+	}
+
+	if selinux.Policy != "" && !slices.Contains(selinuxPolicies, selinux.Policy) {
+		return fmt.Errorf("SELinux policy %q is not supported (must be one of %s)", selinux.Policy, strings.Join(selinuxPolicies, ", "))
+	}
+
+	for _, name := range sortedBooleanNames(selinux.Booleans) {
+		if !selinuxNameRegex.MatchString(name) {
+			return fmt.Errorf("SELinux boolean name %q is invalid", name)
+		}
+	}
+
+	for _, fc := range selinux.FileContexts {
+		if !selinuxFileContextPathRegex.MatchString(fc.Path) {
+			return fmt.Errorf("SELinux file context path %q is invalid", fc.Path)
+		}
+		if !selinuxNameRegex.MatchString(fc.Type) {
+			return fmt.Errorf("SELinux file context %q: type %q is invalid", fc.Path, fc.Type)
+		}
+		if _, ok := selinuxFileTypes[fc.FileType]; fc.FileType != "" && !ok {
+			return fmt.Errorf("SELinux file context %q: file type %q is not supported", fc.Path, fc.FileType)
+		}
+	}
+
+	return nil
+}
+
+func sortedBooleanNames(booleans map[string]bool) []string {
+	names := make([]string, 0, len(booleans))
+	for name := range booleans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SELinuxFileContextsLocalPath returns the path of the local file context
+// customizations of the given SELinux policy type, which is read by
+// restorecon and setfiles in addition to the file contexts of the policy.
+func SELinuxFileContextsLocalPath(policy string) string {
+	return fmt.Sprintf("/etc/selinux/%s/contexts/files/file_contexts.local", policy)
+}
+
+func selinuxBooleansServiceUnit(booleans map[string]bool) string {
+	settings := make([]string, 0, len(booleans))
+	for _, name := range sortedBooleanNames(booleans) {
+		value := "off"
+		if booleans[name] {
+			value = "on"
+		}
+		settings = append(settings, fmt.Sprintf("%s=%s", name, value))
+	}
+
+	// setsebool can only change the policy of a running system, so the
+	// booleans are set once on first boot
+	return fmt.Sprintf(`[Unit]
+Description=Set the SELinux booleans of the image
+ConditionSecurity=selinux
+ConditionPathExists=!/var/lib/osbuild-selinux-booleans/done
+After=local-fs.target
+
+[Service]
+Type=oneshot
+StateDirectory=osbuild-selinux-booleans
+ExecStart=/usr/sbin/setsebool -P %s
+ExecStartPost=/usr/bin/touch /var/lib/osbuild-selinux-booleans/done
+
+[Install]
+WantedBy=multi-user.target
+`, strings.Join(settings, " "))
+}
+
+// SELinuxCustomizationToFsNodeFiles renders the file context rules of the
+// given SELinux customization as the local file contexts of the policy type
+// and, if booleans are set, the unit file of SELinuxBooleansService, which
+// needs to be enabled.
+func SELinuxCustomizationToFsNodeFiles(selinux *SELinuxCustomization, policy string) ([]*fsnode.File, error) {
+	if selinux == nil {
+		return nil, nil
+	}
+
+	var files []*fsnode.File
+
+	if len(selinux.FileContexts) > 0 {
+		var b strings.Builder
+		for _, fc := range selinux.FileContexts {
+			context := fmt.Sprintf("system_u:object_r:%s:s0", fc.Type)
+			if spec := selinuxFileTypes[fc.FileType]; spec != "" {
+				fmt.Fprintf(&b, "%s %s %s\n", fc.Path, spec, context)
+			} else {
+				fmt.Fprintf(&b, "%s %s\n", fc.Path, context)
+			}
+		}
+		file, err := fsnode.NewFile(SELinuxFileContextsLocalPath(policy), common.ToPtr(os.FileMode(0644)), "root", "root", []byte(b.String()))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if len(selinux.Booleans) > 0 {
+		file, err := fsnode.NewFile(path.Join("/etc/systemd/system", SELinuxBooleansService), common.ToPtr(os.FileMode(0644)), "root", "root", []byte(selinuxBooleansServiceUnit(selinux.Booleans)))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,136 @@
+package blueprint
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSELinuxCustomizationParse(t *testing.T) {
+	blueprint := `
+[customizations.selinux]
+mode = "permissive"
+policy = "targeted"
+booleans = { httpd_can_network_connect = true }
+
+[[customizations.selinux.file_contexts]]
+path = "/srv/www(/.*)?"
+type = "httpd_sys_content_t"
+`
+	var bp Blueprint
+	err := toml.Unmarshal([]byte(blueprint), &bp)
+	require.NoError(t, err)
+
+	selinux, err := bp.Customizations.GetSELinux()
+	require.NoError(t, err)
+	assert.Equal(t, &SELinuxCustomization{
+		Mode:     "permissive",
+		Policy:   "targeted",
+		Booleans: map[string]bool{"httpd_can_network_connect": true},
+		FileContexts: []SELinuxFileContextCustomization{
+			{Path: "/srv/www(/.*)?", Type: "httpd_sys_content_t"},
+		},
+	}, selinux)
+}
+
+func TestGetSELinux(t *testing.T) {
+	testCases := []struct {
+		name    string
+		selinux *SELinuxCustomization
+		wantErr error
+	}{
+		{
+			name: "Test valid customization",
+			selinux: &SELinuxCustomization{
+				Mode:     "enforcing",
+				Policy:   "mls",
+				Booleans: map[string]bool{"virt_use_nfs": true, "ssh_sysadm_login": false},
+				FileContexts: []SELinuxFileContextCustomization{
+					{Path: "/srv/www(/.*)?", Type: "httpd_sys_content_t"},
+					{Path: "/srv/run/app.sock", Type: "httpd_var_run_t", FileType: "socket"},
+				},
+			},
+		},
+		{
+			name:    "Test unsupported mode error",
+			selinux: &SELinuxCustomization{Mode: "enabled"},
+			wantErr: fmt.Errorf("SELinux mode %q is not supported (must be one of enforcing, permissive, disabled)", "enabled"),
+		},
+		{
+			name:    "Test unsupported policy error",
+			selinux: &SELinuxCustomization{Policy: "strict"},
+			wantErr: fmt.Errorf("SELinux policy %q is not supported (must be one of targeted, minimum, mls)", "strict"),
+		},
+		{
+			name:    "Test invalid boolean error",
+			selinux: &SELinuxCustomization{Booleans: map[string]bool{"virt_use_nfs=1": true}},
+			wantErr: fmt.Errorf("SELinux boolean name %q is invalid", "virt_use_nfs=1"),
+		},
+		{
+			name:    "Test relative path error",
+			selinux: &SELinuxCustomization{FileContexts: []SELinuxFileContextCustomization{{Path: "srv/www", Type: "httpd_sys_content_t"}}},
+			wantErr: fmt.Errorf("SELinux file context path %q is invalid", "srv/www"),
+		},
+		{
+			name:    "Test path with whitespace error",
+			selinux: &SELinuxCustomization{FileContexts: []SELinuxFileContextCustomization{{Path: "/srv/my www", Type: "httpd_sys_content_t"}}},
+			wantErr: fmt.Errorf("SELinux file context path %q is invalid", "/srv/my www"),
+		},
+		{
+			name:    "Test full context error",
+			selinux: &SELinuxCustomization{FileContexts: []SELinuxFileContextCustomization{{Path: "/srv/www", Type: "system_u:object_r:httpd_sys_content_t:s0"}}},
+			wantErr: fmt.Errorf("SELinux file context %q: type %q is invalid", "/srv/www", "system_u:object_r:httpd_sys_content_t:s0"),
+		},
+		{
+			name:    "Test unsupported file type error",
+			selinux: &SELinuxCustomization{FileContexts: []SELinuxFileContextCustomization{{Path: "/srv/www", Type: "httpd_sys_content_t", FileType: "dir"}}},
+			wantErr: fmt.Errorf("SELinux file context %q: file type %q is not supported", "/srv/www", "dir"),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Customizations{SELinux: tt.selinux}
+			selinux, err := c.GetSELinux()
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.selinux, selinux)
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSELinuxCustomizationToFsNodeFiles(t *testing.T) {
+	selinux := &SELinuxCustomization{
+		Booleans: map[string]bool{"virt_use_nfs": true, "ssh_sysadm_login": false},
+		FileContexts: []SELinuxFileContextCustomization{
+			{Path: "/srv/www(/.*)?", Type: "httpd_sys_content_t"},
+			{Path: "/srv/run/app.sock", Type: "httpd_var_run_t", FileType: "socket"},
+		},
+	}
+
+	files, err := SELinuxCustomizationToFsNodeFiles(selinux, "targeted")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	assert.Equal(t, "/etc/selinux/targeted/contexts/files/file_contexts.local", files[0].Path())
+	assert.Equal(t, os.FileMode(0644), *files[0].Mode())
+	assert.Equal(t, "root", files[0].User())
+	assert.Equal(t, "root", files[0].Group())
+	assert.Equal(t, `/srv/www(/.*)? system_u:object_r:httpd_sys_content_t:s0
+/srv/run/app.sock -s system_u:object_r:httpd_var_run_t:s0
+`, string(files[0].Data()))
+
+	assert.Equal(t, "/etc/systemd/system/osbuild-selinux-booleans.service", files[1].Path())
+	assert.Contains(t, string(files[1].Data()), "ExecStart=/usr/sbin/setsebool -P ssh_sysadm_login=off virt_use_nfs=on\n")
+
+	files, err = SELinuxCustomizationToFsNodeFiles(&SELinuxCustomization{Mode: "permissive"}, "targeted")
+	assert.NoError(t, err)
+	assert.Nil(t, files)
+}
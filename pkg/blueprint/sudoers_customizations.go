@@ -0,0 +1,167 @@
+package blueprint
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+)
+
+// SudoersDir is the directory of the drop-in files generated from the
+// sudoers customizations
+const SudoersDir = "/etc/sudoers.d"
+
+// SudoersCustomization describes a drop-in file in /etc/sudoers.d
+type SudoersCustomization struct {
+	// Name of the drop-in file
+	Name string `json:"name" toml:"name"`
+	// Defaults entries, e.g. "!requiretty" or "timestamp_timeout=5"
+	Defaults []string                `json:"defaults,omitempty" toml:"defaults,omitempty"`
+	Rules    []SudoRuleCustomization `json:"rules,omitempty" toml:"rules,omitempty"`
+}
+
+// SudoRuleCustomization allows a user or all members of a group to run
+// commands as another user.
+type SudoRuleCustomization struct {
+	// Exactly one of User or Group must be set
+	User  string `json:"user,omitempty" toml:"user,omitempty"`
+	Group string `json:"group,omitempty" toml:"group,omitempty"`
+	// Target user (and optionally group) specified as "user[:group]"
+	// (default ALL)
+	RunAs    string `json:"run_as,omitempty" toml:"run_as,omitempty"`
+	NoPasswd bool   `json:"nopasswd,omitempty" toml:"nopasswd,omitempty"`
+	// Absolute paths of the allowed commands, optionally with arguments, or ALL
+	Commands []string `json:"commands" toml:"commands"`
+}
+
+// sudo ignores drop-in files that contain a '.' or end with '~'
+var sudoersNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func validateSudoersCustomizations(sudoers []SudoersCustomization, users []UserCustomization, groups []GroupCustomization) error {
+	userNames := make(map[string]bool)
+	// groups that exist on every system
+	groupNames := map[string]bool{
+		"root":  true,
+		"wheel": true,
+	}
+	for _, user := range users {
+		userNames[user.Name] = true
+		for _, group := range user.Groups {
+			groupNames[group] = true
+		}
+	}
+	for _, group := range groups {
+		groupNames[group.Name] = true
+	}
+
+	names := make(map[string]bool)
+	for _, s := range sudoers {
+		if !sudoersNameRegex.MatchString(s.Name) {
+			return fmt.Errorf("Sudoers file name %q is invalid", s.Name)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("Sudoers file name %q is used more than once", s.Name)
+		}
+		names[s.Name] = true
+
+		if len(s.Defaults) == 0 && len(s.Rules) == 0 {
+			return fmt.Errorf("Sudoers file %q: at least one defaults entry or rule is required", s.Name)
+		}
+
+		for _, d := range s.Defaults {
+			if err := checkSudoersDefault(d); err != nil {
+				return fmt.Errorf("Sudoers file %q: defaults entry %q is invalid: %v", s.Name, d, err)
+			}
+		}
+
+		for _, rule := range s.Rules {
+			switch {
+			case rule.User != "" && rule.Group != "":
+				return fmt.Errorf("Sudoers file %q: a rule can apply to either a user or a group, not both", s.Name)
+			case rule.User != "":
+				if !userNames[rule.User] {
+					return fmt.Errorf("Sudoers file %q: user %q is not defined in the blueprint", s.Name, rule.User)
+				}
+			case rule.Group != "":
+				if !groupNames[rule.Group] {
+					return fmt.Errorf("Sudoers file %q: group %q is not defined in the blueprint", s.Name, rule.Group)
+				}
+			default:
+				return fmt.Errorf("Sudoers file %q: a rule requires a user or a group", s.Name)
+			}
+
+			if rule.RunAs != "" {
+				if err := checkSudoRunAs(rule.RunAs); err != nil {
+					return fmt.Errorf("Sudoers file %q: run_as %q is invalid: %v", s.Name, rule.RunAs, err)
+				}
+			}
+
+			if len(rule.Commands) == 0 {
+				return fmt.Errorf("Sudoers file %q: a rule requires at least one command", s.Name)
+			}
+			for _, cmd := range rule.Commands {
+				if err := checkSudoCommand(cmd); err != nil {
+					return fmt.Errorf("Sudoers file %q: command %q is invalid: %v", s.Name, cmd, err)
+				}
+			}
+		}
+
+		// the entries are valid on their own, make sure that sudo also
+		// accepts the file they are rendered into
+		if err := checkSudoersSyntax(s.render()); err != nil {
+			return fmt.Errorf("Sudoers file %q is invalid: %v", s.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s SudoersCustomization) render() string {
+	var b strings.Builder
+
+	for _, d := range s.Defaults {
+		fmt.Fprintf(&b, "Defaults %s\n", d)
+	}
+
+	for _, rule := range s.Rules {
+		who := rule.User
+		if rule.Group != "" {
+			who = "%" + rule.Group
+		}
+		runAs := rule.RunAs
+		if runAs == "" {
+			runAs = "ALL"
+		}
+		tag := ""
+		if rule.NoPasswd {
+			tag = "NOPASSWD: "
+		}
+		fmt.Fprintf(&b, "%s ALL=(%s) %s%s\n", who, runAs, tag, strings.Join(rule.Commands, ", "))
+	}
+
+	return b.String()
+}
+
+// SudoersCustomizationsToFsNodeFiles renders the given sudoers
+// customizations as drop-in files. The files are owned by root and are
+// read-only, as required by sudo.
+func SudoersCustomizationsToFsNodeFiles(sudoers []SudoersCustomization) ([]*fsnode.File, error) {
+	if len(sudoers) == 0 {
+		return nil, nil
+	}
+
+	files := make([]*fsnode.File, 0, len(sudoers))
+	for _, s := range sudoers {
+		file, err := fsnode.NewFile(path.Join(SudoersDir, s.Name), common.ToPtr(os.FileMode(0440)), "root", "root", []byte(s.render()))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
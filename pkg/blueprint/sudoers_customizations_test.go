@@ -0,0 +1,200 @@
+package blueprint
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSudoersCustomizationParse(t *testing.T) {
+	blueprint := `
+[[customizations.user]]
+name = "deploy"
+
+[[customizations.sudoers]]
+name = "deploy"
+defaults = ["!requiretty"]
+
+[[customizations.sudoers.rules]]
+user = "deploy"
+nopasswd = true
+commands = ["/usr/bin/systemctl restart httpd"]
+`
+	var bp Blueprint
+	err := toml.Unmarshal([]byte(blueprint), &bp)
+	require.NoError(t, err)
+
+	sudoers, err := bp.Customizations.GetSudoers()
+	require.NoError(t, err)
+	assert.Equal(t, []SudoersCustomization{
+		{
+			Name:     "deploy",
+			Defaults: []string{"!requiretty"},
+			Rules: []SudoRuleCustomization{
+				{
+					User:     "deploy",
+					NoPasswd: true,
+					Commands: []string{"/usr/bin/systemctl restart httpd"},
+				},
+			},
+		},
+	}, sudoers)
+}
+
+func TestGetSudoers(t *testing.T) {
+	users := []UserCustomization{{Name: "alice", Groups: []string{"wheel"}}}
+	groups := []GroupCustomization{{Name: "ops"}}
+
+	testCases := []struct {
+		name    string
+		sudoers []SudoersCustomization
+		wantErr error
+	}{
+		{
+			name: "Test user and group rules",
+			sudoers: []SudoersCustomization{
+				{
+					Name:     "10-ops",
+					Defaults: []string{"timestamp_timeout=5", `env_keep += "HTTP_PROXY"`},
+					Rules: []SudoRuleCustomization{
+						{User: "alice", RunAs: "postgres", Commands: []string{"/usr/bin/psql"}},
+						{Group: "ops", NoPasswd: true, Commands: []string{"/usr/bin/systemctl restart *", "/usr/bin/journalctl"}},
+						{Group: "wheel", Commands: []string{"ALL"}},
+					},
+				},
+			},
+		},
+		{
+			name:    "Test invalid name error",
+			sudoers: []SudoersCustomization{{Name: "ops.conf", Defaults: []string{"!requiretty"}}},
+			wantErr: fmt.Errorf("Sudoers file name %q is invalid", "ops.conf"),
+		},
+		{
+			name: "Test duplicate name error",
+			sudoers: []SudoersCustomization{
+				{Name: "ops", Defaults: []string{"!requiretty"}},
+				{Name: "ops", Defaults: []string{"!requiretty"}},
+			},
+			wantErr: fmt.Errorf("Sudoers file name %q is used more than once", "ops"),
+		},
+		{
+			name:    "Test empty file error",
+			sudoers: []SudoersCustomization{{Name: "ops"}},
+			wantErr: fmt.Errorf("Sudoers file %q: at least one defaults entry or rule is required", "ops"),
+		},
+		{
+			name:    "Test invalid defaults error",
+			sudoers: []SudoersCustomization{{Name: "ops", Defaults: []string{"!requiretty\nALL ALL=(ALL) ALL"}}},
+			wantErr: fmt.Errorf("Sudoers file %q: defaults entry %q is invalid: %v", "ops", "!requiretty\nALL ALL=(ALL) ALL", `syntax error at "\n"`),
+		},
+		{
+			name:    "Test flag with value error",
+			sudoers: []SudoersCustomization{{Name: "ops", Defaults: []string{"requiretty=5"}}},
+			wantErr: fmt.Errorf("Sudoers file %q: defaults entry %q is invalid: %v", "ops", "requiretty=5", `defaults parameter "requiretty" does not take a value`),
+		},
+		{
+			name:    "Test unquoted value with spaces error",
+			sudoers: []SudoersCustomization{{Name: "ops", Defaults: []string{"env_keep += HTTP_PROXY HTTPS_PROXY"}}},
+			wantErr: fmt.Errorf("Sudoers file %q: defaults entry %q is invalid: %v", "ops", "env_keep += HTTP_PROXY HTTPS_PROXY", `syntax error at "HTTPS_PROXY"`),
+		},
+		{
+			name:    "Test unknown user error",
+			sudoers: []SudoersCustomization{{Name: "ops", Rules: []SudoRuleCustomization{{User: "alcie", Commands: []string{"ALL"}}}}},
+			wantErr: fmt.Errorf("Sudoers file %q: user %q is not defined in the blueprint", "ops", "alcie"),
+		},
+		{
+			name:    "Test unknown group error",
+			sudoers: []SudoersCustomization{{Name: "ops", Rules: []SudoRuleCustomization{{Group: "admins", Commands: []string{"ALL"}}}}},
+			wantErr: fmt.Errorf("Sudoers file %q: group %q is not defined in the blueprint", "ops", "admins"),
+		},
+		{
+			name:    "Test user and group error",
+			sudoers: []SudoersCustomization{{Name: "ops", Rules: []SudoRuleCustomization{{User: "alice", Group: "ops", Commands: []string{"ALL"}}}}},
+			wantErr: fmt.Errorf("Sudoers file %q: a rule can apply to either a user or a group, not both", "ops"),
+		},
+		{
+			name:    "Test invalid run_as error",
+			sudoers: []SudoersCustomization{{Name: "ops", Rules: []SudoRuleCustomization{{User: "alice", RunAs: "(root)", Commands: []string{"ALL"}}}}},
+			wantErr: fmt.Errorf("Sudoers file %q: run_as %q is invalid: %v", "ops", "(root)", `syntax error at "(root))"`),
+		},
+		{
+			name:    "Test missing commands error",
+			sudoers: []SudoersCustomization{{Name: "ops", Rules: []SudoRuleCustomization{{User: "alice"}}}},
+			wantErr: fmt.Errorf("Sudoers file %q: a rule requires at least one command", "ops"),
+		},
+		{
+			name:    "Test relative command error",
+			sudoers: []SudoersCustomization{{Name: "ops", Rules: []SudoRuleCustomization{{User: "alice", Commands: []string{"systemctl"}}}}},
+			wantErr: fmt.Errorf("Sudoers file %q: command %q is invalid: %v", "ops", "systemctl", "command must be an absolute path, sudoedit or ALL"),
+		},
+		{
+			name:    "Test command with separator error",
+			sudoers: []SudoersCustomization{{Name: "ops", Rules: []SudoRuleCustomization{{User: "alice", Commands: []string{"/usr/bin/id, ALL"}}}}},
+			wantErr: fmt.Errorf("Sudoers file %q: command %q is invalid: %v", "ops", "/usr/bin/id, ALL", `syntax error at ", ALL"`),
+		},
+		{
+			name:    "Test unescaped argument error",
+			sudoers: []SudoersCustomization{{Name: "ops", Rules: []SudoRuleCustomization{{User: "alice", Commands: []string{"/usr/bin/env FOO=bar"}}}}},
+			wantErr: fmt.Errorf("Sudoers file %q: command %q is invalid: %v", "ops", "/usr/bin/env FOO=bar", `'=' must be escaped in arguments "FOO=bar"`),
+		},
+		{
+			name: "Test standard groups",
+			sudoers: []SudoersCustomization{
+				{
+					Name: "admins",
+					Rules: []SudoRuleCustomization{
+						{Group: "wheel", Commands: []string{"ALL"}},
+						{Group: "root", Commands: []string{"ALL"}},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Customizations{User: users, Group: groups, Sudoers: tt.sudoers}
+			sudoers, err := c.GetSudoers()
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.sudoers, sudoers)
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSudoersCustomizationsToFsNodeFiles(t *testing.T) {
+	sudoers := []SudoersCustomization{
+		{
+			Name:     "10-ops",
+			Defaults: []string{"!requiretty"},
+			Rules: []SudoRuleCustomization{
+				{User: "alice", RunAs: "postgres", Commands: []string{"/usr/bin/psql"}},
+				{Group: "ops", NoPasswd: true, Commands: []string{"/usr/bin/systemctl restart *", "/usr/bin/journalctl"}},
+			},
+		},
+	}
+
+	files, err := SudoersCustomizationsToFsNodeFiles(sudoers)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	assert.Equal(t, "/etc/sudoers.d/10-ops", files[0].Path())
+	assert.Equal(t, os.FileMode(0440), *files[0].Mode())
+	assert.Equal(t, "root", files[0].User())
+	assert.Equal(t, "root", files[0].Group())
+	assert.Equal(t, `Defaults !requiretty
+alice ALL=(postgres) /usr/bin/psql
+%ops ALL=(ALL) NOPASSWD: /usr/bin/systemctl restart *, /usr/bin/journalctl
+`, string(files[0].Data()))
+
+	files, err = SudoersCustomizationsToFsNodeFiles(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, files)
+}
@@ -0,0 +1,534 @@
+package blueprint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The sudoers drop-ins generated from the customizations are checked against
+// the subset of the sudoers(5) grammar they can contain:
+//
+//	Default_Entry  ::= 'Defaults' Parameter_List
+//	Parameter_List ::= Parameter | Parameter ',' Parameter_List
+//	Parameter      ::= Parameter '=' Value | Parameter '+=' Value |
+//	                   Parameter '-=' Value | '!'* Parameter
+//	User_Spec      ::= User 'ALL' '=' Cmnd_Spec_List
+//	Cmnd_Spec_List ::= Cmnd_Spec | Cmnd_Spec ',' Cmnd_Spec_List
+//	Cmnd_Spec      ::= Runas_Spec? Tag_Spec* Cmnd
+//	Runas_Spec     ::= '(' Runas_List? (':' Runas_List)? ')'
+//	Cmnd           ::= '!'* 'ALL' | '!'* 'sudoedit' Args |
+//	                   '!'* Directory | '!'* Path Args?
+//
+// sudo refuses to run if any file it includes does not parse, so the check
+// has to be at least as strict as sudo itself.
+
+type sudoDefaultType int
+
+const (
+	// sudoFlag is a boolean parameter that never takes a value
+	sudoFlag sudoDefaultType = iota
+	// sudoInteger is a numeric parameter
+	sudoInteger
+	// sudoString is a parameter that requires a value unless it is negated
+	sudoString
+	// sudoBoolString is a string parameter that can also be used as a flag
+	sudoBoolString
+	// sudoList is a parameter that can be set, appended or removed from
+	sudoList
+)
+
+// sudoDefaults are the Defaults parameters that are accepted in a
+// customization. sudo rejects unknown parameters.
+var sudoDefaults = map[string]sudoDefaultType{
+	"always_query_group_plugin": sudoFlag,
+	"always_set_home":           sudoFlag,
+	"authenticate":              sudoFlag,
+	"env_editor":                sudoFlag,
+	"env_reset":                 sudoFlag,
+	"fqdn":                      sudoFlag,
+	"ignore_dot":                sudoFlag,
+	"insults":                   sudoFlag,
+	"log_host":                  sudoFlag,
+	"log_input":                 sudoFlag,
+	"log_output":                sudoFlag,
+	"log_year":                  sudoFlag,
+	"mail_always":               sudoFlag,
+	"mail_badpass":              sudoFlag,
+	"mail_no_host":              sudoFlag,
+	"mail_no_perms":             sudoFlag,
+	"mail_no_user":              sudoFlag,
+	"preserve_groups":           sudoFlag,
+	"pwfeedback":                sudoFlag,
+	"requiretty":                sudoFlag,
+	"root_sudo":                 sudoFlag,
+	"rootpw":                    sudoFlag,
+	"runaspw":                   sudoFlag,
+	"set_home":                  sudoFlag,
+	"setenv":                    sudoFlag,
+	"shell_noargs":              sudoFlag,
+	"targetpw":                  sudoFlag,
+	"umask_override":            sudoFlag,
+	"use_pty":                   sudoFlag,
+	"visiblepw":                 sudoFlag,
+
+	"closefrom":         sudoInteger,
+	"command_timeout":   sudoInteger,
+	"passwd_timeout":    sudoInteger,
+	"passwd_tries":      sudoInteger,
+	"timestamp_timeout": sudoInteger,
+	"umask":             sudoInteger,
+
+	"badpass_message": sudoString,
+	"editor":          sudoString,
+	"iolog_dir":       sudoString,
+	"iolog_file":      sudoString,
+	"lecture_file":    sudoString,
+	"mailerpath":      sudoString,
+	"mailsub":         sudoString,
+	"passprompt":      sudoString,
+	"runas_default":   sudoString,
+	"secure_path":     sudoString,
+	"timestamp_type":  sudoString,
+	"timestampdir":    sudoString,
+	"timestampowner":  sudoString,
+
+	"lecture":  sudoBoolString,
+	"listpw":   sudoBoolString,
+	"logfile":  sudoBoolString,
+	"mailto":   sudoBoolString,
+	"syslog":   sudoBoolString,
+	"verifypw": sudoBoolString,
+
+	"env_check":  sudoList,
+	"env_delete": sudoList,
+	"env_keep":   sudoList,
+}
+
+// sudoTags are the tags that can precede a command
+var sudoTags = map[string]bool{
+	"EXEC":         true,
+	"NOEXEC":       true,
+	"FOLLOW":       true,
+	"NOFOLLOW":     true,
+	"LOG_INPUT":    true,
+	"NOLOG_INPUT":  true,
+	"LOG_OUTPUT":   true,
+	"NOLOG_OUTPUT": true,
+	"MAIL":         true,
+	"NOMAIL":       true,
+	"PASSWD":       true,
+	"NOPASSWD":     true,
+	"SETENV":       true,
+	"NOSETENV":     true,
+}
+
+var sudoNumberRegex = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+type sudoersParser struct {
+	input string
+	pos   int
+}
+
+func (p *sudoersParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *sudoersParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *sudoersParser) skipSpace() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+func (p *sudoersParser) consume(s string) bool {
+	if strings.HasPrefix(p.input[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *sudoersParser) syntaxError() error {
+	if p.eof() {
+		return fmt.Errorf("unexpected end of input")
+	}
+	rest := p.input[p.pos:]
+	switch i := strings.IndexByte(rest, '\n'); {
+	case i == 0:
+		rest = "\n"
+	case i > 0:
+		rest = rest[:i]
+	}
+	return fmt.Errorf("syntax error at %q", rest)
+}
+
+// end checks that the whole input has been consumed
+func (p *sudoersParser) end() error {
+	p.skipSpace()
+	if !p.eof() {
+		return p.syntaxError()
+	}
+	return nil
+}
+
+func isSudoNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-'
+}
+
+// word returns the user, group or parameter name at the current position
+func (p *sudoersParser) word() string {
+	start := p.pos
+	for isSudoNameChar(p.peek()) {
+		p.pos++
+	}
+	// user names of machine accounts end with '$'
+	if p.pos > start && p.peek() == '$' {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+// escape consumes a backslash and the escaped character, which must be one
+// of the given special characters
+func (p *sudoersParser) escape(special string) error {
+	p.pos++
+	if p.eof() || !strings.ContainsRune(special, rune(p.peek())) {
+		return fmt.Errorf("invalid escape sequence at %q", p.input[p.pos-1:])
+	}
+	p.pos++
+	return nil
+}
+
+func (p *sudoersParser) parameterList() error {
+	for {
+		if err := p.parameter(); err != nil {
+			return err
+		}
+		p.skipSpace()
+		if !p.consume(",") {
+			return nil
+		}
+		p.skipSpace()
+	}
+}
+
+func (p *sudoersParser) parameter() error {
+	negated := false
+	for p.consume("!") {
+		negated = true
+	}
+
+	name := p.word()
+	if name == "" {
+		return p.syntaxError()
+	}
+	kind, ok := sudoDefaults[name]
+	if !ok {
+		return fmt.Errorf("unknown defaults parameter %q", name)
+	}
+
+	p.skipSpace()
+	op := ""
+	for _, o := range []string{"+=", "-=", "="} {
+		if p.consume(o) {
+			op = o
+			break
+		}
+	}
+
+	if op == "" {
+		if !negated && kind != sudoFlag && kind != sudoBoolString {
+			return fmt.Errorf("defaults parameter %q requires a value", name)
+		}
+		return nil
+	}
+
+	switch {
+	case negated:
+		return fmt.Errorf("negated defaults parameter %q cannot have a value", name)
+	case kind == sudoFlag:
+		return fmt.Errorf("defaults parameter %q does not take a value", name)
+	case op != "=" && kind != sudoList:
+		return fmt.Errorf("defaults parameter %q is not a list", name)
+	}
+
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	if kind == sudoInteger && !sudoNumberRegex.MatchString(value) {
+		return fmt.Errorf("defaults parameter %q requires a number", name)
+	}
+
+	return nil
+}
+
+// value returns a quoted or unquoted value of a defaults parameter
+func (p *sudoersParser) value() (string, error) {
+	p.skipSpace()
+
+	if p.consume(`"`) {
+		start := p.pos
+		for p.peek() != '"' {
+			if p.eof() || p.peek() == '\n' {
+				return "", fmt.Errorf("unterminated quoted value")
+			}
+			if p.peek() == '\\' {
+				p.pos++
+				if p.eof() || p.peek() == '\n' {
+					return "", fmt.Errorf("unterminated quoted value")
+				}
+			}
+			p.pos++
+		}
+		p.pos++
+		return p.input[start : p.pos-1], nil
+	}
+
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t,\n", rune(p.peek())) {
+		switch p.peek() {
+		case '"':
+			return "", p.syntaxError()
+		case '\\':
+			p.pos++
+			if p.eof() || p.peek() == '\n' {
+				return "", fmt.Errorf("line continuations are not allowed")
+			}
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return "", fmt.Errorf("missing value")
+	}
+
+	return p.input[start:p.pos], nil
+}
+
+func (p *sudoersParser) userSpec() error {
+	p.consume("%")
+	if p.word() == "" {
+		return p.syntaxError()
+	}
+	p.skipSpace()
+	if !p.consume("ALL") {
+		return p.syntaxError()
+	}
+	p.skipSpace()
+	if !p.consume("=") {
+		return p.syntaxError()
+	}
+
+	for {
+		p.skipSpace()
+		if p.consume("(") {
+			if err := p.runAsSpec(); err != nil {
+				return err
+			}
+		}
+		p.skipSpace()
+		for p.tag() {
+			p.skipSpace()
+		}
+		if err := p.command(); err != nil {
+			return err
+		}
+		p.skipSpace()
+		if !p.consume(",") {
+			return nil
+		}
+	}
+}
+
+// runAsSpec parses a Runas_Spec after its opening parenthesis
+func (p *sudoersParser) runAsSpec() error {
+	p.skipSpace()
+	if p.peek() != ':' && p.peek() != ')' {
+		if err := p.runAsList(true); err != nil {
+			return err
+		}
+	}
+	if p.consume(":") {
+		p.skipSpace()
+		if p.peek() != ')' {
+			if err := p.runAsList(false); err != nil {
+				return err
+			}
+		}
+	}
+	if !p.consume(")") {
+		return p.syntaxError()
+	}
+	return nil
+}
+
+// runAsList parses a comma separated list of users or, if users is false, of
+// groups. '%group' is allowed in a list of users.
+func (p *sudoersParser) runAsList(users bool) error {
+	for {
+		p.skipSpace()
+		switch {
+		case p.consume("#"):
+			start := p.pos
+			for p.peek() >= '0' && p.peek() <= '9' {
+				p.pos++
+			}
+			if p.pos == start {
+				return p.syntaxError()
+			}
+		default:
+			if users {
+				p.consume("%")
+			}
+			if p.word() == "" {
+				return p.syntaxError()
+			}
+		}
+		p.skipSpace()
+		if !p.consume(",") {
+			return nil
+		}
+	}
+}
+
+func (p *sudoersParser) tag() bool {
+	start := p.pos
+	for c := p.peek(); c >= 'A' && c <= 'Z' || c == '_'; c = p.peek() {
+		p.pos++
+	}
+	if sudoTags[p.input[start:p.pos]] && p.consume(":") {
+		return true
+	}
+	p.pos = start
+	return false
+}
+
+// special characters that have to be escaped in commands and their arguments
+const sudoCommandSpecial = `\,:= ` + "\t#"
+
+// glob characters that can be escaped to match them literally
+const sudoGlobSpecial = `*?[]!^`
+
+func (p *sudoersParser) command() error {
+	for p.consume("!") {
+	}
+
+	if p.peek() != '/' {
+		switch p.word() {
+		case "ALL":
+			return nil
+		case "sudoedit":
+			p.skipSpace()
+			if p.eof() || p.peek() == ',' || p.peek() == '\n' {
+				return fmt.Errorf("sudoedit requires at least one file")
+			}
+			return p.args()
+		default:
+			return fmt.Errorf("command must be an absolute path, sudoedit or ALL")
+		}
+	}
+
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t,\n", rune(p.peek())) {
+		switch p.peek() {
+		case ':', '=', '#':
+			return fmt.Errorf("%q must be escaped in command %q", p.peek(), p.input[start:])
+		case '\\':
+			if err := p.escape(sudoCommandSpecial); err != nil {
+				return err
+			}
+		default:
+			p.pos++
+		}
+	}
+
+	end := p.pos
+	p.skipSpace()
+	if p.eof() || p.peek() == ',' || p.peek() == '\n' {
+		return nil
+	}
+	if strings.HasSuffix(p.input[start:end], "/") {
+		return fmt.Errorf("directory %q cannot have arguments", p.input[start:end])
+	}
+	return p.args()
+}
+
+func (p *sudoersParser) args() error {
+	start := p.pos
+	for !p.eof() && p.peek() != ',' && p.peek() != '\n' {
+		switch p.peek() {
+		case ':', '=', '#':
+			return fmt.Errorf("%q must be escaped in arguments %q", p.peek(), p.input[start:])
+		case '\\':
+			if err := p.escape(sudoCommandSpecial + sudoGlobSpecial); err != nil {
+				return err
+			}
+		default:
+			p.pos++
+		}
+	}
+	return nil
+}
+
+// checkSudoersDefault checks a single defaults entry
+func checkSudoersDefault(entry string) error {
+	p := &sudoersParser{input: entry}
+	if err := p.parameterList(); err != nil {
+		return err
+	}
+	return p.end()
+}
+
+// checkSudoRunAs checks the target users and groups of a rule given as
+// "users[:groups]"
+func checkSudoRunAs(runAs string) error {
+	p := &sudoersParser{input: "(" + runAs + ")"}
+	p.pos++
+	if err := p.runAsSpec(); err != nil {
+		return err
+	}
+	return p.end()
+}
+
+// checkSudoCommand checks a single command of a rule
+func checkSudoCommand(cmd string) error {
+	p := &sudoersParser{input: cmd}
+	if err := p.command(); err != nil {
+		return err
+	}
+	return p.end()
+}
+
+// checkSudoersSyntax parses a complete drop-in file
+func checkSudoersSyntax(data string) error {
+	if data == "" || !strings.HasSuffix(data, "\n") {
+		return fmt.Errorf("missing newline at end of file")
+	}
+
+	for i, line := range strings.Split(strings.TrimSuffix(data, "\n"), "\n") {
+		p := &sudoersParser{input: line}
+		var err error
+		if p.consume("Defaults") && (p.peek() == ' ' || p.peek() == '\t') {
+			p.skipSpace()
+			err = p.parameterList()
+		} else {
+			p.pos = 0
+			err = p.userSpec()
+		}
+		if err == nil {
+			err = p.end()
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %v", i+1, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSudoersSyntax(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "Test valid drop-in",
+			data: `Defaults !requiretty, timestamp_timeout=-1
+Defaults secure_path = /sbin:/bin:/usr/sbin:/usr/bin
+Defaults env_keep += "HTTP_PROXY HTTPS_PROXY", !lecture
+alice ALL=(postgres:#1000) NOPASSWD: SETENV: /usr/bin/psql, !/usr/bin/su, /usr/sbin/
+%ops ALL=(ALL) /usr/bin/systemctl restart *, /usr/bin/env FOO\=bar, /usr/bin/id ""
+bob$ ALL=(:wheel) sudoedit /etc/hosts
+`,
+		},
+		{
+			name:    "Test missing newline",
+			data:    "Defaults !requiretty",
+			wantErr: "missing newline at end of file",
+		},
+		{
+			name:    "Test unknown parameter",
+			data:    "Defaults requiretyy\n",
+			wantErr: `line 1: unknown defaults parameter "requiretyy"`,
+		},
+		{
+			name:    "Test missing value",
+			data:    "Defaults secure_path\n",
+			wantErr: `line 1: defaults parameter "secure_path" requires a value`,
+		},
+		{
+			name:    "Test negated parameter with value",
+			data:    "Defaults !timestamp_timeout=5\n",
+			wantErr: `line 1: negated defaults parameter "timestamp_timeout" cannot have a value`,
+		},
+		{
+			name:    "Test non-numeric value",
+			data:    "Defaults passwd_tries=three\n",
+			wantErr: `line 1: defaults parameter "passwd_tries" requires a number`,
+		},
+		{
+			name:    "Test append to non-list",
+			data:    "Defaults secure_path += /opt/bin\n",
+			wantErr: `line 1: defaults parameter "secure_path" is not a list`,
+		},
+		{
+			name:    "Test unterminated quote",
+			data:    "Defaults env_keep = \"HOME\n",
+			wantErr: "line 1: unterminated quoted value",
+		},
+		{
+			name:    "Test line continuation",
+			data:    "Defaults secure_path = /usr/bin\\\n",
+			wantErr: "line 1: line continuations are not allowed",
+		},
+		{
+			name:    "Test unknown tag",
+			data:    "alice ALL=(ALL) NOPASSWORD: ALL\n",
+			wantErr: "line 1: command must be an absolute path, sudoedit or ALL",
+		},
+		{
+			name:    "Test arguments to ALL",
+			data:    "alice ALL=(ALL) ALL --help\n",
+			wantErr: `line 1: syntax error at "--help"`,
+		},
+		{
+			name:    "Test directory with arguments",
+			data:    "alice ALL=(ALL) /usr/bin/ -h\n",
+			wantErr: `line 1: directory "/usr/bin/" cannot have arguments`,
+		},
+		{
+			name:    "Test unescaped colon in command",
+			data:    "alice ALL=(ALL) /usr/bin/a:b\n",
+			wantErr: `line 1: ':' must be escaped in command "/usr/bin/a:b"`,
+		},
+		{
+			name:    "Test comment in arguments",
+			data:    "alice ALL=(ALL) /usr/bin/echo #1\n",
+			wantErr: `line 1: '#' must be escaped in arguments "#1"`,
+		},
+		{
+			name:    "Test invalid escape",
+			data:    "alice ALL=(ALL) /usr/bin/echo \\n\n",
+			wantErr: `line 1: invalid escape sequence at "\\n"`,
+		},
+		{
+			name:    "Test sudoedit without files",
+			data:    "alice ALL=(ALL) sudoedit\n",
+			wantErr: "line 1: sudoedit requires at least one file",
+		},
+		{
+			name:    "Test missing host",
+			data:    "alice =(ALL) ALL\n",
+			wantErr: `line 1: syntax error at "=(ALL) ALL"`,
+		},
+		{
+			name:    "Test unclosed run as",
+			data:    "alice ALL=(root ALL\n",
+			wantErr: `line 1: syntax error at "ALL"`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSudoersSyntax(tt.data)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
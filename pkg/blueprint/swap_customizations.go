@@ -0,0 +1,245 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+)
+
+const (
+	// SwapPartition places the swap space on a separate partition
+	SwapPartition = "partition"
+
+	// SwapLogicalVolume places the swap space on a logical volume in the
+	// volume group of the root filesystem
+	SwapLogicalVolume = "lv"
+
+	// SwapFile places the swap space in a file on the filesystem that holds
+	// the path of the file
+	SwapFile = "file"
+
+	// DefaultSwapFilePath is the path of the swap file if none is set
+	DefaultSwapFilePath = "/swapfile"
+
+	// SwapFileService is the name of the systemd service that creates the
+	// swap file on first boot
+	SwapFileService = "osbuild-swapfile.service"
+)
+
+// SwapCustomization adds swap space to the image.
+type SwapCustomization struct {
+	// Size of the swap space in bytes
+	Size uint64 `json:"size" toml:"size"`
+	// Placement of the swap space: partition (default), lv or file
+	Type string `json:"type,omitempty" toml:"type,omitempty"`
+	// Path of the swap file, only valid for type file
+	Path string `json:"path,omitempty" toml:"path,omitempty"`
+	// Resume from hibernation on the swap space
+	Resume bool `json:"resume,omitempty" toml:"resume,omitempty"`
+}
+
+func (sc *SwapCustomization) UnmarshalTOML(data interface{}) error {
+	d, _ := data.(map[string]interface{})
+
+	switch d["size"].(type) {
+	case int64:
+		sc.Size = uint64(d["size"].(int64))
+	case string:
+		size, err := common.DataSizeToUint64(d["size"].(string))
+		if err != nil {
+			return fmt.Errorf("TOML unmarshal: size is not valid swap size (%w)", err)
+		}
+		sc.Size = size
+	default:
+		return fmt.Errorf("TOML unmarshal: size must be integer or string, got %v of type %T", d["size"], d["size"])
+	}
+
+	for _, opt := range []struct {
+		key   string
+		value *string
+	}{
+		{"type", &sc.Type},
+		{"path", &sc.Path},
+	} {
+		key := opt.key
+		switch d[key].(type) {
+		case nil:
+		case string:
+			*opt.value = d[key].(string)
+		default:
+			return fmt.Errorf("TOML unmarshal: %s must be string, got %v of type %T", key, d[key], d[key])
+		}
+	}
+
+	switch d["resume"].(type) {
+	case nil:
+	case bool:
+		sc.Resume = d["resume"].(bool)
+	default:
+		return fmt.Errorf("TOML unmarshal: resume must be bool, got %v of type %T", d["resume"], d["resume"])
+	}
+
+	return nil
+}
+
+func (sc *SwapCustomization) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Size   interface{} `json:"size"`
+		Type   string      `json:"type"`
+		Path   string      `json:"path"`
+		Resume bool        `json:"resume"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch v.Size.(type) {
+	case float64:
+		sc.Size = uint64(v.Size.(float64))
+	case string:
+		size, err := common.DataSizeToUint64(v.Size.(string))
+		if err != nil {
+			return fmt.Errorf("JSON unmarshal: size is not valid swap size (%w)", err)
+		}
+		sc.Size = size
+	default:
+		return fmt.Errorf("JSON unmarshal: size must be float64 number or string, got %v of type %T", v.Size, v.Size)
+	}
+
+	sc.Type = v.Type
+	sc.Path = v.Path
+	sc.Resume = v.Resume
+
+	return nil
+}
+
+// GetType returns the placement of the swap space
+func (sc *SwapCustomization) GetType() string {
+	if sc.Type == "" {
+		return SwapPartition
+	}
+	return sc.Type
+}
+
+// GetPath returns the path of the swap file
+func (sc *SwapCustomization) GetPath() string {
+	if sc.Path == "" {
+		return DefaultSwapFilePath
+	}
+	return sc.Path
+}
+
+// swap files cannot be placed on these paths, since they are either virtual
+// filesystems or read by the bootloader
+var swapFileDeniedDirs = []string{"/boot", "/dev", "/proc", "/run", "/sys", "/tmp"}
+
+// the path of a swap file is written unquoted into the unit files, so it is
+// restricted to characters that systemd doesn't interpret
+var swapFilePathRegex = regexp.MustCompile(`^[A-Za-z0-9/._-]+$`)
+
+func validateSwapCustomization(swap *SwapCustomization) error {
+	if swap.Size < common.MebiByte {
+		return fmt.Errorf("Swap size must be at least 1 MiB")
+	}
+
+	switch swap.GetType() {
+	case SwapPartition, SwapLogicalVolume:
+		if swap.Path != "" {
+			return fmt.Errorf("Swap path can only be set for swap files")
+		}
+	case SwapFile:
+		p := swap.GetPath()
+		if !filepath.IsAbs(p) || filepath.Clean(p) != p || p == "/" {
+			return fmt.Errorf("Swap file path %q must be a clean absolute path", p)
+		}
+		if !swapFilePathRegex.MatchString(p) {
+			return fmt.Errorf("Swap file path %q must only contain the characters A-Z, a-z, 0-9, '/', '.', '_' and '-'", p)
+		}
+		for _, dir := range swapFileDeniedDirs {
+			if p == dir || strings.HasPrefix(p, dir+"/") {
+				return fmt.Errorf("Swap file path %q is not allowed in %s", p, dir)
+			}
+		}
+		// the offset of the file on its device is only known after it was
+		// created on first boot
+		if swap.Resume {
+			return fmt.Errorf("Resume from hibernation is not supported for swap files")
+		}
+	default:
+		return fmt.Errorf("Swap type %q is not supported (must be one of %s, %s, %s)", swap.Type, SwapPartition, SwapLogicalVolume, SwapFile)
+	}
+
+	return nil
+}
+
+// SwapFileUnit returns the name of the swap unit that activates the swap file
+// at the given path
+func SwapFileUnit(swapFile string) string {
+	return systemdEscapePath(swapFile) + ".swap"
+}
+
+// SwapFileToFsNodeFiles renders the unit files that create and activate the
+// swap file of the given swap customization. The swap file is created on
+// first boot by SwapFileService, since it would otherwise take up its full
+// size in the image. The swap unit of the file, see SwapFileUnit, needs to be
+// enabled. Nothing is returned for other types of swap space.
+func SwapFileToFsNodeFiles(swap *SwapCustomization) ([]*fsnode.File, error) {
+	if swap == nil || swap.GetType() != SwapFile {
+		return nil, nil
+	}
+
+	swapFile := swap.GetPath()
+	sizeMiB := (swap.Size + common.MebiByte - 1) / common.MebiByte
+
+	service := fmt.Sprintf(`[Unit]
+Description=Create the swap file %[1]s
+DefaultDependencies=no
+ConditionPathExists=!%[1]s
+RequiresMountsFor=%[2]s
+Before=%[3]s
+
+[Service]
+Type=oneshot
+ExecStart=/usr/bin/touch %[1]s
+ExecStart=/usr/bin/chmod 0600 %[1]s
+ExecStart=-/usr/bin/chattr +C %[1]s
+ExecStart=/usr/bin/dd if=/dev/zero of=%[1]s bs=1M count=%[4]d status=none
+ExecStart=/usr/sbin/mkswap %[1]s
+`, swapFile, path.Dir(swapFile), SwapFileUnit(swapFile), sizeMiB)
+
+	unit := fmt.Sprintf(`[Unit]
+Description=Swap file %[1]s
+Requires=%[2]s
+After=%[2]s
+
+[Swap]
+What=%[1]s
+
+[Install]
+WantedBy=swap.target
+`, swapFile, SwapFileService)
+
+	var files []*fsnode.File
+	for _, f := range []struct {
+		name string
+		data string
+	}{
+		{SwapFileService, service},
+		{SwapFileUnit(swapFile), unit},
+	} {
+		file, err := fsnode.NewFile(path.Join(SystemdUnitsDir, f.name), common.ToPtr(os.FileMode(0644)), "root", "root", []byte(f.data))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,154 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/osbuild/images/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwapCustomizationParse(t *testing.T) {
+	blueprint := `
+[customizations.disk.swap]
+size = "2 GiB"
+type = "lv"
+resume = true
+`
+	var bp Blueprint
+	err := toml.Unmarshal([]byte(blueprint), &bp)
+	require.NoError(t, err)
+
+	swap, err := bp.Customizations.GetSwap()
+	require.NoError(t, err)
+	assert.Equal(t, &SwapCustomization{Size: 2 * common.GibiByte, Type: SwapLogicalVolume, Resume: true}, swap)
+
+	var sc SwapCustomization
+	err = json.Unmarshal([]byte(`{"size": 1073741824, "type": "file", "path": "/var/swap"}`), &sc)
+	require.NoError(t, err)
+	assert.Equal(t, SwapCustomization{Size: common.GibiByte, Type: SwapFile, Path: "/var/swap"}, sc)
+
+	err = toml.Unmarshal([]byte("[customizations.disk.swap]\nsize = true\n"), &bp)
+	assert.Error(t, err)
+}
+
+func TestGetSwap(t *testing.T) {
+	testCases := []struct {
+		name    string
+		swap    *SwapCustomization
+		wantErr error
+	}{
+		{
+			name: "Test partition",
+			swap: &SwapCustomization{Size: common.GibiByte},
+		},
+		{
+			name: "Test logical volume with resume",
+			swap: &SwapCustomization{Size: common.GibiByte, Type: SwapLogicalVolume, Resume: true},
+		},
+		{
+			name: "Test file",
+			swap: &SwapCustomization{Size: common.GibiByte, Type: SwapFile, Path: "/var/swapfile"},
+		},
+		{
+			name:    "Test size error",
+			swap:    &SwapCustomization{Size: 512},
+			wantErr: fmt.Errorf("Swap size must be at least 1 MiB"),
+		},
+		{
+			name:    "Test type error",
+			swap:    &SwapCustomization{Size: common.GibiByte, Type: "zram"},
+			wantErr: fmt.Errorf("Swap type %q is not supported (must be one of %s, %s, %s)", "zram", SwapPartition, SwapLogicalVolume, SwapFile),
+		},
+		{
+			name:    "Test path for partition error",
+			swap:    &SwapCustomization{Size: common.GibiByte, Path: "/swapfile"},
+			wantErr: fmt.Errorf("Swap path can only be set for swap files"),
+		},
+		{
+			name:    "Test relative path error",
+			swap:    &SwapCustomization{Size: common.GibiByte, Type: SwapFile, Path: "var/swapfile"},
+			wantErr: fmt.Errorf("Swap file path %q must be a clean absolute path", "var/swapfile"),
+		},
+		{
+			name:    "Test path characters error",
+			swap:    &SwapCustomization{Size: common.GibiByte, Type: SwapFile, Path: "/var/swap file"},
+			wantErr: fmt.Errorf("Swap file path %q must only contain the characters A-Z, a-z, 0-9, '/', '.', '_' and '-'", "/var/swap file"),
+		},
+		{
+			name:    "Test specifier path error",
+			swap:    &SwapCustomization{Size: common.GibiByte, Type: SwapFile, Path: "/var/%h"},
+			wantErr: fmt.Errorf("Swap file path %q must only contain the characters A-Z, a-z, 0-9, '/', '.', '_' and '-'", "/var/%h"),
+		},
+		{
+			name:    "Test denied path error",
+			swap:    &SwapCustomization{Size: common.GibiByte, Type: SwapFile, Path: "/boot/swapfile"},
+			wantErr: fmt.Errorf("Swap file path %q is not allowed in %s", "/boot/swapfile", "/boot"),
+		},
+		{
+			name:    "Test resume from file error",
+			swap:    &SwapCustomization{Size: common.GibiByte, Type: SwapFile, Resume: true},
+			wantErr: fmt.Errorf("Resume from hibernation is not supported for swap files"),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Customizations{Disk: &DiskCustomization{Swap: tt.swap}}
+			swap, err := c.GetSwap()
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.swap, swap)
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSwapFileToFsNodeFiles(t *testing.T) {
+	swap := &SwapCustomization{Size: 100*common.MebiByte + 1, Type: SwapFile, Path: "/var/swap-file"}
+
+	files, err := SwapFileToFsNodeFiles(swap)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	assert.Equal(t, "/etc/systemd/system/osbuild-swapfile.service", files[0].Path())
+	assert.Equal(t, os.FileMode(0644), *files[0].Mode())
+	assert.Equal(t, `[Unit]
+Description=Create the swap file /var/swap-file
+DefaultDependencies=no
+ConditionPathExists=!/var/swap-file
+RequiresMountsFor=/var
+Before=var-swap\x2dfile.swap
+
+[Service]
+Type=oneshot
+ExecStart=/usr/bin/touch /var/swap-file
+ExecStart=/usr/bin/chmod 0600 /var/swap-file
+ExecStart=-/usr/bin/chattr +C /var/swap-file
+ExecStart=/usr/bin/dd if=/dev/zero of=/var/swap-file bs=1M count=101 status=none
+ExecStart=/usr/sbin/mkswap /var/swap-file
+`, string(files[0].Data()))
+
+	assert.Equal(t, `/etc/systemd/system/var-swap\x2dfile.swap`, files[1].Path())
+	assert.Equal(t, `[Unit]
+Description=Swap file /var/swap-file
+Requires=osbuild-swapfile.service
+After=osbuild-swapfile.service
+
+[Swap]
+What=/var/swap-file
+
+[Install]
+WantedBy=swap.target
+`, string(files[1].Data()))
+
+	files, err = SwapFileToFsNodeFiles(&SwapCustomization{Size: common.GibiByte})
+	assert.NoError(t, err)
+	assert.Nil(t, files)
+}
@@ -0,0 +1,401 @@
+package blueprint
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+	"golang.org/x/exp/slices"
+)
+
+// SystemdUnitsDir is the directory of the unit files and drop-ins generated
+// from the systemd unit customizations
+const SystemdUnitsDir = "/etc/systemd/system"
+
+type SystemdCustomization struct {
+	Units []SystemdUnitCustomization `json:"unit,omitempty" toml:"unit,omitempty"`
+}
+
+// SystemdUnitCustomization describes a systemd unit file or, if Dropin is
+// set, a drop-in that overrides the configuration of an existing unit.
+type SystemdUnitCustomization struct {
+	// Name of the unit including its type suffix, e.g. "agent.service"
+	Name string `json:"name" toml:"name"`
+	// Name of the drop-in file, e.g. "override.conf"
+	Dropin string `json:"dropin,omitempty" toml:"dropin,omitempty"`
+	// Enable the unit according to its [Install] section
+	Enabled bool `json:"enabled,omitempty" toml:"enabled,omitempty"`
+
+	Unit    *SystemdUnitSection    `json:"Unit,omitempty" toml:"Unit,omitempty"`
+	Service *SystemdServiceSection `json:"Service,omitempty" toml:"Service,omitempty"`
+	Timer   *SystemdTimerSection   `json:"Timer,omitempty" toml:"Timer,omitempty"`
+	Mount   *SystemdMountSection   `json:"Mount,omitempty" toml:"Mount,omitempty"`
+	Path    *SystemdPathSection    `json:"Path,omitempty" toml:"Path,omitempty"`
+	Install *SystemdInstallSection `json:"Install,omitempty" toml:"Install,omitempty"`
+}
+
+type SystemdUnitSection struct {
+	Description         string   `json:"Description,omitempty" toml:"Description,omitempty"`
+	Documentation       []string `json:"Documentation,omitempty" toml:"Documentation,omitempty"`
+	Wants               []string `json:"Wants,omitempty" toml:"Wants,omitempty"`
+	Requires            []string `json:"Requires,omitempty" toml:"Requires,omitempty"`
+	After               []string `json:"After,omitempty" toml:"After,omitempty"`
+	Before              []string `json:"Before,omitempty" toml:"Before,omitempty"`
+	ConditionPathExists []string `json:"ConditionPathExists,omitempty" toml:"ConditionPathExists,omitempty"`
+}
+
+type SystemdServiceSection struct {
+	Type             string   `json:"Type,omitempty" toml:"Type,omitempty"`
+	ExecStartPre     []string `json:"ExecStartPre,omitempty" toml:"ExecStartPre,omitempty"`
+	ExecStart        []string `json:"ExecStart,omitempty" toml:"ExecStart,omitempty"`
+	ExecStartPost    []string `json:"ExecStartPost,omitempty" toml:"ExecStartPost,omitempty"`
+	ExecStop         []string `json:"ExecStop,omitempty" toml:"ExecStop,omitempty"`
+	RemainAfterExit  *bool    `json:"RemainAfterExit,omitempty" toml:"RemainAfterExit,omitempty"`
+	Restart          string   `json:"Restart,omitempty" toml:"Restart,omitempty"`
+	RestartSec       string   `json:"RestartSec,omitempty" toml:"RestartSec,omitempty"`
+	User             string   `json:"User,omitempty" toml:"User,omitempty"`
+	Group            string   `json:"Group,omitempty" toml:"Group,omitempty"`
+	WorkingDirectory string   `json:"WorkingDirectory,omitempty" toml:"WorkingDirectory,omitempty"`
+	Environment      []string `json:"Environment,omitempty" toml:"Environment,omitempty"`
+	EnvironmentFile  []string `json:"EnvironmentFile,omitempty" toml:"EnvironmentFile,omitempty"`
+}
+
+type SystemdTimerSection struct {
+	OnCalendar         []string `json:"OnCalendar,omitempty" toml:"OnCalendar,omitempty"`
+	OnBootSec          string   `json:"OnBootSec,omitempty" toml:"OnBootSec,omitempty"`
+	OnUnitActiveSec    string   `json:"OnUnitActiveSec,omitempty" toml:"OnUnitActiveSec,omitempty"`
+	RandomizedDelaySec string   `json:"RandomizedDelaySec,omitempty" toml:"RandomizedDelaySec,omitempty"`
+	Persistent         *bool    `json:"Persistent,omitempty" toml:"Persistent,omitempty"`
+	Unit               string   `json:"Unit,omitempty" toml:"Unit,omitempty"`
+}
+
+type SystemdMountSection struct {
+	What    string `json:"What,omitempty" toml:"What,omitempty"`
+	Where   string `json:"Where,omitempty" toml:"Where,omitempty"`
+	Type    string `json:"Type,omitempty" toml:"Type,omitempty"`
+	Options string `json:"Options,omitempty" toml:"Options,omitempty"`
+}
+
+type SystemdPathSection struct {
+	PathExists        []string `json:"PathExists,omitempty" toml:"PathExists,omitempty"`
+	PathChanged       []string `json:"PathChanged,omitempty" toml:"PathChanged,omitempty"`
+	PathModified      []string `json:"PathModified,omitempty" toml:"PathModified,omitempty"`
+	DirectoryNotEmpty []string `json:"DirectoryNotEmpty,omitempty" toml:"DirectoryNotEmpty,omitempty"`
+	Unit              string   `json:"Unit,omitempty" toml:"Unit,omitempty"`
+}
+
+type SystemdInstallSection struct {
+	WantedBy   []string `json:"WantedBy,omitempty" toml:"WantedBy,omitempty"`
+	RequiredBy []string `json:"RequiredBy,omitempty" toml:"RequiredBy,omitempty"`
+	Alias      []string `json:"Alias,omitempty" toml:"Alias,omitempty"`
+}
+
+var (
+	systemdUnitNameRegex   = regexp.MustCompile(`^[A-Za-z0-9:_.\\-]+(@[A-Za-z0-9:_.\\-]*)?\.(service|timer|mount|path)$`)
+	systemdDropinNameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+\.conf$`)
+)
+
+// unitType returns the type suffix of the unit name, e.g. "service"
+func (u SystemdUnitCustomization) unitType() string {
+	return strings.TrimPrefix(path.Ext(u.Name), ".")
+}
+
+// filePath returns the path of the unit file or drop-in
+func (u SystemdUnitCustomization) filePath() string {
+	if u.Dropin != "" {
+		return path.Join(SystemdUnitsDir, u.Name+".d", u.Dropin)
+	}
+	return path.Join(SystemdUnitsDir, u.Name)
+}
+
+// systemdEscapePath escapes an absolute path like 'systemd-escape --path',
+// which is required for the names of mount units.
+func systemdEscapePath(p string) string {
+	p = strings.Trim(path.Clean(p), "/")
+	if p == "" {
+		return "-"
+	}
+
+	var b strings.Builder
+	for idx, c := range []byte(p) {
+		switch {
+		case c == '/':
+			b.WriteByte('-')
+		case c == '.' && idx == 0:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == ':', c == '_', c == '.':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		}
+	}
+	return b.String()
+}
+
+func validateSystemdUnits(units []SystemdUnitCustomization, services *ServicesCustomization) error {
+	paths := make(map[string]bool, len(units))
+	for _, unit := range units {
+		if err := validateSystemdUnit(unit); err != nil {
+			return err
+		}
+
+		if paths[unit.filePath()] {
+			if unit.Dropin != "" {
+				return fmt.Errorf("Systemd unit %q: drop-in %q is defined more than once", unit.Name, unit.Dropin)
+			}
+			return fmt.Errorf("Systemd unit %q is defined more than once", unit.Name)
+		}
+		paths[unit.filePath()] = true
+
+		if unit.Enabled && services != nil && slices.Contains(services.Masked, unit.Name) {
+			return fmt.Errorf("Systemd unit %q cannot be both enabled and masked", unit.Name)
+		}
+	}
+
+	return nil
+}
+
+func validateSystemdUnit(unit SystemdUnitCustomization) error {
+	if !systemdUnitNameRegex.MatchString(unit.Name) {
+		return fmt.Errorf("Systemd unit name %q is invalid (must be a service, timer, mount or path unit)", unit.Name)
+	}
+
+	if unit.Dropin != "" && !systemdDropinNameRegex.MatchString(unit.Dropin) {
+		return fmt.Errorf("Systemd unit %q: drop-in name %q is invalid (must end with .conf)", unit.Name, unit.Dropin)
+	}
+
+	unitType := unit.unitType()
+	typed := []struct {
+		name string
+		set  bool
+	}{
+		{"Service", unit.Service != nil},
+		{"Timer", unit.Timer != nil},
+		{"Mount", unit.Mount != nil},
+		{"Path", unit.Path != nil},
+	}
+	for _, section := range typed {
+		if section.set && strings.ToLower(section.name) != unitType {
+			return fmt.Errorf("Systemd unit %q: [%s] section is not supported for %s units", unit.Name, section.name, unitType)
+		}
+	}
+
+	// empty values are allowed, they reset list options in drop-ins
+	for _, entry := range unit.entries() {
+		if strings.ContainsAny(entry.value, "\n\r") {
+			return fmt.Errorf("Systemd unit %q: value %q of %s is invalid", unit.Name, entry.value, entry.key)
+		}
+	}
+
+	// drop-ins only need to set the options they override
+	if unit.Dropin != "" {
+		return nil
+	}
+
+	switch unitType {
+	case "service":
+		if unit.Service == nil || len(unit.Service.ExecStart) == 0 {
+			return fmt.Errorf("Systemd unit %q requires ExecStart in the [Service] section", unit.Name)
+		}
+	case "timer":
+		if unit.Timer == nil || (len(unit.Timer.OnCalendar) == 0 && unit.Timer.OnBootSec == "" && unit.Timer.OnUnitActiveSec == "") {
+			return fmt.Errorf("Systemd unit %q requires OnCalendar, OnBootSec or OnUnitActiveSec in the [Timer] section", unit.Name)
+		}
+	case "mount":
+		if unit.Mount == nil || unit.Mount.What == "" || unit.Mount.Where == "" {
+			return fmt.Errorf("Systemd unit %q requires What and Where in the [Mount] section", unit.Name)
+		}
+		if !path.IsAbs(unit.Mount.Where) {
+			return fmt.Errorf("Systemd unit %q: mount point %q must be an absolute path", unit.Name, unit.Mount.Where)
+		}
+		if expected := systemdEscapePath(unit.Mount.Where) + ".mount"; unit.Name != expected {
+			return fmt.Errorf("Systemd unit %q must be named %q after its mount point", unit.Name, expected)
+		}
+	case "path":
+		if unit.Path == nil || (len(unit.Path.PathExists) == 0 && len(unit.Path.PathChanged) == 0 &&
+			len(unit.Path.PathModified) == 0 && len(unit.Path.DirectoryNotEmpty) == 0) {
+			return fmt.Errorf("Systemd unit %q requires a path to watch in the [Path] section", unit.Name)
+		}
+	}
+
+	if unit.Enabled && (unit.Install == nil || len(unit.Install.WantedBy)+len(unit.Install.RequiredBy) == 0) {
+		return fmt.Errorf("Systemd unit %q cannot be enabled without WantedBy or RequiredBy in the [Install] section", unit.Name)
+	}
+
+	return nil
+}
+
+type systemdEntry struct {
+	section string
+	key     string
+	value   string
+}
+
+type systemdEntries []systemdEntry
+
+func (e *systemdEntries) add(section, key string, values ...string) {
+	for _, value := range values {
+		*e = append(*e, systemdEntry{section, key, value})
+	}
+}
+
+func (e *systemdEntries) addString(section, key, value string) {
+	if value != "" {
+		e.add(section, key, value)
+	}
+}
+
+func (e *systemdEntries) addBool(section, key string, value *bool) {
+	if value == nil {
+		return
+	}
+	if *value {
+		e.add(section, key, "yes")
+	} else {
+		e.add(section, key, "no")
+	}
+}
+
+// entries returns the options of all sections of the unit in the order they
+// are written to the unit file
+func (u SystemdUnitCustomization) entries() []systemdEntry {
+	var e systemdEntries
+
+	if s := u.Unit; s != nil {
+		e.addString("Unit", "Description", s.Description)
+		e.add("Unit", "Documentation", s.Documentation...)
+		e.add("Unit", "Wants", s.Wants...)
+		e.add("Unit", "Requires", s.Requires...)
+		e.add("Unit", "After", s.After...)
+		e.add("Unit", "Before", s.Before...)
+		e.add("Unit", "ConditionPathExists", s.ConditionPathExists...)
+	}
+
+	if s := u.Service; s != nil {
+		e.addString("Service", "Type", s.Type)
+		e.add("Service", "ExecStartPre", s.ExecStartPre...)
+		e.add("Service", "ExecStart", s.ExecStart...)
+		e.add("Service", "ExecStartPost", s.ExecStartPost...)
+		e.add("Service", "ExecStop", s.ExecStop...)
+		e.addBool("Service", "RemainAfterExit", s.RemainAfterExit)
+		e.addString("Service", "Restart", s.Restart)
+		e.addString("Service", "RestartSec", s.RestartSec)
+		e.addString("Service", "User", s.User)
+		e.addString("Service", "Group", s.Group)
+		e.addString("Service", "WorkingDirectory", s.WorkingDirectory)
+		e.add("Service", "Environment", s.Environment...)
+		e.add("Service", "EnvironmentFile", s.EnvironmentFile...)
+	}
+
+	if s := u.Timer; s != nil {
+		e.add("Timer", "OnCalendar", s.OnCalendar...)
+		e.addString("Timer", "OnBootSec", s.OnBootSec)
+		e.addString("Timer", "OnUnitActiveSec", s.OnUnitActiveSec)
+		e.addString("Timer", "RandomizedDelaySec", s.RandomizedDelaySec)
+		e.addBool("Timer", "Persistent", s.Persistent)
+		e.addString("Timer", "Unit", s.Unit)
+	}
+
+	if s := u.Mount; s != nil {
+		e.addString("Mount", "What", s.What)
+		e.addString("Mount", "Where", s.Where)
+		e.addString("Mount", "Type", s.Type)
+		e.addString("Mount", "Options", s.Options)
+	}
+
+	if s := u.Path; s != nil {
+		e.add("Path", "PathExists", s.PathExists...)
+		e.add("Path", "PathChanged", s.PathChanged...)
+		e.add("Path", "PathModified", s.PathModified...)
+		e.add("Path", "DirectoryNotEmpty", s.DirectoryNotEmpty...)
+		e.addString("Path", "Unit", s.Unit)
+	}
+
+	if s := u.Install; s != nil {
+		e.add("Install", "WantedBy", s.WantedBy...)
+		e.add("Install", "RequiredBy", s.RequiredBy...)
+		e.add("Install", "Alias", s.Alias...)
+	}
+
+	return e
+}
+
+func (u SystemdUnitCustomization) render() string {
+	var b strings.Builder
+	section := ""
+	for _, entry := range u.entries() {
+		if entry.section != section {
+			if section != "" {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "[%s]\n", entry.section)
+			section = entry.section
+		}
+		fmt.Fprintf(&b, "%s=%s\n", entry.key, entry.value)
+	}
+	return b.String()
+}
+
+// SystemdUnitsToFsNodeDirectories returns the drop-in directories of the
+// given systemd unit customizations, which must be created before the files
+// returned by SystemdUnitsToFsNodeFiles.
+func SystemdUnitsToFsNodeDirectories(units []SystemdUnitCustomization) ([]*fsnode.Directory, error) {
+	var dirs []*fsnode.Directory
+	seen := make(map[string]bool)
+	for _, unit := range units {
+		if unit.Dropin == "" {
+			continue
+		}
+		dirPath := path.Dir(unit.filePath())
+		if seen[dirPath] {
+			continue
+		}
+		seen[dirPath] = true
+
+		// the directory may already exist in the image, its permissions and
+		// ownership are kept then
+		dir, err := fsnode.NewDirectory(dirPath, nil, nil, nil, false)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+// SystemdUnitsToFsNodeFiles renders the given systemd unit customizations as
+// unit files and drop-ins in SystemdUnitsDir. The directories of the drop-ins
+// are returned by SystemdUnitsToFsNodeDirectories and the units that need to
+// be enabled by EnabledSystemdUnits.
+func SystemdUnitsToFsNodeFiles(units []SystemdUnitCustomization) ([]*fsnode.File, error) {
+	if len(units) == 0 {
+		return nil, nil
+	}
+
+	files := make([]*fsnode.File, 0, len(units))
+	for _, unit := range units {
+		file, err := fsnode.NewFile(unit.filePath(), common.ToPtr(os.FileMode(0644)), "root", "root", []byte(unit.render()))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// EnabledSystemdUnits returns the names of the units that should be enabled,
+// in the order they are defined and without duplicates.
+func EnabledSystemdUnits(units []SystemdUnitCustomization) []string {
+	var enabled []string
+	for _, unit := range units {
+		if unit.Enabled && !slices.Contains(enabled, unit.Name) {
+			enabled = append(enabled, unit.Name)
+		}
+	}
+	return enabled
+}
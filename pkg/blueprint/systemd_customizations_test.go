@@ -0,0 +1,251 @@
+package blueprint
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/osbuild/images/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemdCustomizationParse(t *testing.T) {
+	blueprint := `
+[customizations.services]
+masked = ["kdump.service"]
+
+[[customizations.systemd.unit]]
+name = "backup.service"
+
+[customizations.systemd.unit.Service]
+Type = "oneshot"
+ExecStart = ["/usr/local/bin/backup"]
+
+[[customizations.systemd.unit]]
+name = "backup.timer"
+enabled = true
+
+[customizations.systemd.unit.Timer]
+OnCalendar = ["daily"]
+Persistent = true
+
+[customizations.systemd.unit.Install]
+WantedBy = ["timers.target"]
+
+[[customizations.systemd.unit]]
+name = "sshd.service"
+dropin = "10-restart.conf"
+
+[customizations.systemd.unit.Service]
+Restart = "always"
+`
+	var bp Blueprint
+	err := toml.Unmarshal([]byte(blueprint), &bp)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"kdump.service"}, bp.Customizations.GetServices().Masked)
+
+	units, err := bp.Customizations.GetSystemdUnits()
+	require.NoError(t, err)
+	assert.Equal(t, []SystemdUnitCustomization{
+		{
+			Name:    "backup.service",
+			Service: &SystemdServiceSection{Type: "oneshot", ExecStart: []string{"/usr/local/bin/backup"}},
+		},
+		{
+			Name:    "backup.timer",
+			Enabled: true,
+			Timer:   &SystemdTimerSection{OnCalendar: []string{"daily"}, Persistent: common.ToPtr(true)},
+			Install: &SystemdInstallSection{WantedBy: []string{"timers.target"}},
+		},
+		{
+			Name:    "sshd.service",
+			Dropin:  "10-restart.conf",
+			Service: &SystemdServiceSection{Restart: "always"},
+		},
+	}, units)
+}
+
+func TestGetSystemdUnits(t *testing.T) {
+	testCases := []struct {
+		name     string
+		units    []SystemdUnitCustomization
+		services *ServicesCustomization
+		wantErr  error
+	}{
+		{
+			name: "Test valid customization",
+			units: []SystemdUnitCustomization{
+				{
+					Name:    "agent.service",
+					Enabled: true,
+					Service: &SystemdServiceSection{ExecStart: []string{"/usr/bin/agent"}},
+					Install: &SystemdInstallSection{WantedBy: []string{"multi-user.target"}},
+				},
+				{Name: "agent.service", Dropin: "10-env.conf", Service: &SystemdServiceSection{Environment: []string{"DEBUG=1"}}},
+				{Name: "var-lib-data.mount", Mount: &SystemdMountSection{What: "/dev/vdb1", Where: "/var/lib/data"}},
+				{Name: "spool.path", Path: &SystemdPathSection{DirectoryNotEmpty: []string{"/var/spool/app"}}},
+				// drop-ins can reset list options with an empty value
+				{Name: "getty@.service", Dropin: "autologin.conf", Service: &SystemdServiceSection{ExecStart: []string{"", "/sbin/agetty --autologin root %I"}}},
+			},
+		},
+		{
+			name:    "Test unsupported unit type error",
+			units:   []SystemdUnitCustomization{{Name: "agent.socket"}},
+			wantErr: fmt.Errorf("Systemd unit name %q is invalid (must be a service, timer, mount or path unit)", "agent.socket"),
+		},
+		{
+			name:    "Test invalid drop-in name error",
+			units:   []SystemdUnitCustomization{{Name: "sshd.service", Dropin: "../override.conf"}},
+			wantErr: fmt.Errorf("Systemd unit %q: drop-in name %q is invalid (must end with .conf)", "sshd.service", "../override.conf"),
+		},
+		{
+			name:    "Test section of other unit type error",
+			units:   []SystemdUnitCustomization{{Name: "backup.timer", Service: &SystemdServiceSection{ExecStart: []string{"/bin/true"}}}},
+			wantErr: fmt.Errorf("Systemd unit %q: [%s] section is not supported for %s units", "backup.timer", "Service", "timer"),
+		},
+		{
+			name:    "Test newline in value error",
+			units:   []SystemdUnitCustomization{{Name: "agent.service", Service: &SystemdServiceSection{ExecStart: []string{"/usr/bin/agent\nUser=root"}}}},
+			wantErr: fmt.Errorf("Systemd unit %q: value %q of %s is invalid", "agent.service", "/usr/bin/agent\nUser=root", "ExecStart"),
+		},
+		{
+			name:    "Test service without ExecStart error",
+			units:   []SystemdUnitCustomization{{Name: "agent.service", Service: &SystemdServiceSection{Type: "simple"}}},
+			wantErr: fmt.Errorf("Systemd unit %q requires ExecStart in the [Service] section", "agent.service"),
+		},
+		{
+			name:    "Test timer without trigger error",
+			units:   []SystemdUnitCustomization{{Name: "backup.timer", Timer: &SystemdTimerSection{Unit: "backup.service"}}},
+			wantErr: fmt.Errorf("Systemd unit %q requires OnCalendar, OnBootSec or OnUnitActiveSec in the [Timer] section", "backup.timer"),
+		},
+		{
+			name:    "Test mount unit name error",
+			units:   []SystemdUnitCustomization{{Name: "data.mount", Mount: &SystemdMountSection{What: "/dev/vdb1", Where: "/var/lib/data"}}},
+			wantErr: fmt.Errorf("Systemd unit %q must be named %q after its mount point", "data.mount", "var-lib-data.mount"),
+		},
+		{
+			name:    "Test path unit without path error",
+			units:   []SystemdUnitCustomization{{Name: "spool.path", Path: &SystemdPathSection{Unit: "spool.service"}}},
+			wantErr: fmt.Errorf("Systemd unit %q requires a path to watch in the [Path] section", "spool.path"),
+		},
+		{
+			name:    "Test enabled without install section error",
+			units:   []SystemdUnitCustomization{{Name: "agent.service", Enabled: true, Service: &SystemdServiceSection{ExecStart: []string{"/usr/bin/agent"}}}},
+			wantErr: fmt.Errorf("Systemd unit %q cannot be enabled without WantedBy or RequiredBy in the [Install] section", "agent.service"),
+		},
+		{
+			name: "Test duplicate unit error",
+			units: []SystemdUnitCustomization{
+				{Name: "agent.service", Service: &SystemdServiceSection{ExecStart: []string{"/usr/bin/agent"}}},
+				{Name: "agent.service", Service: &SystemdServiceSection{ExecStart: []string{"/usr/bin/agent2"}}},
+			},
+			wantErr: fmt.Errorf("Systemd unit %q is defined more than once", "agent.service"),
+		},
+		{
+			name: "Test duplicate drop-in error",
+			units: []SystemdUnitCustomization{
+				{Name: "sshd.service", Dropin: "override.conf"},
+				{Name: "sshd.service", Dropin: "override.conf"},
+			},
+			wantErr: fmt.Errorf("Systemd unit %q: drop-in %q is defined more than once", "sshd.service", "override.conf"),
+		},
+		{
+			name:     "Test enabled and masked error",
+			units:    []SystemdUnitCustomization{{Name: "sshd.service", Dropin: "override.conf", Enabled: true}},
+			services: &ServicesCustomization{Masked: []string{"sshd.service"}},
+			wantErr:  fmt.Errorf("Systemd unit %q cannot be both enabled and masked", "sshd.service"),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Customizations{Systemd: &SystemdCustomization{Units: tt.units}, Services: tt.services}
+			units, err := c.GetSystemdUnits()
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.units, units)
+			} else {
+				assert.Equal(t, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSystemdEscapePath(t *testing.T) {
+	assert.Equal(t, "-", systemdEscapePath("/"))
+	assert.Equal(t, "var-lib-data", systemdEscapePath("/var/lib/data/"))
+	assert.Equal(t, `mnt-my\x2ddisk`, systemdEscapePath("/mnt/my-disk"))
+	assert.Equal(t, `\x2esnapshots`, systemdEscapePath("/.snapshots"))
+}
+
+func TestSystemdUnitsToFsNodeFiles(t *testing.T) {
+	units := []SystemdUnitCustomization{
+		{
+			Name:    "backup.timer",
+			Enabled: true,
+			Unit:    &SystemdUnitSection{Description: "Daily backup"},
+			Timer:   &SystemdTimerSection{OnCalendar: []string{"daily"}, Persistent: common.ToPtr(true)},
+			Install: &SystemdInstallSection{WantedBy: []string{"timers.target"}},
+		},
+		{
+			Name:    "getty@.service",
+			Dropin:  "autologin.conf",
+			Service: &SystemdServiceSection{ExecStart: []string{"", "/sbin/agetty --autologin root %I"}},
+		},
+	}
+
+	files, err := SystemdUnitsToFsNodeFiles(units)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	assert.Equal(t, "/etc/systemd/system/backup.timer", files[0].Path())
+	assert.Equal(t, os.FileMode(0644), *files[0].Mode())
+	assert.Equal(t, "root", files[0].User())
+	assert.Equal(t, "root", files[0].Group())
+	assert.Equal(t, `[Unit]
+Description=Daily backup
+
+[Timer]
+OnCalendar=daily
+Persistent=yes
+
+[Install]
+WantedBy=timers.target
+`, string(files[0].Data()))
+
+	assert.Equal(t, "/etc/systemd/system/getty@.service.d/autologin.conf", files[1].Path())
+	assert.Equal(t, `[Service]
+ExecStart=
+ExecStart=/sbin/agetty --autologin root %I
+`, string(files[1].Data()))
+
+	assert.Equal(t, []string{"backup.timer"}, EnabledSystemdUnits(units))
+
+	files, err = SystemdUnitsToFsNodeFiles(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, files)
+}
+
+func TestSystemdUnitsToFsNodeDirectories(t *testing.T) {
+	units := []SystemdUnitCustomization{
+		{Name: "backup.service", Service: &SystemdServiceSection{ExecStart: []string{"/usr/bin/backup"}}},
+		{Name: "getty@.service", Dropin: "autologin.conf", Service: &SystemdServiceSection{ExecStart: []string{""}}},
+		{Name: "getty@.service", Dropin: "timeout.conf", Service: &SystemdServiceSection{Restart: "always"}},
+	}
+
+	// the copy stage of the drop-ins doesn't create their directory
+	dirs, err := SystemdUnitsToFsNodeDirectories(units)
+	require.NoError(t, err)
+	require.Len(t, dirs, 1)
+	assert.Equal(t, "/etc/systemd/system/getty@.service.d", dirs[0].Path())
+	assert.Nil(t, dirs[0].Mode())
+	assert.Nil(t, dirs[0].User())
+	assert.Nil(t, dirs[0].Group())
+
+	dirs, err = SystemdUnitsToFsNodeDirectories(units[:1])
+	assert.NoError(t, err)
+	assert.Nil(t, dirs)
+}
@@ -0,0 +1,139 @@
+package blueprint
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+)
+
+// ModprobeOptionsPath is the path of the modprobe configuration file
+// generated from the module options of the modprobe customization
+const ModprobeOptionsPath = "/etc/modprobe.d/blueprint-options.conf"
+
+// ModprobeCustomization configures the loading of kernel modules
+type ModprobeCustomization struct {
+	// Kernel modules that are not loaded automatically
+	Blacklist []string `json:"blacklist,omitempty" toml:"blacklist,omitempty"`
+	// Options passed to kernel modules when they are loaded, indexed by
+	// module name, e.g. {"kvm_intel": "nested=1"}
+	Options map[string]string `json:"options,omitempty" toml:"options,omitempty"`
+}
+
+// TunedCustomization selects the TuneD profiles to activate
+type TunedCustomization struct {
+	Profiles []string `json:"profiles" toml:"profiles"`
+}
+
+// DracutCustomization adds modules and drivers to the initramfs
+type DracutCustomization struct {
+	// Additional dracut modules, e.g. "multipath"
+	AddModules []string `json:"add_modules,omitempty" toml:"add_modules,omitempty"`
+	// Additional kernel modules, e.g. "nvme"
+	AddDrivers []string `json:"add_drivers,omitempty" toml:"add_drivers,omitempty"`
+}
+
+var (
+	sysctlKeyRegex    = regexp.MustCompile(`^[A-Za-z0-9_*]+([./][A-Za-z0-9_*-]+)*$`)
+	kernelModuleRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	tunedProfileRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// sortedKeys returns the keys of m in lexical order, so that validation errors
+// and rendered files are stable
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func validateSysctl(sysctl map[string]string) error {
+	for _, key := range sortedKeys(sysctl) {
+		value := sysctl[key]
+		if !sysctlKeyRegex.MatchString(key) {
+			return fmt.Errorf("Sysctl key %q is invalid", key)
+		}
+		if value == "" || strings.ContainsAny(value, "\n\r") {
+			return fmt.Errorf("Sysctl value %q of key %q is invalid", value, key)
+		}
+	}
+
+	return nil
+}
+
+func validateModprobe(modprobe *ModprobeCustomization) error {
+	for _, module := range modprobe.Blacklist {
+		if !kernelModuleRegex.MatchString(module) {
+			return fmt.Errorf("Modprobe blacklist: module name %q is invalid", module)
+		}
+	}
+
+	for _, module := range sortedKeys(modprobe.Options) {
+		options := modprobe.Options[module]
+		if !kernelModuleRegex.MatchString(module) {
+			return fmt.Errorf("Modprobe options: module name %q is invalid", module)
+		}
+		if options == "" || strings.ContainsAny(options, "\n\r") {
+			return fmt.Errorf("Modprobe options %q of module %q are invalid", options, module)
+		}
+	}
+
+	return nil
+}
+
+func validateTuned(tuned *TunedCustomization) error {
+	if len(tuned.Profiles) == 0 {
+		return fmt.Errorf("Tuned customization requires at least one profile")
+	}
+
+	for _, profile := range tuned.Profiles {
+		if !tunedProfileRegex.MatchString(profile) {
+			return fmt.Errorf("Tuned profile name %q is invalid", profile)
+		}
+	}
+
+	return nil
+}
+
+func validateDracut(dracut *DracutCustomization) error {
+	for _, module := range dracut.AddModules {
+		if !kernelModuleRegex.MatchString(module) {
+			return fmt.Errorf("Dracut module name %q is invalid", module)
+		}
+	}
+
+	for _, driver := range dracut.AddDrivers {
+		if !kernelModuleRegex.MatchString(driver) {
+			return fmt.Errorf("Dracut driver name %q is invalid", driver)
+		}
+	}
+
+	return nil
+}
+
+// ModprobeOptionsToFsNodeFiles renders the module options of the given
+// modprobe customization as a modprobe configuration file.
+func ModprobeOptionsToFsNodeFiles(modprobe *ModprobeCustomization) ([]*fsnode.File, error) {
+	if modprobe == nil || len(modprobe.Options) == 0 {
+		return nil, nil
+	}
+
+	var b strings.Builder
+	for _, module := range sortedKeys(modprobe.Options) {
+		fmt.Fprintf(&b, "options %s %s\n", module, modprobe.Options[module])
+	}
+
+	file, err := fsnode.NewFile(ModprobeOptionsPath, common.ToPtr(os.FileMode(0644)), "root", "root", []byte(b.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	return []*fsnode.File{file}, nil
+}
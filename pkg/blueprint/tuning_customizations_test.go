@@ -0,0 +1,148 @@
+package blueprint
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTuningCustomizationsParse(t *testing.T) {
+	blueprint := `
+[customizations.sysctl]
+"net.ipv4.ip_forward" = "1"
+
+[customizations.modprobe]
+blacklist = ["nouveau"]
+options = { kvm_intel = "nested=1" }
+
+[customizations.tuned]
+profiles = ["virtual-guest"]
+
+[customizations.dracut]
+add_modules = ["multipath"]
+add_drivers = ["nvme"]
+`
+	var bp Blueprint
+	err := toml.Unmarshal([]byte(blueprint), &bp)
+	require.NoError(t, err)
+
+	sysctl, err := bp.Customizations.GetSysctl()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"net.ipv4.ip_forward": "1"}, sysctl)
+
+	modprobe, err := bp.Customizations.GetModprobe()
+	require.NoError(t, err)
+	assert.Equal(t, &ModprobeCustomization{
+		Blacklist: []string{"nouveau"},
+		Options:   map[string]string{"kvm_intel": "nested=1"},
+	}, modprobe)
+
+	tuned, err := bp.Customizations.GetTuned()
+	require.NoError(t, err)
+	assert.Equal(t, &TunedCustomization{Profiles: []string{"virtual-guest"}}, tuned)
+
+	dracut, err := bp.Customizations.GetDracut()
+	require.NoError(t, err)
+	assert.Equal(t, &DracutCustomization{
+		AddModules: []string{"multipath"},
+		AddDrivers: []string{"nvme"},
+	}, dracut)
+}
+
+func TestGetTuningCustomizations(t *testing.T) {
+	testCases := []struct {
+		name    string
+		c       *Customizations
+		wantErr error
+	}{
+		{
+			name: "Test valid customizations",
+			c: &Customizations{
+				Sysctl:   map[string]string{"kernel.sched_autogroup_enabled": "0", "net/ipv4/conf/eth0.100/rp_filter": "2"},
+				Modprobe: &ModprobeCustomization{Blacklist: []string{"floppy"}, Options: map[string]string{"bonding": "max_bonds=0"}},
+				Tuned:    &TunedCustomization{Profiles: []string{"throughput-performance", "sap-hana"}},
+				Dracut:   &DracutCustomization{AddModules: []string{"fips"}, AddDrivers: []string{"hv_vmbus"}},
+			},
+		},
+		{
+			name:    "Test invalid sysctl key error",
+			c:       &Customizations{Sysctl: map[string]string{"vm swappiness": "10"}},
+			wantErr: fmt.Errorf("Sysctl key %q is invalid", "vm swappiness"),
+		},
+		{
+			name:    "Test invalid sysctl value error",
+			c:       &Customizations{Sysctl: map[string]string{"vm.swappiness": "10\nkernel.panic = 1"}},
+			wantErr: fmt.Errorf("Sysctl value %q of key %q is invalid", "10\nkernel.panic = 1", "vm.swappiness"),
+		},
+		{
+			name:    "Test invalid blacklisted module error",
+			c:       &Customizations{Modprobe: &ModprobeCustomization{Blacklist: []string{"nouveau.ko"}}},
+			wantErr: fmt.Errorf("Modprobe blacklist: module name %q is invalid", "nouveau.ko"),
+		},
+		{
+			name:    "Test empty module options error",
+			c:       &Customizations{Modprobe: &ModprobeCustomization{Options: map[string]string{"kvm_intel": ""}}},
+			wantErr: fmt.Errorf("Modprobe options %q of module %q are invalid", "", "kvm_intel"),
+		},
+		{
+			name:    "Test missing tuned profile error",
+			c:       &Customizations{Tuned: &TunedCustomization{}},
+			wantErr: fmt.Errorf("Tuned customization requires at least one profile"),
+		},
+		{
+			name:    "Test invalid tuned profile error",
+			c:       &Customizations{Tuned: &TunedCustomization{Profiles: []string{"../virtual-guest"}}},
+			wantErr: fmt.Errorf("Tuned profile name %q is invalid", "../virtual-guest"),
+		},
+		{
+			name:    "Test invalid dracut driver error",
+			c:       &Customizations{Dracut: &DracutCustomization{AddDrivers: []string{"nvme nvme_core"}}},
+			wantErr: fmt.Errorf("Dracut driver name %q is invalid", "nvme nvme_core"),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs []error
+			_, err := tt.c.GetSysctl()
+			errs = append(errs, err)
+			_, err = tt.c.GetModprobe()
+			errs = append(errs, err)
+			_, err = tt.c.GetTuned()
+			errs = append(errs, err)
+			_, err = tt.c.GetDracut()
+			errs = append(errs, err)
+
+			if tt.wantErr == nil {
+				assert.Equal(t, []error{nil, nil, nil, nil}, errs)
+			} else {
+				assert.Contains(t, errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestModprobeOptionsToFsNodeFiles(t *testing.T) {
+	files, err := ModprobeOptionsToFsNodeFiles(&ModprobeCustomization{
+		Blacklist: []string{"nouveau"},
+		Options:   map[string]string{"kvm_intel": "nested=1", "bonding": "max_bonds=0 miimon=100"},
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	assert.Equal(t, "/etc/modprobe.d/blueprint-options.conf", files[0].Path())
+	assert.Equal(t, os.FileMode(0644), *files[0].Mode())
+	assert.Equal(t, "root", files[0].User())
+	assert.Equal(t, "root", files[0].Group())
+	assert.Equal(t, `options bonding max_bonds=0 miimon=100
+options kvm_intel nested=1
+`, string(files[0].Data()))
+
+	files, err = ModprobeOptionsToFsNodeFiles(&ModprobeCustomization{Blacklist: []string{"nouveau"}})
+	assert.NoError(t, err)
+	assert.Nil(t, files)
+}
@@ -2,7 +2,10 @@ package container
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -19,6 +22,13 @@ type Resolver struct {
 
 	Arch         string
 	AuthFilePath string
+
+	// path to a PEM bundle of CA certificates, see SetCACertPath()
+	caCertPath string
+
+	// directory with the CA certificates of caCertPath in the layout that
+	// is expected by the registry client
+	certDir string
 }
 
 type SourceSpec struct {
@@ -35,12 +45,25 @@ func NewResolver(arch string) Resolver {
 	}
 }
 
+// SetCACertPath sets the path to a PEM bundle of CA certificates used to
+// verify the registries, i.e. the same file that is passed to
+// dnfjson.Solver.SetCACertPath(). The certificates are trusted in addition to
+// the system trust store. Returns an error if the bundle cannot be read or
+// doesn't contain any certificate.
+func (r *Resolver) SetCACertPath(caCertPath string) error {
+	if _, err := readCACerts(caCertPath); err != nil {
+		return err
+	}
+	r.caCertPath = caCertPath
+	return nil
+}
+
 func (r *Resolver) Add(spec SourceSpec) {
 	client, err := NewClient(spec.Source)
 	r.jobs += 1
 
 	if err != nil {
-		r.queue <- resolveResult{err: err}
+		r.fail(err)
 		return
 	}
 
@@ -49,6 +72,16 @@ func (r *Resolver) Add(spec SourceSpec) {
 	if r.AuthFilePath != "" {
 		client.SetAuthFilePath(r.AuthFilePath)
 	}
+	if r.caCertPath != "" {
+		if r.certDir == "" {
+			r.certDir, err = newCertDir(r.caCertPath)
+			if err != nil {
+				r.fail(err)
+				return
+			}
+		}
+		client.SetDockerCertPath(r.certDir)
+	}
 
 	go func() {
 		spec, err := client.Resolve(r.ctx, spec.Name)
@@ -59,7 +92,57 @@ func (r *Resolver) Add(spec SourceSpec) {
 	}()
 }
 
+// fail reports the error of a job that was added. The queue is only read by
+// Finish(), so the error is sent from a goroutine, like the results of the
+// other jobs, to not block once the queue is full.
+func (r *Resolver) fail(err error) {
+	go func() {
+		r.queue <- resolveResult{err: err}
+	}()
+}
+
+// readCACerts reads the PEM bundle at caCertPath and returns an error if it
+// doesn't contain any certificate
+func readCACerts(caCertPath string) ([]byte, error) {
+	caCerts, err := os.ReadFile(caCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificates: %w", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(caCerts) {
+		return nil, fmt.Errorf("no CA certificates found in %s", caCertPath)
+	}
+	return caCerts, nil
+}
+
+// newCertDir validates the PEM bundle at caCertPath and copies it to a new
+// temporary directory, from which the registry client loads it into the
+// root CAs of its TLS configuration.
+func newCertDir(caCertPath string) (string, error) {
+	caCerts, err := readCACerts(caCertPath)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "osbuild-container-ca-")
+	if err != nil {
+		return "", err
+	}
+	// only files with a .crt suffix are read as CA certificates
+	if err := os.WriteFile(filepath.Join(dir, "ca.crt"), caCerts, 0600); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	return dir, nil
+}
+
 func (r *Resolver) Finish() ([]Spec, error) {
+	defer func() {
+		if r.certDir != "" {
+			os.RemoveAll(r.certDir)
+			r.certDir = ""
+		}
+	}()
 
 	specs := make([]Spec, 0, r.jobs)
 	errs := make([]string, 0, r.jobs)
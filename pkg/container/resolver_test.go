@@ -1,12 +1,16 @@
 package container_test
 
 import (
+	"encoding/pem"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/pkg/container"
@@ -83,3 +87,57 @@ func TestResolverFail(t *testing.T) {
 	assert.Error(t, err)
 	assert.Len(t, specs, 0)
 }
+
+func TestResolverCACertPath(t *testing.T) {
+	registry := NewTestRegistry()
+	defer registry.Close()
+
+	repo := registry.AddRepo("library/osbuild")
+	checksum := repo.AddImage(
+		[]Blob{NewDataBlobFromBase64(rootLayer)},
+		[]string{"amd64"},
+		"image",
+		time.Time{})
+	repo.AddTag(checksum, "latest")
+	ref := registry.GetRef("library/osbuild") + ":latest"
+
+	// without the CA certificate of the registry TLS verification fails
+	resolver := container.NewResolver("amd64")
+	resolver.Add(container.SourceSpec{ref, "", nil})
+	_, err := resolver.Finish()
+	assert.Error(t, err)
+
+	// the same PEM bundle that is used for depsolving
+	caCertPath := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: registry.server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caCertPath, ca, 0644))
+
+	resolver = container.NewResolver("amd64")
+	require.NoError(t, resolver.SetCACertPath(caCertPath))
+	resolver.Add(container.SourceSpec{ref, "", nil})
+	resolver.Add(container.SourceSpec{ref, "", nil})
+	specs, err := resolver.Finish()
+	assert.NoError(t, err)
+	assert.Len(t, specs, 2)
+
+	invalidPath := filepath.Join(t.TempDir(), "invalid.pem")
+	require.NoError(t, os.WriteFile(invalidPath, []byte("not a certificate"), 0644))
+
+	resolver = container.NewResolver("amd64")
+	assert.EqualError(t, resolver.SetCACertPath(invalidPath), "no CA certificates found in "+invalidPath)
+
+	// the CA certificates are only copied when the first container is added,
+	// a bundle that became invalid fails every container without blocking
+	// the resolver
+	validPath := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(validPath, ca, 0644))
+	resolver = container.NewResolver("amd64")
+	require.NoError(t, resolver.SetCACertPath(validPath))
+	require.NoError(t, os.WriteFile(validPath, []byte("not a certificate"), 0644))
+	for i := 0; i < 3; i++ {
+		resolver.Add(container.SourceSpec{ref, "", nil})
+	}
+	specs, err = resolver.Finish()
+	assert.ErrorContains(t, err, "no CA certificates found in "+validPath)
+	assert.Len(t, specs, 0)
+}
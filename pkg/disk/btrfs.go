@@ -51,16 +51,20 @@ func (b *Btrfs) GetChild(n uint) Entity {
 	return &b.Subvolumes[n]
 }
 func (b *Btrfs) CreateMountpoint(mountpoint string, size uint64) (Entity, error) {
-	name := mountpoint
-	if name == "/" {
-		name = "root"
-	}
 	subvolume := BtrfsSubvolume{
 		Size:       size,
 		Mountpoint: mountpoint,
 		GroupID:    0,
 		UUID:       b.UUID, // subvolumes inherit UUID of main volume
-		Name:       name,
+		Name:       pathdot(mountpoint),
+	}
+
+	// most btrfs mount options, e.g. compression, apply to the whole
+	// filesystem, so new subvolumes use the same ones as the root subvolume
+	for _, sv := range b.Subvolumes {
+		if sv.Mountpoint == "/" {
+			subvolume.MntOps = sv.MntOps
+		}
 	}
 
 	b.Subvolumes = append(b.Subvolumes, subvolume)
@@ -75,6 +79,11 @@ func (b *Btrfs) GenUUID(rng *rand.Rand) {
 	if b.UUID == "" {
 		b.UUID = uuid.Must(newRandomUUIDFromReader(rng)).String()
 	}
+
+	// subvolumes inherit UUID of main volume
+	for idx := range b.Subvolumes {
+		b.Subvolumes[idx].UUID = b.UUID
+	}
 }
 
 type BtrfsSubvolume struct {
@@ -149,7 +158,10 @@ func (bs *BtrfsSubvolume) GetFSTabOptions() FSTabOptions {
 		return FSTabOptions{}
 	}
 
-	ops := strings.Join([]string{bs.MntOps, fmt.Sprintf("subvol=%s", bs.Name)}, ",")
+	ops := fmt.Sprintf("subvol=%s", bs.Name)
+	if bs.MntOps != "" {
+		ops = strings.Join([]string{bs.MntOps, ops}, ",")
+	}
 	return FSTabOptions{
 		MntOps: ops,
 		Freq:   0,
@@ -48,6 +48,11 @@ const (
 
 	// Extended Boot Loader Partition
 	XBootLDRPartitionGUID = "BC13C2FF-59E6-4262-A352-B275FD6F7172"
+	// vfat file system of an Extended Boot Loader Partition that is read by
+	// systemd-boot
+	XBootLDRFilesystemUUID = "C8A0-2D4B"
+
+	SwapPartitionGUID = "0657FD6D-A4AB-43C4-84E5-0933C84B4F4F"
 )
 
 // Entity is the base interface for all disk-related entities.
@@ -74,7 +74,7 @@ func TestDisk_DynamicallyResizePartitionTable(t *testing.T) {
 	// math/rand is good enough in this case
 	/* #nosec G404 */
 	rng := rand.New(rand.NewSource(0))
-	newpt, err := NewPartitionTable(&pt, mountpoints, 1024, false, nil, rng)
+	newpt, err := NewPartitionTable(&pt, mountpoints, 1024, blueprint.RawPartitioningMode, nil, nil, nil, rng)
 	assert.NoError(t, err)
 	assert.GreaterOrEqual(t, newpt.Size, expectedSize)
 }
@@ -449,7 +449,7 @@ func TestCreatePartitionTable(t *testing.T) {
 	for ptName := range testPartitionTables {
 		pt := testPartitionTables[ptName]
 		for bpName, bp := range testBlueprints {
-			mpt, err := NewPartitionTable(&pt, bp, uint64(13*MiB), false, nil, rng)
+			mpt, err := NewPartitionTable(&pt, bp, uint64(13*MiB), blueprint.RawPartitioningMode, nil, nil, nil, rng)
 			assert.NoError(err, "Partition table generation failed: PT %q BP %q (%s)", ptName, bpName, err)
 			assert.NotNil(mpt, "Partition table generation failed: PT %q BP %q (nil partition table)", ptName, bpName)
 			assert.Greater(mpt.GetSize(), sumSizes(bp))
@@ -474,13 +474,12 @@ func TestCreatePartitionTableLVMify(t *testing.T) {
 			pt := testPartitionTables[ptName]
 
 			if tbp != nil && (ptName == "btrfs" || ptName == "luks") {
-				assert.Panics(func() {
-					_, _ = NewPartitionTable(&pt, tbp, uint64(13*MiB), true, nil, rng)
-				}, fmt.Sprintf("PT %q BP %q: should panic", ptName, bpName))
+				_, err := NewPartitionTable(&pt, tbp, uint64(13*MiB), blueprint.AutoLVMPartitioningMode, nil, nil, nil, rng)
+				assert.Error(err, "PT %q BP %q: should fail", ptName, bpName)
 				continue
 			}
 
-			mpt, err := NewPartitionTable(&pt, tbp, uint64(13*MiB), true, nil, rng)
+			mpt, err := NewPartitionTable(&pt, tbp, uint64(13*MiB), blueprint.AutoLVMPartitioningMode, nil, nil, nil, rng)
 			assert.NoError(err, "PT %q BP %q: Partition table generation failed: (%s)", ptName, bpName, err)
 
 			rootPath := entityPath(mpt, "/")
@@ -588,7 +587,7 @@ func TestMinimumSizes(t *testing.T) {
 
 	for idx, tc := range testCases {
 		{ // without LVM
-			mpt, err := NewPartitionTable(&pt, tc.Blueprint, uint64(3*GiB), false, nil, rng)
+			mpt, err := NewPartitionTable(&pt, tc.Blueprint, uint64(3*GiB), blueprint.RawPartitioningMode, nil, nil, nil, rng)
 			assert.NoError(err)
 			for mnt, minSize := range tc.ExpectedMinSizes {
 				path := entityPath(mpt, mnt)
@@ -602,7 +601,7 @@ func TestMinimumSizes(t *testing.T) {
 		}
 
 		{ // with LVM
-			mpt, err := NewPartitionTable(&pt, tc.Blueprint, uint64(3*GiB), true, nil, rng)
+			mpt, err := NewPartitionTable(&pt, tc.Blueprint, uint64(3*GiB), blueprint.AutoLVMPartitioningMode, nil, nil, nil, rng)
 			assert.NoError(err)
 			for mnt, minSize := range tc.ExpectedMinSizes {
 				path := entityPath(mpt, mnt)
@@ -689,7 +688,7 @@ func TestLVMExtentAlignment(t *testing.T) {
 	}
 
 	for idx, tc := range testCases {
-		mpt, err := NewPartitionTable(&pt, tc.Blueprint, uint64(3*GiB), true, nil, rng)
+		mpt, err := NewPartitionTable(&pt, tc.Blueprint, uint64(3*GiB), blueprint.AutoLVMPartitioningMode, nil, nil, nil, rng)
 		assert.NoError(err)
 		for mnt, expSize := range tc.ExpectedSizes {
 			path := entityPath(mpt, mnt)
@@ -718,7 +717,7 @@ func TestNewBootWithSizeLVMify(t *testing.T) {
 		},
 	}
 
-	mpt, err := NewPartitionTable(&pt, custom, uint64(3*GiB), true, nil, rng)
+	mpt, err := NewPartitionTable(&pt, custom, uint64(3*GiB), blueprint.AutoLVMPartitioningMode, nil, nil, nil, rng)
 	assert.NoError(err)
 
 	for idx, c := range custom {
@@ -733,6 +732,315 @@ func TestNewBootWithSizeLVMify(t *testing.T) {
 	}
 }
 
+func TestNewPartitionTablePartitioningModes(t *testing.T) {
+	assert := assert.New(t)
+
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+
+	pt := testPartitionTables["plain-noboot"]
+	custom := []blueprint.FilesystemCustomization{
+		{
+			Mountpoint: "/var",
+			MinSize:    1 * GiB,
+		},
+	}
+
+	// raw mode adds new mountpoints as plain partitions
+	mpt, err := NewPartitionTable(&pt, custom, uint64(3*GiB), blueprint.RawPartitioningMode, nil, nil, nil, rng)
+	assert.NoError(err)
+	assert.Nil(entityPath(mpt, "/boot"))
+	assert.IsType(&Partition{}, entityPath(mpt, "/")[1])
+	assert.IsType(&Partition{}, entityPath(mpt, "/var")[1])
+
+	// lvm mode converts the root partition even without any new mountpoints
+	mpt, err = NewPartitionTable(&pt, nil, uint64(3*GiB), blueprint.LVMPartitioningMode, nil, nil, nil, rng)
+	assert.NoError(err)
+	assert.NotNil(entityPath(mpt, "/boot"))
+	assert.IsType(&LVMLogicalVolume{}, entityPath(mpt, "/")[1])
+
+	// auto mode only converts the root partition if there are new mountpoints
+	mpt, err = NewPartitionTable(&pt, nil, uint64(3*GiB), blueprint.AutoLVMPartitioningMode, nil, nil, nil, rng)
+	assert.NoError(err)
+	assert.IsType(&Partition{}, entityPath(mpt, "/")[1])
+
+	mpt, err = NewPartitionTable(&pt, custom, uint64(3*GiB), blueprint.AutoLVMPartitioningMode, nil, nil, nil, rng)
+	assert.NoError(err)
+	assert.IsType(&LVMLogicalVolume{}, entityPath(mpt, "/")[1])
+	assert.IsType(&LVMLogicalVolume{}, entityPath(mpt, "/var")[1])
+
+	// impossible modes
+	btrfs := testPartitionTables["btrfs"]
+	_, err = NewPartitionTable(&btrfs, nil, uint64(3*GiB), blueprint.LVMPartitioningMode, nil, nil, nil, rng)
+	assert.EqualError(err, "cannot convert the root filesystem to LVM: unsupported parent *disk.Btrfs")
+
+	_, err = NewPartitionTable(&pt, nil, uint64(3*GiB), blueprint.BtrfsPartitioningMode, nil, nil, nil, rng)
+	assert.EqualError(err, "btrfs partitioning mode requires the root filesystem to be a btrfs subvolume")
+
+	_, err = NewPartitionTable(&pt, nil, uint64(3*GiB), "zfs", nil, nil, nil, rng)
+	assert.EqualError(err, `unsupported partitioning mode "zfs"`)
+}
+
+func TestNewPartitionTableEncryption(t *testing.T) {
+	assert := assert.New(t)
+
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+
+	encryption := &blueprint.EncryptionCustomization{
+		Passphrase:  "secret",
+		Clevis:      &blueprint.ClevisCustomization{Pin: "tpm2"},
+		Mountpoints: []string{"/var"},
+	}
+	custom := []blueprint.FilesystemCustomization{
+		{
+			Mountpoint: "/var",
+			MinSize:    2 * GiB,
+		},
+	}
+
+	for _, mode := range []blueprint.PartitioningMode{blueprint.RawPartitioningMode, blueprint.AutoLVMPartitioningMode, blueprint.LVMPartitioningMode} {
+		pt := testPartitionTables["plain-noboot"]
+		mpt, err := NewPartitionTable(&pt, custom, uint64(5*GiB), mode, encryption, nil, nil, rng)
+		assert.NoError(err)
+
+		bootPath := entityPath(mpt, "/boot")
+		assert.NotNil(bootPath, "mode=%v: no boot mountpoint", mode)
+		for _, ent := range bootPath {
+			_, ok := ent.(*LUKSContainer)
+			assert.False(ok, "mode=%v: /boot must not be encrypted", mode)
+		}
+
+		for _, mnt := range []string{"/", "/var"} {
+			path := entityPath(mpt, mnt)
+			assert.NotNil(path, "mode=%v: mountpoint %q not found", mode, mnt)
+
+			var luks *LUKSContainer
+			var part *Partition
+			for _, ent := range path {
+				switch e := ent.(type) {
+				case *LUKSContainer:
+					luks = e
+				case *Partition:
+					part = e
+				}
+			}
+			assert.NotNil(luks, "mode=%v: %q is not encrypted", mode, mnt)
+			assert.Equal("secret", luks.Passphrase)
+			assert.Equal("tpm2", luks.Clevis.Pin)
+			assert.Equal(FilesystemDataGUID, part.Type)
+			assert.GreaterOrEqual(part.GetSize(), luks.MetadataSize())
+		}
+	}
+
+	// encrypting a mountpoint that is not part of the table fails
+	pt := testPartitionTables["plain-noboot"]
+	_, err := NewPartitionTable(&pt, nil, uint64(5*GiB), blueprint.RawPartitioningMode, &blueprint.EncryptionCustomization{
+		Passphrase:  "secret",
+		Mountpoints: []string{"/data"},
+	}, nil, nil, rng)
+	assert.EqualError(err, `cannot encrypt "/data": mountpoint does not exist`)
+}
+
+func TestNewPartitionTableSwap(t *testing.T) {
+	assert := assert.New(t)
+
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+
+	// swap partition
+	for _, mode := range []blueprint.PartitioningMode{blueprint.RawPartitioningMode, blueprint.AutoLVMPartitioningMode, blueprint.LVMPartitioningMode} {
+		pt := testPartitionTables["plain"]
+		mpt, err := NewPartitionTable(&pt, nil, uint64(5*GiB), mode, nil, &blueprint.SwapCustomization{Size: 2*GiB + 1}, nil, rng)
+		assert.NoError(err)
+
+		swap := mpt.FindSwap()
+		assert.NotNil(swap, "mode=%v: no swap area", mode)
+		assert.NotEmpty(swap.UUID)
+		assert.Equal("defaults", swap.FSTabOptions)
+
+		part := &mpt.Partitions[len(mpt.Partitions)-1]
+		assert.Equal(swap, part.Payload)
+		assert.Equal(SwapPartitionGUID, part.Type)
+		assert.Equal(uint64(2*GiB+1*MiB), part.Size)
+	}
+
+	// swap partition on a dos partition table
+	pt := testPartitionTables["plain-noboot"]
+	pt.Type = "dos"
+	mpt, err := NewPartitionTable(&pt, nil, uint64(5*GiB), blueprint.RawPartitioningMode, nil, &blueprint.SwapCustomization{Size: 1 * GiB}, nil, rng)
+	assert.NoError(err)
+	assert.Equal("82", mpt.Partitions[len(mpt.Partitions)-1].Type)
+
+	// swap logical volume
+	for _, mode := range []blueprint.PartitioningMode{blueprint.AutoLVMPartitioningMode, blueprint.LVMPartitioningMode} {
+		pt := testPartitionTables["plain"]
+		swapCustomization := &blueprint.SwapCustomization{Size: 2 * GiB, Type: blueprint.SwapLogicalVolume}
+		mpt, err := NewPartitionTable(&pt, nil, uint64(5*GiB), mode, nil, swapCustomization, nil, rng)
+		assert.NoError(err)
+
+		swap := mpt.FindSwap()
+		assert.NotNil(swap, "mode=%v: no swap area", mode)
+
+		rootPath := entityPath(mpt, "/")
+		vg := rootPath[2].(*LVMVolumeGroup)
+		part := rootPath[3].(*Partition)
+		assert.Len(vg.LogicalVolumes, 2)
+		assert.Equal("swaplv", vg.LogicalVolumes[1].Name)
+		assert.Equal(uint64(2*GiB), vg.LogicalVolumes[1].Size)
+		assert.Equal(swap, vg.LogicalVolumes[1].Payload)
+		assert.GreaterOrEqual(part.Size, vg.LogicalVolumes[0].Size+vg.LogicalVolumes[1].Size)
+	}
+
+	// swap logical volume together with encryption is encrypted with the
+	// volume group of the root filesystem
+	pt = testPartitionTables["plain-noboot"]
+	mpt, err = NewPartitionTable(&pt, nil, uint64(5*GiB), blueprint.AutoLVMPartitioningMode, &blueprint.EncryptionCustomization{
+		Passphrase: "secret",
+	}, &blueprint.SwapCustomization{Size: 1 * GiB, Type: blueprint.SwapLogicalVolume}, nil, rng)
+	assert.NoError(err)
+	var encrypted bool
+	_ = mpt.ForEachEntity(func(e Entity, path []Entity) error {
+		if _, ok := e.(*Swap); ok {
+			for _, ent := range path {
+				if _, ok := ent.(*LUKSContainer); ok {
+					encrypted = true
+				}
+			}
+		}
+		return nil
+	})
+	assert.True(encrypted)
+
+	// swap files are not part of the partition table
+	pt = testPartitionTables["plain"]
+	mpt, err = NewPartitionTable(&pt, nil, uint64(5*GiB), blueprint.RawPartitioningMode, nil, &blueprint.SwapCustomization{Size: 1 * GiB, Type: blueprint.SwapFile}, nil, rng)
+	assert.NoError(err)
+	assert.Nil(mpt.FindSwap())
+
+	pt = testPartitionTables["plain"]
+	_, err = NewPartitionTable(&pt, nil, uint64(5*GiB), blueprint.RawPartitioningMode, nil, &blueprint.SwapCustomization{Size: 1 * GiB, Type: blueprint.SwapLogicalVolume}, nil, rng)
+	assert.EqualError(err, `swap logical volumes are not supported in partitioning mode "raw"`)
+
+	pt = testPartitionTables["plain"]
+	_, err = NewPartitionTable(&pt, nil, uint64(5*GiB), blueprint.RawPartitioningMode, &blueprint.EncryptionCustomization{
+		Passphrase: "secret",
+	}, &blueprint.SwapCustomization{Size: 1 * GiB}, nil, rng)
+	assert.EqualError(err, "swap partitions cannot be combined with disk encryption, use a swap logical volume instead")
+}
+
+func TestNewPartitionTableFilesystemOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+
+	custom := []blueprint.FilesystemCustomization{
+		{
+			Mountpoint: "/",
+			FSType:     "ext4",
+			Label:      "rootfs",
+		},
+		{
+			Mountpoint:   "/var/tmp",
+			MinSize:      1 * GiB,
+			FSType:       "ext4",
+			MountOptions: "nodev,nosuid,noexec",
+		},
+		{
+			Mountpoint: "/home",
+			MinSize:    1 * GiB,
+		},
+	}
+
+	for _, mode := range []blueprint.PartitioningMode{blueprint.RawPartitioningMode, blueprint.AutoLVMPartitioningMode, blueprint.LVMPartitioningMode} {
+		pt := testPartitionTables["plain"]
+		mpt, err := NewPartitionTable(&pt, custom, uint64(5*GiB), mode, nil, nil, nil, rng)
+		assert.NoError(err)
+
+		root := mpt.FindMountable("/").(*Filesystem)
+		assert.Equal("ext4", root.Type)
+		assert.Equal("rootfs", root.Label)
+		assert.Equal("defaults", root.FSTabOptions)
+
+		varTmp := mpt.FindMountable("/var/tmp").(*Filesystem)
+		assert.Equal("ext4", varTmp.Type)
+		assert.Equal("nodev,nosuid,noexec", varTmp.FSTabOptions)
+
+		// unset options keep the defaults
+		home := mpt.FindMountable("/home").(*Filesystem)
+		assert.Equal("xfs", home.Type)
+		assert.Equal("defaults", home.FSTabOptions)
+	}
+
+	pt := testPartitionTables["plain"]
+	_, err := NewPartitionTable(&pt, []blueprint.FilesystemCustomization{
+		{
+			Mountpoint: "/",
+			Label:      "a-very-long-root-label",
+		},
+	}, uint64(5*GiB), blueprint.RawPartitioningMode, nil, nil, nil, rng)
+	assert.EqualError(err, `label "a-very-long-root-label" of "/" is too long for filesystem type "xfs" (max 12 characters)`)
+
+	pt = testPartitionTables["btrfs"]
+	_, err = NewPartitionTable(&pt, []blueprint.FilesystemCustomization{
+		{
+			Mountpoint: "/",
+			FSType:     "xfs",
+		},
+	}, uint64(5*GiB), blueprint.RawPartitioningMode, nil, nil, nil, rng)
+	assert.EqualError(err, `cannot set filesystem type or label of "/": mountpoint is a btrfs subvolume`)
+}
+
+func TestNewPartitionTableBtrfsSubvolumes(t *testing.T) {
+	assert := assert.New(t)
+
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+
+	custom := []blueprint.FilesystemCustomization{
+		{
+			Mountpoint: "/var/log",
+			MinSize:    2 * GiB,
+		},
+		{
+			Mountpoint:   "/home",
+			MountOptions: "compress=zstd:1,nodev",
+		},
+	}
+
+	pt := testPartitionTables["btrfs"]
+	pt = *pt.Clone().(*PartitionTable) // don't modify the original test data
+	pt.Partitions[3].Payload.(*Btrfs).Subvolumes[0].MntOps = "compress=zstd:1"
+	mpt, err := NewPartitionTable(&pt, custom, uint64(5*GiB), blueprint.RawPartitioningMode, nil, nil, nil, rng)
+	assert.NoError(err)
+
+	volume := mpt.Partitions[3].Payload.(*Btrfs)
+	assert.NotEmpty(volume.UUID)
+
+	for _, mnt := range []string{"/", "/var/log", "/home"} {
+		path := entityPath(mpt, mnt)
+		assert.NotNil(path, "mountpoint %q not found", mnt)
+		subvol, ok := path[0].(*BtrfsSubvolume)
+		assert.True(ok, "%q is not a btrfs subvolume", mnt)
+		assert.Equal(volume, path[1], "%q is not on the root btrfs volume", mnt)
+		assert.Equal(volume.UUID, subvol.GetFSSpec().UUID)
+	}
+
+	varLog := mpt.FindMountable("/var/log").(*BtrfsSubvolume)
+	assert.Equal("var.log", varLog.Name)
+	assert.Equal("compress=zstd:1,subvol=var.log", varLog.GetFSTabOptions().MntOps)
+	assert.GreaterOrEqual(mpt.Partitions[3].Size, uint64(2*GiB))
+
+	home := mpt.FindMountable("/home").(*BtrfsSubvolume)
+	assert.Equal("compress=zstd:1,nodev,subvol=home", home.GetFSTabOptions().MntOps)
+}
+
 func collectEntities(pt *PartitionTable) []Entity {
 	entities := make([]Entity, 0)
 	collector := func(ent Entity, path []Entity) error {
@@ -1058,7 +1366,7 @@ func TestMinimumSizesWithRequiredSizes(t *testing.T) {
 
 	for idx, tc := range testCases {
 		{ // without LVM
-			mpt, err := NewPartitionTable(&pt, tc.Blueprint, uint64(3*GiB), false, map[string]uint64{"/": 1 * GiB, "/usr": 3 * GiB}, rng)
+			mpt, err := NewPartitionTable(&pt, tc.Blueprint, uint64(3*GiB), blueprint.RawPartitioningMode, nil, nil, map[string]uint64{"/": 1 * GiB, "/usr": 3 * GiB}, rng)
 			assert.NoError(err)
 			for mnt, minSize := range tc.ExpectedMinSizes {
 				path := entityPath(mpt, mnt)
@@ -1072,7 +1380,7 @@ func TestMinimumSizesWithRequiredSizes(t *testing.T) {
 		}
 
 		{ // with LVM
-			mpt, err := NewPartitionTable(&pt, tc.Blueprint, uint64(3*GiB), true, map[string]uint64{"/": 1 * GiB, "/usr": 3 * GiB}, rng)
+			mpt, err := NewPartitionTable(&pt, tc.Blueprint, uint64(3*GiB), blueprint.AutoLVMPartitioningMode, nil, nil, map[string]uint64{"/": 1 * GiB, "/usr": 3 * GiB}, rng)
 			assert.NoError(err)
 			for mnt, minSize := range tc.ExpectedMinSizes {
 				path := entityPath(mpt, mnt)
@@ -5,6 +5,7 @@ import (
 	"math/rand"
 
 	"github.com/google/uuid"
+	"github.com/osbuild/images/pkg/blueprint"
 )
 
 type Argon2id struct {
@@ -98,3 +99,36 @@ func (lc *LUKSContainer) MetadataSize() uint64 {
 	// 16 MiB is the default size for the LUKS2 header
 	return 16 * 1024 * 1024
 }
+
+// Default argon2id parameters for LUKS containers created from blueprint
+// customizations.
+var defaultLUKSPBKDF = Argon2id{
+	Iterations:  4,
+	Memory:      64 * 1024, // KiB
+	Parallelism: 1,
+}
+
+// newLUKSContainerFromBP creates a new LUKS2 container around payload
+// configured according to the blueprint encryption customization.
+func newLUKSContainerFromBP(enc *blueprint.EncryptionCustomization, payload Entity) *LUKSContainer {
+	lc := &LUKSContainer{
+		Passphrase: enc.Passphrase,
+		Cipher:     enc.Cipher,
+		PBKDF:      defaultLUKSPBKDF,
+		Payload:    payload,
+	}
+
+	if enc.Clevis != nil {
+		policy := enc.Clevis.Policy
+		if policy == "" {
+			policy = "{}"
+		}
+		lc.Clevis = &ClevisBind{
+			Pin:              enc.Clevis.Pin,
+			Policy:           policy,
+			RemovePassphrase: enc.RemovePassphrase,
+		}
+	}
+
+	return lc
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"path/filepath"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/osbuild/images/pkg/blueprint"
@@ -19,15 +20,42 @@ type PartitionTable struct {
 	ExtraPadding uint64 // Extra space at the end of the partition table (sectors)
 }
 
-func NewPartitionTable(basePT *PartitionTable, mountpoints []blueprint.FilesystemCustomization, imageSize uint64, lvmify bool, requiredSizes map[string]uint64, rng *rand.Rand) (*PartitionTable, error) {
+func NewPartitionTable(basePT *PartitionTable, mountpoints []blueprint.FilesystemCustomization, imageSize uint64, mode blueprint.PartitioningMode, encryption *blueprint.EncryptionCustomization, swap *blueprint.SwapCustomization, requiredSizes map[string]uint64, rng *rand.Rand) (*PartitionTable, error) {
 	newPT := basePT.Clone().(*PartitionTable)
 
+	var lvmify bool
+	switch mode {
+	case blueprint.LVMPartitioningMode:
+		// convert the root partition to LVM before applying the
+		// customizations, so that all new mountpoints become LVs
+		err := newPT.ensureLVM()
+		if err != nil {
+			return nil, err
+		}
+	case blueprint.AutoLVMPartitioningMode, blueprint.DefaultPartitioningMode:
+		lvmify = true
+	case blueprint.RawPartitioningMode:
+	case blueprint.BtrfsPartitioningMode:
+		rootPath := entityPath(newPT, "/")
+		if rootPath == nil {
+			panic("no root mountpoint for PartitionTable")
+		}
+		if _, ok := rootPath[0].(*BtrfsSubvolume); !ok {
+			return nil, fmt.Errorf("btrfs partitioning mode requires the root filesystem to be a btrfs subvolume")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported partitioning mode %q", mode)
+	}
+
 	// first pass: enlarge existing mountpoints and collect new ones
-	newMountpoints, _ := newPT.applyCustomization(mountpoints, false)
+	newMountpoints, err := newPT.applyCustomization(mountpoints, false)
+	if err != nil {
+		return nil, err
+	}
 
 	// if there is any new mountpoint and lvmify is enabled, ensure we have LVM layout
 	if lvmify && len(newMountpoints) > 0 {
-		err := newPT.ensureLVM()
+		err = newPT.ensureLVM()
 		if err != nil {
 			return nil, err
 		}
@@ -35,11 +63,30 @@ func NewPartitionTable(basePT *PartitionTable, mountpoints []blueprint.Filesyste
 
 	// second pass: deal with new mountpoints and newly created ones, after switching to
 	// the LVM layout, if requested, which might introduce new mount points, i.e. `/boot`
-	_, err := newPT.applyCustomization(newMountpoints, true)
+	_, err = newPT.applyCustomization(newMountpoints, true)
 	if err != nil {
 		return nil, err
 	}
 
+	// swap partitions and logical volumes are created before encryption, so
+	// that a swap logical volume is encrypted together with the root volume
+	if swap != nil {
+		err = newPT.ensureSwap(swap, mode, encryption != nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// wrap the root filesystem, and the requested additional mountpoints,
+	// in LUKS2 containers; this needs to happen after all the mountpoints
+	// have been created so that new ones can be encrypted as well
+	if encryption != nil {
+		err = newPT.ensureEncryption(encryption)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// If no separate requiredSizes are given then we use our defaults
 	if requiredSizes == nil {
 		requiredSizes = map[string]uint64{
@@ -324,8 +371,8 @@ func (pt *PartitionTable) HeaderSize() uint64 {
 
 // Apply filesystem filesystem customization to the partiton table. If create is false
 // will only apply customizations to existing partitions and return unhandled, i.e new
-// ones. An error can only occur if create is set. Conversely, it will only return non
-// empty list of new mountpoints if create is false.
+// ones. Conversely, it will only return non empty list of new mountpoints if create
+// is false.
 // Does not relayout the table, i.e. a call to relayout might be needed.
 func (pt *PartitionTable) applyCustomization(mountpoints []blueprint.FilesystemCustomization, create bool) ([]blueprint.FilesystemCustomization, error) {
 
@@ -339,15 +386,77 @@ func (pt *PartitionTable) applyCustomization(mountpoints []blueprint.FilesystemC
 		} else {
 			if !create {
 				newMountpoints = append(newMountpoints, mnt)
+				continue
 			} else if err := pt.createFilesystem(mnt.Mountpoint, size); err != nil {
 				return nil, err
 			}
 		}
+
+		if err := pt.applyFilesystemOptions(mnt); err != nil {
+			return nil, err
+		}
 	}
 
 	return newMountpoints, nil
 }
 
+// maximum length of the filesystem label for each supported filesystem type
+var maxFSLabelLength = map[string]int{
+	"xfs":   12,
+	"ext4":  16,
+	"vfat":  11,
+	"btrfs": 255,
+}
+
+// applyFilesystemOptions sets the filesystem type, label and mount options of
+// the customization on the filesystem of its (existing) mountpoint.
+func (pt *PartitionTable) applyFilesystemOptions(mnt blueprint.FilesystemCustomization) error {
+	if mnt.FSType == "" && mnt.Label == "" && mnt.MountOptions == "" {
+		return nil
+	}
+
+	path := entityPath(pt, mnt.Mountpoint)
+	if path == nil {
+		panic(fmt.Sprintf("mountpoint %q not found; this is a programming error", mnt.Mountpoint))
+	}
+
+	if subvol, ok := path[0].(*BtrfsSubvolume); ok {
+		// subvolumes share the filesystem and its label, only the mount
+		// options can be set
+		if (mnt.FSType != "" && mnt.FSType != "btrfs") || mnt.Label != "" {
+			return fmt.Errorf("cannot set filesystem type or label of %q: mountpoint is a btrfs subvolume", mnt.Mountpoint)
+		}
+		if mnt.MountOptions != "" {
+			subvol.MntOps = mnt.MountOptions
+		}
+		return nil
+	}
+
+	fs, ok := path[0].(*Filesystem)
+	if !ok {
+		return fmt.Errorf("cannot set filesystem options of %q: mountpoint is not a filesystem", mnt.Mountpoint)
+	}
+
+	if mnt.FSType != "" {
+		if _, ok := maxFSLabelLength[mnt.FSType]; !ok {
+			return fmt.Errorf("unsupported filesystem type %q for %q", mnt.FSType, mnt.Mountpoint)
+		}
+		fs.Type = mnt.FSType
+	}
+	if mnt.Label != "" {
+		fs.Label = mnt.Label
+	}
+	if mnt.MountOptions != "" {
+		fs.FSTabOptions = mnt.MountOptions
+	}
+
+	if maxLen, ok := maxFSLabelLength[fs.Type]; ok && len(fs.Label) > maxLen {
+		return fmt.Errorf("label %q of %q is too long for filesystem type %q (max %d characters)", fs.Label, mnt.Mountpoint, fs.Type, maxLen)
+	}
+
+	return nil
+}
+
 // Dynamically calculate and update the start point for each of the existing
 // partitions. Adjusts the overall size of image to either the supplied
 // value in `size` or to the sum of all partitions if that is lager.
@@ -502,6 +611,16 @@ func (pt *PartitionTable) FindMountable(mountpoint string) Mountable {
 	return path[0].(Mountable)
 }
 
+// pathdot returns a name for an entity based on the mountpoint.
+func pathdot(path string) string {
+	if path == "/" {
+		return "root"
+	}
+
+	path = strings.TrimLeft(path, "/")
+	return strings.ReplaceAll(path, "/", ".")
+}
+
 func clampFSSize(mountpoint string, size uint64) uint64 {
 	// set a minimum size of 1GB for all mountpoints
 	// with the exception for '/boot' (= 500 MB)
@@ -623,7 +742,156 @@ func (pt *PartitionTable) ensureLVM() error {
 		}
 
 	} else {
-		panic("unsupported parent for LVM")
+		return fmt.Errorf("cannot convert the root filesystem to LVM: unsupported parent %T", parent)
+	}
+
+	return nil
+}
+
+// ensureSwap creates the swap partition or logical volume of the swap
+// customization. Swap files are not part of the partition table.
+func (pt *PartitionTable) ensureSwap(swap *blueprint.SwapCustomization, mode blueprint.PartitioningMode, encrypted bool) error {
+	if pt.FindSwap() != nil {
+		return fmt.Errorf("partition table already contains a swap area")
+	}
+
+	switch swap.GetType() {
+	case blueprint.SwapPartition:
+		if encrypted {
+			// the partition would not be wrapped in a LUKS container and
+			// leak the memory of the encrypted volumes
+			return fmt.Errorf("swap partitions cannot be combined with disk encryption, use a swap logical volume instead")
+		}
+		return pt.createSwapPartition(swap.Size)
+	case blueprint.SwapLogicalVolume:
+		if mode == blueprint.RawPartitioningMode || mode == blueprint.BtrfsPartitioningMode {
+			return fmt.Errorf("swap logical volumes are not supported in partitioning mode %q", mode)
+		}
+		if err := pt.ensureLVM(); err != nil {
+			return err
+		}
+		return pt.createSwapLogicalVolume(swap.Size)
+	}
+
+	return nil
+}
+
+func (pt *PartitionTable) createSwapPartition(size uint64) error {
+	partition := Partition{
+		Size:    pt.AlignUp(size),
+		Payload: &Swap{FSTabOptions: "defaults"},
+	}
+
+	maxNo := 4
+	if pt.Type == "gpt" {
+		partition.Type = SwapPartitionGUID
+		maxNo = 128
+	} else {
+		partition.Type = "82"
+	}
+
+	if len(pt.Partitions) == maxNo {
+		return fmt.Errorf("maximum number of partitions reached (%d)", maxNo)
+	}
+
+	pt.Partitions = append(pt.Partitions, partition)
+	return nil
+}
+
+func (pt *PartitionTable) createSwapLogicalVolume(size uint64) error {
+	rootPath := entityPath(pt, "/")
+	if rootPath == nil {
+		panic("no root mountpoint for PartitionTable")
+	}
+
+	var vg *LVMVolumeGroup
+	var idx int
+	for i, entity := range rootPath {
+		var ok bool
+		if vg, ok = entity.(*LVMVolumeGroup); ok {
+			idx = i
+			break
+		}
+	}
+
+	if vg == nil {
+		panic("could not find root volume group")
+	}
+
+	lv, err := vg.CreateLogicalVolume("swap", 0, &Swap{FSTabOptions: "defaults"})
+	if err != nil {
+		return fmt.Errorf("failed creating swap volume: " + err.Error())
+	}
+	vgPath := append([]Entity{lv}, rootPath[idx:]...)
+	size = alignEntityBranch(vgPath, size)
+	resizeEntityBranch(vgPath, size)
+	return nil
+}
+
+// ensureEncryption wraps the volumes holding the root filesystem and the
+// additional mountpoints of the encryption customization in LUKS2 containers.
+// Volumes that are already encrypted are left untouched. A separate /boot
+// partition is created if needed, since the bootloader must be able to read
+// the kernel and initrd without unlocking any container.
+func (pt *PartitionTable) ensureEncryption(enc *blueprint.EncryptionCustomization) error {
+	if entityPath(pt, "/") == nil {
+		panic("no root mountpoint for PartitionTable")
+	}
+
+	if entityPath(pt, "/boot") == nil {
+		_, err := pt.CreateMountpoint("/boot", 512*1024*1024)
+		if err != nil {
+			return err
+		}
+	}
+
+	mountpoints := append([]string{"/"}, enc.Mountpoints...)
+	for _, mountpoint := range mountpoints {
+		path := entityPath(pt, mountpoint)
+		if path == nil {
+			return fmt.Errorf("cannot encrypt %q: mountpoint does not exist", mountpoint)
+		}
+
+		var part *Partition
+		encrypted := false
+		for _, ent := range path {
+			switch e := ent.(type) {
+			case *LUKSContainer:
+				encrypted = true
+			case *Partition:
+				part = e
+			}
+		}
+
+		if encrypted {
+			continue
+		}
+
+		if part == nil {
+			panic(fmt.Sprintf("mountpoint %q is not on a partition; this is a programming error", mountpoint))
+		}
+
+		if part.Payload == nil {
+			panic(fmt.Sprintf("partition for mountpoint %q has no payload; this is a programming error", mountpoint))
+		}
+
+		luks := newLUKSContainerFromBP(enc, part.Payload)
+		luks.Label = "crypt_" + pathdot(mountpoint)
+		part.Payload = luks
+
+		// the partition now contains a LUKS2 container, not a LVM PV
+		if pt.Type == "gpt" {
+			if part.Type == LVMPartitionGUID {
+				part.Type = FilesystemDataGUID
+			}
+		} else if part.Type == "8e" {
+			part.Type = "83"
+		}
+
+		// grow the partition to make space for the LUKS2 header: the
+		// filesystem inside the container has no size of its own, so it
+		// keeps the current size of the partition next to the header
+		resizeEntityBranch([]Entity{luks, part, pt}, part.Size+luks.MetadataSize())
 	}
 
 	return nil
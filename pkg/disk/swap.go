@@ -0,0 +1,62 @@
+package disk
+
+import (
+	"math/rand"
+
+	"github.com/google/uuid"
+)
+
+// Swap is a swap area. It is not Mountable, since it has no mountpoint, but
+// it is activated via an fstab(5) entry.
+type Swap struct {
+	UUID  string
+	Label string
+	// The fourth field of fstab(5); fs_mntops
+	FSTabOptions string
+}
+
+func (s *Swap) IsContainer() bool {
+	return false
+}
+
+// Clone the swap structure
+func (s *Swap) Clone() Entity {
+	if s == nil {
+		return nil
+	}
+
+	return &Swap{
+		UUID:         s.UUID,
+		Label:        s.Label,
+		FSTabOptions: s.FSTabOptions,
+	}
+}
+
+func (s *Swap) GetFSSpec() FSSpec {
+	if s == nil {
+		return FSSpec{}
+	}
+	return FSSpec{
+		UUID:  s.UUID,
+		Label: s.Label,
+	}
+}
+
+func (s *Swap) GenUUID(rng *rand.Rand) {
+	if s.UUID == "" {
+		s.UUID = uuid.Must(newRandomUUIDFromReader(rng)).String()
+	}
+}
+
+// FindSwap returns the first swap area in the PartitionTable. Returns nil if
+// the PartitionTable has no swap area.
+func (pt *PartitionTable) FindSwap() *Swap {
+	var swap *Swap
+	_ = pt.ForEachEntity(func(e Entity, path []Entity) error {
+		if s, ok := e.(*Swap); ok && swap == nil {
+			swap = s
+		}
+		return nil
+	})
+	return swap
+}
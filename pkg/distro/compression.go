@@ -0,0 +1,89 @@
+package distro
+
+import (
+	"fmt"
+)
+
+// CompressionType is the algorithm used to compress the artifact of an image
+// build
+type CompressionType string
+
+const (
+	CompressionNone CompressionType = "none"
+	CompressionXZ   CompressionType = "xz"
+	CompressionZstd CompressionType = "zstd"
+	CompressionGzip CompressionType = "gzip"
+)
+
+// CompressionOptions select how the artifact of an image build is compressed,
+// overriding the default compression of the image type. Compression trades
+// build time for a smaller artifact: xz is the slowest with the best ratio,
+// zstd is much faster and gzip is the most widely supported.
+type CompressionOptions struct {
+	Type CompressionType
+
+	// Compression level (1-9), the default of gzip if nil. Only gzip supports
+	// setting the level.
+	Level *uint
+}
+
+// Validate returns an error if the compression type is unknown or the level
+// is not supported by it
+func (c *CompressionOptions) Validate() error {
+	switch c.Type {
+	case CompressionNone, CompressionXZ, CompressionZstd:
+		if c.Level != nil {
+			return fmt.Errorf("compression level is not supported by compression type %q", c.Type)
+		}
+	case CompressionGzip:
+		if c.Level != nil && (*c.Level < 1 || *c.Level > 9) {
+			return fmt.Errorf("compression level %d is out of range for gzip (1-9)", *c.Level)
+		}
+	default:
+		return fmt.Errorf("unsupported compression type %q", c.Type)
+	}
+	return nil
+}
+
+// GetCompression returns the compression of an image build: the compression
+// selected in the image options, or the default compression of the image type
+// if none is selected. An empty compression type means no compression.
+func GetCompression(options ImageOptions, defaultCompression string) CompressionOptions {
+	if options.Compression == nil {
+		return CompressionOptions{Type: CompressionType(defaultCompression)}
+	}
+	if options.Compression.Type == CompressionNone {
+		return CompressionOptions{}
+	}
+	return *options.Compression
+}
+
+// Extension returns the filename extension of files compressed with the type,
+// including the leading dot, or an empty string for no compression
+func (t CompressionType) Extension() string {
+	switch t {
+	case CompressionXZ:
+		return ".xz"
+	case CompressionZstd:
+		return ".zst"
+	case CompressionGzip:
+		return ".gz"
+	default:
+		return ""
+	}
+}
+
+// MIMEType returns the MIME type of files compressed with the type, or an
+// empty string for no compression
+func (t CompressionType) MIMEType() string {
+	switch t {
+	case CompressionXZ:
+		return "application/xz"
+	case CompressionZstd:
+		return "application/zstd"
+	case CompressionGzip:
+		return "application/gzip"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,73 @@
+package distro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/osbuild/images/internal/common"
+)
+
+func TestCompressionOptionsValidate(t *testing.T) {
+	tests := []struct {
+		options CompressionOptions
+		err     string
+	}{
+		{options: CompressionOptions{Type: CompressionNone}},
+		{options: CompressionOptions{Type: CompressionXZ}},
+		{options: CompressionOptions{Type: CompressionZstd}},
+		{options: CompressionOptions{Type: CompressionGzip}},
+		{options: CompressionOptions{Type: CompressionGzip, Level: common.ToPtr(uint(1))}},
+		{options: CompressionOptions{Type: CompressionGzip, Level: common.ToPtr(uint(9))}},
+		{
+			options: CompressionOptions{Type: "bzip2"},
+			err:     `unsupported compression type "bzip2"`,
+		},
+		{
+			options: CompressionOptions{},
+			err:     `unsupported compression type ""`,
+		},
+		{
+			options: CompressionOptions{Type: CompressionNone, Level: common.ToPtr(uint(1))},
+			err:     `compression level is not supported by compression type "none"`,
+		},
+		{
+			options: CompressionOptions{Type: CompressionXZ, Level: common.ToPtr(uint(6))},
+			err:     `compression level is not supported by compression type "xz"`,
+		},
+		{
+			options: CompressionOptions{Type: CompressionZstd, Level: common.ToPtr(uint(3))},
+			err:     `compression level is not supported by compression type "zstd"`,
+		},
+		{
+			options: CompressionOptions{Type: CompressionGzip, Level: common.ToPtr(uint(0))},
+			err:     "compression level 0 is out of range for gzip (1-9)",
+		},
+		{
+			options: CompressionOptions{Type: CompressionGzip, Level: common.ToPtr(uint(10))},
+			err:     "compression level 10 is out of range for gzip (1-9)",
+		},
+	}
+
+	for _, tt := range tests {
+		err := tt.options.Validate()
+		if tt.err == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tt.err)
+		}
+	}
+}
+
+func TestGetCompression(t *testing.T) {
+	assert.Equal(t, CompressionOptions{Type: CompressionXZ}, GetCompression(ImageOptions{}, "xz"))
+	assert.Equal(t, CompressionOptions{}, GetCompression(ImageOptions{}, ""))
+
+	gzip := CompressionOptions{Type: CompressionGzip, Level: common.ToPtr(uint(3))}
+	assert.Equal(t, gzip, GetCompression(ImageOptions{Compression: &gzip}, "xz"))
+	assert.Equal(t, CompressionOptions{}, GetCompression(ImageOptions{Compression: &CompressionOptions{Type: CompressionNone}}, "xz"))
+
+	assert.Equal(t, ".zst", CompressionZstd.Extension())
+	assert.Equal(t, "application/gzip", CompressionGzip.MIMEType())
+	assert.Equal(t, "", CompressionNone.Extension())
+}
@@ -1,6 +1,8 @@
 package distro
 
 import (
+	"fmt"
+
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/manifest"
@@ -134,10 +136,95 @@ type ImageOptions struct {
 	OSTree       *ostree.ImageOptions
 	Subscription *subscription.ImageOptions
 	Facts        *facts.ImageOptions
+
+	// PartitioningMode selects how custom mountpoints are added to the
+	// base partition table of the image type. If empty, the mode requested
+	// by the blueprint customizations is used.
+	PartitioningMode blueprint.PartitioningMode
+
+	// Compression selects how the artifact is compressed. If nil, the
+	// default compression of the image type is used. Not all image types
+	// support compression.
+	Compression *CompressionOptions
 }
 
 type BasePartitionTableMap map[string]disk.PartitionTable
 
+// GetPartitioningMode returns the partitioning mode requested for an image
+// build. The mode can be selected either by the image options or by the
+// blueprint customizations, but if both are set they must agree.
+func GetPartitioningMode(options ImageOptions, customizations *blueprint.Customizations) (blueprint.PartitioningMode, error) {
+	bpMode, err := customizations.GetPartitioningMode()
+	if err != nil {
+		return blueprint.DefaultPartitioningMode, err
+	}
+
+	switch options.PartitioningMode {
+	case blueprint.DefaultPartitioningMode:
+		return bpMode, nil
+	case blueprint.RawPartitioningMode, blueprint.LVMPartitioningMode, blueprint.AutoLVMPartitioningMode, blueprint.BtrfsPartitioningMode:
+		if bpMode != blueprint.DefaultPartitioningMode && bpMode != options.PartitioningMode {
+			return blueprint.DefaultPartitioningMode, fmt.Errorf("partitioning mode %q of the image options conflicts with partitioning mode %q of the blueprint", options.PartitioningMode, bpMode)
+		}
+		return options.PartitioningMode, nil
+	default:
+		return blueprint.DefaultPartitioningMode, fmt.Errorf("unsupported partitioning mode %q", options.PartitioningMode)
+	}
+}
+
+// GetInstallerKickstart returns the kickstart configuration of the installer
+// customization, together with the kernel options it adds to the installed
+// system. The primary locale, keyboard and timezone customizations are
+// applied to the kickstart file as well. Returns nil if there is no installer
+// customization.
+func GetInstallerKickstart(customizations *blueprint.Customizations) (*manifest.Kickstart, []string, error) {
+	installer, err := customizations.GetInstaller()
+	if err != nil || installer == nil {
+		return nil, nil, err
+	}
+
+	ks := &manifest.Kickstart{
+		Unattended:  installer.Unattended,
+		TargetDisks: installer.TargetDisks,
+		ClearPart:   installer.ClearPart,
+		Finish:      installer.Finish,
+	}
+
+	lang, keyboard := customizations.GetPrimaryLocale()
+	if lang != nil {
+		ks.Lang = *lang
+	}
+	if keyboard != nil {
+		ks.Keyboard = *keyboard
+	}
+	if timezone, _ := customizations.GetTimezoneSettings(); timezone != nil {
+		ks.Timezone = *timezone
+	}
+
+	var kernelOptions []string
+	if bl := installer.Bootloader; bl != nil {
+		ks.BootloaderTimeout = bl.Timeout
+		if bl.Append != "" {
+			kernelOptions = append(kernelOptions, bl.Append)
+		}
+	}
+
+	for _, script := range installer.PostScripts {
+		ks.PostScripts = append(ks.PostScripts, manifest.KickstartPostScript{
+			Interpreter: script.Interpreter,
+			NoChroot:    script.NoChroot,
+			ErrorOnFail: script.ErrorOnFail,
+			Script:      script.Script,
+		})
+	}
+
+	if installer.Kickstart != nil {
+		ks.UserContents = installer.Kickstart.Contents
+	}
+
+	return ks, kernelOptions, nil
+}
+
 // Fallbacks: When a new method is added to an interface to provide to provide
 // information that isn't available for older implementations, the older
 // methods should return a fallback/default value by calling the appropriate
@@ -159,3 +246,50 @@ func ExportsFallback() []string {
 func PayloadPackageSets() []string {
 	return []string{}
 }
+
+// ImageTypeWithOptions is implemented by image types whose output depends on
+// the image options, e.g. on the selected compression. Use FilenameFor(),
+// MIMETypeFor() and ExportsFor() to fall back to the methods of ImageType for
+// other image types.
+type ImageTypeWithOptions interface {
+	ImageType
+
+	// Returns the filename for the image type, with the extension of the
+	// compression selected in the image options.
+	FilenameFor(options ImageOptions) string
+
+	// Returns the MIME-type for the image type, which is the MIME-type of the
+	// compression if one is selected in the image options.
+	MIMETypeFor(options ImageOptions) string
+
+	// Returns the names of the stages that will produce the build output for
+	// the given image options.
+	ExportsFor(options ImageOptions) []string
+}
+
+// FilenameFor returns the filename of the image type for the image options,
+// or its Filename() if it doesn't implement ImageTypeWithOptions.
+func FilenameFor(t ImageType, options ImageOptions) string {
+	if it, ok := t.(ImageTypeWithOptions); ok {
+		return it.FilenameFor(options)
+	}
+	return t.Filename()
+}
+
+// MIMETypeFor returns the MIME-type of the image type for the image options,
+// or its MIMEType() if it doesn't implement ImageTypeWithOptions.
+func MIMETypeFor(t ImageType, options ImageOptions) string {
+	if it, ok := t.(ImageTypeWithOptions); ok {
+		return it.MIMETypeFor(options)
+	}
+	return t.MIMEType()
+}
+
+// ExportsFor returns the exports of the image type for the image options, or
+// its Exports() if it doesn't implement ImageTypeWithOptions.
+func ExportsFor(t ImageType, options ImageOptions) []string {
+	if it, ok := t.(ImageTypeWithOptions); ok {
+		return it.ExportsFor(options)
+	}
+	return t.Exports()
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distro/test_distro"
 	"github.com/osbuild/images/pkg/distroregistry"
 	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -136,12 +137,14 @@ func TestImageTypePipelineNames(t *testing.T) {
 					}
 
 					// Pipelines that require package sets will fail if none
-					// are defined. OS pipelines require a kernel.
-					// Add kernel and filesystem to every pipeline so that the
-					// manifest creation doesn't fail.
+					// are defined. OS pipelines require a kernel and the
+					// SELinux policy.
+					// Add kernel, SELinux policy and filesystem to every
+					// pipeline so that the manifest creation doesn't fail.
 					allPipelines := append(imageType.BuildPipelines(), imageType.PayloadPipelines()...)
 					minimalPackageSet := []rpmmd.PackageSpec{
 						{Name: "kernel", Checksum: "sha256:a0c936696eb7d5ee3192bf53b9d281cecbb40ca9db520de72cb95817ad92ac72"},
+						{Name: "selinux-policy-targeted", Checksum: "sha256:4a6b2f6e13b2e0e2e5c29d3a6b4d7b9f1d8bb8dc6c3f1a36fd5a9d2c6aa0f1b7"},
 						{Name: "filesystem", Checksum: "sha256:6b4bf18ba28ccbdd49f2716c9f33c9211155ff703fa6c195c78a07bd160da0eb"},
 					}
 
@@ -550,3 +553,48 @@ func (s stringSet) Merge(other stringSet) stringSet {
 	}
 	return merged
 }
+
+func TestGetPartitioningMode(t *testing.T) {
+	assert := assert.New(t)
+
+	mode, err := distro.GetPartitioningMode(distro.ImageOptions{}, nil)
+	assert.NoError(err)
+	assert.Equal(blueprint.DefaultPartitioningMode, mode)
+
+	mode, err = distro.GetPartitioningMode(distro.ImageOptions{PartitioningMode: blueprint.RawPartitioningMode}, nil)
+	assert.NoError(err)
+	assert.Equal(blueprint.RawPartitioningMode, mode)
+
+	mode, err = distro.GetPartitioningMode(distro.ImageOptions{}, &blueprint.Customizations{PartitioningMode: blueprint.LVMPartitioningMode})
+	assert.NoError(err)
+	assert.Equal(blueprint.LVMPartitioningMode, mode)
+
+	mode, err = distro.GetPartitioningMode(distro.ImageOptions{PartitioningMode: blueprint.LVMPartitioningMode}, &blueprint.Customizations{PartitioningMode: blueprint.LVMPartitioningMode})
+	assert.NoError(err)
+	assert.Equal(blueprint.LVMPartitioningMode, mode)
+
+	_, err = distro.GetPartitioningMode(distro.ImageOptions{PartitioningMode: blueprint.RawPartitioningMode}, &blueprint.Customizations{PartitioningMode: blueprint.LVMPartitioningMode})
+	assert.EqualError(err, `partitioning mode "raw" of the image options conflicts with partitioning mode "lvm" of the blueprint`)
+
+	_, err = distro.GetPartitioningMode(distro.ImageOptions{PartitioningMode: "zfs"}, nil)
+	assert.EqualError(err, `unsupported partitioning mode "zfs"`)
+
+	_, err = distro.GetPartitioningMode(distro.ImageOptions{}, &blueprint.Customizations{PartitioningMode: "zfs"})
+	assert.EqualError(err, `Partitioning mode "zfs" is not supported`)
+}
+
+func TestImageTypeWithOptionsFallback(t *testing.T) {
+	arch, err := test_distro.New().GetArch(test_distro.TestArchName)
+	require.NoError(t, err)
+	imgType, err := arch.GetImageType(test_distro.TestImageTypeName)
+	require.NoError(t, err)
+
+	// the test image type doesn't implement ImageTypeWithOptions
+	_, ok := imgType.(distro.ImageTypeWithOptions)
+	assert.False(t, ok)
+
+	options := distro.ImageOptions{Compression: &distro.CompressionOptions{Type: distro.CompressionGzip}}
+	assert.Equal(t, imgType.Filename(), distro.FilenameFor(imgType, options))
+	assert.Equal(t, imgType.MIMEType(), distro.MIMETypeFor(imgType, options))
+	assert.Equal(t, imgType.Exports(), distro.ExportsFor(imgType, options))
+}
@@ -31,7 +31,7 @@ const (
 	// blueprint package set name
 	blueprintPkgsKey = "blueprint"
 
-	//Kernel options for ami, qcow2, openstack, vhd and vmdk types
+	//Kernel options for ami, qcow2, openstack, vhd, vmdk, vhdx and vdi types
 	defaultKernelOptions = "ro no_timer_check console=ttyS0,115200n8 biosdevname=0 net.ifnames=0"
 )
 
@@ -161,9 +161,9 @@ var (
 	iotRawImgType = imageType{
 		name:        "iot-raw-image",
 		nameAliases: []string{"fedora-iot-raw-image"},
-		filename:    "image.raw.xz",
+		filename:    "image.raw",
 		compression: "xz",
-		mimeType:    "application/xz",
+		mimeType:    "application/octet-stream",
 		packageSets: map[string]packageSetFunc{},
 		defaultImageConfig: &distro.ImageConfig{
 			Locale: common.ToPtr("en_US.UTF-8"),
@@ -174,7 +174,7 @@ var (
 		image:               iotRawImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"ostree-deployment", "image", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"image"},
 		basePartitionTables: iotBasePartitionTables,
 
 		// Passing an empty map into the required partition sizes disables the
@@ -284,6 +284,84 @@ var (
 		basePartitionTables: defaultBasePartitionTables,
 	}
 
+	localVMDefaultImageConfig = &distro.ImageConfig{
+		Locale: common.ToPtr("en_US.UTF-8"),
+	}
+
+	vhdxImgType = imageType{
+		name:     "vhdx",
+		filename: "disk.vhdx",
+		mimeType: "application/x-vhdx",
+		packageSets: map[string]packageSetFunc{
+			osPkgsKey: vhdxCommonPackageSet,
+		},
+		defaultImageConfig:  localVMDefaultImageConfig,
+		kernelOptions:       defaultKernelOptions,
+		bootable:            true,
+		defaultSize:         2 * common.GibiByte,
+		image:               liveImage,
+		buildPipelines:      []string{"build"},
+		payloadPipelines:    []string{"os", "image", "vhdx"},
+		exports:             []string{"vhdx"},
+		basePartitionTables: defaultBasePartitionTables,
+	}
+
+	vdiImgType = imageType{
+		name:     "vdi",
+		filename: "disk.vdi",
+		mimeType: "application/x-virtualbox-vdi",
+		packageSets: map[string]packageSetFunc{
+			osPkgsKey: vdiCommonPackageSet,
+		},
+		defaultImageConfig:  localVMDefaultImageConfig,
+		kernelOptions:       defaultKernelOptions,
+		bootable:            true,
+		defaultSize:         2 * common.GibiByte,
+		image:               liveImage,
+		buildPipelines:      []string{"build"},
+		payloadPipelines:    []string{"os", "image", "vdi"},
+		exports:             []string{"vdi"},
+		basePartitionTables: defaultBasePartitionTables,
+	}
+
+	vagrantLibvirtImgType = imageType{
+		name:     "vagrant-libvirt",
+		filename: "vagrant-libvirt.box",
+		mimeType: "application/x-tar",
+		packageSets: map[string]packageSetFunc{
+			osPkgsKey: vagrantLibvirtPackageSet,
+		},
+		defaultImageConfig:  localVMDefaultImageConfig,
+		kernelOptions:       defaultKernelOptions,
+		bootable:            true,
+		defaultSize:         10 * common.GibiByte,
+		image:               liveImage,
+		buildPipelines:      []string{"build"},
+		payloadPipelines:    []string{"os", "image", "qcow2", "vagrant", "archive"},
+		exports:             []string{"archive"},
+		basePartitionTables: defaultBasePartitionTables,
+		environment:         &environment.Vagrant{},
+	}
+
+	vagrantVirtualBoxImgType = imageType{
+		name:     "vagrant-virtualbox",
+		filename: "vagrant-virtualbox.box",
+		mimeType: "application/x-tar",
+		packageSets: map[string]packageSetFunc{
+			osPkgsKey: localVMCommonPackageSet,
+		},
+		defaultImageConfig:  localVMDefaultImageConfig,
+		kernelOptions:       defaultKernelOptions,
+		bootable:            true,
+		defaultSize:         10 * common.GibiByte,
+		image:               liveImage,
+		buildPipelines:      []string{"build"},
+		payloadPipelines:    []string{"os", "image", "vmdk", "ovf", "vagrant", "archive"},
+		exports:             []string{"archive"},
+		basePartitionTables: defaultBasePartitionTables,
+		environment:         &environment.Vagrant{},
+	}
+
 	containerImgType = imageType{
 		name:     "container",
 		filename: "container.tar",
@@ -331,9 +409,9 @@ var (
 
 	minimalrawImgType = imageType{
 		name:        "minimal-raw",
-		filename:    "raw.img.xz",
+		filename:    "raw.img",
 		compression: "xz",
-		mimeType:    "application/xz",
+		mimeType:    "application/octet-stream",
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: minimalrpmPackageSet,
 		},
@@ -344,7 +422,7 @@ var (
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"image"},
 		basePartitionTables: defaultBasePartitionTables,
 	}
 )
@@ -531,6 +609,18 @@ func newDistro(version int) distro.Distro {
 	openstackImgType := qcow2ImgType
 	openstackImgType.name = "openstack"
 
+	ukiQcow2ImgType := qcow2ImgType
+	ukiQcow2ImgType.name = "qcow2-uki"
+	ukiQcow2ImgType.basePartitionTables = ukiBasePartitionTables
+
+	containerDiskImgType := qcow2ImgType
+	containerDiskImgType.name = "containerdisk"
+	containerDiskImgType.filename = "container.tar"
+	containerDiskImgType.mimeType = "application/x-tar"
+	containerDiskImgType.payloadPipelines = []string{"os", "image", "qcow2", "containerdisk", "container"}
+	containerDiskImgType.exports = []string{"container"}
+	containerDiskImgType.environment = &environment.KubeVirt{}
+
 	x86_64.addImageTypes(
 		&platform.X86{
 			BIOS:       true,
@@ -543,6 +633,28 @@ func newDistro(version int) distro.Distro {
 		qcow2ImgType,
 		ociImgType,
 	)
+	x86_64.addImageTypes(
+		&platform.X86{
+			BIOS:       true,
+			UEFIVendor: "fedora",
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_CONTAINERDISK,
+				QCOW2Compat: "1.1",
+			},
+		},
+		containerDiskImgType,
+	)
+	x86_64.addImageTypes(
+		&platform.X86{
+			UEFIVendor: "fedora",
+			Bootloader: platform.BOOTLOADER_UKI,
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_QCOW2,
+				QCOW2Compat: "1.1",
+			},
+		},
+		ukiQcow2ImgType,
+	)
 	x86_64.addImageTypes(
 		&platform.X86{
 			BIOS:       true,
@@ -583,6 +695,47 @@ func newDistro(version int) distro.Distro {
 		},
 		ovaImgType,
 	)
+	x86_64.addImageTypes(
+		&platform.X86{
+			BIOS:       true,
+			UEFIVendor: "fedora",
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_VHDX,
+			},
+		},
+		vhdxImgType,
+	)
+	x86_64.addImageTypes(
+		&platform.X86{
+			BIOS:       true,
+			UEFIVendor: "fedora",
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_VDI,
+			},
+		},
+		vdiImgType,
+	)
+	x86_64.addImageTypes(
+		&platform.X86{
+			BIOS:       true,
+			UEFIVendor: "fedora",
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_VAGRANT_LIBVIRT,
+				QCOW2Compat: "1.1",
+			},
+		},
+		vagrantLibvirtImgType,
+	)
+	x86_64.addImageTypes(
+		&platform.X86{
+			BIOS:       true,
+			UEFIVendor: "fedora",
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_VAGRANT_VIRTUALBOX,
+			},
+		},
+		vagrantVirtualBoxImgType,
+	)
 	x86_64.addImageTypes(
 		&platform.X86{
 			BIOS:       true,
@@ -655,6 +808,27 @@ func newDistro(version int) distro.Distro {
 		qcow2ImgType,
 		ociImgType,
 	)
+	aarch64.addImageTypes(
+		&platform.Aarch64{
+			UEFIVendor: "fedora",
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_CONTAINERDISK,
+				QCOW2Compat: "1.1",
+			},
+		},
+		containerDiskImgType,
+	)
+	aarch64.addImageTypes(
+		&platform.Aarch64{
+			UEFIVendor: "fedora",
+			Bootloader: platform.BOOTLOADER_UKI,
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_QCOW2,
+				QCOW2Compat: "1.1",
+			},
+		},
+		ukiQcow2ImgType,
+	)
 	aarch64.addImageTypes(
 		&platform.Aarch64{
 			UEFIVendor: "fedora",
@@ -12,6 +12,7 @@ import (
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distro/distro_test_common"
 	"github.com/osbuild/images/pkg/distro/fedora"
+	"github.com/osbuild/images/pkg/rpmmd"
 )
 
 type fedoraFamilyDistro struct {
@@ -96,6 +97,54 @@ func TestFilenameFromType(t *testing.T) {
 				mimeType: "application/ovf",
 			},
 		},
+		{
+			name: "vhdx",
+			args: args{"vhdx"},
+			want: wantResult{
+				filename: "disk.vhdx",
+				mimeType: "application/x-vhdx",
+			},
+		},
+		{
+			name: "vdi",
+			args: args{"vdi"},
+			want: wantResult{
+				filename: "disk.vdi",
+				mimeType: "application/x-virtualbox-vdi",
+			},
+		},
+		{
+			name: "vagrant-libvirt",
+			args: args{"vagrant-libvirt"},
+			want: wantResult{
+				filename: "vagrant-libvirt.box",
+				mimeType: "application/x-tar",
+			},
+		},
+		{
+			name: "vagrant-virtualbox",
+			args: args{"vagrant-virtualbox"},
+			want: wantResult{
+				filename: "vagrant-virtualbox.box",
+				mimeType: "application/x-tar",
+			},
+		},
+		{
+			name: "qcow2-uki",
+			args: args{"qcow2-uki"},
+			want: wantResult{
+				filename: "disk.qcow2",
+				mimeType: "application/x-qemu-disk",
+			},
+		},
+		{
+			name: "containerdisk",
+			args: args{"containerdisk"},
+			want: wantResult{
+				filename: "container.tar",
+				mimeType: "application/x-tar",
+			},
+		},
 		{
 			name: "container",
 			args: args{"container"},
@@ -288,9 +337,15 @@ func TestImageType_Name(t *testing.T) {
 			imgNames: []string{
 				"qcow2",
 				"openstack",
+				"containerdisk",
+				"qcow2-uki",
 				"vhd",
 				"vmdk",
 				"ova",
+				"vhdx",
+				"vdi",
+				"vagrant-libvirt",
+				"vagrant-virtualbox",
 				"ami",
 				"iot-commit",
 				"iot-container",
@@ -308,6 +363,8 @@ func TestImageType_Name(t *testing.T) {
 			imgNames: []string{
 				"qcow2",
 				"openstack",
+				"containerdisk",
+				"qcow2-uki",
 				"ami",
 				"oci",
 				"iot-commit",
@@ -447,7 +504,7 @@ func TestDistro_ManifestError(t *testing.T) {
 			} else if imgTypeName == "iot-installer" {
 				assert.EqualError(t, err, fmt.Sprintf("boot ISO image type \"%s\" requires specifying a URL from which to retrieve the OSTree commit", imgTypeName))
 			} else if imgTypeName == "image-installer" {
-				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: User, Group)", imgTypeName))
+				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: User, Group, Network, Installer, Locale, Timezone)", imgTypeName))
 			} else if imgTypeName == "live-installer" {
 				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: None)", imgTypeName))
 			} else if imgTypeName == "iot-raw-image" {
@@ -470,9 +527,15 @@ func TestArchitecture_ListImageTypes(t *testing.T) {
 			imgNames: []string{
 				"qcow2",
 				"openstack",
+				"containerdisk",
+				"qcow2-uki",
 				"vhd",
 				"vmdk",
 				"ova",
+				"vhdx",
+				"vdi",
+				"vagrant-libvirt",
+				"vagrant-virtualbox",
 				"ami",
 				"iot-commit",
 				"iot-container",
@@ -491,6 +554,8 @@ func TestArchitecture_ListImageTypes(t *testing.T) {
 			imgNames: []string{
 				"qcow2",
 				"openstack",
+				"containerdisk",
+				"qcow2-uki",
 				"ami",
 				"iot-commit",
 				"iot-container",
@@ -823,3 +888,142 @@ func TestDistro_CustomUsrPartitionNotLargeEnough(t *testing.T) {
 		}
 	}
 }
+
+func TestDistro_BtrfsPartitioningMode(t *testing.T) {
+	fedoraDistro := fedora.NewF38()
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			PartitioningMode: blueprint.BtrfsPartitioningMode,
+			Filesystem: []blueprint.FilesystemCustomization{
+				{
+					MinSize:    1024,
+					Mountpoint: "/var",
+				},
+			},
+		},
+	}
+	for _, archName := range fedoraDistro.ListArches() {
+		arch, _ := fedoraDistro.GetArch(archName)
+		for _, imgTypeName := range []string{"qcow2", "minimal-raw", "iot-commit", "live-installer"} {
+			imgType, err := arch.GetImageType(imgTypeName)
+			if err != nil {
+				// not available on this architecture
+				continue
+			}
+			_, _, err = imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+			switch imgTypeName {
+			case "qcow2", "minimal-raw":
+				assert.NoError(t, err)
+			case "iot-commit":
+				assert.EqualError(t, err, "Custom mountpoints are not supported for ostree types")
+			default:
+				assert.Error(t, err)
+			}
+		}
+	}
+
+	bp.Customizations.PartitioningMode = "zfs"
+	arch, _ := fedoraDistro.GetArch("x86_64")
+	imgType, _ := arch.GetImageType("qcow2")
+	_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `Partitioning mode "zfs" is not supported`)
+}
+
+func TestDistro_PartitioningModes(t *testing.T) {
+	fedoraDistro := fedora.NewF38()
+	arch, _ := fedoraDistro.GetArch("x86_64")
+	bp := blueprint.Blueprint{}
+
+	qcow2, _ := arch.GetImageType("qcow2")
+	for _, mode := range []blueprint.PartitioningMode{blueprint.RawPartitioningMode, blueprint.LVMPartitioningMode, blueprint.AutoLVMPartitioningMode} {
+		_, _, err := qcow2.Manifest(&bp, distro.ImageOptions{PartitioningMode: mode}, nil, 0)
+		assert.NoError(t, err)
+	}
+
+	_, _, err := qcow2.Manifest(&bp, distro.ImageOptions{PartitioningMode: "zfs"}, nil, 0)
+	assert.EqualError(t, err, `unsupported partitioning mode "zfs"`)
+
+	bp.Customizations = &blueprint.Customizations{PartitioningMode: blueprint.RawPartitioningMode}
+	_, _, err = qcow2.Manifest(&bp, distro.ImageOptions{PartitioningMode: blueprint.LVMPartitioningMode}, nil, 0)
+	assert.EqualError(t, err, `partitioning mode "lvm" of the image options conflicts with partitioning mode "raw" of the blueprint`)
+
+	bp = blueprint.Blueprint{}
+	iotCommit, _ := arch.GetImageType("iot-commit")
+	_, _, err = iotCommit.Manifest(&bp, distro.ImageOptions{PartitioningMode: blueprint.RawPartitioningMode}, nil, 0)
+	assert.EqualError(t, err, `partitioning mode "raw" is not supported for image type "iot-commit"`)
+
+	iotRaw, _ := arch.GetImageType("iot-raw-image")
+	_, _, err = iotRaw.Manifest(&bp, distro.ImageOptions{PartitioningMode: blueprint.LVMPartitioningMode}, nil, 0)
+	assert.EqualError(t, err, `partitioning mode "lvm" is not supported for ostree types`)
+}
+
+func TestDistro_UKI(t *testing.T) {
+	fedoraDistro := fedora.NewF38()
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Kernel: &blueprint.KernelCustomization{Append: "debug"},
+		},
+	}
+	for _, archName := range []string{"x86_64", "aarch64"} {
+		arch, _ := fedoraDistro.GetArch(archName)
+		imgType, err := arch.GetImageType("qcow2-uki")
+		require.NoError(t, err, archName)
+		assert.Equal(t, distro.BOOT_UEFI, imgType.BootMode(), archName)
+
+		manifest, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		require.NoError(t, err, archName)
+		chains := manifest.GetPackageSetChains()
+		assert.Contains(t, chains["os"][0].Include, "systemd-boot-unsigned", archName)
+		for _, pkg := range chains["os"][0].Include {
+			assert.False(t, strings.HasPrefix(pkg, "grub2"), "%s: unexpected package %s", archName, pkg)
+		}
+		assert.Contains(t, chains["os"][0].Include, "binutils", archName)
+	}
+}
+
+func TestDistro_RepositoryDownloadSettings(t *testing.T) {
+	fedoraDistro := fedora.NewF38()
+	arch, _ := fedoraDistro.GetArch("x86_64")
+	imgType, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	repos := []rpmmd.RepoConfig{
+		{Name: "custom", BaseURLs: []string{"https://example.com/repo"}, Proxy: "http://proxy.example.com:3128"},
+	}
+	_, _, err = imgType.Manifest(&blueprint.Blueprint{}, distro.ImageOptions{}, repos, 0)
+	assert.EqualError(t, err, `packages of repository "custom" cannot be downloaded by osbuild with a proxy`)
+}
+
+func TestDistro_InvalidPackagePin(t *testing.T) {
+	fedoraDistro := fedora.NewF38()
+	arch, _ := fedoraDistro.GetArch("x86_64")
+	imgType, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	bp := blueprint.Blueprint{
+		Packages: []blueprint.Package{{Name: "kernel", Version: "x:6.5.6-300.fc38"}},
+	}
+	_, _, err = imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `package pin "kernel-x:6.5.6-300.fc38" has an invalid epoch "x"`)
+}
+
+func TestDistro_Vagrant(t *testing.T) {
+	fedoraDistro := fedora.NewF38()
+	arch, _ := fedoraDistro.GetArch("x86_64")
+
+	for _, imgTypeName := range []string{"vagrant-libvirt", "vagrant-virtualbox"} {
+		imgType, err := arch.GetImageType(imgTypeName)
+		require.NoError(t, err, imgTypeName)
+		m, _, err := imgType.Manifest(&blueprint.Blueprint{}, distro.ImageOptions{}, nil, 0)
+		require.NoError(t, err, imgTypeName)
+		assert.Equal(t, []string{"archive"}, m.GetExports(), imgTypeName)
+
+		var packages []string
+		for _, pkgSet := range m.GetPackageSetChains()["os"] {
+			packages = append(packages, pkgSet.Include...)
+		}
+		// rsync is used by the synced folder of the Vagrantfile
+		assert.Subset(t, packages, []string{"openssh-server", "rsync", "sudo"}, imgTypeName)
+		assert.NotContains(t, packages, "virtualbox-guest-additions", imgTypeName)
+	}
+}
@@ -27,7 +27,13 @@ func osCustomizations(
 	containers []container.SourceSpec,
 	c *blueprint.Customizations) manifest.OSCustomizations {
 
-	imageConfig := t.getDefaultImageConfig()
+	// merge the kernel tuning customizations on top of the image type defaults
+	imageConfig, err := t.getDefaultImageConfig().ApplyKernelCustomizations(c)
+	if err != nil {
+		// This shouldn't happen since the customizations
+		// should have already been validated
+		panic(fmt.Sprintf("failed to apply kernel tuning customizations: %v", err))
+	}
 
 	osc := manifest.OSCustomizations{}
 
@@ -48,6 +54,11 @@ func osCustomizations(
 	osc.ExcludeBasePackages = osPackageSet.Exclude
 	osc.ExtraBaseRepos = osPackageSet.Repositories
 
+	// clevis needs to be part of the initrd to unlock the root filesystem
+	if encryption, _ := c.GetDiskEncryption(); encryption != nil && encryption.Clevis != nil {
+		osc.ExtraBasePackages = append(osc.ExtraBasePackages, "clevis-dracut")
+	}
+
 	osc.Containers = containers
 
 	osc.GPGKeyFiles = imageConfig.GPGKeyFiles
@@ -113,9 +124,19 @@ func osCustomizations(
 		osc.NTPServers = imageConfig.TimeSynchronization.Servers
 	}
 
+	selinux, err := c.GetSELinux()
+	if err != nil {
+		// This shouldn't happen since the SELinux customization
+		// should have already been validated
+		panic(fmt.Sprintf("failed to get SELinux customization: %v", err))
+	}
+
 	// Relabel the tree, unless the `NoSElinux` flag is explicitly set to `true`
 	if imageConfig.NoSElinux == nil || imageConfig.NoSElinux != nil && !*imageConfig.NoSElinux {
 		osc.SElinux = "targeted"
+		if selinux != nil && selinux.Policy != "" {
+			osc.SElinux = selinux.Policy
+		}
 	}
 
 	if oscapConfig := c.GetOpenSCAP(); oscapConfig != nil {
@@ -134,7 +155,6 @@ func osCustomizations(
 		)
 	}
 
-	var err error
 	osc.Directories, err = blueprint.DirectoryCustomizationsToFsNodeDirectories(c.GetDirectories())
 	if err != nil {
 		// In theory this should never happen, because the blueprint directory customizations
@@ -174,6 +194,120 @@ func osCustomizations(
 		osc.YUMRepos = append(osc.YUMRepos, osbuild.NewYumReposStageOptions(filename, repos))
 	}
 
+	networkConnections, err := c.GetNetworkConnections()
+	if err != nil {
+		// This shouldn't happen since the network connections
+		// should have already been validated
+		panic(fmt.Sprintf("failed to get network connections: %v", err))
+	}
+
+	// render the network connections as NetworkManager keyfiles
+	networkFiles, err := blueprint.NetworkConnectionsToFsNodeFiles(networkConnections)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert network connections to fs node files: %v", err))
+	}
+	if len(networkFiles) > 0 {
+		osc.Files = append(osc.Files, networkFiles...)
+		osc.ExtraBasePackages = append(osc.ExtraBasePackages, "NetworkManager")
+	}
+
+	sudoers, err := c.GetSudoers()
+	if err != nil {
+		// This shouldn't happen since the sudoers customizations
+		// should have already been validated
+		panic(fmt.Sprintf("failed to get sudoers customizations: %v", err))
+	}
+
+	sudoersFiles, err := blueprint.SudoersCustomizationsToFsNodeFiles(sudoers)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert sudoers customizations to fs node files: %v", err))
+	}
+	if len(sudoersFiles) > 0 {
+		osc.Files = append(osc.Files, sudoersFiles...)
+		osc.ExtraBasePackages = append(osc.ExtraBasePackages, "sudo")
+	}
+
+	cacerts, err := c.GetCACerts()
+	if err != nil {
+		// This shouldn't happen since the CA certificates
+		// should have already been validated
+		panic(fmt.Sprintf("failed to get CA certificates: %v", err))
+	}
+
+	osc.CACerts, err = blueprint.CACertsToFsNodeFiles(cacerts)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert CA certificates to fs node files: %v", err))
+	}
+
+	modprobe, err := c.GetModprobe()
+	if err != nil {
+		// This shouldn't happen since the modprobe customization
+		// should have already been validated
+		panic(fmt.Sprintf("failed to get modprobe customization: %v", err))
+	}
+
+	modprobeFiles, err := blueprint.ModprobeOptionsToFsNodeFiles(modprobe)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert modprobe options to fs node files: %v", err))
+	}
+	osc.Files = append(osc.Files, modprobeFiles...)
+
+	// the file contexts are written before the tree is relabelled
+	selinuxFiles, err := blueprint.SELinuxCustomizationToFsNodeFiles(selinux, osc.SElinux)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert SELinux customization to fs node files: %v", err))
+	}
+	osc.Files = append(osc.Files, selinuxFiles...)
+	if selinux != nil && len(selinux.Booleans) > 0 {
+		osc.EnabledServices = append(osc.EnabledServices, blueprint.SELinuxBooleansService)
+		osc.ExtraBasePackages = append(osc.ExtraBasePackages, "policycoreutils")
+	}
+
+	systemdUnits, err := c.GetSystemdUnits()
+	if err != nil {
+		// This shouldn't happen since the systemd unit customizations
+		// should have already been validated
+		panic(fmt.Sprintf("failed to get systemd unit customizations: %v", err))
+	}
+
+	systemdDirs, err := blueprint.SystemdUnitsToFsNodeDirectories(systemdUnits)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert systemd unit customizations to fs node directories: %v", err))
+	}
+	osc.Directories = append(osc.Directories, systemdDirs...)
+
+	systemdFiles, err := blueprint.SystemdUnitsToFsNodeFiles(systemdUnits)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert systemd unit customizations to fs node files: %v", err))
+	}
+	osc.Files = append(osc.Files, systemdFiles...)
+	osc.EnabledServices = append(osc.EnabledServices, blueprint.EnabledSystemdUnits(systemdUnits)...)
+	if services := c.GetServices(); services != nil {
+		osc.MaskedServices = services.Masked
+	}
+
+	swap, err := c.GetSwap()
+	if err != nil {
+		// This shouldn't happen since the swap customization should have
+		// already been validated
+		panic(fmt.Sprintf("failed to get swap customization: %v", err))
+	}
+
+	swapFiles, err := blueprint.SwapFileToFsNodeFiles(swap)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert swap customization to fs node files: %v", err))
+	}
+	if len(swapFiles) > 0 {
+		osc.Files = append(osc.Files, swapFiles...)
+		osc.EnabledServices = append(osc.EnabledServices, blueprint.SwapFileUnit(swap.GetPath()))
+	}
+	osc.Resume = swap != nil && swap.Resume
+
+	// the tuned stage requires TuneD to be installed in the image
+	if tuned, _ := c.GetTuned(); tuned != nil {
+		osc.ExtraBasePackages = append(osc.ExtraBasePackages, "tuned")
+	}
+
 	osc.ShellInit = imageConfig.ShellInit
 
 	osc.Grub2Config = imageConfig.Grub2Config
@@ -185,6 +319,19 @@ func osCustomizations(
 	osc.SystemdUnit = imageConfig.SystemdUnit
 	osc.Authselect = imageConfig.Authselect
 	osc.SELinuxConfig = imageConfig.SELinuxConfig
+	if selinux != nil && (selinux.Mode != "" || selinux.Policy != "") {
+		selinuxConfig := &osbuild.SELinuxConfigStageOptions{}
+		if osc.SELinuxConfig != nil {
+			*selinuxConfig = *osc.SELinuxConfig
+		}
+		if selinux.Mode != "" {
+			selinuxConfig.State = osbuild.SELinuxPolicyState(selinux.Mode)
+		}
+		if selinux.Policy != "" {
+			selinuxConfig.Type = osbuild.SELinuxPolicyType(selinux.Policy)
+		}
+		osc.SELinuxConfig = selinuxConfig
+	}
 	osc.Tuned = imageConfig.Tuned
 	osc.Tmpfilesd = imageConfig.Tmpfilesd
 	osc.PamLimitsConf = imageConfig.PamLimitsConf
@@ -213,15 +360,17 @@ func liveImage(workload workload.Workload,
 	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], containers, customizations)
 	img.Environment = t.environment
 	img.Workload = workload
-	img.Compression = t.compression
+	compression := t.getCompression(options)
+	img.Compression = string(compression.Type)
+	img.CompressionLevel = compression.Level
 	// TODO: move generation into LiveImage
-	pt, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	pt, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
 	img.PartitionTable = pt
 
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	return img, nil
 }
@@ -240,7 +389,7 @@ func containerImage(workload workload.Workload,
 	img.Environment = t.environment
 	img.Workload = workload
 
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	return img, nil
 }
@@ -278,7 +427,7 @@ func liveInstallerImage(workload workload.Workload,
 	img.OSVersion = d.osVersion
 	img.Release = fmt.Sprintf("%s %s", d.product, d.osVersion)
 
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	return img, nil
 }
@@ -293,6 +442,18 @@ func imageInstallerImage(workload workload.Workload,
 
 	img := image.NewAnacondaTarInstaller()
 
+	kickstart, kickstartKernelOptions, err := distro.GetInstallerKickstart(customizations)
+	if err != nil {
+		return nil, err
+	}
+	if kickstart != nil {
+		// the installer configuration requires a kickstart file that is
+		// read by Anaconda instead of the interactive defaults
+		img.ISORootKickstart = true
+		img.Kickstart = kickstart
+		img.KickstartKernelOptionsAppend = kickstartKernelOptions
+	}
+
 	// Enable anaconda-webui for Fedora > 38
 	distro := t.Arch().Distro()
 	if strings.HasPrefix(distro.Name(), "fedora") && !common.VersionLessThan(distro.Releasever(), "38") {
@@ -322,7 +483,7 @@ func imageInstallerImage(workload workload.Workload,
 	img.OSVersion = d.osVersion
 	img.Release = fmt.Sprintf("%s %s", d.product, d.osVersion)
 
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	return img, nil
 }
@@ -344,7 +505,7 @@ func iotCommitImage(workload workload.Workload,
 	img.Workload = workload
 	img.OSTreeParent = parentCommit
 	img.OSVersion = t.arch.distro.osVersion
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	return img, nil
 }
@@ -368,7 +529,7 @@ func iotContainerImage(workload workload.Workload,
 	img.OSTreeParent = parentCommit
 	img.OSVersion = t.arch.distro.osVersion
 	img.ExtraContainerPackages = packageSets[containerPkgsKey]
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	return img, nil
 }
@@ -394,6 +555,22 @@ func iotInstallerImage(workload workload.Workload,
 	img.ExtraBasePackages = packageSets[installerPkgsKey]
 	img.Users = users.UsersFromBP(customizations.GetUsers())
 	img.Groups = users.GroupsFromBP(customizations.GetGroups())
+
+	networkConnections, err := customizations.GetNetworkConnections()
+	if err != nil {
+		return nil, err
+	}
+	img.Network, err = osbuild.NewKickstartNetworkOptions(networkConnections)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", t.Name(), err.Error())
+	}
+
+	kickstart, kickstartKernelOptions, err := distro.GetInstallerKickstart(customizations)
+	if err != nil {
+		return nil, err
+	}
+	img.Kickstart = kickstart
+	img.KickstartKernelOptionsAppend = kickstartKernelOptions
 	img.AdditionalAnacondaModules = []string{
 		"org.fedoraproject.Anaconda.Modules.Timezone",
 		"org.fedoraproject.Anaconda.Modules.Localization",
@@ -409,7 +586,7 @@ func iotInstallerImage(workload workload.Workload,
 	img.OSVersion = d.osVersion
 	img.Release = fmt.Sprintf("%s %s", d.product, d.osVersion)
 
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	return img, nil
 }
@@ -465,14 +642,16 @@ func iotRawImage(workload workload.Workload,
 	img.OSName = "fedora-iot"
 
 	// TODO: move generation into LiveImage
-	pt, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	pt, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
 	img.PartitionTable = pt
 
-	img.Filename = t.Filename()
-	img.Compression = t.compression
+	img.Filename = t.FilenameFor(options)
+	compression := t.getCompression(options)
+	img.Compression = string(compression.Type)
+	img.CompressionLevel = compression.Level
 
 	return img, nil
 }
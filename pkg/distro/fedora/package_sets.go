@@ -74,6 +74,46 @@ func vmdkCommonPackageSet(t *imageType) rpmmd.PackageSet {
 	}
 }
 
+func localVMCommonPackageSet(t *imageType) rpmmd.PackageSet {
+	return rpmmd.PackageSet{
+		Include: []string{
+			"@core",
+			"chrony",
+			"langpacks-en",
+		},
+		Exclude: []string{
+			"dracut-config-rescue",
+			"geolite2-city",
+			"geolite2-country",
+			"zram-generator-defaults",
+		},
+	}
+}
+
+func vhdxCommonPackageSet(t *imageType) rpmmd.PackageSet {
+	return rpmmd.PackageSet{
+		Include: []string{
+			"hyperv-daemons",
+		},
+	}.Append(localVMCommonPackageSet(t))
+}
+
+func vdiCommonPackageSet(t *imageType) rpmmd.PackageSet {
+	return rpmmd.PackageSet{
+		Include: []string{
+			"virtualbox-guest-additions",
+		},
+	}.Append(localVMCommonPackageSet(t))
+}
+
+func vagrantLibvirtPackageSet(t *imageType) rpmmd.PackageSet {
+	return rpmmd.PackageSet{
+		Include: []string{
+			"qemu-guest-agent",
+		},
+	}.Append(localVMCommonPackageSet(t))
+}
+
 // fedora iot commit OS package set
 func iotCommitPackageSet(t *imageType) rpmmd.PackageSet {
 	return rpmmd.PackageSet{
@@ -3,8 +3,10 @@ package distro
 import (
 	"fmt"
 	"reflect"
+	"sort"
 
 	"github.com/osbuild/images/internal/shell"
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/subscription"
 )
@@ -88,3 +90,75 @@ func (c *ImageConfig) InheritFrom(parentConfig *ImageConfig) *ImageConfig {
 	}
 	return &finalConfig
 }
+
+// ApplyKernelCustomizations returns a new ImageConfig with the sysctl,
+// modprobe blacklist, TuneD and dracut customizations of the blueprint merged
+// on top of the configuration. The customizations are converted to an
+// ImageConfig which inherits all unset values from the configuration. The
+// sysctl, modprobe and dracut configuration files of the blueprint are added
+// to the ones of the configuration, while the TuneD profiles replace them.
+// The module options of the modprobe customization are not part of the
+// ImageConfig (see blueprint.ModprobeOptionsToFsNodeFiles()).
+func (c *ImageConfig) ApplyKernelCustomizations(customizations *blueprint.Customizations) (*ImageConfig, error) {
+	bpConfig := &ImageConfig{}
+
+	sysctl, err := customizations.GetSysctl()
+	if err != nil {
+		return nil, err
+	}
+	if len(sysctl) > 0 {
+		keys := make([]string, 0, len(sysctl))
+		for key := range sysctl {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		config := make([]osbuild.SysctldConfigLine, 0, len(keys))
+		for _, key := range keys {
+			config = append(config, osbuild.SysctldConfigLine{Key: key, Value: sysctl[key]})
+		}
+		bpConfig.Sysctld = append(bpConfig.Sysctld, c.Sysctld...)
+		bpConfig.Sysctld = append(bpConfig.Sysctld, osbuild.NewSysctldStageOptions("99-blueprint.conf", config))
+	}
+
+	modprobe, err := customizations.GetModprobe()
+	if err != nil {
+		return nil, err
+	}
+	if modprobe != nil && len(modprobe.Blacklist) > 0 {
+		commands := make(osbuild.ModprobeConfigCmdList, 0, len(modprobe.Blacklist))
+		for _, module := range modprobe.Blacklist {
+			commands = append(commands, osbuild.NewModprobeConfigCmdBlacklist(module))
+		}
+		bpConfig.Modprobe = append(bpConfig.Modprobe, c.Modprobe...)
+		bpConfig.Modprobe = append(bpConfig.Modprobe, &osbuild.ModprobeStageOptions{
+			Filename: "blueprint-blacklist.conf",
+			Commands: commands,
+		})
+	}
+
+	tuned, err := customizations.GetTuned()
+	if err != nil {
+		return nil, err
+	}
+	if tuned != nil {
+		bpConfig.Tuned = osbuild.NewTunedStageOptions(tuned.Profiles...)
+	}
+
+	dracut, err := customizations.GetDracut()
+	if err != nil {
+		return nil, err
+	}
+	if dracut != nil && (len(dracut.AddModules) > 0 || len(dracut.AddDrivers) > 0) {
+		bpConfig.DracutConf = append(bpConfig.DracutConf, c.DracutConf...)
+		bpConfig.DracutConf = append(bpConfig.DracutConf, &osbuild.DracutConfStageOptions{
+			Filename: "blueprint.conf",
+			Config: osbuild.DracutConfigFile{
+				AddModules: dracut.AddModules,
+				AddDrivers: dracut.AddDrivers,
+			},
+		})
+	}
+
+	return bpConfig.InheritFrom(c), nil
+}
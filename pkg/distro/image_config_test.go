@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/osbuild"
 )
 
@@ -216,3 +217,80 @@ func TestImageConfigInheritFrom(t *testing.T) {
 		})
 	}
 }
+
+func TestImageConfigApplyKernelCustomizations(t *testing.T) {
+	defaultConfig := &ImageConfig{
+		Timezone: common.ToPtr("UTC"),
+		Modprobe: []*osbuild.ModprobeStageOptions{
+			{
+				Filename: "blacklist-floppy.conf",
+				Commands: osbuild.ModprobeConfigCmdList{
+					osbuild.NewModprobeConfigCmdBlacklist("floppy"),
+				},
+			},
+		},
+		Tuned: osbuild.NewTunedStageOptions("sap-hana"),
+		Sysctld: []*osbuild.SysctldStageOptions{
+			osbuild.NewSysctldStageOptions("sap.conf", []osbuild.SysctldConfigLine{{Key: "kernel.pid_max", Value: "4194304"}}),
+		},
+	}
+
+	// without customizations the configuration is unchanged
+	config, err := defaultConfig.ApplyKernelCustomizations(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultConfig, config)
+
+	config, err = defaultConfig.ApplyKernelCustomizations(&blueprint.Customizations{
+		Sysctl: map[string]string{
+			"vm.swappiness":       "10",
+			"net.ipv4.ip_forward": "1",
+		},
+		Modprobe: &blueprint.ModprobeCustomization{
+			Blacklist: []string{"nouveau"},
+			Options:   map[string]string{"kvm_intel": "nested=1"},
+		},
+		Tuned: &blueprint.TunedCustomization{Profiles: []string{"virtual-guest"}},
+		Dracut: &blueprint.DracutCustomization{
+			AddModules: []string{"multipath"},
+			AddDrivers: []string{"nvme"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &ImageConfig{
+		Timezone: common.ToPtr("UTC"),
+		Modprobe: []*osbuild.ModprobeStageOptions{
+			defaultConfig.Modprobe[0],
+			{
+				Filename: "blueprint-blacklist.conf",
+				Commands: osbuild.ModprobeConfigCmdList{
+					osbuild.NewModprobeConfigCmdBlacklist("nouveau"),
+				},
+			},
+		},
+		DracutConf: []*osbuild.DracutConfStageOptions{
+			{
+				Filename: "blueprint.conf",
+				Config: osbuild.DracutConfigFile{
+					AddModules: []string{"multipath"},
+					AddDrivers: []string{"nvme"},
+				},
+			},
+		},
+		Tuned: osbuild.NewTunedStageOptions("virtual-guest"),
+		Sysctld: []*osbuild.SysctldStageOptions{
+			defaultConfig.Sysctld[0],
+			osbuild.NewSysctldStageOptions("99-blueprint.conf", []osbuild.SysctldConfigLine{
+				{Key: "net.ipv4.ip_forward", Value: "1"},
+				{Key: "vm.swappiness", Value: "10"},
+			}),
+		},
+	}, config)
+	// the default configuration is not modified
+	assert.Len(t, defaultConfig.Modprobe, 1)
+	assert.Len(t, defaultConfig.Sysctld, 1)
+
+	_, err = defaultConfig.ApplyKernelCustomizations(&blueprint.Customizations{
+		Sysctl: map[string]string{"vm.swappiness": ""},
+	})
+	assert.EqualError(t, err, `Sysctl value "" of key "vm.swappiness" is invalid`)
+}
@@ -12,8 +12,8 @@ import (
 
 var azureRhuiImgType = imageType{
 	name:        "azure-rhui",
-	filename:    "disk.vhd.xz",
-	mimeType:    "application/xz",
+	filename:    "disk.vhd",
+	mimeType:    "application/x-vhd",
 	compression: "xz",
 	packageSets: map[string]packageSetFunc{
 		osPkgsKey: azureRhuiCommonPackageSet,
@@ -28,7 +28,7 @@ var azureRhuiImgType = imageType{
 	image:               liveImage,
 	buildPipelines:      []string{"build"},
 	payloadPipelines:    []string{"os", "image", "vpc", "xz"},
-	exports:             []string{"xz"},
+	exports:             []string{"vpc"},
 	basePartitionTables: azureRhuiBasePartitionTables,
 }
 
@@ -229,7 +229,9 @@ func liveImage(workload workload.Workload,
 	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], options, containers, customizations)
 	img.Environment = t.environment
 	img.Workload = workload
-	img.Compression = t.compression
+	compression := t.getCompression(options)
+	img.Compression = string(compression.Type)
+	img.CompressionLevel = compression.Level
 	img.PartTool = osbuild.PTSgdisk     // all RHEL 7 images should use sgdisk
 	img.ForceSize = common.ToPtr(false) // RHEL 7 qemu vpc subformat does not support force_size
 	img.NoBLS = true                    // RHEL 7 grub does not support BLS
@@ -244,7 +246,7 @@ func liveImage(workload workload.Workload,
 	}
 	img.PartitionTable = pt
 
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	return img, nil
 }
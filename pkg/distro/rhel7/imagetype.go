@@ -30,7 +30,7 @@ type imageType struct {
 	name               string
 	nameAliases        []string
 	filename           string
-	compression        string // TODO: remove from image definition and make it a transport option
+	compression        string // default compression, see distro.ImageOptions.Compression
 	mimeType           string
 	packageSets        map[string]packageSetFunc
 	packageSetChains   map[string][]string
@@ -57,13 +57,30 @@ func (t *imageType) Arch() distro.Arch {
 }
 
 func (t *imageType) Filename() string {
-	return t.filename
+	return t.FilenameFor(distro.ImageOptions{})
+}
+
+func (t *imageType) FilenameFor(options distro.ImageOptions) string {
+	return t.filename + t.getCompression(options).Type.Extension()
 }
 
 func (t *imageType) MIMEType() string {
+	return t.MIMETypeFor(distro.ImageOptions{})
+}
+
+func (t *imageType) MIMETypeFor(options distro.ImageOptions) string {
+	if mimeType := t.getCompression(options).Type.MIMEType(); mimeType != "" {
+		return mimeType
+	}
 	return t.mimeType
 }
 
+// getCompression returns the compression selected in the image options or
+// the default compression of the image type
+func (t *imageType) getCompression(options distro.ImageOptions) distro.CompressionOptions {
+	return distro.GetCompression(options, t.compression)
+}
+
 func (t *imageType) OSTreeRef() string {
 	// Not supported
 	return ""
@@ -93,12 +110,34 @@ func (t *imageType) PackageSetsChains() map[string][]string {
 }
 
 func (t *imageType) Exports() []string {
+	return t.ExportsFor(distro.ImageOptions{})
+}
+
+func (t *imageType) ExportsFor(options distro.ImageOptions) []string {
+	if compression := t.getCompression(options); compression.Type != "" {
+		// the compression pipeline is named after its type
+		return []string{string(compression.Type)}
+	}
 	if len(t.exports) == 0 {
 		panic(fmt.Sprintf("programming error: no exports for '%s'", t.name))
 	}
 	return t.exports
 }
 
+// supportsCompression returns true if the artifact of the image type can be
+// compressed, i.e. if it is a single disk image file
+func (t *imageType) supportsCompression() bool {
+	if !t.bootable {
+		return false
+	}
+	switch t.platform.GetImageFormat() {
+	case platform.FORMAT_OVA, platform.FORMAT_GCE:
+		// archives of disk images
+		return false
+	}
+	return true
+}
+
 func (t *imageType) BootMode() distro.BootMode {
 	if t.platform.GetUEFIVendor() != "" && t.platform.GetBIOSPlatform() != "" {
 		return distro.BOOT_HYBRID
@@ -125,7 +164,7 @@ func (t *imageType) getPartitionTable(
 
 	imageSize := t.Size(options.Size)
 
-	return disk.NewPartitionTable(&basePartitionTable, mountpoints, imageSize, true, nil, rng)
+	return disk.NewPartitionTable(&basePartitionTable, mountpoints, imageSize, options.PartitioningMode, nil, nil, nil, rng)
 }
 
 func (t *imageType) getDefaultImageConfig() *distro.ImageConfig {
@@ -153,6 +192,12 @@ func (t *imageType) Manifest(bp *blueprint.Blueprint,
 	repos []rpmmd.RepoConfig,
 	seed int64) (*manifest.Manifest, []string, error) {
 
+	partitioningMode, err := distro.GetPartitioningMode(options, bp.Customizations)
+	if err != nil {
+		return nil, nil, err
+	}
+	options.PartitioningMode = partitioningMode
+
 	warnings, err := t.checkOptions(bp, options)
 	if err != nil {
 		return nil, nil, err
@@ -169,6 +214,9 @@ func (t *imageType) Manifest(bp *blueprint.Blueprint,
 	// amend with repository information and collect payload repos
 	payloadRepos := make([]rpmmd.RepoConfig, 0)
 	for _, repo := range repos {
+		if err := repo.CheckPackageDownloads(); err != nil {
+			return nil, nil, err
+		}
 		if len(repo.PackageSets) > 0 {
 			// only apply the repo to the listed package sets
 			for _, psName := range repo.PackageSets {
@@ -184,11 +232,17 @@ func (t *imageType) Manifest(bp *blueprint.Blueprint,
 
 	w := t.workload
 	if w == nil {
+		packagePins, err := bp.GetPackagePins()
+		if err != nil {
+			return nil, nil, err
+		}
 		cw := &workload.Custom{
 			BaseWorkload: workload.BaseWorkload{
 				Repos: payloadRepos,
 			},
-			Packages: bp.GetPackagesEx(false),
+			Packages:        bp.GetPackagesEx(false),
+			ExcludePackages: bp.ExcludePackages,
+			PackagePins:     packagePins,
 		}
 		if services := bp.Customizations.GetServices(); services != nil {
 			cw.Services = services.Enabled
@@ -245,6 +299,16 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, fmt.Errorf("embedding containers is not supported for %s on %s", t.name, t.arch.distro.name)
 	}
 
+	// check if compression options are valid
+	if options.Compression != nil {
+		if !t.supportsCompression() {
+			return warnings, fmt.Errorf("compression is not supported for image type %q", t.name)
+		}
+		if err := options.Compression.Validate(); err != nil {
+			return warnings, err
+		}
+	}
+
 	mountpoints := customizations.GetFilesystems()
 
 	err := blueprint.CheckMountpointsPolicy(mountpoints, pathpolicy.MountpointPolicies)
@@ -252,6 +316,67 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, err
 	}
 
+	err = blueprint.CheckFilesystemCustomizations(mountpoints, []string{"xfs", "ext4", "vfat"})
+	if err != nil {
+		return warnings, err
+	}
+
+	if mode := options.PartitioningMode; mode != blueprint.DefaultPartitioningMode {
+		if mode == blueprint.BtrfsPartitioningMode {
+			return warnings, fmt.Errorf("btrfs partitioning is not supported on %s", t.arch.distro.name)
+		}
+		if t.basePartitionTables == nil {
+			return warnings, fmt.Errorf("partitioning mode %q is not supported for image type %q", mode, t.name)
+		}
+	}
+
+	if customizations != nil && customizations.Disk != nil {
+		return warnings, fmt.Errorf("disk customizations are not supported on %s", t.arch.distro.name)
+	}
+
+	if customizations != nil && customizations.Network != nil {
+		return warnings, fmt.Errorf("network customizations are not supported on %s", t.arch.distro.name)
+	}
+
+	if customizations != nil && len(customizations.Sudoers) > 0 {
+		return warnings, fmt.Errorf("sudoers customizations are not supported on %s", t.arch.distro.name)
+	}
+
+	if customizations != nil && len(customizations.CACerts) > 0 {
+		return warnings, fmt.Errorf("CA certificate customizations are not supported on %s", t.arch.distro.name)
+	}
+
+	if customizations != nil && (len(customizations.Sysctl) > 0 || customizations.Modprobe != nil ||
+		customizations.Tuned != nil || customizations.Dracut != nil) {
+		return warnings, fmt.Errorf("kernel tuning customizations are not supported on %s", t.arch.distro.name)
+	}
+
+	if customizations != nil && customizations.SELinux != nil {
+		return warnings, fmt.Errorf("SELinux customizations are not supported on %s", t.arch.distro.name)
+	}
+
+	if customizations.GetFIPS() {
+		return warnings, fmt.Errorf("FIPS mode is not supported on %s", t.arch.distro.name)
+	}
+
+	if customizations != nil && customizations.Systemd != nil {
+		return warnings, fmt.Errorf("systemd unit customizations are not supported on %s", t.arch.distro.name)
+	}
+
+	if services := customizations.GetServices(); services != nil && len(services.Masked) > 0 {
+		return warnings, fmt.Errorf("masked services are not supported on %s", t.arch.distro.name)
+	}
+
+	for _, module := range bp.Modules {
+		if module.IsModule() {
+			return warnings, fmt.Errorf("module streams are not supported on %s", t.arch.distro.name)
+		}
+	}
+
+	if customizations != nil && customizations.Installer != nil {
+		return warnings, fmt.Errorf("installer customizations are not supported on %s", t.arch.distro.name)
+	}
+
 	if osc := customizations.GetOpenSCAP(); osc != nil {
 		return warnings, fmt.Errorf(fmt.Sprintf("OpenSCAP unsupported os version: %s", t.arch.distro.osVersion))
 	}
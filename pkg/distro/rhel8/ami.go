@@ -39,8 +39,8 @@ func ec2ImgTypeX86_64(rd distribution) imageType {
 
 	it := imageType{
 		name:        "ec2",
-		filename:    "image.raw.xz",
-		mimeType:    "application/xz",
+		filename:    "image.raw",
+		mimeType:    "application/octet-stream",
 		compression: "xz",
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: rhelEc2PackageSet,
@@ -52,7 +52,7 @@ func ec2ImgTypeX86_64(rd distribution) imageType {
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"image"},
 		basePartitionTables: basePartitionTables,
 	}
 	return it
@@ -67,8 +67,8 @@ func ec2HaImgTypeX86_64(rd distribution) imageType {
 
 	it := imageType{
 		name:        "ec2-ha",
-		filename:    "image.raw.xz",
-		mimeType:    "application/xz",
+		filename:    "image.raw",
+		mimeType:    "application/octet-stream",
 		compression: "xz",
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: rhelEc2HaPackageSet,
@@ -80,7 +80,7 @@ func ec2HaImgTypeX86_64(rd distribution) imageType {
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"image"},
 		basePartitionTables: basePartitionTables,
 	}
 	return it
@@ -116,8 +116,8 @@ func ec2ImgTypeAarch64(rd distribution) imageType {
 
 	it := imageType{
 		name:        "ec2",
-		filename:    "image.raw.xz",
-		mimeType:    "application/xz",
+		filename:    "image.raw",
+		mimeType:    "application/octet-stream",
 		compression: "xz",
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: rhelEc2PackageSet,
@@ -129,7 +129,7 @@ func ec2ImgTypeAarch64(rd distribution) imageType {
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"image"},
 		basePartitionTables: basePartitionTables,
 	}
 	return it
@@ -144,8 +144,8 @@ func ec2SapImgTypeX86_64(rd distribution) imageType {
 
 	it := imageType{
 		name:        "ec2-sap",
-		filename:    "image.raw.xz",
-		mimeType:    "application/xz",
+		filename:    "image.raw",
+		mimeType:    "application/octet-stream",
 		compression: "xz",
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: rhelEc2SapPackageSet,
@@ -157,7 +157,7 @@ func ec2SapImgTypeX86_64(rd distribution) imageType {
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"image"},
 		basePartitionTables: basePartitionTables,
 	}
 	return it
@@ -16,8 +16,8 @@ const defaultAzureKernelOptions = "ro crashkernel=auto console=tty1 console=ttyS
 func azureRhuiImgType() imageType {
 	return imageType{
 		name:        "azure-rhui",
-		filename:    "disk.vhd.xz",
-		mimeType:    "application/xz",
+		filename:    "disk.vhd",
+		mimeType:    "application/x-vhd",
 		compression: "xz",
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: azureRhuiPackageSet,
@@ -29,7 +29,7 @@ func azureRhuiImgType() imageType {
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "vpc", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"vpc"},
 		basePartitionTables: azureRhuiBasePartitionTables,
 	}
 }
@@ -37,8 +37,8 @@ func azureRhuiImgType() imageType {
 func azureSapRhuiImgType(rd distribution) imageType {
 	return imageType{
 		name:        "azure-sap-rhui",
-		filename:    "disk.vhd.xz",
-		mimeType:    "application/xz",
+		filename:    "disk.vhd",
+		mimeType:    "application/x-vhd",
 		compression: "xz",
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: azureSapPackageSet,
@@ -50,7 +50,7 @@ func azureSapRhuiImgType(rd distribution) imageType {
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "vpc", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"vpc"},
 		basePartitionTables: azureRhuiBasePartitionTables,
 	}
 }
@@ -100,8 +100,8 @@ func azureEap7RhuiImgType() imageType {
 	return imageType{
 		name:        "azure-eap7-rhui",
 		workload:    eapWorkload(),
-		filename:    "disk.vhd.xz",
-		mimeType:    "application/xz",
+		filename:    "disk.vhd",
+		mimeType:    "application/x-vhd",
 		compression: "xz",
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: azureEapPackageSet,
@@ -113,7 +113,7 @@ func azureEap7RhuiImgType() imageType {
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "vpc", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"vpc"},
 		basePartitionTables: azureRhuiBasePartitionTables,
 	}
 }
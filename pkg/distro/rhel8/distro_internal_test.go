@@ -36,7 +36,7 @@ func TestDistro_UnsupportedArch(t *testing.T) {
 	testBasicImageType.arch = &architecture{
 		name: "unsupported_arch",
 	}
-	_, err := testBasicImageType.getPartitionTable(mountpoints, distro.ImageOptions{}, rng)
+	_, err := testBasicImageType.getPartitionTable(&blueprint.Customizations{Filesystem: mountpoints}, distro.ImageOptions{}, rng)
 	require.EqualError(t, err, fmt.Sprintf("no partition table defined for architecture %q for image type %q", testBasicImageType.arch.name, testBasicImageType.name))
 }
 
@@ -46,7 +46,7 @@ func TestDistro_DefaultPartitionTables(t *testing.T) {
 		testBasicImageType.arch = &architecture{
 			name: archName,
 		}
-		pt, err := testBasicImageType.getPartitionTable(mountpoints, distro.ImageOptions{}, rng)
+		pt, err := testBasicImageType.getPartitionTable(&blueprint.Customizations{Filesystem: mountpoints}, distro.ImageOptions{}, rng)
 		require.Nil(t, err)
 		for _, m := range mountpoints {
 			assert.True(t, pt.ContainsMountpoint(m.Mountpoint))
@@ -60,7 +60,7 @@ func TestDistro_Ec2PartitionTables(t *testing.T) {
 		testEc2ImageType.arch = &architecture{
 			name: archName,
 		}
-		pt, err := testEc2ImageType.getPartitionTable(mountpoints, distro.ImageOptions{}, rng)
+		pt, err := testEc2ImageType.getPartitionTable(&blueprint.Customizations{Filesystem: mountpoints}, distro.ImageOptions{}, rng)
 		if _, exists := testEc2ImageType.basePartitionTables[archName]; exists {
 			require.Nil(t, err)
 			for _, m := range mountpoints {
@@ -61,9 +61,9 @@ func edgeRawImgType() imageType {
 	it := imageType{
 		name:                "edge-raw-image",
 		nameAliases:         []string{"rhel-edge-raw-image"},
-		filename:            "image.raw.xz",
+		filename:            "image.raw",
 		compression:         "xz",
-		mimeType:            "application/xz",
+		mimeType:            "application/octet-stream",
 		packageSets:         nil,
 		defaultSize:         10 * common.GibiByte,
 		rpmOstree:           true,
@@ -72,7 +72,7 @@ func edgeRawImgType() imageType {
 		image:               edgeRawImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"ostree-deployment", "image", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"image"},
 		basePartitionTables: edgeBasePartitionTables,
 	}
 	return it
@@ -142,9 +142,9 @@ func edgeSimplifiedInstallerImgType(rd distribution) imageType {
 func minimalRawImgType(rd distribution) imageType {
 	it := imageType{
 		name:        "minimal-raw",
-		filename:    "raw.img.xz",
+		filename:    "raw.img",
 		compression: "xz",
-		mimeType:    "application/xz",
+		mimeType:    "application/octet-stream",
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: minimalrpmPackageSet,
 		},
@@ -155,7 +155,7 @@ func minimalRawImgType(rd distribution) imageType {
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"image"},
 		basePartitionTables: defaultBasePartitionTables,
 	}
 	return it
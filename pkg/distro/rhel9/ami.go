@@ -30,8 +30,8 @@ var (
 
 	ec2ImgTypeX86_64 = imageType{
 		name:        "ec2",
-		filename:    "image.raw.xz",
-		mimeType:    "application/xz",
+		filename:    "image.raw",
+		mimeType:    "application/octet-stream",
 		compression: "xz",
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: rhelEc2PackageSet,
@@ -42,14 +42,14 @@ var (
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"image"},
 		basePartitionTables: defaultBasePartitionTables,
 	}
 
 	ec2HaImgTypeX86_64 = imageType{
 		name:        "ec2-ha",
-		filename:    "image.raw.xz",
-		mimeType:    "application/xz",
+		filename:    "image.raw",
+		mimeType:    "application/octet-stream",
 		compression: "xz",
 		packageSets: map[string]packageSetFunc{
 			buildPkgsKey: ec2BuildPackageSet,
@@ -61,7 +61,7 @@ var (
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"image"},
 		basePartitionTables: defaultBasePartitionTables,
 	}
 
@@ -85,8 +85,8 @@ var (
 
 	ec2ImgTypeAarch64 = imageType{
 		name:        "ec2",
-		filename:    "image.raw.xz",
-		mimeType:    "application/xz",
+		filename:    "image.raw",
+		mimeType:    "application/octet-stream",
 		compression: "xz",
 		packageSets: map[string]packageSetFunc{
 			buildPkgsKey: ec2BuildPackageSet,
@@ -98,14 +98,14 @@ var (
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"image"},
 		basePartitionTables: defaultBasePartitionTables,
 	}
 
 	ec2SapImgTypeX86_64 = imageType{
 		name:        "ec2-sap",
-		filename:    "image.raw.xz",
-		mimeType:    "application/xz",
+		filename:    "image.raw",
+		mimeType:    "application/octet-stream",
 		compression: "xz",
 		packageSets: map[string]packageSetFunc{
 			buildPkgsKey: ec2BuildPackageSet,
@@ -117,7 +117,7 @@ var (
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"image"},
 		basePartitionTables: defaultBasePartitionTables,
 	}
 )
@@ -52,8 +52,8 @@ var (
 	// Azure RHUI image type
 	azureRhuiImgType = imageType{
 		name:        "azure-rhui",
-		filename:    "disk.vhd.xz",
-		mimeType:    "application/xz",
+		filename:    "disk.vhd",
+		mimeType:    "application/x-vhd",
 		compression: "xz",
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: azureRhuiPackageSet,
@@ -65,7 +65,7 @@ var (
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "vpc", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"vpc"},
 		basePartitionTables: azureRhuiBasePartitionTables,
 	}
 )
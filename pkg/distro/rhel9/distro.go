@@ -209,6 +209,8 @@ func newDistro(name string, minor int) *distribution {
 	ociImgType := qcow2ImgType
 	ociImgType.name = "oci"
 
+	containerDiskImgType := mkContainerDiskImgType(rd)
+
 	x86_64.addImageTypes(
 		&platform.X86{
 			BIOS:       true,
@@ -222,6 +224,18 @@ func newDistro(name string, minor int) *distribution {
 		ociImgType,
 	)
 
+	x86_64.addImageTypes(
+		&platform.X86{
+			BIOS:       true,
+			UEFIVendor: rd.vendor,
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_CONTAINERDISK,
+				QCOW2Compat: "1.1",
+			},
+		},
+		containerDiskImgType,
+	)
+
 	x86_64.addImageTypes(
 		&platform.X86{
 			BIOS:       true,
@@ -270,6 +284,51 @@ func newDistro(name string, minor int) *distribution {
 		ovaImgType,
 	)
 
+	x86_64.addImageTypes(
+		&platform.X86{
+			BIOS:       true,
+			UEFIVendor: rd.vendor,
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_VHDX,
+			},
+		},
+		vhdxImgType,
+	)
+
+	x86_64.addImageTypes(
+		&platform.X86{
+			BIOS:       true,
+			UEFIVendor: rd.vendor,
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_VDI,
+			},
+		},
+		vdiImgType,
+	)
+
+	x86_64.addImageTypes(
+		&platform.X86{
+			BIOS:       true,
+			UEFIVendor: rd.vendor,
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_VAGRANT_LIBVIRT,
+				QCOW2Compat: "1.1",
+			},
+		},
+		vagrantLibvirtImgType,
+	)
+
+	x86_64.addImageTypes(
+		&platform.X86{
+			BIOS:       true,
+			UEFIVendor: rd.vendor,
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_VAGRANT_VIRTUALBOX,
+			},
+		},
+		vagrantVirtualBoxImgType,
+	)
+
 	ec2X86Platform := &platform.X86{
 		BIOS:       true,
 		UEFIVendor: rd.vendor,
@@ -410,6 +469,16 @@ func newDistro(name string, minor int) *distribution {
 		},
 		qcow2ImgType,
 	)
+	aarch64.addImageTypes(
+		&platform.Aarch64{
+			UEFIVendor: rd.vendor,
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_CONTAINERDISK,
+				QCOW2Compat: "1.1",
+			},
+		},
+		containerDiskImgType,
+	)
 	aarch64.addImageTypes(
 		&platform.Aarch64{
 			UEFIVendor: rd.vendor,
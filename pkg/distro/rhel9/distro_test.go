@@ -8,11 +8,14 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distro/distro_test_common"
 	"github.com/osbuild/images/pkg/distro/rhel9"
+	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/platform"
+	"github.com/osbuild/images/pkg/rpmmd"
 )
 
 type rhelFamilyDistro struct {
@@ -121,6 +124,46 @@ func TestFilenameFromType(t *testing.T) {
 				mimeType: "application/ovf",
 			},
 		},
+		{
+			name: "vhdx",
+			args: args{"vhdx"},
+			want: wantResult{
+				filename: "disk.vhdx",
+				mimeType: "application/x-vhdx",
+			},
+		},
+		{
+			name: "vdi",
+			args: args{"vdi"},
+			want: wantResult{
+				filename: "disk.vdi",
+				mimeType: "application/x-virtualbox-vdi",
+			},
+		},
+		{
+			name: "vagrant-libvirt",
+			args: args{"vagrant-libvirt"},
+			want: wantResult{
+				filename: "vagrant-libvirt.box",
+				mimeType: "application/x-tar",
+			},
+		},
+		{
+			name: "vagrant-virtualbox",
+			args: args{"vagrant-virtualbox"},
+			want: wantResult{
+				filename: "vagrant-virtualbox.box",
+				mimeType: "application/x-tar",
+			},
+		},
+		{
+			name: "containerdisk",
+			args: args{"containerdisk"},
+			want: wantResult{
+				filename: "container.tar",
+				mimeType: "application/x-tar",
+			},
+		},
 		{
 			name: "tar",
 			args: args{"tar"},
@@ -316,10 +359,15 @@ func TestImageType_Name(t *testing.T) {
 			imgNames: []string{
 				"qcow2",
 				"openstack",
+				"containerdisk",
 				"vhd",
 				"azure-rhui",
 				"vmdk",
 				"ova",
+				"vhdx",
+				"vdi",
+				"vagrant-libvirt",
+				"vagrant-virtualbox",
 				"ami",
 				"ec2",
 				"ec2-ha",
@@ -338,6 +386,7 @@ func TestImageType_Name(t *testing.T) {
 			imgNames: []string{
 				"qcow2",
 				"openstack",
+				"containerdisk",
 				"ami",
 				"ec2",
 				"edge-commit",
@@ -509,10 +558,15 @@ func TestArchitecture_ListImageTypes(t *testing.T) {
 			imgNames: []string{
 				"qcow2",
 				"openstack",
+				"containerdisk",
 				"vhd",
 				"azure-rhui",
 				"vmdk",
 				"ova",
+				"vhdx",
+				"vdi",
+				"vagrant-libvirt",
+				"vagrant-virtualbox",
 				"ami",
 				"ec2",
 				"ec2-ha",
@@ -538,6 +592,7 @@ func TestArchitecture_ListImageTypes(t *testing.T) {
 			imgNames: []string{
 				"qcow2",
 				"openstack",
+				"containerdisk",
 				"ami",
 				"ec2",
 				"edge-commit",
@@ -870,3 +925,450 @@ func TestDistro_CustomUsrPartitionNotLargeEnough(t *testing.T) {
 		}
 	}
 }
+
+func TestDistro_DiskEncryption(t *testing.T) {
+	r9distro := rhel9.New()
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Disk: &blueprint.DiskCustomization{
+				Encryption: &blueprint.EncryptionCustomization{
+					Passphrase: "secret",
+				},
+			},
+		},
+	}
+	for _, archName := range r9distro.ListArches() {
+		arch, _ := r9distro.GetArch(archName)
+		for _, imgTypeName := range arch.ListImageTypes() {
+			imgType, _ := arch.GetImageType(imgTypeName)
+			_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+			if imgTypeName == "qcow2" || imgTypeName == "ami" || imgTypeName == "minimal-raw" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err, "image type %q should not support disk encryption", imgTypeName)
+			}
+		}
+	}
+}
+
+func TestDistro_PartitioningModes(t *testing.T) {
+	r9distro := rhel9.New()
+	arch, _ := r9distro.GetArch("x86_64")
+	bp := blueprint.Blueprint{}
+
+	qcow2, _ := arch.GetImageType("qcow2")
+	for _, mode := range []blueprint.PartitioningMode{blueprint.RawPartitioningMode, blueprint.LVMPartitioningMode, blueprint.AutoLVMPartitioningMode} {
+		_, _, err := qcow2.Manifest(&bp, distro.ImageOptions{PartitioningMode: mode}, nil, 0)
+		assert.NoError(t, err)
+	}
+
+	_, _, err := qcow2.Manifest(&bp, distro.ImageOptions{PartitioningMode: blueprint.BtrfsPartitioningMode}, nil, 0)
+	assert.EqualError(t, err, "btrfs partitioning is not supported on rhel-9")
+
+	tar, _ := arch.GetImageType("tar")
+	_, _, err = tar.Manifest(&bp, distro.ImageOptions{PartitioningMode: blueprint.LVMPartitioningMode}, nil, 0)
+	assert.EqualError(t, err, `partitioning mode "lvm" is not supported for image type "tar"`)
+}
+
+func TestDistro_NetworkConnections(t *testing.T) {
+	r9distro := rhel9.New()
+	arch, _ := r9distro.GetArch("x86_64")
+	bond := blueprint.NetworkConnectionCustomization{
+		ID:            "bond0",
+		Type:          "bond",
+		InterfaceName: "bond0",
+	}
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Network: &blueprint.NetworkCustomization{
+				Connections: []blueprint.NetworkConnectionCustomization{
+					bond,
+					{ID: "eth0", InterfaceName: "eth0", Controller: "bond0"},
+				},
+			},
+		},
+	}
+
+	for _, imgTypeName := range []string{"qcow2", "image-installer"} {
+		imgType, _ := arch.GetImageType(imgTypeName)
+		_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		assert.NoError(t, err)
+	}
+
+	// the kickstart file of an ostree installer can only configure plain
+	// ethernet devices
+	edgeInstaller, _ := arch.GetImageType("edge-installer")
+	ostreeOptions := distro.ImageOptions{OSTree: &ostree.ImageOptions{URL: "https://example.com/repo"}}
+	_, _, err := edgeInstaller.Manifest(&bp, ostreeOptions, nil, 0)
+	assert.EqualError(t, err, `edge-installer: network connection "bond0" cannot be configured by a kickstart file: only standalone ethernet connections are supported`)
+
+	bp.Customizations.Network.Connections = []blueprint.NetworkConnectionCustomization{{ID: "eth0", InterfaceName: "eth0"}}
+	_, _, err = edgeInstaller.Manifest(&bp, ostreeOptions, nil, 0)
+	assert.NoError(t, err)
+
+	bp.Customizations.Network.Connections = []blueprint.NetworkConnectionCustomization{{ID: "eth0", Controller: "bond0"}}
+	qcow2, _ := arch.GetImageType("qcow2")
+	_, _, err = qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `Network connection "eth0": controller "bond0" does not exist`)
+}
+
+func TestDistro_Sudoers(t *testing.T) {
+	r9distro := rhel9.New()
+	arch, _ := r9distro.GetArch("x86_64")
+	qcow2, _ := arch.GetImageType("qcow2")
+
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			User: []blueprint.UserCustomization{{Name: "deploy"}},
+			Sudoers: []blueprint.SudoersCustomization{
+				{
+					Name: "deploy",
+					Rules: []blueprint.SudoRuleCustomization{
+						{User: "deploy", NoPasswd: true, Commands: []string{"/usr/bin/systemctl"}},
+					},
+				},
+			},
+		},
+	}
+	_, _, err := qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.NoError(t, err)
+
+	bp.Customizations.Sudoers[0].Rules[0].User = "depoly"
+	_, _, err = qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `Sudoers file "deploy": user "depoly" is not defined in the blueprint`)
+}
+
+func TestDistro_CACerts(t *testing.T) {
+	r9distro := rhel9.New()
+	arch, _ := r9distro.GetArch("x86_64")
+	qcow2, _ := arch.GetImageType("qcow2")
+
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			CACerts: []string{"-----BEGIN CERTIFICATE-----\nMAA=\n-----END CERTIFICATE-----\n"},
+		},
+	}
+	_, _, err := qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.ErrorContains(t, err, "CA certificate bundle 0 is invalid: ")
+}
+
+func TestDistro_KernelTuning(t *testing.T) {
+	r9distro := rhel9.New()
+	arch, _ := r9distro.GetArch("x86_64")
+
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Sysctl:   map[string]string{"vm.swappiness": "10"},
+			Modprobe: &blueprint.ModprobeCustomization{Blacklist: []string{"pcspkr"}, Options: map[string]string{"kvm_intel": "nested=1"}},
+			Tuned:    &blueprint.TunedCustomization{Profiles: []string{"virtual-guest"}},
+			Dracut:   &blueprint.DracutCustomization{AddDrivers: []string{"nvme"}},
+		},
+	}
+
+	for _, imgTypeName := range []string{"qcow2", "vhd", "ec2-sap"} {
+		imgType, _ := arch.GetImageType(imgTypeName)
+		_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		assert.NoError(t, err, imgTypeName)
+	}
+
+	bp.Customizations.Tuned.Profiles = nil
+	qcow2, _ := arch.GetImageType("qcow2")
+	_, _, err := qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, "Tuned customization requires at least one profile")
+}
+
+func TestDistro_SELinux(t *testing.T) {
+	r9distro := rhel9.New()
+	arch, _ := r9distro.GetArch("x86_64")
+
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			SELinux: &blueprint.SELinuxCustomization{
+				Mode:     "permissive",
+				Policy:   "targeted",
+				Booleans: map[string]bool{"httpd_can_network_connect": true},
+				FileContexts: []blueprint.SELinuxFileContextCustomization{
+					{Path: "/srv/www(/.*)?", Type: "httpd_sys_content_t"},
+				},
+			},
+		},
+	}
+
+	for _, imgTypeName := range []string{"qcow2", "ec2-sap"} {
+		imgType, _ := arch.GetImageType(imgTypeName)
+		_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		assert.NoError(t, err, imgTypeName)
+	}
+
+	// the tree of WSL images is not labelled
+	wsl, _ := arch.GetImageType("wsl")
+	_, _, err := wsl.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `SELinux customizations are not supported for image type "wsl"`)
+
+	bp.Customizations.SELinux.Mode = "enabled"
+	qcow2, _ := arch.GetImageType("qcow2")
+	_, _, err = qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `SELinux mode "enabled" is not supported (must be one of enforcing, permissive, disabled)`)
+}
+
+func TestDistro_FIPS(t *testing.T) {
+	r9distro := rhel9.New()
+	arch, _ := r9distro.GetArch("x86_64")
+
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			FIPS: common.ToPtr(true),
+		},
+	}
+
+	for _, imgTypeName := range []string{"qcow2", "image-installer", "edge-commit"} {
+		imgType, _ := arch.GetImageType(imgTypeName)
+		_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		assert.NoError(t, err, imgTypeName)
+	}
+
+	ostreeOptions := distro.ImageOptions{OSTree: &ostree.ImageOptions{URL: "https://example.com/repo"}}
+	for _, imgTypeName := range []string{"edge-installer", "edge-raw-image"} {
+		imgType, _ := arch.GetImageType(imgTypeName)
+		_, _, err := imgType.Manifest(&bp, ostreeOptions, nil, 0)
+		assert.NoError(t, err, imgTypeName)
+	}
+
+	bp.Customizations.InstallationDevice = "/dev/vda"
+	simplifiedInstaller, _ := arch.GetImageType("edge-simplified-installer")
+	_, _, err := simplifiedInstaller.Manifest(&bp, ostreeOptions, nil, 0)
+	assert.NoError(t, err)
+}
+
+func TestDistro_SystemdUnits(t *testing.T) {
+	r9distro := rhel9.New()
+	arch, _ := r9distro.GetArch("x86_64")
+	qcow2, _ := arch.GetImageType("qcow2")
+
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Services: &blueprint.ServicesCustomization{
+				Masked: []string{"kdump.service"},
+			},
+			Systemd: &blueprint.SystemdCustomization{
+				Units: []blueprint.SystemdUnitCustomization{
+					{
+						Name:    "agent.service",
+						Enabled: true,
+						Service: &blueprint.SystemdServiceSection{ExecStart: []string{"/usr/bin/agent"}},
+						Install: &blueprint.SystemdInstallSection{WantedBy: []string{"multi-user.target"}},
+					},
+				},
+			},
+		},
+	}
+	_, _, err := qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.NoError(t, err)
+
+	// the directory of a drop-in is created before the drop-in is copied
+	bp.Customizations.Systemd.Units = append(bp.Customizations.Systemd.Units, blueprint.SystemdUnitCustomization{
+		Name:    "getty@.service",
+		Dropin:  "autologin.conf",
+		Service: &blueprint.SystemdServiceSection{ExecStart: []string{"", "/sbin/agetty --autologin root %I"}},
+	})
+	m, _, err := qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	require.NoError(t, err)
+	packages := []rpmmd.PackageSpec{
+		{Name: "kernel", Checksum: "sha256:a0c936696eb7d5ee3192bf53b9d281cecbb40ca9db520de72cb95817ad92ac72"},
+		{Name: "selinux-policy-targeted", Checksum: "sha256:4a6b2f6e13b2e0e2e5c29d3a6b4d7b9f1d8bb8dc6c3f1a36fd5a9d2c6aa0f1b7"},
+	}
+	packageSets := make(map[string][]rpmmd.PackageSpec)
+	for _, name := range append(qcow2.BuildPipelines(), qcow2.PayloadPipelines()...) {
+		packageSets[name] = packages
+	}
+	mf, err := m.Serialize(packageSets, nil, nil)
+	require.NoError(t, err)
+	mkdir := strings.Index(string(mf), `"path":"/etc/systemd/system/getty@.service.d"`)
+	dropin := strings.Index(string(mf), `"to":"tree:///etc/systemd/system/getty@.service.d/autologin.conf"`)
+	require.NotEqual(t, -1, mkdir)
+	require.NotEqual(t, -1, dropin)
+	assert.Less(t, mkdir, dropin)
+
+	bp.Customizations.Systemd.Units = bp.Customizations.Systemd.Units[:1]
+	bp.Customizations.Systemd.Units[0].Install = nil
+	_, _, err = qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `Systemd unit "agent.service" cannot be enabled without WantedBy or RequiredBy in the [Install] section`)
+}
+
+func TestDistro_Swap(t *testing.T) {
+	r9distro := rhel9.New()
+	arch, _ := r9distro.GetArch("x86_64")
+
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Disk: &blueprint.DiskCustomization{
+				Swap: &blueprint.SwapCustomization{Size: 2 * common.GibiByte, Type: blueprint.SwapLogicalVolume, Resume: true},
+			},
+		},
+	}
+
+	for _, imgTypeName := range []string{"qcow2", "vmdk", "ami"} {
+		imgType, _ := arch.GetImageType(imgTypeName)
+		_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		assert.NoError(t, err, imgTypeName)
+	}
+
+	bp.Customizations.Disk.Swap = &blueprint.SwapCustomization{Size: 2 * common.GibiByte, Type: blueprint.SwapFile}
+	for _, imgTypeName := range []string{"qcow2", "tar"} {
+		imgType, _ := arch.GetImageType(imgTypeName)
+		_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		assert.NoError(t, err, imgTypeName)
+	}
+
+	// the installer doesn't create any swap space
+	installer, _ := arch.GetImageType("image-installer")
+	for _, swap := range []*blueprint.SwapCustomization{
+		{Size: 2 * common.GibiByte},
+		{Size: 2 * common.GibiByte, Type: blueprint.SwapFile},
+	} {
+		bp.Customizations.Disk.Swap = swap
+		_, _, err := installer.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		assert.EqualError(t, err, `swap customizations are not supported for installer image type "image-installer"`)
+	}
+
+	bp.Customizations.Disk.Swap = &blueprint.SwapCustomization{Size: 2 * common.GibiByte, Type: blueprint.SwapLogicalVolume}
+	qcow2, _ := arch.GetImageType("qcow2")
+	_, _, err := qcow2.Manifest(&bp, distro.ImageOptions{PartitioningMode: blueprint.RawPartitioningMode}, nil, 0)
+	assert.EqualError(t, err, `swap logical volumes are not supported in partitioning mode "raw"`)
+
+	bp.Customizations.Disk.Swap = &blueprint.SwapCustomization{Size: 2 * common.GibiByte, Type: blueprint.SwapFile, Resume: true}
+	_, _, err = qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, "Resume from hibernation is not supported for swap files")
+}
+
+func TestDistro_Installer(t *testing.T) {
+	r9distro := rhel9.New()
+	arch, _ := r9distro.GetArch("x86_64")
+
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Installer: &blueprint.InstallerCustomization{
+				Unattended:  true,
+				TargetDisks: []string{"vda"},
+				Bootloader:  &blueprint.InstallerBootloaderCustomization{Append: "console=ttyS0", Timeout: common.ToPtr(1)},
+				PostScripts: []blueprint.InstallerPostScriptCustomization{{Script: "touch /etc/installed"}},
+			},
+			Timezone: &blueprint.TimezoneCustomization{Timezone: common.ToPtr("Europe/Prague")},
+		},
+	}
+
+	imageInstaller, _ := arch.GetImageType("image-installer")
+	_, _, err := imageInstaller.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.NoError(t, err)
+
+	edgeInstaller, _ := arch.GetImageType("edge-installer")
+	_, _, err = edgeInstaller.Manifest(&bp, distro.ImageOptions{OSTree: &ostree.ImageOptions{URL: "https://example.com/repo"}}, nil, 0)
+	assert.NoError(t, err)
+
+	qcow2, _ := arch.GetImageType("qcow2")
+	_, _, err = qcow2.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `installer customizations are not supported for image type "qcow2"`)
+
+	// the edge installer configures the timezone only in the kickstart file
+	bp.Customizations.Installer = nil
+	_, _, err = edgeInstaller.Manifest(&bp, distro.ImageOptions{OSTree: &ostree.ImageOptions{URL: "https://example.com/repo"}}, nil, 0)
+	assert.EqualError(t, err, `locale and timezone customizations require installer customizations for boot ISO image type "edge-installer"`)
+
+	bp.Customizations.Installer = &blueprint.InstallerCustomization{ClearPart: "everything"}
+	_, _, err = imageInstaller.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+	assert.EqualError(t, err, `Installer clearpart policy "everything" is not supported (must be one of all, linux, none)`)
+}
+
+func TestDistro_Compression(t *testing.T) {
+	r9distro := rhel9.New()
+	arch, _ := r9distro.GetArch("x86_64")
+	bp := blueprint.Blueprint{}
+
+	ec2, _ := arch.GetImageType("ec2")
+	assert.Equal(t, "image.raw.xz", ec2.Filename())
+	assert.Equal(t, "application/xz", ec2.MIMEType())
+	assert.Equal(t, []string{"xz"}, ec2.Exports())
+	assert.Equal(t, "image.raw.xz", distro.FilenameFor(ec2, distro.ImageOptions{}))
+	assert.Equal(t, []string{"xz"}, distro.ExportsFor(ec2, distro.ImageOptions{}))
+
+	options := distro.ImageOptions{
+		Compression: &distro.CompressionOptions{Type: distro.CompressionZstd},
+	}
+	assert.Equal(t, "image.raw.zst", distro.FilenameFor(ec2, options))
+	assert.Equal(t, "application/zstd", distro.MIMETypeFor(ec2, options))
+	assert.Equal(t, []string{"zstd"}, distro.ExportsFor(ec2, options))
+	m, _, err := ec2.Manifest(&bp, options, nil, 0)
+	assert.NoError(t, err)
+	assert.Contains(t, m.GetExports(), "zstd")
+
+	options.Compression = &distro.CompressionOptions{Type: distro.CompressionNone}
+	assert.Equal(t, "image.raw", distro.FilenameFor(ec2, options))
+	assert.Equal(t, "application/octet-stream", distro.MIMETypeFor(ec2, options))
+	assert.Equal(t, []string{"image"}, distro.ExportsFor(ec2, options))
+	_, _, err = ec2.Manifest(&bp, options, nil, 0)
+	assert.NoError(t, err)
+
+	qcow2, _ := arch.GetImageType("qcow2")
+	options.Compression = &distro.CompressionOptions{Type: distro.CompressionGzip, Level: common.ToPtr(uint(6))}
+	assert.Equal(t, "disk.qcow2.gz", distro.FilenameFor(qcow2, options))
+	assert.Equal(t, "application/gzip", distro.MIMETypeFor(qcow2, options))
+	_, _, err = qcow2.Manifest(&bp, options, nil, 0)
+	assert.NoError(t, err)
+
+	options.Compression = &distro.CompressionOptions{Type: distro.CompressionZstd, Level: common.ToPtr(uint(10))}
+	_, _, err = qcow2.Manifest(&bp, options, nil, 0)
+	assert.EqualError(t, err, `compression level is not supported by compression type "zstd"`)
+
+	installer, _ := arch.GetImageType("image-installer")
+	options.Compression = &distro.CompressionOptions{Type: distro.CompressionXZ}
+	_, _, err = installer.Manifest(&bp, options, nil, 0)
+	assert.EqualError(t, err, `compression is not supported for image type "image-installer"`)
+}
+
+func TestDistro_Vagrant(t *testing.T) {
+	r9distro := rhel9.New()
+	arch, _ := r9distro.GetArch("x86_64")
+
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			User: []blueprint.UserCustomization{{Name: "vagrant", Groups: []string{"wheel", "libvirt"}}},
+		},
+	}
+
+	for _, imgTypeName := range []string{"vagrant-libvirt", "vagrant-virtualbox"} {
+		imgType, _ := arch.GetImageType(imgTypeName)
+		assert.Equal(t, []string{"archive"}, imgType.Exports())
+		m, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		assert.NoError(t, err, imgTypeName)
+		assert.Equal(t, []string{"archive"}, m.GetExports(), imgTypeName)
+
+		var packages []string
+		for _, pkgSet := range m.GetPackageSetChains()["os"] {
+			packages = append(packages, pkgSet.Include...)
+		}
+		assert.Subset(t, packages, []string{"openssh-server", "rsync", "sudo"}, imgTypeName)
+
+		_, _, err = imgType.Manifest(&bp, distro.ImageOptions{Compression: &distro.CompressionOptions{Type: distro.CompressionXZ}}, nil, 0)
+		assert.EqualError(t, err, fmt.Sprintf("compression is not supported for image type %q", imgTypeName))
+	}
+}
+
+func TestDistro_ContainerDisk(t *testing.T) {
+	r9distro := rhel9.New()
+	bp := blueprint.Blueprint{}
+
+	for _, archName := range []string{"x86_64", "aarch64"} {
+		arch, _ := r9distro.GetArch(archName)
+		containerDisk, err := arch.GetImageType("containerdisk")
+		require.NoError(t, err, archName)
+		m, _, err := containerDisk.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		assert.NoError(t, err, archName)
+		assert.Equal(t, []string{"container"}, m.GetExports(), archName)
+
+		var packages []string
+		for _, pkgSet := range m.GetPackageSetChains()["os"] {
+			packages = append(packages, pkgSet.Include...)
+		}
+		assert.Subset(t, packages, []string{"cloud-init", "qemu-guest-agent"}, archName)
+	}
+}
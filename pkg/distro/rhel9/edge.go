@@ -58,9 +58,9 @@ var (
 	edgeRawImgType = imageType{
 		name:        "edge-raw-image",
 		nameAliases: []string{"rhel-edge-raw-image"},
-		filename:    "image.raw.xz",
+		filename:    "image.raw",
 		compression: "xz",
-		mimeType:    "application/xz",
+		mimeType:    "application/octet-stream",
 		packageSets: nil,
 		defaultImageConfig: &distro.ImageConfig{
 			Locale: common.ToPtr("en_US.UTF-8"),
@@ -72,7 +72,7 @@ var (
 		image:               edgeRawImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"ostree-deployment", "image", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"image"},
 		basePartitionTables: edgeBasePartitionTables,
 	}
 
@@ -174,9 +174,9 @@ var (
 
 	minimalrawImgType = imageType{
 		name:        "minimal-raw",
-		filename:    "raw.img.xz",
+		filename:    "raw.img",
 		compression: "xz",
-		mimeType:    "application/xz",
+		mimeType:    "application/octet-stream",
 		packageSets: map[string]packageSetFunc{
 			osPkgsKey: minimalrpmPackageSet,
 		},
@@ -187,7 +187,7 @@ var (
 		image:               liveImage,
 		buildPipelines:      []string{"build"},
 		payloadPipelines:    []string{"os", "image", "xz"},
-		exports:             []string{"xz"},
+		exports:             []string{"image"},
 		basePartitionTables: defaultBasePartitionTables,
 	}
 
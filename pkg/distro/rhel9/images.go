@@ -28,7 +28,13 @@ func osCustomizations(
 	c *blueprint.Customizations,
 ) manifest.OSCustomizations {
 
-	imageConfig := t.getDefaultImageConfig()
+	// merge the kernel tuning customizations on top of the image type defaults
+	imageConfig, err := t.getDefaultImageConfig().ApplyKernelCustomizations(c)
+	if err != nil {
+		// This shouldn't happen since the customizations
+		// should have already been validated
+		panic(fmt.Sprintf("failed to apply kernel tuning customizations: %v", err))
+	}
 
 	osc := manifest.OSCustomizations{}
 
@@ -49,6 +55,11 @@ func osCustomizations(
 	osc.ExcludeBasePackages = osPackageSet.Exclude
 	osc.ExtraBaseRepos = osPackageSet.Repositories
 
+	// clevis needs to be part of the initrd to unlock the root filesystem
+	if encryption, _ := c.GetDiskEncryption(); encryption != nil && encryption.Clevis != nil {
+		osc.ExtraBasePackages = append(osc.ExtraBasePackages, "clevis-dracut")
+	}
+
 	osc.Containers = containers
 
 	osc.GPGKeyFiles = imageConfig.GPGKeyFiles
@@ -125,9 +136,19 @@ func osCustomizations(
 		osc.LeapSecTZ = imageConfig.TimeSynchronization.LeapsecTz
 	}
 
+	selinux, err := c.GetSELinux()
+	if err != nil {
+		// This shouldn't happen since the SELinux customization
+		// should have already been validated
+		panic(fmt.Sprintf("failed to get SELinux customization: %v", err))
+	}
+
 	// Relabel the tree, unless the `NoSElinux` flag is explicitly set to `true`
 	if imageConfig.NoSElinux == nil || imageConfig.NoSElinux != nil && !*imageConfig.NoSElinux {
 		osc.SElinux = "targeted"
+		if selinux != nil && selinux.Policy != "" {
+			osc.SElinux = selinux.Policy
+		}
 	}
 
 	if oscapConfig := c.GetOpenSCAP(); oscapConfig != nil {
@@ -150,7 +171,6 @@ func osCustomizations(
 		osc.FactAPIType = &options.Facts.APIType
 	}
 
-	var err error
 	osc.Directories, err = blueprint.DirectoryCustomizationsToFsNodeDirectories(c.GetDirectories())
 	if err != nil {
 		// In theory this should never happen, because the blueprint directory customizations
@@ -194,6 +214,120 @@ func osCustomizations(
 		osc.YUMRepos = append(osc.YUMRepos, osbuild.NewYumReposStageOptions(filename, repos))
 	}
 
+	networkConnections, err := c.GetNetworkConnections()
+	if err != nil {
+		// This shouldn't happen since the network connections
+		// should have already been validated
+		panic(fmt.Sprintf("failed to get network connections: %v", err))
+	}
+
+	// render the network connections as NetworkManager keyfiles
+	networkFiles, err := blueprint.NetworkConnectionsToFsNodeFiles(networkConnections)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert network connections to fs node files: %v", err))
+	}
+	if len(networkFiles) > 0 {
+		osc.Files = append(osc.Files, networkFiles...)
+		osc.ExtraBasePackages = append(osc.ExtraBasePackages, "NetworkManager")
+	}
+
+	sudoers, err := c.GetSudoers()
+	if err != nil {
+		// This shouldn't happen since the sudoers customizations
+		// should have already been validated
+		panic(fmt.Sprintf("failed to get sudoers customizations: %v", err))
+	}
+
+	sudoersFiles, err := blueprint.SudoersCustomizationsToFsNodeFiles(sudoers)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert sudoers customizations to fs node files: %v", err))
+	}
+	if len(sudoersFiles) > 0 {
+		osc.Files = append(osc.Files, sudoersFiles...)
+		osc.ExtraBasePackages = append(osc.ExtraBasePackages, "sudo")
+	}
+
+	cacerts, err := c.GetCACerts()
+	if err != nil {
+		// This shouldn't happen since the CA certificates
+		// should have already been validated
+		panic(fmt.Sprintf("failed to get CA certificates: %v", err))
+	}
+
+	osc.CACerts, err = blueprint.CACertsToFsNodeFiles(cacerts)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert CA certificates to fs node files: %v", err))
+	}
+
+	modprobe, err := c.GetModprobe()
+	if err != nil {
+		// This shouldn't happen since the modprobe customization
+		// should have already been validated
+		panic(fmt.Sprintf("failed to get modprobe customization: %v", err))
+	}
+
+	modprobeFiles, err := blueprint.ModprobeOptionsToFsNodeFiles(modprobe)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert modprobe options to fs node files: %v", err))
+	}
+	osc.Files = append(osc.Files, modprobeFiles...)
+
+	// the file contexts are written before the tree is relabelled
+	selinuxFiles, err := blueprint.SELinuxCustomizationToFsNodeFiles(selinux, osc.SElinux)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert SELinux customization to fs node files: %v", err))
+	}
+	osc.Files = append(osc.Files, selinuxFiles...)
+	if selinux != nil && len(selinux.Booleans) > 0 {
+		osc.EnabledServices = append(osc.EnabledServices, blueprint.SELinuxBooleansService)
+		osc.ExtraBasePackages = append(osc.ExtraBasePackages, "policycoreutils")
+	}
+
+	systemdUnits, err := c.GetSystemdUnits()
+	if err != nil {
+		// This shouldn't happen since the systemd unit customizations
+		// should have already been validated
+		panic(fmt.Sprintf("failed to get systemd unit customizations: %v", err))
+	}
+
+	systemdDirs, err := blueprint.SystemdUnitsToFsNodeDirectories(systemdUnits)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert systemd unit customizations to fs node directories: %v", err))
+	}
+	osc.Directories = append(osc.Directories, systemdDirs...)
+
+	systemdFiles, err := blueprint.SystemdUnitsToFsNodeFiles(systemdUnits)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert systemd unit customizations to fs node files: %v", err))
+	}
+	osc.Files = append(osc.Files, systemdFiles...)
+	osc.EnabledServices = append(osc.EnabledServices, blueprint.EnabledSystemdUnits(systemdUnits)...)
+	if services := c.GetServices(); services != nil {
+		osc.MaskedServices = services.Masked
+	}
+
+	swap, err := c.GetSwap()
+	if err != nil {
+		// This shouldn't happen since the swap customization should have
+		// already been validated
+		panic(fmt.Sprintf("failed to get swap customization: %v", err))
+	}
+
+	swapFiles, err := blueprint.SwapFileToFsNodeFiles(swap)
+	if err != nil {
+		panic(fmt.Sprintf("failed to convert swap customization to fs node files: %v", err))
+	}
+	if len(swapFiles) > 0 {
+		osc.Files = append(osc.Files, swapFiles...)
+		osc.EnabledServices = append(osc.EnabledServices, blueprint.SwapFileUnit(swap.GetPath()))
+	}
+	osc.Resume = swap != nil && swap.Resume
+
+	// the tuned stage requires TuneD to be installed in the image
+	if tuned, _ := c.GetTuned(); tuned != nil {
+		osc.ExtraBasePackages = append(osc.ExtraBasePackages, "tuned")
+	}
+
 	osc.ShellInit = imageConfig.ShellInit
 
 	osc.Grub2Config = imageConfig.Grub2Config
@@ -205,6 +339,19 @@ func osCustomizations(
 	osc.SystemdUnit = imageConfig.SystemdUnit
 	osc.Authselect = imageConfig.Authselect
 	osc.SELinuxConfig = imageConfig.SELinuxConfig
+	if selinux != nil && (selinux.Mode != "" || selinux.Policy != "") {
+		selinuxConfig := &osbuild.SELinuxConfigStageOptions{}
+		if osc.SELinuxConfig != nil {
+			*selinuxConfig = *osc.SELinuxConfig
+		}
+		if selinux.Mode != "" {
+			selinuxConfig.State = osbuild.SELinuxPolicyState(selinux.Mode)
+		}
+		if selinux.Policy != "" {
+			selinuxConfig.Type = osbuild.SELinuxPolicyType(selinux.Policy)
+		}
+		osc.SELinuxConfig = selinuxConfig
+	}
 	osc.Tuned = imageConfig.Tuned
 	osc.Tmpfilesd = imageConfig.Tmpfilesd
 	osc.PamLimitsConf = imageConfig.PamLimitsConf
@@ -220,6 +367,7 @@ func osCustomizations(
 	osc.UdevRules = imageConfig.UdevRules
 	osc.GCPGuestAgentConfig = imageConfig.GCPGuestAgentConfig
 	osc.WSLConfig = imageConfig.WSLConfig
+	osc.FIPS = c.GetFIPS()
 
 	return osc
 }
@@ -237,15 +385,17 @@ func liveImage(workload workload.Workload,
 	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], options, containers, customizations)
 	img.Environment = t.environment
 	img.Workload = workload
-	img.Compression = t.compression
+	compression := t.getCompression(options)
+	img.Compression = string(compression.Type)
+	img.CompressionLevel = compression.Level
 	// TODO: move generation into LiveImage
-	pt, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	pt, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
 	img.PartitionTable = pt
 
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	return img, nil
 }
@@ -267,7 +417,7 @@ func edgeCommitImage(workload workload.Workload,
 	img.Workload = workload
 	img.OSTreeParent = parentCommit
 	img.OSVersion = t.arch.distro.osVersion
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	if !common.VersionLessThan(t.arch.distro.osVersion, "9.2") || t.arch.distro.osVersion == "9-stream" {
 		img.OSCustomizations.EnabledServices = append(img.OSCustomizations.EnabledServices, "ignition-firstboot-complete.service", "coreos-ignition-write-issues.service")
@@ -277,7 +427,7 @@ func edgeCommitImage(workload workload.Workload,
 
 	img.OSVersion = t.arch.distro.osVersion
 
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	return img, nil
 }
@@ -301,7 +451,7 @@ func edgeContainerImage(workload workload.Workload,
 	img.OSTreeParent = parentCommit
 	img.OSVersion = t.arch.distro.osVersion
 	img.ExtraContainerPackages = packageSets[containerPkgsKey]
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	if !common.VersionLessThan(t.arch.distro.osVersion, "9.2") || t.arch.distro.osVersion == "9-stream" {
 		img.OSCustomizations.EnabledServices = append(img.OSCustomizations.EnabledServices, "ignition-firstboot-complete.service", "coreos-ignition-write-issues.service")
@@ -332,6 +482,15 @@ func edgeInstallerImage(workload workload.Workload,
 	img.Users = users.UsersFromBP(customizations.GetUsers())
 	img.Groups = users.GroupsFromBP(customizations.GetGroups())
 
+	networkConnections, err := customizations.GetNetworkConnections()
+	if err != nil {
+		return nil, err
+	}
+	img.Network, err = osbuild.NewKickstartNetworkOptions(networkConnections)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", t.Name(), err.Error())
+	}
+
 	img.SquashfsCompression = "xz"
 	img.AdditionalDracutModules = []string{
 		"nvdimm", // non-volatile DIMM firmware (provides nfit, cuse, and nd_e820)
@@ -340,6 +499,19 @@ func edgeInstallerImage(workload workload.Workload,
 	}
 	img.AdditionalDrivers = []string{"cuse", "ipmi_devintf", "ipmi_msghandler"}
 
+	if customizations.GetFIPS() {
+		img.AdditionalKernelOpts = []string{"fips=1"}
+		img.KickstartKernelOptionsAppend = []string{"fips=1"}
+		img.AdditionalDracutModules = append(img.AdditionalDracutModules, "fips")
+	}
+
+	kickstart, kickstartKernelOptions, err := distro.GetInstallerKickstart(customizations)
+	if err != nil {
+		return nil, err
+	}
+	img.Kickstart = kickstart
+	img.KickstartKernelOptionsAppend = append(img.KickstartKernelOptionsAppend, kickstartKernelOptions...)
+
 	if len(img.Users)+len(img.Groups) > 0 {
 		// only enable the users module if needed
 		img.AdditionalAnacondaModules = []string{"org.fedoraproject.Anaconda.Modules.Users"}
@@ -352,7 +524,7 @@ func edgeInstallerImage(workload workload.Workload,
 	img.OSVersion = d.osVersion
 	img.Release = fmt.Sprintf("%s %s", d.product, d.osVersion)
 
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	return img, nil
 }
@@ -389,6 +561,8 @@ func edgeRawImage(workload workload.Workload,
 		img.KernelOptionsAppend = append(img.KernelOptionsAppend, "rw")
 	}
 
+	img.FIPS = customizations.GetFIPS()
+
 	img.Platform = t.platform
 	img.Workload = workload
 	img.Remote = ostree.Remote{
@@ -408,14 +582,16 @@ func edgeRawImage(workload workload.Workload,
 	}
 
 	// TODO: move generation into LiveImage
-	pt, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	pt, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
 	img.PartitionTable = pt
 
-	img.Filename = t.Filename()
-	img.Compression = t.compression
+	img.Filename = t.FilenameFor(options)
+	compression := t.getCompression(options)
+	img.Compression = string(compression.Type)
+	img.CompressionLevel = compression.Level
 
 	return img, nil
 }
@@ -451,6 +627,8 @@ func edgeSimplifiedInstallerImage(workload workload.Workload,
 		rawImg.KernelOptionsAppend = append(rawImg.KernelOptionsAppend, "rw")
 	}
 
+	rawImg.FIPS = customizations.GetFIPS()
+
 	rawImg.Platform = t.platform
 	rawImg.Workload = workload
 	rawImg.Remote = ostree.Remote{
@@ -461,13 +639,13 @@ func edgeSimplifiedInstallerImage(workload workload.Workload,
 	rawImg.OSName = "redhat"
 
 	// TODO: move generation into LiveImage
-	pt, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	pt, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
 	rawImg.PartitionTable = pt
 
-	rawImg.Filename = t.Filename()
+	rawImg.Filename = t.FilenameFor(options)
 
 	if bpIgnition := customizations.GetIgnition(); bpIgnition != nil && bpIgnition.FirstBoot != nil && bpIgnition.FirstBoot.ProvisioningURL != "" {
 		rawImg.KernelOptionsAppend = append(rawImg.KernelOptionsAppend, "ignition.config.url="+bpIgnition.FirstBoot.ProvisioningURL)
@@ -482,7 +660,7 @@ func edgeSimplifiedInstallerImage(workload workload.Workload,
 	img.ExtraBasePackages = packageSets[installerPkgsKey]
 	// img.Workload = workload
 	img.Platform = t.platform
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 	if bpFDO := customizations.GetFDO(); bpFDO != nil {
 		img.FDO = fdo.FromBP(*bpFDO)
 	}
@@ -504,6 +682,10 @@ func edgeSimplifiedInstallerImage(workload workload.Workload,
 	img.OSName = "redhat"
 	img.OSVersion = d.osVersion
 	img.AdditionalDracutModules = []string{"prefixdevname", "prefixdevname-tools"}
+	if customizations.GetFIPS() {
+		img.AdditionalKernelOpts = []string{"fips=1"}
+		img.AdditionalDracutModules = append(img.AdditionalDracutModules, "fips")
+	}
 
 	return img, nil
 }
@@ -533,6 +715,19 @@ func imageInstallerImage(workload workload.Workload,
 	img.AdditionalDrivers = []string{"cuse", "ipmi_devintf", "ipmi_msghandler"}
 	img.AdditionalAnacondaModules = []string{"org.fedoraproject.Anaconda.Modules.Users"}
 
+	if customizations.GetFIPS() {
+		img.AdditionalKernelOpts = []string{"fips=1"}
+		img.KickstartKernelOptionsAppend = []string{"fips=1"}
+		img.AdditionalDracutModules = append(img.AdditionalDracutModules, "fips")
+	}
+
+	kickstart, kickstartKernelOptions, err := distro.GetInstallerKickstart(customizations)
+	if err != nil {
+		return nil, err
+	}
+	img.Kickstart = kickstart
+	img.KickstartKernelOptionsAppend = append(img.KickstartKernelOptionsAppend, kickstartKernelOptions...)
+
 	img.SquashfsCompression = "xz"
 
 	// put the kickstart file in the root of the iso
@@ -546,7 +741,7 @@ func imageInstallerImage(workload workload.Workload,
 	img.OSVersion = d.osVersion
 	img.Release = fmt.Sprintf("%s %s", d.product, d.osVersion)
 
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	return img, nil
 }
@@ -565,7 +760,7 @@ func tarImage(workload workload.Workload,
 	img.Environment = t.environment
 	img.Workload = workload
 
-	img.Filename = t.Filename()
+	img.Filename = t.FilenameFor(options)
 
 	return img, nil
 
@@ -55,7 +55,7 @@ type imageType struct {
 	name               string
 	nameAliases        []string
 	filename           string
-	compression        string // TODO: remove from image definition and make it a transport option
+	compression        string // default compression, see distro.ImageOptions.Compression
 	mimeType           string
 	packageSets        map[string]packageSetFunc
 	defaultImageConfig *distro.ImageConfig
@@ -85,13 +85,30 @@ func (t *imageType) Arch() distro.Arch {
 }
 
 func (t *imageType) Filename() string {
-	return t.filename
+	return t.FilenameFor(distro.ImageOptions{})
+}
+
+func (t *imageType) FilenameFor(options distro.ImageOptions) string {
+	return t.filename + t.getCompression(options).Type.Extension()
 }
 
 func (t *imageType) MIMEType() string {
+	return t.MIMETypeFor(distro.ImageOptions{})
+}
+
+func (t *imageType) MIMETypeFor(options distro.ImageOptions) string {
+	if mimeType := t.getCompression(options).Type.MIMEType(); mimeType != "" {
+		return mimeType
+	}
 	return t.mimeType
 }
 
+// getCompression returns the compression selected in the image options or
+// the default compression of the image type
+func (t *imageType) getCompression(options distro.ImageOptions) distro.CompressionOptions {
+	return distro.GetCompression(options, t.compression)
+}
+
 func (t *imageType) OSTreeRef() string {
 	d := t.arch.distro
 	if t.rpmOstree {
@@ -128,12 +145,37 @@ func (t *imageType) PackageSetsChains() map[string][]string {
 }
 
 func (t *imageType) Exports() []string {
+	return t.ExportsFor(distro.ImageOptions{})
+}
+
+func (t *imageType) ExportsFor(options distro.ImageOptions) []string {
+	if compression := t.getCompression(options); compression.Type != "" {
+		// the compression pipeline is named after its type
+		return []string{string(compression.Type)}
+	}
 	if len(t.exports) > 0 {
 		return t.exports
 	}
 	return []string{"assembler"}
 }
 
+// supportsCompression returns true if the artifact of the image type can be
+// compressed, i.e. if it is a single disk image file
+func (t *imageType) supportsCompression() bool {
+	if !t.bootable || t.bootISO {
+		return false
+	}
+	switch t.platform.GetImageFormat() {
+	case platform.FORMAT_OVA, platform.FORMAT_GCE, platform.FORMAT_VAGRANT_LIBVIRT, platform.FORMAT_VAGRANT_VIRTUALBOX, platform.FORMAT_CONTAINERDISK:
+		// archives of disk images
+		return false
+	case platform.FORMAT_VMDK:
+		// ostree based vmdk images are never compressed
+		return !t.rpmOstree
+	}
+	return true
+}
+
 func (t *imageType) BootMode() distro.BootMode {
 	if t.platform.GetUEFIVendor() != "" && t.platform.GetBIOSPlatform() != "" {
 		return distro.BOOT_HYBRID
@@ -146,7 +188,7 @@ func (t *imageType) BootMode() distro.BootMode {
 }
 
 func (t *imageType) getPartitionTable(
-	mountpoints []blueprint.FilesystemCustomization,
+	customizations *blueprint.Customizations,
 	options distro.ImageOptions,
 	rng *rand.Rand,
 ) (*disk.PartitionTable, error) {
@@ -160,9 +202,23 @@ func (t *imageType) getPartitionTable(
 
 	imageSize := t.Size(options.Size)
 
-	lvmify := !t.rpmOstree
+	partitioningMode := options.PartitioningMode
+	if t.rpmOstree {
+		// ostree deployments do not support LVM
+		partitioningMode = blueprint.RawPartitioningMode
+	}
+
+	encryption, err := customizations.GetDiskEncryption()
+	if err != nil {
+		return nil, err
+	}
+
+	swap, err := customizations.GetSwap()
+	if err != nil {
+		return nil, err
+	}
 
-	return disk.NewPartitionTable(&basePartitionTable, mountpoints, imageSize, lvmify, nil, rng)
+	return disk.NewPartitionTable(&basePartitionTable, customizations.GetFilesystems(), imageSize, partitioningMode, encryption, swap, nil, rng)
 }
 
 func (t *imageType) getDefaultImageConfig() *distro.ImageConfig {
@@ -190,6 +246,12 @@ func (t *imageType) Manifest(bp *blueprint.Blueprint,
 	repos []rpmmd.RepoConfig,
 	seed int64) (*manifest.Manifest, []string, error) {
 
+	partitioningMode, err := distro.GetPartitioningMode(options, bp.Customizations)
+	if err != nil {
+		return nil, nil, err
+	}
+	options.PartitioningMode = partitioningMode
+
 	warnings, err := t.checkOptions(bp, options)
 	if err != nil {
 		return nil, nil, err
@@ -206,6 +268,9 @@ func (t *imageType) Manifest(bp *blueprint.Blueprint,
 	// amend with repository information and collect payload repos
 	payloadRepos := make([]rpmmd.RepoConfig, 0)
 	for _, repo := range repos {
+		if err := repo.CheckPackageDownloads(); err != nil {
+			return nil, nil, err
+		}
 		if len(repo.PackageSets) > 0 {
 			// only apply the repo to the listed package sets
 			for _, psName := range repo.PackageSets {
@@ -221,11 +286,22 @@ func (t *imageType) Manifest(bp *blueprint.Blueprint,
 
 	w := t.workload
 	if w == nil {
+		packagePins, err := bp.GetPackagePins()
+		if err != nil {
+			return nil, nil, err
+		}
+		enabledModules, err := bp.GetEnabledModules()
+		if err != nil {
+			return nil, nil, err
+		}
 		cw := &workload.Custom{
 			BaseWorkload: workload.BaseWorkload{
 				Repos: payloadRepos,
 			},
-			Packages: bp.GetPackagesEx(false),
+			Packages:        bp.GetPackagesEx(false),
+			ExcludePackages: bp.ExcludePackages,
+			PackagePins:     packagePins,
+			EnabledModules:  enabledModules,
 		}
 		if services := bp.Customizations.GetServices(); services != nil {
 			cw.Services = services.Enabled
@@ -282,6 +358,25 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, fmt.Errorf("embedding containers is not supported for %s on %s", t.name, t.arch.distro.name)
 	}
 
+	// check if installer customizations are valid
+	installer, err := customizations.GetInstaller()
+	if err != nil {
+		return warnings, err
+	}
+	if installer != nil && !slices.Contains([]string{"image-installer", "edge-installer"}, t.name) {
+		return warnings, fmt.Errorf("installer customizations are not supported for image type %q", t.name)
+	}
+
+	// check if compression options are valid
+	if options.Compression != nil {
+		if !t.supportsCompression() {
+			return warnings, fmt.Errorf("compression is not supported for image type %q", t.name)
+		}
+		if err := options.Compression.Validate(); err != nil {
+			return warnings, err
+		}
+	}
+
 	ostreeURL := ""
 	if options.OSTree != nil {
 		if options.OSTree.ParentRef != "" && options.OSTree.URL == "" {
@@ -298,7 +393,7 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		}
 
 		if t.name == "edge-simplified-installer" {
-			allowed := []string{"InstallationDevice", "FDO", "Ignition", "Kernel", "User", "Group"}
+			allowed := []string{"InstallationDevice", "FDO", "Ignition", "Kernel", "User", "Group", "FIPS"}
 			if err := customizations.CheckAllowed(allowed...); err != nil {
 				return warnings, fmt.Errorf("unsupported blueprint customizations found for boot ISO image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
 			}
@@ -336,10 +431,14 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 				}
 			}
 		} else if t.name == "edge-installer" {
-			allowed := []string{"User", "Group"}
+			allowed := []string{"User", "Group", "Network", "FIPS", "Installer", "Locale", "Timezone"}
 			if err := customizations.CheckAllowed(allowed...); err != nil {
 				return warnings, fmt.Errorf("unsupported blueprint customizations found for boot ISO image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
 			}
+			// the locale and timezone are only set by the kickstart file
+			if installer == nil && customizations != nil && (customizations.Locale != nil || customizations.Timezone != nil) {
+				return warnings, fmt.Errorf("locale and timezone customizations require installer customizations for boot ISO image type %q", t.name)
+			}
 		}
 	}
 
@@ -349,7 +448,7 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 			return warnings, fmt.Errorf("%q images require specifying a URL from which to retrieve the OSTree commit", t.name)
 		}
 
-		allowed := []string{"Ignition", "Kernel", "User", "Group"}
+		allowed := []string{"Ignition", "Kernel", "User", "Group", "FIPS"}
 		if err := customizations.CheckAllowed(allowed...); err != nil {
 			return warnings, fmt.Errorf("unsupported blueprint customizations found for image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
 		}
@@ -381,10 +480,54 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, fmt.Errorf("Custom mountpoints are not supported for ostree types")
 	}
 
-	err := blueprint.CheckMountpointsPolicy(mountpoints, pathpolicy.MountpointPolicies)
+	err = blueprint.CheckMountpointsPolicy(mountpoints, pathpolicy.MountpointPolicies)
+	if err != nil {
+		return warnings, err
+	}
+
+	err = blueprint.CheckFilesystemCustomizations(mountpoints, []string{"xfs", "ext4", "vfat"})
+	if err != nil {
+		return warnings, err
+	}
+
+	if mode := options.PartitioningMode; mode != blueprint.DefaultPartitioningMode {
+		if mode == blueprint.BtrfsPartitioningMode {
+			return warnings, fmt.Errorf("btrfs partitioning is not supported on %s", t.arch.distro.name)
+		}
+		if t.basePartitionTables == nil {
+			return warnings, fmt.Errorf("partitioning mode %q is not supported for image type %q", mode, t.name)
+		}
+		if mode == blueprint.LVMPartitioningMode && t.rpmOstree {
+			return warnings, fmt.Errorf("partitioning mode %q is not supported for ostree types", mode)
+		}
+	}
+
+	encryption, err := customizations.GetDiskEncryption()
 	if err != nil {
 		return warnings, err
 	}
+	if encryption != nil && !slices.Contains([]string{"qcow2", "ami", "minimal-raw"}, t.name) {
+		return warnings, fmt.Errorf("disk encryption is not supported for image type %q", t.name)
+	}
+
+	// check if swap customizations are valid
+	swap, err := customizations.GetSwap()
+	if err != nil {
+		return warnings, err
+	}
+	if swap != nil {
+		if t.rpmOstree {
+			return warnings, fmt.Errorf("swap customizations are not supported for ostree types")
+		}
+		// the installer doesn't create swap space and a swap file in the
+		// payload would not be sized for the installed system
+		if t.bootISO {
+			return warnings, fmt.Errorf("swap customizations are not supported for installer image type %q", t.name)
+		}
+		if swap.GetType() != blueprint.SwapFile && t.basePartitionTables == nil {
+			return warnings, fmt.Errorf("swap partitions and logical volumes are not supported for image type %q", t.name)
+		}
+	}
 
 	if osc := customizations.GetOpenSCAP(); osc != nil {
 		if t.arch.distro.osVersion == "9.0" {
@@ -425,5 +568,64 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, err
 	}
 
+	// check if network customizations are valid
+	_, err = customizations.GetNetworkConnections()
+	if err != nil {
+		return warnings, err
+	}
+
+	// check if sudoers customizations are valid
+	_, err = customizations.GetSudoers()
+	if err != nil {
+		return warnings, err
+	}
+
+	// check if CA certificate customizations are valid
+	_, err = customizations.GetCACerts()
+	if err != nil {
+		return warnings, err
+	}
+
+	// check if sysctl customizations are valid
+	_, err = customizations.GetSysctl()
+	if err != nil {
+		return warnings, err
+	}
+
+	// check if modprobe customizations are valid
+	_, err = customizations.GetModprobe()
+	if err != nil {
+		return warnings, err
+	}
+
+	// check if tuned customizations are valid
+	_, err = customizations.GetTuned()
+	if err != nil {
+		return warnings, err
+	}
+
+	// check if dracut customizations are valid
+	_, err = customizations.GetDracut()
+	if err != nil {
+		return warnings, err
+	}
+
+	// check if SELinux customizations are valid
+	selinux, err := customizations.GetSELinux()
+	if err != nil {
+		return warnings, err
+	}
+	if selinux != nil {
+		if noSElinux := t.getDefaultImageConfig().NoSElinux; noSElinux != nil && *noSElinux {
+			return warnings, fmt.Errorf("SELinux customizations are not supported for image type %q", t.name)
+		}
+	}
+
+	// check if systemd unit customizations are valid
+	_, err = customizations.GetSystemdUnits()
+	if err != nil {
+		return warnings, err
+	}
+
 	return warnings, nil
 }
@@ -2,6 +2,7 @@ package rhel9
 
 import (
 	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/environment"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/platform"
@@ -171,3 +172,16 @@ func mkQcow2ImgType(d distribution) imageType {
 	it.defaultImageConfig = qcowImageConfig(d)
 	return it
 }
+
+// mkContainerDiskImgType returns the qcow2 image type wrapped into a KubeVirt
+// containerDisk
+func mkContainerDiskImgType(d distribution) imageType {
+	it := mkQcow2ImgType(d)
+	it.name = "containerdisk"
+	it.filename = "container.tar"
+	it.mimeType = "application/x-tar"
+	it.payloadPipelines = []string{"os", "image", "qcow2", "containerdisk", "container"}
+	it.exports = []string{"container"}
+	it.environment = &environment.KubeVirt{}
+	return it
+}
@@ -0,0 +1,58 @@
+package rhel9
+
+import (
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/environment"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+var vagrantLibvirtImgType = imageType{
+	name:     "vagrant-libvirt",
+	filename: "vagrant-libvirt.box",
+	mimeType: "application/x-tar",
+	packageSets: map[string]packageSetFunc{
+		osPkgsKey: vagrantLibvirtPackageSet,
+	},
+	defaultImageConfig: &distro.ImageConfig{
+		Locale: common.ToPtr("en_US.UTF-8"),
+	},
+	kernelOptions:       localVMKernelOptions,
+	bootable:            true,
+	defaultSize:         10 * common.GibiByte,
+	image:               liveImage,
+	buildPipelines:      []string{"build"},
+	payloadPipelines:    []string{"os", "image", "qcow2", "vagrant", "archive"},
+	exports:             []string{"archive"},
+	basePartitionTables: defaultBasePartitionTables,
+	environment:         &environment.Vagrant{},
+}
+
+var vagrantVirtualBoxImgType = imageType{
+	name:     "vagrant-virtualbox",
+	filename: "vagrant-virtualbox.box",
+	mimeType: "application/x-tar",
+	packageSets: map[string]packageSetFunc{
+		osPkgsKey: localVMCommonPackageSet,
+	},
+	defaultImageConfig: &distro.ImageConfig{
+		Locale: common.ToPtr("en_US.UTF-8"),
+	},
+	kernelOptions:       localVMKernelOptions,
+	bootable:            true,
+	defaultSize:         10 * common.GibiByte,
+	image:               liveImage,
+	buildPipelines:      []string{"build"},
+	payloadPipelines:    []string{"os", "image", "vmdk", "ovf", "vagrant", "archive"},
+	exports:             []string{"archive"},
+	basePartitionTables: defaultBasePartitionTables,
+	environment:         &environment.Vagrant{},
+}
+
+func vagrantLibvirtPackageSet(t *imageType) rpmmd.PackageSet {
+	return rpmmd.PackageSet{
+		Include: []string{
+			"qemu-guest-agent",
+		},
+	}.Append(localVMCommonPackageSet(t))
+}
@@ -0,0 +1,77 @@
+package rhel9
+
+import (
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// Images for local hypervisors: Hyper-V (vhdx) and VirtualBox (vdi)
+
+const localVMKernelOptions = "ro console=tty0 console=ttyS0,115200n8 net.ifnames=0"
+
+var vhdxImgType = imageType{
+	name:     "vhdx",
+	filename: "disk.vhdx",
+	mimeType: "application/x-vhdx",
+	packageSets: map[string]packageSetFunc{
+		osPkgsKey: vhdxCommonPackageSet,
+	},
+	defaultImageConfig: &distro.ImageConfig{
+		Locale: common.ToPtr("en_US.UTF-8"),
+	},
+	kernelOptions:       localVMKernelOptions,
+	bootable:            true,
+	defaultSize:         4 * common.GibiByte,
+	image:               liveImage,
+	buildPipelines:      []string{"build"},
+	payloadPipelines:    []string{"os", "image", "vhdx"},
+	exports:             []string{"vhdx"},
+	basePartitionTables: defaultBasePartitionTables,
+}
+
+var vdiImgType = imageType{
+	name:     "vdi",
+	filename: "disk.vdi",
+	mimeType: "application/x-virtualbox-vdi",
+	packageSets: map[string]packageSetFunc{
+		osPkgsKey: vdiCommonPackageSet,
+	},
+	defaultImageConfig: &distro.ImageConfig{
+		Locale: common.ToPtr("en_US.UTF-8"),
+	},
+	kernelOptions:       localVMKernelOptions,
+	bootable:            true,
+	defaultSize:         4 * common.GibiByte,
+	image:               liveImage,
+	buildPipelines:      []string{"build"},
+	payloadPipelines:    []string{"os", "image", "vdi"},
+	exports:             []string{"vdi"},
+	basePartitionTables: defaultBasePartitionTables,
+}
+
+func localVMCommonPackageSet(t *imageType) rpmmd.PackageSet {
+	return rpmmd.PackageSet{
+		Include: []string{
+			"chrony",
+			"firewalld",
+			"langpacks-en",
+		},
+		Exclude: []string{
+			"rng-tools",
+		},
+	}.Append(coreOsCommonPackageSet(t))
+}
+
+func vhdxCommonPackageSet(t *imageType) rpmmd.PackageSet {
+	return rpmmd.PackageSet{
+		Include: []string{
+			// KVP, VSS and file copy daemons of the Hyper-V integration services
+			"hyperv-daemons",
+		},
+	}.Append(localVMCommonPackageSet(t))
+}
+
+func vdiCommonPackageSet(t *imageType) rpmmd.PackageSet {
+	return localVMCommonPackageSet(t)
+}
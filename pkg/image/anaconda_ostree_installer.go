@@ -9,6 +9,7 @@ import (
 	"github.com/osbuild/images/pkg/artifact"
 	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -21,6 +22,7 @@ type AnacondaOSTreeInstaller struct {
 	ExtraBasePackages rpmmd.PackageSet
 	Users             []users.User
 	Groups            []users.Group
+	Network           []osbuild.KickstartNetworkOptions
 
 	SquashfsCompression string
 
@@ -38,6 +40,15 @@ type AnacondaOSTreeInstaller struct {
 	AdditionalDracutModules   []string
 	AdditionalAnacondaModules []string
 	AdditionalDrivers         []string
+
+	// Kernel options added to the boot menu entries of the installer
+	AdditionalKernelOpts []string
+
+	// Kernel options of the installed system, added to the kickstart file
+	KickstartKernelOptionsAppend []string
+
+	// Installer configuration of the kickstart file
+	Kickstart *manifest.Kickstart
 }
 
 func NewAnacondaOSTreeInstaller(commit ostree.SourceSpec) *AnacondaOSTreeInstaller {
@@ -70,7 +81,7 @@ func (img *AnacondaOSTreeInstaller) InstantiateManifest(m *manifest.Manifest,
 	anacondaPipeline.Biosdevname = (img.Platform.GetArch() == platform.ARCH_X86_64)
 	anacondaPipeline.Checkpoint()
 	anacondaPipeline.AdditionalDracutModules = img.AdditionalDracutModules
-	anacondaPipeline.AdditionalAnacondaModules = img.AdditionalAnacondaModules
+	anacondaPipeline.AdditionalAnacondaModules = kickstartAnacondaModules(img.AdditionalAnacondaModules, img.Kickstart)
 	anacondaPipeline.AdditionalDrivers = img.AdditionalDrivers
 
 	rootfsPartitionTable := &disk.PartitionTable{
@@ -99,6 +110,7 @@ func (img *AnacondaOSTreeInstaller) InstantiateManifest(m *manifest.Manifest,
 	bootTreePipeline.UEFIVendor = img.Platform.GetUEFIVendor()
 	bootTreePipeline.ISOLabel = isoLabel
 	bootTreePipeline.KernelOpts = []string{fmt.Sprintf("inst.stage2=hd:LABEL=%s", isoLabel), fmt.Sprintf("inst.ks=hd:LABEL=%s:%s", isoLabel, kspath)}
+	bootTreePipeline.KernelOpts = append(bootTreePipeline.KernelOpts, img.AdditionalKernelOpts...)
 
 	// enable ISOLinux on x86_64 only
 	isoLinuxEnabled := img.Platform.GetArch() == platform.ARCH_X86_64
@@ -114,6 +126,10 @@ func (img *AnacondaOSTreeInstaller) InstantiateManifest(m *manifest.Manifest,
 	isoTreePipeline.OSName = img.OSName
 	isoTreePipeline.Users = img.Users
 	isoTreePipeline.Groups = img.Groups
+	isoTreePipeline.Network = img.Network
+	isoTreePipeline.KickstartKernelOptionsAppend = img.KickstartKernelOptionsAppend
+	isoTreePipeline.Kickstart = img.Kickstart
+	isoTreePipeline.KernelOpts = img.AdditionalKernelOpts
 
 	isoTreePipeline.SquashfsCompression = img.SquashfsCompression
 
@@ -15,6 +15,7 @@ import (
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
 	"github.com/osbuild/images/pkg/runner"
+	"golang.org/x/exp/slices"
 )
 
 const kspath = "/osbuild.ks"
@@ -50,6 +51,12 @@ type AnacondaTarInstaller struct {
 	AdditionalAnacondaModules []string
 	AdditionalDracutModules   []string
 	AdditionalDrivers         []string
+
+	// Kernel options of the installed system, added to the kickstart file
+	KickstartKernelOptionsAppend []string
+
+	// Installer configuration of the kickstart file, requires ISORootKickstart
+	Kickstart *manifest.Kickstart
 }
 
 func NewAnacondaTarInstaller() *AnacondaTarInstaller {
@@ -80,7 +87,7 @@ func (img *AnacondaTarInstaller) InstantiateManifest(m *manifest.Manifest,
 	anacondaPipeline.Groups = img.Groups
 	anacondaPipeline.Variant = img.Variant
 	anacondaPipeline.Biosdevname = (img.Platform.GetArch() == platform.ARCH_X86_64)
-	anacondaPipeline.AdditionalAnacondaModules = img.AdditionalAnacondaModules
+	anacondaPipeline.AdditionalAnacondaModules = kickstartAnacondaModules(img.AdditionalAnacondaModules, img.Kickstart)
 	anacondaPipeline.AdditionalDracutModules = img.AdditionalDracutModules
 	anacondaPipeline.AdditionalDrivers = img.AdditionalDrivers
 
@@ -89,6 +96,7 @@ func (img *AnacondaTarInstaller) InstantiateManifest(m *manifest.Manifest,
 	if !img.ISORootKickstart {
 		payloadPath := filepath.Join("/run/install/repo/", tarPath)
 		anacondaPipeline.InteractiveDefaults = manifest.NewAnacondaInteractiveDefaults(fmt.Sprintf("file://%s", payloadPath))
+		anacondaPipeline.InteractiveDefaults.KernelOptionsAppend = img.KickstartKernelOptionsAppend
 	}
 
 	anacondaPipeline.Checkpoint()
@@ -145,6 +153,8 @@ func (img *AnacondaTarInstaller) InstantiateManifest(m *manifest.Manifest,
 	isoTreePipeline.OSName = img.OSName
 	isoTreePipeline.Users = img.Users
 	isoTreePipeline.Groups = img.Groups
+	isoTreePipeline.KickstartKernelOptionsAppend = img.KickstartKernelOptionsAppend
+	isoTreePipeline.Kickstart = img.Kickstart
 	isoTreePipeline.PayloadPath = tarPath
 	if img.ISORootKickstart {
 		isoTreePipeline.KSPath = kspath
@@ -164,3 +174,22 @@ func (img *AnacondaTarInstaller) InstantiateManifest(m *manifest.Manifest,
 
 	return artifact, nil
 }
+
+// kickstartAnacondaModules adds the Anaconda modules that handle the
+// commands of the kickstart configuration to the given modules
+func kickstartAnacondaModules(modules []string, ks *manifest.Kickstart) []string {
+	if ks == nil {
+		return modules
+	}
+
+	for _, module := range []string{
+		"org.fedoraproject.Anaconda.Modules.Localization",
+		"org.fedoraproject.Anaconda.Modules.Timezone",
+		"org.fedoraproject.Anaconda.Modules.Users",
+	} {
+		if !slices.Contains(modules, module) {
+			modules = append(modules, module)
+		}
+	}
+	return modules
+}
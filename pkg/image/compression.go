@@ -0,0 +1,40 @@
+package image
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/pkg/artifact"
+	"github.com/osbuild/images/pkg/manifest"
+)
+
+// compressionPipeline adds a pipeline to the manifest that compresses the
+// artifact of the input pipeline into a file with the given name. Returns the
+// artifact of the new pipeline, or nil if compression is empty. The level is
+// only supported by gzip and must be nil for the other compression types.
+func compressionPipeline(m *manifest.Manifest,
+	buildPipeline *manifest.Build,
+	inputPipeline manifest.Pipeline,
+	compression string,
+	level *uint,
+	filename string) *artifact.Artifact {
+	switch compression {
+	case "xz":
+		xzPipeline := manifest.NewXZ(m, buildPipeline, inputPipeline)
+		xzPipeline.Filename = filename
+		return xzPipeline.Export()
+	case "zstd":
+		zstdPipeline := manifest.NewZstd(m, buildPipeline, inputPipeline)
+		zstdPipeline.Filename = filename
+		return zstdPipeline.Export()
+	case "gzip":
+		gzipPipeline := manifest.NewGzip(m, buildPipeline, inputPipeline)
+		gzipPipeline.Filename = filename
+		gzipPipeline.Level = level
+		return gzipPipeline.Export()
+	case "":
+		return nil
+	default:
+		// panic on unknown strings
+		panic(fmt.Sprintf("unsupported compression type %q", compression))
+	}
+}
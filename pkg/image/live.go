@@ -1,7 +1,6 @@
 package image
 
 import (
-	"fmt"
 	"math/rand"
 
 	"github.com/osbuild/images/internal/common"
@@ -25,6 +24,7 @@ type LiveImage struct {
 	Workload         workload.Workload
 	Filename         string
 	Compression      string
+	CompressionLevel *uint
 	ForceSize        *bool
 	PartTool         osbuild.PartTool
 
@@ -93,6 +93,20 @@ func (img *LiveImage) InstantiateManifest(m *manifest.Manifest,
 		}
 		artifactPipeline = vmdkPipeline
 		artifact = vmdkPipeline.Export()
+	case platform.FORMAT_VHDX:
+		vhdxPipeline := manifest.NewVHDX(m, buildPipeline, imagePipeline)
+		if img.Compression == "" {
+			vhdxPipeline.Filename = img.Filename
+		}
+		artifactPipeline = vhdxPipeline
+		artifact = vhdxPipeline.Export()
+	case platform.FORMAT_VDI:
+		vdiPipeline := manifest.NewVDI(m, buildPipeline, imagePipeline)
+		if img.Compression == "" {
+			vdiPipeline.Filename = img.Filename
+		}
+		artifactPipeline = vdiPipeline
+		artifact = vdiPipeline.Export()
 	case platform.FORMAT_OVA:
 		vmdkPipeline := manifest.NewVMDK(m, buildPipeline, imagePipeline, nil)
 		ovfPipeline := manifest.NewOVF(m, buildPipeline, vmdkPipeline)
@@ -101,6 +115,29 @@ func (img *LiveImage) InstantiateManifest(m *manifest.Manifest,
 		artifactPipeline.RootNode = osbuild.TarRootNodeOmit
 		artifactPipeline.Filename = img.Filename
 		artifact = artifactPipeline.Export()
+	case platform.FORMAT_VAGRANT_LIBVIRT:
+		qcow2Pipeline := manifest.NewQCOW2(m, buildPipeline, imagePipeline)
+		qcow2Pipeline.Compat = img.Platform.GetQCOW2Compat()
+		vagrantPipeline := manifest.NewVagrant(m, buildPipeline, qcow2Pipeline, manifest.VagrantProviderLibvirt)
+		vagrantPipeline.VirtualSize = img.PartitionTable.Size
+		boxPipeline := vagrantBoxPipeline(m, buildPipeline, vagrantPipeline, img.Filename)
+		artifactPipeline = boxPipeline
+		artifact = boxPipeline.Export()
+	case platform.FORMAT_VAGRANT_VIRTUALBOX:
+		vmdkPipeline := manifest.NewVMDK(m, buildPipeline, imagePipeline, nil)
+		ovfPipeline := manifest.NewOVF(m, buildPipeline, vmdkPipeline)
+		vagrantPipeline := manifest.NewVagrant(m, buildPipeline, ovfPipeline, manifest.VagrantProviderVirtualBox)
+		boxPipeline := vagrantBoxPipeline(m, buildPipeline, vagrantPipeline, img.Filename)
+		artifactPipeline = boxPipeline
+		artifact = boxPipeline.Export()
+	case platform.FORMAT_CONTAINERDISK:
+		qcow2Pipeline := manifest.NewQCOW2(m, buildPipeline, imagePipeline)
+		qcow2Pipeline.Compat = img.Platform.GetQCOW2Compat()
+		containerDiskPipeline := manifest.NewContainerDisk(m, buildPipeline, qcow2Pipeline)
+		containerPipeline := manifest.NewOCIContainer(m, buildPipeline, containerDiskPipeline)
+		containerPipeline.Filename = img.Filename
+		artifactPipeline = containerPipeline
+		artifact = containerPipeline.Export()
 	case platform.FORMAT_GCE:
 		// NOTE(akoutsou): temporary workaround; filename required for GCP
 		// TODO: define internal raw filename on image type
@@ -117,17 +154,19 @@ func (img *LiveImage) InstantiateManifest(m *manifest.Manifest,
 		panic("invalid image format for image kind")
 	}
 
-	switch img.Compression {
-	case "xz":
-		xzPipeline := manifest.NewXZ(m, buildPipeline, artifactPipeline)
-		xzPipeline.Filename = img.Filename
-		artifact = xzPipeline.Export()
-	case "":
-		// do nothing
-	default:
-		// panic on unknown strings
-		panic(fmt.Sprintf("unsupported compression type %q", img.Compression))
+	if compressed := compressionPipeline(m, buildPipeline, artifactPipeline, img.Compression, img.CompressionLevel, img.Filename); compressed != nil {
+		artifact = compressed
 	}
 
 	return artifact, nil
 }
+
+// vagrantBoxPipeline archives the tree of a Vagrant box into the box file
+func vagrantBoxPipeline(m *manifest.Manifest, buildPipeline *manifest.Build, vagrantPipeline *manifest.Vagrant, filename string) *manifest.Tar {
+	boxPipeline := manifest.NewTar(m, buildPipeline, vagrantPipeline, "archive")
+	// the images can be larger than the 8 GiB limit of ustar
+	boxPipeline.Format = osbuild.TarArchiveFormatGnu
+	boxPipeline.RootNode = osbuild.TarRootNodeOmit
+	boxPipeline.Filename = filename
+	return boxPipeline
+}
@@ -39,10 +39,13 @@ type OSTreeRawImage struct {
 
 	Filename string
 
-	Compression string
+	Compression      string
+	CompressionLevel *uint
 
 	Ignition bool
 
+	FIPS bool
+
 	Directories []*fsnode.Directory
 	Files       []*fsnode.File
 }
@@ -68,6 +71,7 @@ func baseRawOstreeImage(img *OSTreeRawImage, m *manifest.Manifest, buildPipeline
 	osPipeline.PartitionTable = img.PartitionTable
 	osPipeline.Remote = img.Remote
 	osPipeline.KernelOptionsAppend = img.KernelOptionsAppend
+	osPipeline.FIPS = img.FIPS
 	osPipeline.Keyboard = img.Keyboard
 	osPipeline.Locale = img.Locale
 	osPipeline.Users = img.Users
@@ -101,16 +105,12 @@ func (img *OSTreeRawImage) InstantiateManifest(m *manifest.Manifest,
 		vmdkPipeline.Filename = img.Filename
 		art = vmdkPipeline.Export()
 	default:
-		switch img.Compression {
-		case "xz":
-			ostreeCompressed := ostreeCompressedImagePipelines(img, m, buildPipeline)
-			art = ostreeCompressed.Export()
-		case "":
-			ostreeBase := baseRawOstreeImage(img, m, buildPipeline)
+		ostreeBase := baseRawOstreeImage(img, m, buildPipeline)
+		if img.Compression == "" {
 			ostreeBase.Filename = img.Filename
 			art = ostreeBase.Export()
-		default:
-			panic(fmt.Sprintf("unsupported compression type %q on %q", img.Compression, img.name))
+		} else {
+			art = compressionPipeline(m, buildPipeline, ostreeBase, img.Compression, img.CompressionLevel, img.Filename)
 		}
 	}
 
@@ -58,6 +58,9 @@ type OSTreeSimplifiedInstaller struct {
 	IgnitionEmbedded *ignition.EmbeddedOptions
 
 	AdditionalDracutModules []string
+
+	// Kernel options added to the boot menu entries of the installer
+	AdditionalKernelOpts []string
 }
 
 func NewOSTreeSimplifiedInstaller(rawImage *OSTreeRawImage, installDevice string) *OSTreeSimplifiedInstaller {
@@ -127,6 +130,8 @@ func (img *OSTreeSimplifiedInstaller) InstantiateManifest(m *manifest.Manifest,
 		}
 	}
 
+	kernelOpts = append(kernelOpts, img.AdditionalKernelOpts...)
+
 	bootTreePipeline.KernelOpts = kernelOpts
 
 	rootfsPartitionTable := &disk.PartitionTable{
@@ -305,6 +305,7 @@ func (p *AnacondaInstaller) serialize() osbuild.Pipeline {
 			if err != nil {
 				panic("failed to create kickstartstage options for interactive defaults")
 			}
+			kickstartOptions.Bootloader = osbuild.NewKickstartBootloaderOptions(p.InteractiveDefaults.KernelOptionsAppend)
 
 			pipeline.AddStage(osbuild.NewKickstartStage(kickstartOptions))
 		}
@@ -373,6 +374,9 @@ func (p *AnacondaInstaller) GetPlatform() platform.Platform {
 
 type AnacondaInteractiveDefaults struct {
 	TarPath string
+
+	// Kernel options of the installed system
+	KernelOptionsAppend []string
 }
 
 func NewAnacondaInteractiveDefaults(tarPath string) *AnacondaInteractiveDefaults {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path"
 
+	"github.com/osbuild/images/internal/fsnode"
 	"github.com/osbuild/images/internal/users"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/disk"
@@ -25,6 +26,16 @@ type AnacondaInstallerISOTree struct {
 	Users   []users.User
 	Groups  []users.Group
 
+	// Network configuration of the installed system, added to the
+	// kickstart file
+	Network []osbuild.KickstartNetworkOptions
+
+	// Kernel options of the installed system, added to the kickstart file
+	KickstartKernelOptionsAppend []string
+
+	// Installer configuration of the kickstart file
+	Kickstart *Kickstart
+
 	PartitionTable *disk.PartitionTable
 
 	anacondaPipeline *AnacondaInstaller
@@ -281,7 +292,9 @@ func (p *AnacondaInstallerISOTree) serialize() osbuild.Pipeline {
 			panic("failed to create kickstartstage options")
 		}
 
-		pipeline.AddStage(osbuild.NewKickstartStage(kickstartOptions))
+		for _, stage := range p.makeKickstartStages(kickstartOptions) {
+			pipeline.AddStage(stage)
+		}
 	}
 
 	if p.OSPipeline != nil {
@@ -296,7 +309,9 @@ func (p *AnacondaInstallerISOTree) serialize() osbuild.Pipeline {
 				panic("failed to create kickstartstage options")
 			}
 
-			pipeline.AddStage(osbuild.NewKickstartStage(kickstartOptions))
+			for _, stage := range p.makeKickstartStages(kickstartOptions) {
+				pipeline.AddStage(stage)
+			}
 		}
 	}
 
@@ -308,6 +323,52 @@ func (p *AnacondaInstallerISOTree) serialize() osbuild.Pipeline {
 	return pipeline
 }
 
+// makeKickstartStages returns the stages that write the kickstart file with
+// the given options and the options of the pipeline to KSPath. If the
+// kickstart stage does not support the whole Kickstart configuration, the
+// stage writes its file next to KSPath and the file at KSPath includes it.
+func (p *AnacondaInstallerISOTree) makeKickstartStages(options *osbuild.KickstartStageOptions) []*osbuild.Stage {
+	options.Network = p.Network
+	options.Bootloader = osbuild.NewKickstartBootloaderOptions(p.KickstartKernelOptionsAppend)
+	p.Kickstart.applyTo(options)
+
+	includeFile := p.kickstartIncludeFile()
+	if includeFile == nil {
+		return []*osbuild.Stage{osbuild.NewKickstartStage(options)}
+	}
+
+	options.Path = kickstartBasePath(p.KSPath)
+	if p.Kickstart.BootloaderTimeout != nil {
+		// set by the including file
+		options.Bootloader = nil
+	}
+
+	stages := []*osbuild.Stage{osbuild.NewKickstartStage(options)}
+	return append(stages, osbuild.GenFileNodesStages([]*fsnode.File{includeFile})...)
+}
+
+func (p *AnacondaInstallerISOTree) kickstartIncludeFile() *fsnode.File {
+	if p.KSPath == "" {
+		return nil
+	}
+
+	file, err := p.Kickstart.includeFile(p.KSPath, p.KickstartKernelOptionsAppend)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create the kickstart file: %v", err))
+	}
+	return file
+}
+
+func (p *AnacondaInstallerISOTree) getInline() []string {
+	inlineData := []string{}
+
+	if file := p.kickstartIncludeFile(); file != nil {
+		inlineData = append(inlineData, string(file.Data()))
+	}
+
+	return inlineData
+}
+
 // makeISORootPath return a path that can be used to address files and folders
 // in the root of the iso
 func makeISORootPath(p string) string {
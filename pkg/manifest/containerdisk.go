@@ -0,0 +1,87 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/platform"
+)
+
+// Directory of the disk image in a containerDisk and the UID and GID of the
+// qemu user of KubeVirt, which has to be able to read it
+const (
+	containerDiskDir = "/disk"
+	containerDiskUID = 107
+	containerDiskGID = 107
+)
+
+// A ContainerDisk creates the tree of a KubeVirt containerDisk: the qcow2
+// image in /disk, owned by the qemu user of KubeVirt. The tree is wrapped into
+// a container by an OCIContainer pipeline.
+type ContainerDisk struct {
+	Base
+
+	imgPipeline *QCOW2
+}
+
+// NewContainerDisk creates a new ContainerDisk pipeline. imgPipeline is the
+// pipeline producing the qcow2 image.
+func NewContainerDisk(m *Manifest,
+	buildPipeline *Build,
+	imgPipeline *QCOW2) *ContainerDisk {
+	p := &ContainerDisk{
+		Base:        NewBase(m, "containerdisk", buildPipeline),
+		imgPipeline: imgPipeline,
+	}
+	if imgPipeline.Base.manifest != m {
+		panic("qcow2 image pipeline from different manifest")
+	}
+	buildPipeline.addDependent(p)
+	m.addPipeline(p)
+	return p
+}
+
+func (p *ContainerDisk) serialize() osbuild.Pipeline {
+	pipeline := p.Base.serialize()
+
+	pipeline.AddStage(osbuild.NewMkdirStage(&osbuild.MkdirStageOptions{
+		Paths: []osbuild.MkdirStagePath{
+			{
+				Path: containerDiskDir,
+				Mode: common.ToPtr(os.FileMode(0755)),
+			},
+		},
+	}))
+
+	inputName := "image-tree"
+	pipeline.AddStage(osbuild.NewCopyStageSimple(
+		&osbuild.CopyStageOptions{
+			Paths: []osbuild.CopyStagePath{
+				{
+					From: fmt.Sprintf("input://%s/%s", inputName, p.imgPipeline.Filename),
+					To:   fmt.Sprintf("tree://%s", filepath.Join(containerDiskDir, p.imgPipeline.Filename)),
+				},
+			},
+		},
+		osbuild.NewPipelineTreeInputs(inputName, p.imgPipeline.Name()),
+	))
+
+	pipeline.AddStage(osbuild.NewChownStage(&osbuild.ChownStageOptions{
+		Items: map[string]osbuild.ChownStagePathOptions{
+			containerDiskDir: {
+				User:      int64(containerDiskUID),
+				Group:     int64(containerDiskGID),
+				Recursive: true,
+			},
+		},
+	}))
+
+	return pipeline
+}
+
+func (p *ContainerDisk) GetPlatform() platform.Platform {
+	return p.imgPipeline.imgPipeline.treePipeline.GetPlatform()
+}
@@ -0,0 +1,56 @@
+package manifest
+
+import (
+	"github.com/osbuild/images/pkg/artifact"
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+// The Gzip pipeline compresses a raw image file using gzip.
+type Gzip struct {
+	Base
+	Filename string
+
+	// Compression level (1-9), the default of gzip if nil
+	Level *uint
+
+	imgPipeline Pipeline
+}
+
+// NewGzip creates a new Gzip pipeline. imgPipeline is the pipeline producing
+// the raw image that will be gzip compressed.
+func NewGzip(m *Manifest,
+	buildPipeline *Build,
+	imgPipeline Pipeline) *Gzip {
+	p := &Gzip{
+		Base:        NewBase(m, "gzip", buildPipeline),
+		Filename:    "image.gz",
+		imgPipeline: imgPipeline,
+	}
+	buildPipeline.addDependent(p)
+	m.addPipeline(p)
+	return p
+}
+
+func (p *Gzip) serialize() osbuild.Pipeline {
+	pipeline := p.Base.serialize()
+
+	options := osbuild.NewGzipStageOptions(p.Filename)
+	options.Level = p.Level
+
+	pipeline.AddStage(osbuild.NewGzipStage(
+		options,
+		osbuild.NewGzipStageInputs(osbuild.NewFilesInputPipelineObjectRef(p.imgPipeline.Name(), p.imgPipeline.Export().Filename(), nil)),
+	))
+
+	return pipeline
+}
+
+func (p *Gzip) getBuildPackages(Distro) []string {
+	return []string{"gzip"}
+}
+
+func (p *Gzip) Export() *artifact.Artifact {
+	p.Base.export = true
+	mimeType := "application/gzip"
+	return artifact.New(p.Name(), p.Filename, &mimeType)
+}
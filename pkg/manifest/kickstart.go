@@ -0,0 +1,164 @@
+package manifest
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+// Kickstart configures the installer beyond the payload, users, groups and
+// network of the kickstart file.
+type Kickstart struct {
+	// Install without any user interaction: the target disks are
+	// partitioned automatically and defaults are set for all settings that
+	// Anaconda would otherwise ask for
+	Unattended bool
+
+	Lang     string
+	Keyboard string
+	Timezone string
+
+	// Disks the installer is allowed to use, all other disks are ignored
+	TargetDisks []string
+
+	// Partitions removed from the target disks: all, linux or none. Defaults
+	// to all for unattended installations.
+	ClearPart string
+
+	// Seconds the bootloader menu of the installed system waits
+	BootloaderTimeout *int
+
+	// Action after the installation: reboot or poweroff. Unattended
+	// installations reboot by default.
+	Finish string
+
+	PostScripts []KickstartPostScript
+
+	// Raw contents appended to the kickstart file
+	UserContents string
+}
+
+// KickstartPostScript is a %post section of the kickstart file
+type KickstartPostScript struct {
+	Interpreter string
+	NoChroot    bool
+	ErrorOnFail bool
+	Script      string
+}
+
+// applyTo sets the options of the kickstart stage that correspond to the
+// kickstart configuration
+func (ks *Kickstart) applyTo(options *osbuild.KickstartStageOptions) {
+	if ks == nil {
+		return
+	}
+
+	options.Lang = ks.Lang
+	options.Keyboard = ks.Keyboard
+	options.Timezone = ks.Timezone
+
+	clearPart := ks.ClearPart
+	if ks.Unattended {
+		options.DisplayMode = "text"
+		options.RootPassword = &osbuild.KickstartRootPasswordOptions{Lock: true}
+		options.AutoPart = &osbuild.KickstartAutoPartOptions{}
+		if options.Lang == "" {
+			options.Lang = "en_US.UTF-8"
+		}
+		if options.Keyboard == "" {
+			options.Keyboard = "us"
+		}
+		if options.Timezone == "" {
+			options.Timezone = "UTC"
+		}
+		if clearPart == "" {
+			clearPart = "all"
+		}
+	}
+
+	switch clearPart {
+	case "all":
+		options.ZeroMBR = true
+		options.ClearPart = &osbuild.KickstartClearPartOptions{
+			All:       true,
+			InitLabel: true,
+			Drives:    ks.TargetDisks,
+		}
+	case "linux":
+		options.ClearPart = &osbuild.KickstartClearPartOptions{
+			Linux:  true,
+			Drives: ks.TargetDisks,
+		}
+	}
+
+	if ks.Finish == "reboot" || (ks.Finish == "" && ks.Unattended) {
+		options.Reboot = &osbuild.KickstartRebootOptions{Eject: true}
+	}
+}
+
+// kickstartBasePath returns the path of the kickstart file written by the
+// kickstart stage when it is included by another kickstart file at ksPath
+func kickstartBasePath(ksPath string) string {
+	ext := path.Ext(ksPath)
+	return strings.TrimSuffix(ksPath, ext) + "-base" + ext
+}
+
+// includeFile returns the kickstart file at ksPath that includes the file
+// written by the kickstart stage, see kickstartBasePath, and adds all
+// commands and sections the stage does not support. Returns nil if the stage
+// supports the whole configuration. The installation media is mounted at
+// /run/install/repo while Anaconda reads the kickstart files.
+func (ks *Kickstart) includeFile(ksPath string, kernelOptionsAppend []string) (*fsnode.File, error) {
+	if ks == nil {
+		return nil, nil
+	}
+
+	var commands []string
+	if len(ks.TargetDisks) > 0 {
+		commands = append(commands, "ignoredisk --only-use="+strings.Join(ks.TargetDisks, ","))
+	}
+	if ks.BootloaderTimeout != nil {
+		// the bootloader command replaces the one of the included file
+		bootloader := "bootloader"
+		if len(kernelOptionsAppend) > 0 {
+			bootloader += fmt.Sprintf(" --append=\"%s\"", strings.Join(kernelOptionsAppend, " "))
+		}
+		commands = append(commands, fmt.Sprintf("%s --timeout=%d", bootloader, *ks.BootloaderTimeout))
+	}
+	if ks.Finish == "poweroff" {
+		commands = append(commands, "poweroff")
+	}
+
+	if len(commands) == 0 && len(ks.PostScripts) == 0 && ks.UserContents == "" {
+		return nil, nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%%include %s\n", path.Join("/run/install/repo", kickstartBasePath(ksPath)))
+	for _, command := range commands {
+		fmt.Fprintln(&b, command)
+	}
+
+	for _, script := range ks.PostScripts {
+		header := "%post"
+		if script.Interpreter != "" {
+			header += " --interpreter=" + script.Interpreter
+		}
+		if script.NoChroot {
+			header += " --nochroot"
+		}
+		if script.ErrorOnFail {
+			header += " --erroronfail"
+		}
+		fmt.Fprintf(&b, "\n%s\n%s\n%%end\n", header, strings.TrimRight(script.Script, "\n"))
+	}
+
+	if ks.UserContents != "" {
+		fmt.Fprintf(&b, "\n%s\n", strings.TrimRight(ks.UserContents, "\n"))
+	}
+
+	return fsnode.NewFile(ksPath, nil, nil, nil, []byte(b.String()))
+}
@@ -0,0 +1,85 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKickstartApplyTo(t *testing.T) {
+	var options osbuild.KickstartStageOptions
+	var ks *Kickstart
+	ks.applyTo(&options)
+	assert.Equal(t, osbuild.KickstartStageOptions{}, options)
+
+	ks = &Kickstart{
+		Unattended:  true,
+		Timezone:    "Europe/Berlin",
+		TargetDisks: []string{"vda"},
+	}
+	ks.applyTo(&options)
+	assert.Equal(t, osbuild.KickstartStageOptions{
+		Lang:         "en_US.UTF-8",
+		Keyboard:     "us",
+		Timezone:     "Europe/Berlin",
+		DisplayMode:  "text",
+		Reboot:       &osbuild.KickstartRebootOptions{Eject: true},
+		RootPassword: &osbuild.KickstartRootPasswordOptions{Lock: true},
+		ZeroMBR:      true,
+		ClearPart:    &osbuild.KickstartClearPartOptions{All: true, InitLabel: true, Drives: []string{"vda"}},
+		AutoPart:     &osbuild.KickstartAutoPartOptions{},
+	}, options)
+
+	options = osbuild.KickstartStageOptions{}
+	ks = &Kickstart{ClearPart: "linux", Finish: "poweroff"}
+	ks.applyTo(&options)
+	assert.Equal(t, osbuild.KickstartStageOptions{
+		ClearPart: &osbuild.KickstartClearPartOptions{Linux: true},
+	}, options)
+}
+
+func TestKickstartIncludeFile(t *testing.T) {
+	var ks *Kickstart
+	file, err := ks.includeFile("/osbuild.ks", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, file)
+
+	// everything is supported by the kickstart stage
+	ks = &Kickstart{Unattended: true, Finish: "reboot"}
+	file, err = ks.includeFile("/osbuild.ks", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, file)
+
+	ks = &Kickstart{
+		Unattended:        true,
+		TargetDisks:       []string{"vda", "vdb"},
+		BootloaderTimeout: common.ToPtr(0),
+		Finish:            "poweroff",
+		PostScripts: []KickstartPostScript{
+			{Script: "echo hello > /etc/motd\n"},
+			{Interpreter: "/usr/bin/python3", NoChroot: true, ErrorOnFail: true, Script: "print('hello')"},
+		},
+		UserContents: "sshkey --username root \"ssh-ed25519 AAAA\"\n",
+	}
+	file, err = ks.includeFile("/osbuild.ks", []string{"fips=1", "console=ttyS0"})
+	require.NoError(t, err)
+	assert.Equal(t, "/osbuild.ks", file.Path())
+	assert.Equal(t, `%include /run/install/repo/osbuild-base.ks
+ignoredisk --only-use=vda,vdb
+bootloader --append="fips=1 console=ttyS0" --timeout=0
+poweroff
+
+%post
+echo hello > /etc/motd
+%end
+
+%post --interpreter=/usr/bin/python3 --nochroot --erroronfail
+print('hello')
+%end
+
+sshkey --username root "ssh-ed25519 AAAA"
+`, string(file.Data()))
+}
@@ -0,0 +1,195 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// LockFileVersion is the version of the lock file format written by
+// NewLockFile. Lock files of other versions are rejected.
+const LockFileVersion = 1
+
+// A LockFile records the resolved content of a manifest, i.e. the depsolved
+// packages and module states, the resolved containers and the resolved ostree commits of each
+// pipeline, together with the inputs they were resolved from. A manifest can
+// be serialized from a lock file with SerializeLocked to reproduce it later
+// without resolving any content again.
+type LockFile struct {
+	Version   int                       `json:"version"`
+	Pipelines map[string]LockedPipeline `json:"pipelines"`
+}
+
+// LockedPipeline is the resolved content of a single pipeline
+type LockedPipeline struct {
+	// Digest of the package set chain the packages were depsolved from
+	PackageSetChain string              `json:"package_set_chain,omitempty"`
+	Packages        []rpmmd.PackageSpec `json:"packages,omitempty"`
+	Modules         []rpmmd.ModuleState `json:"modules,omitempty"`
+
+	ContainerSources []container.SourceSpec `json:"container_sources,omitempty"`
+	Containers       []container.Spec       `json:"containers,omitempty"`
+
+	OSTreeSources []ostree.SourceSpec `json:"ostree_sources,omitempty"`
+	OSTreeCommits []ostree.CommitSpec `json:"ostree_commits,omitempty"`
+}
+
+// NewLockFile returns the lock file of the manifest with the given resolved
+// content, which must cover all package set chains, container sources and
+// ostree sources of the manifest. The module states are optional, see
+// Manifest.SerializeWithModules.
+func NewLockFile(m Manifest, packageSets map[string][]rpmmd.PackageSpec, moduleStates map[string][]rpmmd.ModuleState, containerSpecs map[string][]container.Spec, ostreeCommits map[string][]ostree.CommitSpec) (*LockFile, error) {
+	lock := &LockFile{
+		Version:   LockFileVersion,
+		Pipelines: make(map[string]LockedPipeline),
+	}
+
+	for name, chain := range m.GetPackageSetChains() {
+		packages, ok := packageSets[name]
+		if !ok {
+			return nil, fmt.Errorf("packages of pipeline %q are not depsolved", name)
+		}
+		pl := lock.Pipelines[name]
+		pl.PackageSetChain = packageSetChainDigest(chain)
+		pl.Packages = packages
+		pl.Modules = moduleStates[name]
+		lock.Pipelines[name] = pl
+	}
+
+	for name, sources := range m.GetContainerSourceSpecs() {
+		specs, ok := containerSpecs[name]
+		if !ok {
+			return nil, fmt.Errorf("containers of pipeline %q are not resolved", name)
+		}
+		pl := lock.Pipelines[name]
+		pl.ContainerSources = sources
+		pl.Containers = specs
+		lock.Pipelines[name] = pl
+	}
+
+	for name, sources := range m.GetOSTreeSourceSpecs() {
+		commits, ok := ostreeCommits[name]
+		if !ok {
+			return nil, fmt.Errorf("ostree commits of pipeline %q are not resolved", name)
+		}
+		pl := lock.Pipelines[name]
+		pl.OSTreeSources = sources
+		pl.OSTreeCommits = commits
+		lock.Pipelines[name] = pl
+	}
+
+	return lock, nil
+}
+
+// ReadLockFile reads a lock file and checks its version
+func ReadLockFile(r io.Reader) (*LockFile, error) {
+	var lock LockFile
+	if err := json.NewDecoder(r).Decode(&lock); err != nil {
+		return nil, fmt.Errorf("cannot decode lock file: %w", err)
+	}
+	if lock.Version != LockFileVersion {
+		return nil, fmt.Errorf("unsupported lock file version %d (supported: %d)", lock.Version, LockFileVersion)
+	}
+	return &lock, nil
+}
+
+// SerializeLocked serializes the manifest with the content of the lock file
+// instead of newly resolved content. Returns an error if any package set
+// chain, container source or ostree source of the manifest differs from the
+// one recorded in the lock file, e.g. because the blueprint or the image type
+// changed since the lock file was written.
+func (m Manifest) SerializeLocked(lock *LockFile) (OSBuildManifest, error) {
+	if lock.Version != LockFileVersion {
+		return nil, fmt.Errorf("unsupported lock file version %d (supported: %d)", lock.Version, LockFileVersion)
+	}
+
+	packageSets := make(map[string][]rpmmd.PackageSpec)
+	moduleStates := make(map[string][]rpmmd.ModuleState)
+	containerSpecs := make(map[string][]container.Spec)
+	ostreeCommits := make(map[string][]ostree.CommitSpec)
+
+	chains := m.GetPackageSetChains()
+	for _, name := range sortedKeys(chains) {
+		pl, ok := lock.Pipelines[name]
+		if !ok || pl.PackageSetChain == "" {
+			return nil, fmt.Errorf("lock file does not cover the packages of pipeline %q", name)
+		}
+		if pl.PackageSetChain != packageSetChainDigest(chains[name]) {
+			return nil, fmt.Errorf("lock file does not cover the packages of pipeline %q: the package sets changed since the lock file was written", name)
+		}
+		packageSets[name] = pl.Packages
+		moduleStates[name] = pl.Modules
+	}
+
+	containerSources := m.GetContainerSourceSpecs()
+	for _, name := range sortedKeys(containerSources) {
+		pl := lock.Pipelines[name]
+		if !reflect.DeepEqual(pl.ContainerSources, containerSources[name]) {
+			return nil, fmt.Errorf("lock file does not cover the containers of pipeline %q", name)
+		}
+		containerSpecs[name] = pl.Containers
+	}
+
+	ostreeSources := m.GetOSTreeSourceSpecs()
+	for _, name := range sortedKeys(ostreeSources) {
+		pl := lock.Pipelines[name]
+		if !reflect.DeepEqual(pl.OSTreeSources, ostreeSources[name]) {
+			return nil, fmt.Errorf("lock file does not cover the ostree commits of pipeline %q", name)
+		}
+		ostreeCommits[name] = pl.OSTreeCommits
+	}
+
+	return m.SerializeWithModules(packageSets, moduleStates, containerSpecs, ostreeCommits)
+}
+
+// packageSetChainDigest returns a digest of everything that affects the
+// depsolve of a package set chain. Repositories are represented by their
+// hash, so that the lock file does not depend on their names.
+func packageSetChainDigest(chain []rpmmd.PackageSet) string {
+	type lockedPackageSet struct {
+		Include         []string
+		Exclude         []string
+		Repositories    []string
+		InstallWeakDeps bool
+		Pins            []rpmmd.PackagePin
+		EnabledModules  []rpmmd.ModuleSpec
+	}
+
+	sets := make([]lockedPackageSet, len(chain))
+	for idx, ps := range chain {
+		repos := make([]string, len(ps.Repositories))
+		for ridx, repo := range ps.Repositories {
+			repos[ridx] = repo.Hash()
+		}
+		sets[idx] = lockedPackageSet{
+			Include:         ps.Include,
+			Exclude:         ps.Exclude,
+			Repositories:    repos,
+			InstallWeakDeps: ps.InstallWeakDeps,
+			Pins:            ps.Pins,
+			EnabledModules:  ps.EnabledModules,
+		}
+	}
+
+	data, err := json.Marshal(sets)
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,126 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/osbuild/images/internal/workload"
+	"github.com/osbuild/images/pkg/platform"
+	"github.com/osbuild/images/pkg/rpmmd"
+	"github.com/osbuild/images/pkg/runner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLockManifest(extraPackages ...string) (Manifest, *OS) {
+	repos := []rpmmd.RepoConfig{{Name: "fedora", BaseURLs: []string{"https://example.com/fedora"}}}
+	m := New()
+	build := NewBuild(&m, &runner.Fedora{Version: 37}, repos)
+	os := NewOS(&m, build, &platform.X86{BIOS: true}, repos)
+	os.ExtraBasePackages = extraPackages
+	return m, os
+}
+
+func testLockPackageSets(m Manifest) map[string][]rpmmd.PackageSpec {
+	packageSets := make(map[string][]rpmmd.PackageSpec)
+	for name := range m.GetPackageSetChains() {
+		packageSets[name] = []rpmmd.PackageSpec{
+			{Name: "pkg1", Version: "1.0", Release: "1.fc37", Arch: "x86_64", Checksum: "sha256:c02524e2bd19490f2a7167958f792262754c5f46"},
+		}
+	}
+	return packageSets
+}
+
+func TestLockFile(t *testing.T) {
+	m, _ := newTestLockManifest("tmux")
+	packageSets := testLockPackageSets(m)
+
+	expected, err := m.Serialize(packageSets, nil, nil)
+	require.NoError(t, err)
+
+	lock, err := NewLockFile(m, packageSets, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, LockFileVersion, lock.Version)
+	assert.Contains(t, lock.Pipelines, "build")
+	assert.Contains(t, lock.Pipelines, "os")
+
+	// round trip through the json representation
+	data, err := json.Marshal(lock)
+	require.NoError(t, err)
+	lock, err = ReadLockFile(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// the same manifest serialized from the lock file
+	m, _ = newTestLockManifest("tmux")
+	mf, err := m.SerializeLocked(lock)
+	require.NoError(t, err)
+	assert.Equal(t, expected, mf)
+
+	// a changed package set is not covered by the lock file
+	m, _ = newTestLockManifest("tmux", "vim")
+	_, err = m.SerializeLocked(lock)
+	assert.EqualError(t, err, `lock file does not cover the packages of pipeline "os": the package sets changed since the lock file was written`)
+
+	// a changed repository is not covered either
+	m, os := newTestLockManifest("tmux")
+	os.repos[0].BaseURLs = []string{"https://example.com/rawhide"}
+	_, err = m.SerializeLocked(lock)
+	assert.EqualError(t, err, `lock file does not cover the packages of pipeline "os": the package sets changed since the lock file was written`)
+
+	delete(lock.Pipelines, "build")
+	m, _ = newTestLockManifest("tmux")
+	_, err = m.SerializeLocked(lock)
+	assert.EqualError(t, err, `lock file does not cover the packages of pipeline "build"`)
+}
+
+func TestLockFileModules(t *testing.T) {
+	newManifest := func() Manifest {
+		m, os := newTestLockManifest("@nodejs:18")
+		os.Workload = &workload.Custom{
+			Packages:       []string{"@nodejs:18"},
+			EnabledModules: []rpmmd.ModuleSpec{{Name: "nodejs", Stream: "18"}},
+		}
+		return m
+	}
+	m := newManifest()
+	packageSets := testLockPackageSets(m)
+	moduleStates := map[string][]rpmmd.ModuleState{
+		"os": {{Name: "nodejs", Stream: "18", Profiles: []string{"default"}}},
+	}
+
+	expected, err := m.SerializeWithModules(packageSets, moduleStates, nil, nil)
+	require.NoError(t, err)
+	withoutStates, err := m.Serialize(packageSets, nil, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, withoutStates, expected)
+
+	lock, err := NewLockFile(m, packageSets, moduleStates, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, moduleStates["os"], lock.Pipelines["os"].Modules)
+
+	data, err := json.Marshal(lock)
+	require.NoError(t, err)
+	lock, err = ReadLockFile(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// the module states are reproduced from the lock file
+	mf, err := newManifest().SerializeLocked(lock)
+	require.NoError(t, err)
+	assert.Equal(t, expected, mf)
+}
+
+func TestLockFileErrors(t *testing.T) {
+	m, _ := newTestLockManifest()
+	packageSets := testLockPackageSets(m)
+	delete(packageSets, "os")
+	_, err := NewLockFile(m, packageSets, nil, nil, nil)
+	assert.EqualError(t, err, `packages of pipeline "os" are not depsolved`)
+
+	_, err = ReadLockFile(bytes.NewReader([]byte(`{"version": 2, "pipelines": {}}`)))
+	assert.EqualError(t, err, "unsupported lock file version 2 (supported: 1)")
+
+	_, err = m.SerializeLocked(&LockFile{})
+	assert.EqualError(t, err, "unsupported lock file version 0 (supported: 1)")
+}
@@ -130,13 +130,35 @@ func (m Manifest) GetOSTreeSourceSpecs() map[string][]ostree.SourceSpec {
 }
 
 func (m Manifest) Serialize(packageSets map[string][]rpmmd.PackageSpec, containerSpecs map[string][]container.Spec, ostreeCommits map[string][]ostree.CommitSpec) (OSBuildManifest, error) {
+	return m.SerializeWithModules(packageSets, nil, containerSpecs, ostreeCommits)
+}
+
+// moduleStatePipeline is implemented by pipelines that record the state of
+// the module streams they enable in their tree
+type moduleStatePipeline interface {
+	setModuleStates([]rpmmd.ModuleState)
+}
+
+// SerializeWithModules serializes the manifest like Serialize and records the
+// module states that were reported when the packages of each pipeline were
+// depsolved, see dnfjson.Solver.DepsolveModules. Pipelines without reported
+// module states record the module streams they enabled instead.
+func (m Manifest) SerializeWithModules(packageSets map[string][]rpmmd.PackageSpec, moduleStates map[string][]rpmmd.ModuleState, containerSpecs map[string][]container.Spec, ostreeCommits map[string][]ostree.CommitSpec) (OSBuildManifest, error) {
 	pipelines := make([]osbuild.Pipeline, 0)
 	packages := make([]rpmmd.PackageSpec, 0)
 	commits := make([]ostree.CommitSpec, 0)
 	inline := make([]string, 0)
 	containers := make([]container.Spec, 0)
+	for _, pipeline := range m.pipelines {
+		if err := pipeline.checkPackageSpecs(packageSets[pipeline.Name()]); err != nil {
+			return nil, err
+		}
+	}
 	for _, pipeline := range m.pipelines {
 		pipeline.serializeStart(packageSets[pipeline.Name()], containerSpecs[pipeline.Name()], ostreeCommits[pipeline.Name()])
+		if p, ok := pipeline.(moduleStatePipeline); ok {
+			p.setModuleStates(moduleStates[pipeline.Name()])
+		}
 	}
 	for _, pipeline := range m.pipelines {
 		commits = append(commits, pipeline.getOSTreeCommits()...)
@@ -76,6 +76,7 @@ type OSCustomizations struct {
 	Timezone         string
 	EnabledServices  []string
 	DisabledServices []string
+	MaskedServices   []string
 	DefaultTarget    string
 
 	// SELinux policy, when set it enables the labeling of the tree with the
@@ -129,6 +130,17 @@ type OSCustomizations struct {
 	// Custom directories and files to create in the image
 	Directories []*fsnode.Directory
 	Files       []*fsnode.File
+
+	// Trust anchors to add to the system CA trust store. When set, the
+	// consolidated trust store is regenerated after the anchors are copied.
+	CACerts []*fsnode.File
+
+	// Enable FIPS mode: adds the dracut fips module to the initramfs, sets
+	// the FIPS crypto policy and passes fips=1 to the kernel.
+	FIPS bool
+
+	// Resume from hibernation on the swap area of the partition table
+	Resume bool
 }
 
 // OS represents the filesystem tree of the target image. This roughly
@@ -154,6 +166,7 @@ type OS struct {
 	packageSpecs     []rpmmd.PackageSpec
 	containerSpecs   []container.Spec
 	ostreeParentSpec *ostree.CommitSpec
+	moduleStates     []rpmmd.ModuleState
 
 	platform  platform.Platform
 	kernelVer string
@@ -206,6 +219,14 @@ func (p *OS) getPackageSetChain(Distro) []rpmmd.PackageSet {
 		packages = append(packages, "chrony")
 	}
 
+	if len(p.CACerts) > 0 {
+		packages = append(packages, "ca-certificates")
+	}
+
+	if p.FIPS {
+		packages = append(packages, "crypto-policies-scripts")
+	}
+
 	if p.SElinux != "" {
 		packages = append(packages, fmt.Sprintf("selinux-policy-%s", p.SElinux))
 	}
@@ -228,21 +249,37 @@ func (p *OS) getPackageSetChain(Distro) []rpmmd.PackageSet {
 
 	osRepos := append(p.repos, p.ExtraBaseRepos...)
 
-	chain := []rpmmd.PackageSet{
-		{
-			Include:         append(packages, p.ExtraBasePackages...),
-			Exclude:         p.ExcludeBasePackages,
-			Repositories:    osRepos,
-			InstallWeakDeps: true,
-		},
+	basePackageSet := rpmmd.PackageSet{
+		Include:         append(packages, p.ExtraBasePackages...),
+		Exclude:         p.ExcludeBasePackages,
+		Repositories:    osRepos,
+		InstallWeakDeps: true,
 	}
 
+	var workloadExclude []string
+	var workloadModules []rpmmd.ModuleSpec
+	if p.Workload != nil {
+		// the user excludes, pins and module streams apply to the base
+		// packages as well
+		workloadExclude = p.Workload.GetExcludePackages()
+		if len(workloadExclude) > 0 {
+			basePackageSet.Exclude = append(append([]string{}, p.ExcludeBasePackages...), workloadExclude...)
+		}
+		basePackageSet = basePackageSet.ApplyPins(p.Workload.GetPackagePins())
+		workloadModules = p.Workload.GetEnabledModules()
+		basePackageSet.EnabledModules = workloadModules
+	}
+
+	chain := []rpmmd.PackageSet{basePackageSet}
+
 	if p.Workload != nil {
 		workloadPackages := p.Workload.GetPackages()
 		if len(workloadPackages) > 0 {
 			chain = append(chain, rpmmd.PackageSet{
-				Include:      workloadPackages,
-				Repositories: append(osRepos, p.Workload.GetRepos()...),
+				Include:        workloadPackages,
+				Exclude:        workloadExclude,
+				Repositories:   append(osRepos, p.Workload.GetRepos()...),
+				EnabledModules: workloadModules,
 			})
 		}
 	}
@@ -337,6 +374,18 @@ func (p *OS) serializeStart(packages []rpmmd.PackageSpec, containers []container
 	}
 }
 
+func (p *OS) checkPackageSpecs(packages []rpmmd.PackageSpec) error {
+	// the tree is labelled with the file contexts of the SELinux policy, which
+	// must be part of the depsolved packages
+	if p.SElinux != "" {
+		policyPackage := fmt.Sprintf("selinux-policy-%s", p.SElinux)
+		if _, err := rpmmd.GetVerStrFromPackageSpecList(packages, policyPackage); err != nil {
+			return fmt.Errorf("SELinux policy %q of pipeline %q requires package %q, which is not part of the depsolved packages", p.SElinux, p.Name(), policyPackage)
+		}
+	}
+	return nil
+}
+
 func (p *OS) serializeEnd() {
 	if len(p.packageSpecs) == 0 {
 		panic("serializeEnd() call when serialization not in progress")
@@ -345,6 +394,11 @@ func (p *OS) serializeEnd() {
 	p.packageSpecs = nil
 	p.containerSpecs = nil
 	p.ostreeParentSpec = nil
+	p.moduleStates = nil
+}
+
+func (p *OS) setModuleStates(modules []rpmmd.ModuleState) {
+	p.moduleStates = modules
 }
 
 func (p *OS) serialize() osbuild.Pipeline {
@@ -358,6 +412,21 @@ func (p *OS) serialize() osbuild.Pipeline {
 		pipeline.AddStage(osbuild.NewOSTreePasswdStage("org.osbuild.source", p.ostreeParentSpec.Checksum))
 	}
 
+	// the initramfs is generated when the kernel is installed by the RPM
+	// stage, its dracut configuration must be in place before
+	for _, dracutConfConfig := range p.DracutConf {
+		pipeline.AddStage(osbuild.NewDracutConfStage(dracutConfConfig))
+	}
+
+	if p.FIPS {
+		pipeline.AddStage(osbuild.NewDracutConfStage(&osbuild.DracutConfStageOptions{
+			Filename: "40-fips.conf",
+			Config: osbuild.DracutConfigFile{
+				AddModules: []string{"fips"},
+			},
+		}))
+	}
+
 	// collect all repos for this pipeline to create the repository options
 	allRepos := append(p.repos, p.ExtraBaseRepos...)
 	if p.Workload != nil {
@@ -435,20 +504,20 @@ func (p *OS) serialize() osbuild.Pipeline {
 		pipeline.AddStage(osbuild.GenGroupsStage(p.Groups))
 	}
 
-	if len(p.Users) > 0 {
+	if allUsers := p.getUsers(); len(allUsers) > 0 {
 		if p.OSTreeRef != "" {
 			// for ostree, writing the key during user creation is
 			// redundant and can cause issues so create users without keys
 			// and write them on first boot
-			usersStageSansKeys, err := osbuild.GenUsersStage(p.Users, true)
+			usersStageSansKeys, err := osbuild.GenUsersStage(allUsers, true)
 			if err != nil {
 				// TODO: move encryption into weldr
 				panic("password encryption failed")
 			}
 			pipeline.AddStage(usersStageSansKeys)
-			pipeline.AddStage(osbuild.NewFirstBootStage(usersFirstBootOptions(p.Users)))
+			pipeline.AddStage(osbuild.NewFirstBootStage(usersFirstBootOptions(allUsers)))
 		} else {
-			usersStage, err := osbuild.GenUsersStage(p.Users, false)
+			usersStage, err := osbuild.GenUsersStage(allUsers, false)
 			if err != nil {
 				// TODO: move encryption into weldr
 				panic("password encryption failed")
@@ -477,10 +546,6 @@ func (p *OS) serialize() osbuild.Pipeline {
 		pipeline.AddStage(osbuild.NewModprobeStage(modprobeConfig))
 	}
 
-	for _, dracutConfConfig := range p.DracutConf {
-		pipeline.AddStage(osbuild.NewDracutConfStage(dracutConfConfig))
-	}
-
 	for _, systemdUnitConfig := range p.SystemdUnit {
 		pipeline.AddStage(osbuild.NewSystemdUnitStage(systemdUnitConfig))
 	}
@@ -493,6 +558,12 @@ func (p *OS) serialize() osbuild.Pipeline {
 		pipeline.AddStage(osbuild.NewSELinuxConfigStage(p.SELinuxConfig))
 	}
 
+	if p.FIPS {
+		pipeline.AddStage(osbuild.NewUpdateCryptoPoliciesStage(&osbuild.UpdateCryptoPoliciesStageOptions{
+			Policy: "FIPS",
+		}))
+	}
+
 	if p.Tuned != nil {
 		pipeline.AddStage(osbuild.NewTunedStage(p.Tuned))
 	}
@@ -529,6 +600,12 @@ func (p *OS) serialize() osbuild.Pipeline {
 		pipeline.AddStage(osbuild.NewGcpGuestAgentConfigStage(p.GCPGuestAgentConfig))
 	}
 
+	if p.Environment != nil {
+		if sshdConfig := p.Environment.GetSshdConfig(); sshdConfig != nil {
+			pipeline.AddStage(osbuild.NewSshdConfigStage(sshdConfig))
+		}
+	}
+
 	if p.SshdConfig != nil {
 		pipeline.AddStage((osbuild.NewSshdConfigStage(p.SshdConfig)))
 	}
@@ -589,6 +666,12 @@ func (p *OS) serialize() osbuild.Pipeline {
 
 	if pt := p.PartitionTable; pt != nil {
 		kernelOptions := osbuild.GenImageKernelOptions(p.PartitionTable)
+		if p.FIPS {
+			kernelOptions = append(kernelOptions, osbuild.GenFIPSKernelOptions(p.PartitionTable)...)
+		}
+		if p.Resume {
+			kernelOptions = append(kernelOptions, osbuild.GenResumeKernelOptions(p.PartitionTable)...)
+		}
 		kernelOptions = append(kernelOptions, p.KernelOptionsAppend...)
 		if !p.KernelOptionsBootloader {
 			pipeline = prependKernelCmdlineStage(pipeline, strings.Join(kernelOptions, " "), pt)
@@ -597,9 +680,13 @@ func (p *OS) serialize() osbuild.Pipeline {
 		pipeline.AddStage(osbuild.NewFSTabStage(osbuild.NewFSTabStageOptions(pt)))
 
 		var bootloader *osbuild.Stage
-		switch p.platform.GetArch() {
-		case platform.ARCH_S390X:
+		switch {
+		case p.platform.GetArch() == platform.ARCH_S390X:
 			bootloader = osbuild.NewZiplStage(new(osbuild.ZiplStageOptions))
+		case p.platform.GetBootloader() == platform.BOOTLOADER_UKI:
+			// systemd-boot is installed when the tree is copied to the
+			// image, it has no stage of its own
+			pipeline.AddStages(p.ukiStages(pt, kernelOptions)...)
 		default:
 			if p.NoBLS {
 				// BLS entries not supported: use grub2.legacy
@@ -649,7 +736,9 @@ func (p *OS) serialize() osbuild.Pipeline {
 			}
 		}
 
-		pipeline.AddStage(bootloader)
+		if bootloader != nil {
+			pipeline.AddStage(bootloader)
+		}
 	}
 
 	if p.OpenSCAPConfig != nil {
@@ -685,6 +774,24 @@ func (p *OS) serialize() osbuild.Pipeline {
 		pipeline.AddStages(osbuild.GenFileNodesStages(p.Files)...)
 	}
 
+	if envFiles := p.getEnvironmentFiles(); len(envFiles) > 0 {
+		pipeline.AddStages(osbuild.GenFileNodesStages(envFiles)...)
+	}
+
+	if moduleFiles := p.moduleStateFiles(); len(moduleFiles) > 0 {
+		moduleDir, err := fsnode.NewDirectory(moduleStateDir, nil, nil, nil, true)
+		if err != nil {
+			panic(err)
+		}
+		pipeline.AddStages(osbuild.GenDirectoryNodesStages([]*fsnode.Directory{moduleDir})...)
+		pipeline.AddStages(osbuild.GenFileNodesStages(moduleFiles)...)
+	}
+
+	if len(p.CACerts) > 0 {
+		pipeline.AddStages(osbuild.GenFileNodesStages(p.CACerts)...)
+		pipeline.AddStage(osbuild.NewUpdateCATrustStage())
+	}
+
 	enabledServices := []string{}
 	disabledServices := []string{}
 	enabledServices = append(enabledServices, p.EnabledServices...)
@@ -696,11 +803,12 @@ func (p *OS) serialize() osbuild.Pipeline {
 		enabledServices = append(enabledServices, p.Workload.GetServices()...)
 		disabledServices = append(disabledServices, p.Workload.GetDisabledServices()...)
 	}
-	if len(enabledServices) != 0 ||
-		len(disabledServices) != 0 || p.DefaultTarget != "" {
+	if len(enabledServices) != 0 || len(disabledServices) != 0 ||
+		len(p.MaskedServices) != 0 || p.DefaultTarget != "" {
 		pipeline.AddStage(osbuild.NewSystemdStage(&osbuild.SystemdStageOptions{
 			EnabledServices:  enabledServices,
 			DisabledServices: disabledServices,
+			MaskedServices:   p.MaskedServices,
 			DefaultTarget:    p.DefaultTarget,
 		}))
 	}
@@ -731,6 +839,110 @@ func (p *OS) serialize() osbuild.Pipeline {
 	return pipeline
 }
 
+// ukiStages returns the stages that build the unified kernel image (UKI),
+// with the kernel command line embedded, and prepare the ESP for systemd-boot.
+// dracut writes the UKI to /boot/initramfs-<kver>.img, it is copied to the
+// ESP or the XBOOTLDR partition together with systemd-boot when the tree is
+// copied to the image (see ukiCopyPaths()).
+func (p *OS) ukiStages(pt *disk.PartitionTable, kernelOptions []string) []*osbuild.Stage {
+	if p.platform.GetBIOSPlatform() != "" || pt.FindMountable("/boot/efi") == nil {
+		panic("unified kernel images require UEFI and an ESP mounted at /boot/efi, this is a programming error")
+	}
+	rootFs := pt.FindMountable("/")
+	if rootFs == nil {
+		panic("root filesystem must be defined for unified kernel images, this is a programming error")
+	}
+
+	// there is no boot loader configuration that passes the root file
+	// system to the kernel
+	cmdline := append([]string{"root=UUID=" + rootFs.GetFSSpec().UUID}, kernelOptions...)
+	stages := []*osbuild.Stage{
+		osbuild.NewDracutStage(&osbuild.DracutStageOptions{
+			Kernel: []string{p.kernelVer},
+			Extra:  []string{"--uefi", "--kernel-cmdline", strings.Join(cmdline, " ")},
+		}),
+	}
+
+	dirs, files := p.ukiFileNodes()
+	stages = append(stages, osbuild.GenDirectoryNodesStages(dirs)...)
+	stages = append(stages, osbuild.GenFileNodesStages(files)...)
+	return stages
+}
+
+// ukiFileNodes returns the directories the UKI and systemd-boot are copied to
+// and the loader.conf of systemd-boot, which boots the UKI instead of the BLS
+// entry of the kernel package by default
+func (p *OS) ukiFileNodes() ([]*fsnode.Directory, []*fsnode.File) {
+	var dirs []*fsnode.Directory
+	for _, path := range []string{ukiDir(p.PartitionTable), "/boot/efi/EFI/BOOT", "/boot/efi/EFI/systemd", "/boot/efi/loader"} {
+		dir, err := fsnode.NewDirectory(path, nil, nil, nil, true)
+		if err != nil {
+			panic(err)
+		}
+		dirs = append(dirs, dir)
+	}
+
+	loaderConf, err := fsnode.NewFile("/boot/efi/loader/loader.conf", nil, nil, nil, []byte(fmt.Sprintf("default %s.efi\n", p.kernelVer)))
+	if err != nil {
+		panic(err)
+	}
+	return dirs, []*fsnode.File{loaderConf}
+}
+
+// ukiCopyPaths returns the paths of the org.osbuild.copy stage that copies
+// the UKI and systemd-boot from the tree, the input with the given name, to
+// the mounted file systems of the image
+func (p *OS) ukiCopyPaths(inputName string) []osbuild.CopyStagePath {
+	var efiArch string
+	switch p.platform.GetArch() {
+	case platform.ARCH_X86_64:
+		efiArch = "x64"
+	case platform.ARCH_AARCH64:
+		efiArch = "aa64"
+	default:
+		panic(fmt.Sprintf("unified kernel images are not supported on %s, this is a programming error", p.platform.GetArch()))
+	}
+
+	systemdBoot := fmt.Sprintf("input://%s/usr/lib/systemd/boot/efi/systemd-boot%s.efi", inputName, efiArch)
+	return []osbuild.CopyStagePath{
+		{
+			From: fmt.Sprintf("input://%s/boot/initramfs-%s.img", inputName, p.kernelVer),
+			To:   fmt.Sprintf("mount://root%s/%s.efi", ukiDir(p.PartitionTable), p.kernelVer),
+		},
+		{
+			From: systemdBoot,
+			To:   fmt.Sprintf("mount://root/boot/efi/EFI/systemd/systemd-boot%s.efi", efiArch),
+		},
+		{
+			// fallback boot loader path of removable media
+			From: systemdBoot,
+			To:   fmt.Sprintf("mount://root/boot/efi/EFI/BOOT/BOOT%s.EFI", strings.ToUpper(efiArch)),
+		},
+	}
+}
+
+// ukiDir returns the directory systemd-boot finds the UKIs in: /EFI/Linux on
+// the XBOOTLDR partition if /boot is one and on the ESP otherwise
+func ukiDir(pt *disk.PartitionTable) string {
+	if hasXBootLDR(pt) {
+		return "/boot/EFI/Linux"
+	}
+	return "/boot/efi/EFI/Linux"
+}
+
+// hasXBootLDR returns true if /boot is on an Extended Boot Loader partition
+func hasXBootLDR(pt *disk.PartitionTable) bool {
+	for _, part := range pt.Partitions {
+		if part.Type != disk.XBootLDRPartitionGUID {
+			continue
+		}
+		if fs, ok := part.Payload.(disk.Mountable); ok && fs.GetMountpoint() == "/boot" {
+			return true
+		}
+	}
+	return false
+}
+
 func prependKernelCmdlineStage(pipeline osbuild.Pipeline, kernelOptions string, pt *disk.PartitionTable) osbuild.Pipeline {
 	rootFs := pt.FindMountable("/")
 	if rootFs == nil {
@@ -789,5 +1001,93 @@ func (p *OS) getInline() []string {
 		inlineData = append(inlineData, string(file.Data()))
 	}
 
+	// inline data for the files of the environment
+	for _, file := range p.getEnvironmentFiles() {
+		inlineData = append(inlineData, string(file.Data()))
+	}
+
+	// inline data for CA trust anchors
+	for _, file := range p.CACerts {
+		inlineData = append(inlineData, string(file.Data()))
+	}
+
+	// inline data for the module states
+	for _, file := range p.moduleStateFiles() {
+		inlineData = append(inlineData, string(file.Data()))
+	}
+
+	// inline data for the systemd-boot configuration
+	if p.PartitionTable != nil && p.platform.GetBootloader() == platform.BOOTLOADER_UKI {
+		_, files := p.ukiFileNodes()
+		for _, file := range files {
+			inlineData = append(inlineData, string(file.Data()))
+		}
+	}
+
 	return inlineData
 }
+
+// directory of the DNF module state files
+const moduleStateDir = "/etc/dnf/modules.d"
+
+// getUsers returns the users of the environment followed by the custom users,
+// which take precedence over users of the environment with the same name
+func (p *OS) getUsers() []users.User {
+	var allUsers []users.User
+	if p.Environment != nil {
+		allUsers = append(allUsers, p.Environment.GetUsers()...)
+	}
+	return append(allUsers, p.Users...)
+}
+
+// getEnvironmentFiles returns the files the environment adds to the tree
+func (p *OS) getEnvironmentFiles() []*fsnode.File {
+	if p.Environment == nil {
+		return nil
+	}
+	return p.Environment.GetFiles()
+}
+
+// moduleStateFiles returns the DNF module state files that record the
+// enabled module streams of the workload, like "dnf module install" does.
+// The module states reported by the depsolve are recorded if there are any,
+// otherwise the enabled module streams with the profiles that were selected
+// explicitly. Every module is recorded once.
+func (p *OS) moduleStateFiles() []*fsnode.File {
+	modules := p.moduleStates
+	if len(modules) == 0 && p.Workload != nil {
+		modules = mergeModuleStates(p.Workload.GetEnabledModules())
+	}
+
+	var files []*fsnode.File
+	for _, module := range modules {
+		data := fmt.Sprintf("[%[1]s]\nname=%[1]s\nstream=%[2]s\nprofiles=%[3]s\nstate=enabled\n", module.Name, module.Stream, strings.Join(module.Profiles, ","))
+		file, err := fsnode.NewFile(filepath.Join(moduleStateDir, module.Name+".module"), nil, nil, nil, []byte(data))
+		if err != nil {
+			panic(err)
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// mergeModuleStates returns the state of each module of the module specs
+// with the profiles of all specs of the module
+func mergeModuleStates(specs []rpmmd.ModuleSpec) []rpmmd.ModuleState {
+	var modules []rpmmd.ModuleState
+	idx := make(map[string]int)
+	profiles := make(map[string]bool)
+	for _, spec := range specs {
+		i, ok := idx[spec.Name]
+		if !ok {
+			i = len(modules)
+			idx[spec.Name] = i
+			modules = append(modules, rpmmd.ModuleState{Name: spec.Name, Stream: spec.Stream})
+		}
+		if spec.Profile != "" && !profiles[spec.Name+"/"+spec.Profile] {
+			profiles[spec.Name+"/"+spec.Profile] = true
+			modules[i].Profiles = append(modules[i].Profiles, spec.Profile)
+		}
+	}
+	return modules
+}
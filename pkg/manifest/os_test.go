@@ -3,6 +3,10 @@ package manifest
 import (
 	"testing"
 
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/internal/workload"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -152,3 +156,296 @@ func TestRhcInsightsPackages(t *testing.T) {
 	}
 	CheckPkgSetInclude(t, os.getPackageSetChain(DISTRO_NULL), []string{"rhc", "subscription-manager", "insights-client"})
 }
+
+func TestCACertsStages(t *testing.T) {
+	os := NewTestOS()
+	anchor, err := fsnode.NewFile("/etc/pki/ca-trust/source/anchors/test.pem", nil, nil, nil, []byte("test"))
+	require.NoError(t, err)
+	os.CACerts = []*fsnode.File{anchor}
+
+	CheckPkgSetInclude(t, os.getPackageSetChain(DISTRO_NULL), []string{"ca-certificates"})
+
+	pipeline := os.serialize()
+	copyIdx := -1
+	updateIdx := -1
+	for idx, s := range pipeline.Stages {
+		switch s.Type {
+		case "org.osbuild.copy":
+			copyIdx = idx
+		case "org.osbuild.pki.update-ca-trust":
+			updateIdx = idx
+		}
+	}
+	require.NotEqual(t, -1, copyIdx)
+	require.NotEqual(t, -1, updateIdx)
+	assert.Less(t, copyIdx, updateIdx)
+	assert.Contains(t, os.getInline(), "test")
+}
+
+func TestSELinuxPolicyPackage(t *testing.T) {
+	repos := []rpmmd.RepoConfig{}
+	manifest := New()
+	runner := &runner.Fedora{Version: 37}
+	build := NewBuild(&manifest, runner, repos)
+	os := NewOS(&manifest, build, &platform.X86{BIOS: true}, repos)
+	os.SElinux = "mls"
+
+	CheckPkgSetInclude(t, os.getPackageSetChain(DISTRO_NULL), []string{"selinux-policy-mls"})
+
+	packages := []rpmmd.PackageSpec{
+		{Name: "selinux-policy-targeted", Checksum: "sha1:c02524e2bd19490f2a7167958f792262754c5f46"},
+	}
+	assert.EqualError(t, os.checkPackageSpecs(packages), `SELinux policy "mls" of pipeline "os" requires package "selinux-policy-mls", which is not part of the depsolved packages`)
+
+	packages = append(packages, rpmmd.PackageSpec{Name: "selinux-policy-mls", Checksum: "sha1:b2e0e4d2a1a0d1c3c8d5f4fa5f1e1b4d2f0a7c31"})
+	assert.NoError(t, os.checkPackageSpecs(packages))
+}
+
+func TestPackagePinsAndExcludes(t *testing.T) {
+	repos := []rpmmd.RepoConfig{}
+	manifest := New()
+	runner := &runner.Fedora{Version: 37}
+	build := NewBuild(&manifest, runner, repos)
+	os := NewOS(&manifest, build, &platform.X86{BIOS: true}, repos)
+	os.KernelName = "kernel"
+	os.ExcludeBasePackages = []string{"dracut-config-rescue"}
+	pin := rpmmd.PackagePin{Name: "kernel", Version: "6.5.6", Release: "300.fc39"}
+	os.Workload = &workload.Custom{
+		Packages:        []string{"tmux", pin.String()},
+		ExcludePackages: []string{"firewalld"},
+		PackagePins:     []rpmmd.PackagePin{pin},
+	}
+
+	chain := os.getPackageSetChain(DISTRO_NULL)
+	require.Len(t, chain, 2)
+	assert.NotContains(t, chain[0].Include, "kernel")
+	assert.Contains(t, chain[0].Include, "kernel-6.5.6-300.fc39")
+	assert.Equal(t, []string{"dracut-config-rescue", "firewalld"}, chain[0].Exclude)
+	assert.Equal(t, []rpmmd.PackagePin{pin}, chain[0].Pins)
+	assert.Equal(t, []string{"tmux", "kernel-6.5.6-300.fc39"}, chain[1].Include)
+	assert.Equal(t, []string{"firewalld"}, chain[1].Exclude)
+	assert.Equal(t, []string{"dracut-config-rescue"}, os.ExcludeBasePackages)
+}
+
+func TestEnabledModules(t *testing.T) {
+	os := NewTestOS()
+	modules := []rpmmd.ModuleSpec{
+		{Name: "nodejs", Stream: "18", Profile: "minimal"},
+		{Name: "postgresql", Stream: "15"},
+	}
+	os.Workload = &workload.Custom{
+		Packages:       []string{"@nodejs:18/minimal", "@postgresql:15"},
+		EnabledModules: modules,
+	}
+
+	chain := os.getPackageSetChain(DISTRO_NULL)
+	require.Len(t, chain, 2)
+	assert.Equal(t, modules, chain[0].EnabledModules)
+	assert.Equal(t, modules, chain[1].EnabledModules)
+
+	files := os.moduleStateFiles()
+	require.Len(t, files, 2)
+	assert.Equal(t, "/etc/dnf/modules.d/nodejs.module", files[0].Path())
+	assert.Equal(t, "[nodejs]\nname=nodejs\nstream=18\nprofiles=minimal\nstate=enabled\n", string(files[0].Data()))
+	assert.Equal(t, "/etc/dnf/modules.d/postgresql.module", files[1].Path())
+	assert.Equal(t, "[postgresql]\nname=postgresql\nstream=15\nprofiles=\nstate=enabled\n", string(files[1].Data()))
+
+	pipeline := os.serialize()
+	var mkdir *osbuild.Stage
+	for _, stage := range pipeline.Stages {
+		if stage.Type == "org.osbuild.mkdir" {
+			mkdir = stage
+		}
+	}
+	require.NotNil(t, mkdir)
+	assert.Equal(t, []osbuild.MkdirStagePath{{Path: "/etc/dnf/modules.d", Parents: true, ExistOk: true}}, mkdir.Options.(*osbuild.MkdirStageOptions).Paths)
+	assert.Len(t, os.getInline(), 2)
+}
+
+func TestModuleStates(t *testing.T) {
+	os := NewTestOS()
+	os.Workload = &workload.Custom{
+		Packages: []string{"@nodejs:18/minimal", "@nodejs:18/development", "@postgresql:15"},
+		EnabledModules: []rpmmd.ModuleSpec{
+			{Name: "nodejs", Stream: "18", Profile: "minimal"},
+			{Name: "nodejs", Stream: "18", Profile: "development"},
+			{Name: "postgresql", Stream: "15"},
+		},
+	}
+
+	// every module is recorded once, with the profiles of all its specs
+	files := os.moduleStateFiles()
+	require.Len(t, files, 2)
+	assert.Equal(t, "/etc/dnf/modules.d/nodejs.module", files[0].Path())
+	assert.Equal(t, "[nodejs]\nname=nodejs\nstream=18\nprofiles=minimal,development\nstate=enabled\n", string(files[0].Data()))
+	assert.Equal(t, "/etc/dnf/modules.d/postgresql.module", files[1].Path())
+
+	// the module states reported by the depsolve take precedence, they
+	// include the default profiles
+	os.setModuleStates([]rpmmd.ModuleState{
+		{Name: "nodejs", Stream: "18", Profiles: []string{"development", "minimal"}},
+		{Name: "postgresql", Stream: "15", Profiles: []string{"server"}},
+	})
+	files = os.moduleStateFiles()
+	require.Len(t, files, 2)
+	assert.Equal(t, "[nodejs]\nname=nodejs\nstream=18\nprofiles=development,minimal\nstate=enabled\n", string(files[0].Data()))
+	assert.Equal(t, "[postgresql]\nname=postgresql\nstream=15\nprofiles=server\nstate=enabled\n", string(files[1].Data()))
+	assert.Len(t, os.getInline(), 2)
+
+	os.serializeEnd()
+	assert.Nil(t, os.moduleStates)
+}
+
+func TestFIPSStages(t *testing.T) {
+	os := NewTestOS()
+	os.FIPS = true
+
+	CheckPkgSetInclude(t, os.getPackageSetChain(DISTRO_NULL), []string{"crypto-policies-scripts"})
+
+	pipeline := os.serialize()
+	var dracutConf, rpm, cryptoPolicies *osbuild.Stage
+	for _, s := range pipeline.Stages {
+		switch s.Type {
+		case "org.osbuild.dracut.conf":
+			// the initramfs is generated by the RPM stage
+			assert.Nil(t, rpm, "dracut.conf stage after the RPM stage")
+			dracutConf = s
+		case "org.osbuild.rpm":
+			rpm = s
+		case "org.osbuild.update-crypto-policies":
+			cryptoPolicies = s
+		}
+	}
+	require.NotNil(t, dracutConf)
+	assert.Equal(t, &osbuild.DracutConfStageOptions{
+		Filename: "40-fips.conf",
+		Config:   osbuild.DracutConfigFile{AddModules: []string{"fips"}},
+	}, dracutConf.Options)
+	require.NotNil(t, cryptoPolicies)
+	assert.Equal(t, &osbuild.UpdateCryptoPoliciesStageOptions{Policy: "FIPS"}, cryptoPolicies.Options)
+}
+
+func TestDracutConfStages(t *testing.T) {
+	os := NewTestOS()
+	os.DracutConf = []*osbuild.DracutConfStageOptions{
+		{
+			Filename: "blueprint.conf",
+			Config:   osbuild.DracutConfigFile{AddModules: []string{"multipath"}, AddDrivers: []string{"nvme"}},
+		},
+	}
+
+	// modules and drivers that dracut only includes on request must be
+	// configured when the initramfs is generated by the RPM stage
+	pipeline := os.serialize()
+	var stages []string
+	for _, s := range pipeline.Stages {
+		if s.Type == "org.osbuild.dracut.conf" || s.Type == "org.osbuild.rpm" {
+			stages = append(stages, s.Type)
+		}
+	}
+	assert.Equal(t, []string{"org.osbuild.dracut.conf", "org.osbuild.rpm"}, stages)
+}
+
+func TestMaskedServices(t *testing.T) {
+	os := NewTestOS()
+	os.MaskedServices = []string{"kdump.service"}
+
+	pipeline := os.serialize()
+	var systemd *osbuild.Stage
+	for _, s := range pipeline.Stages {
+		if s.Type == "org.osbuild.systemd" {
+			systemd = s
+		}
+	}
+	require.NotNil(t, systemd)
+	assert.Equal(t, []string{"kdump.service"}, systemd.Options.(*osbuild.SystemdStageOptions).MaskedServices)
+}
+
+func TestUKIStages(t *testing.T) {
+	repos := []rpmmd.RepoConfig{}
+	manifest := New()
+	build := NewBuild(&manifest, &runner.Fedora{Version: 39}, repos)
+	uki := &platform.X86{
+		UEFIVendor: "fedora",
+		Bootloader: platform.BOOTLOADER_UKI,
+	}
+	os := NewOS(&manifest, build, uki, repos)
+	os.KernelName = "kernel"
+	os.KernelOptionsAppend = []string{"ro", "console=ttyS0"}
+	os.PartitionTable = &disk.PartitionTable{
+		Type: "gpt",
+		Size: 4 * common.GibiByte,
+		Partitions: []disk.Partition{
+			{
+				Size:    512 * common.MebiByte,
+				Type:    disk.EFISystemPartitionGUID,
+				Payload: &disk.Filesystem{Type: "vfat", UUID: disk.EFIFilesystemUUID, Mountpoint: "/boot/efi"},
+			},
+			{
+				Size:    1 * common.GibiByte,
+				Type:    disk.XBootLDRPartitionGUID,
+				Payload: &disk.Filesystem{Type: "vfat", UUID: disk.XBootLDRFilesystemUUID, Mountpoint: "/boot"},
+			},
+			{
+				Size:    2 * common.GibiByte,
+				Type:    disk.FilesystemDataGUID,
+				Payload: &disk.Filesystem{Type: "ext4", UUID: "6e4ff95f-f662-45ee-a82a-bdf44a2d0b75", Mountpoint: "/"},
+			},
+		},
+	}
+
+	CheckPkgSetInclude(t, os.getPackageSetChain(DISTRO_FEDORA), []string{"binutils", "systemd-boot-unsigned"})
+	assert.NotContains(t, os.getPackageSetChain(DISTRO_FEDORA)[0].Include, "grub2-efi-x64")
+
+	packages := []rpmmd.PackageSpec{
+		{Name: "kernel", Version: "6.5.6", Release: "300.fc39", Arch: "x86_64", Checksum: "sha256:c02524e2bd19490f2a7167958f792262754c5f46"},
+	}
+	os.serializeStart(packages, nil, nil)
+	pipeline := os.serialize()
+
+	var dracut, mkdir *osbuild.Stage
+	for _, s := range pipeline.Stages {
+		switch s.Type {
+		case "org.osbuild.dracut":
+			dracut = s
+		case "org.osbuild.mkdir":
+			mkdir = s
+		case "org.osbuild.grub2":
+			t.Errorf("unexpected grub2 stage in an image with unified kernel images")
+		}
+	}
+	require.NotNil(t, dracut)
+	assert.Equal(t, &osbuild.DracutStageOptions{
+		Kernel: []string{"6.5.6-300.fc39.x86_64"},
+		Extra:  []string{"--uefi", "--kernel-cmdline", "root=UUID=6e4ff95f-f662-45ee-a82a-bdf44a2d0b75 ro console=ttyS0"},
+	}, dracut.Options)
+	require.NotNil(t, mkdir)
+	assert.Equal(t, "/boot/EFI/Linux", mkdir.Options.(*osbuild.MkdirStageOptions).Paths[0].Path)
+	assert.Contains(t, os.getInline(), "default 6.5.6-300.fc39.x86_64.efi\n")
+
+	raw := NewRawImage(&manifest, build, os)
+	var ukiPaths []osbuild.CopyStagePath
+	for _, s := range raw.serialize().Stages {
+		if s.Type == "org.osbuild.copy" {
+			ukiPaths = s.Options.(*osbuild.CopyStageOptions).Paths
+		}
+	}
+	assert.Equal(t, []osbuild.CopyStagePath{
+		{
+			From: "input://root-tree/boot/initramfs-6.5.6-300.fc39.x86_64.img",
+			To:   "mount://root/boot/EFI/Linux/6.5.6-300.fc39.x86_64.efi",
+		},
+		{
+			From: "input://root-tree/usr/lib/systemd/boot/efi/systemd-bootx64.efi",
+			To:   "mount://root/boot/efi/EFI/systemd/systemd-bootx64.efi",
+		},
+		{
+			From: "input://root-tree/usr/lib/systemd/boot/efi/systemd-bootx64.efi",
+			To:   "mount://root/boot/efi/EFI/BOOT/BOOTX64.EFI",
+		},
+	}, ukiPaths)
+
+	// without an XBOOTLDR partition the UKI is placed on the ESP
+	os.PartitionTable.Partitions = append(os.PartitionTable.Partitions[:1], os.PartitionTable.Partitions[2])
+	assert.Equal(t, "mount://root/boot/efi/EFI/Linux/6.5.6-300.fc39.x86_64.efi", os.ukiCopyPaths("root-tree")[0].To)
+}
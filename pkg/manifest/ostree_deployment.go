@@ -45,6 +45,10 @@ type OSTreeDeployment struct {
 	// Whether ignition is in use or not
 	ignition bool
 
+	// Boot the deployment in FIPS mode. The crypto policy and the dracut
+	// module are part of the commit, so only the kernel options are added.
+	FIPS bool
+
 	Directories []*fsnode.Directory
 	Files       []*fsnode.File
 
@@ -142,6 +146,9 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 		},
 	}))
 	kernelOpts := osbuild.GenImageKernelOptions(p.PartitionTable)
+	if p.FIPS {
+		kernelOpts = append(kernelOpts, osbuild.GenFIPSKernelOptions(p.PartitionTable)...)
+	}
 	kernelOpts = append(kernelOpts, p.KernelOptionsAppend...)
 
 	if p.ignition {
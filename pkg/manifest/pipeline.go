@@ -44,6 +44,10 @@ type Pipeline interface {
 	// its full Spec. See the ostree package for more details.
 	getOSTreeCommitSources() []ostree.SourceSpec
 
+	// checkPackageSpecs returns an error if the depsolved packages of the
+	// pipeline are missing any package the pipeline requires.
+	checkPackageSpecs([]rpmmd.PackageSpec) error
+
 	serializeStart([]rpmmd.PackageSpec, []container.Spec, []ostree.CommitSpec)
 	serializeEnd()
 	serialize() osbuild.Pipeline
@@ -153,6 +157,11 @@ func NewBase(m *Manifest, name string, build *Build) Base {
 	return p
 }
 
+// checkPackageSpecs must be called before serializeStart().
+func (p Base) checkPackageSpecs([]rpmmd.PackageSpec) error {
+	return nil
+}
+
 // serializeStart must be called exactly once before each call
 // to serialize().
 func (p Base) serializeStart([]rpmmd.PackageSpec, []container.Spec, []ostree.CommitSpec) {
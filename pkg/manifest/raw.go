@@ -56,6 +56,11 @@ func (p *RawImage) serialize() osbuild.Pipeline {
 	copyOptions, copyDevices, copyMounts := osbuild.GenCopyFSTreeOptions(inputName, p.treePipeline.Name(), p.Filename, pt)
 	copyInputs := osbuild.NewPipelineTreeInputs(inputName, p.treePipeline.Name())
 	pipeline.AddStage(osbuild.NewCopyStage(copyOptions, copyInputs, copyDevices, copyMounts))
+	if p.treePipeline.platform.GetBootloader() == platform.BOOTLOADER_UKI {
+		// install the UKI and systemd-boot on the ESP and XBOOTLDR partition
+		ukiOptions := &osbuild.CopyStageOptions{Paths: p.treePipeline.ukiCopyPaths(inputName)}
+		pipeline.AddStage(osbuild.NewCopyStage(ukiOptions, copyInputs, copyDevices, copyMounts))
+	}
 
 	for _, stage := range osbuild.GenImageFinishStages(pt, p.Filename) {
 		pipeline.AddStage(stage)
@@ -66,6 +71,11 @@ func (p *RawImage) serialize() osbuild.Pipeline {
 		loopback := osbuild.NewLoopbackDevice(&osbuild.LoopbackDeviceOptions{Filename: p.Filename})
 		pipeline.AddStage(osbuild.NewZiplInstStage(osbuild.NewZiplInstStageOptions(p.treePipeline.kernelVer, pt), loopback, copyDevices, copyMounts))
 	default:
+		// systemd-boot was installed with the tree above, only grub2
+		// needs to be installed into the image for BIOS boot
+		if p.treePipeline.platform.GetBootloader() == platform.BOOTLOADER_UKI {
+			break
+		}
 		if grubLegacy := p.treePipeline.platform.GetBIOSPlatform(); grubLegacy != "" {
 			pipeline.AddStage(osbuild.NewGrub2InstStage(osbuild.NewGrub2InstStageOption(p.Filename, pt, grubLegacy)))
 		}
@@ -0,0 +1,152 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+// VagrantProvider is the Vagrant provider a box is made for
+type VagrantProvider string
+
+const (
+	VagrantProviderLibvirt    VagrantProvider = "libvirt"
+	VagrantProviderVirtualBox VagrantProvider = "virtualbox"
+)
+
+// Name of the disk image in the boxes of the libvirt provider
+const vagrantLibvirtImage = "box.img"
+
+// A Vagrant turns a disk image into the tree of a Vagrant box: the image, the
+// metadata.json of the box and a Vagrantfile with the defaults of the
+// provider. The tree is archived into the box by a Tar pipeline.
+type Vagrant struct {
+	Base
+	Provider VagrantProvider
+
+	// Virtual size of the disk image in bytes, required by the libvirt
+	// provider
+	VirtualSize uint64
+
+	imgPipeline Pipeline
+}
+
+// NewVagrant creates a new Vagrant pipeline. imgPipeline is the pipeline
+// producing the disk image: a QCOW2 for the libvirt provider or an OVF, i.e.
+// a VMDK with its OVF descriptor, for the virtualbox provider.
+func NewVagrant(m *Manifest,
+	buildPipeline *Build,
+	imgPipeline Pipeline,
+	provider VagrantProvider) *Vagrant {
+	switch provider {
+	case VagrantProviderLibvirt:
+		if _, ok := imgPipeline.(*QCOW2); !ok {
+			panic("the vagrant libvirt provider requires a qcow2 image pipeline")
+		}
+	case VagrantProviderVirtualBox:
+		if _, ok := imgPipeline.(*OVF); !ok {
+			panic("the vagrant virtualbox provider requires an ovf pipeline")
+		}
+	default:
+		panic(fmt.Sprintf("unsupported vagrant provider %q", provider))
+	}
+
+	p := &Vagrant{
+		Base:        NewBase(m, "vagrant", buildPipeline),
+		Provider:    provider,
+		imgPipeline: imgPipeline,
+	}
+	buildPipeline.addDependent(p)
+	m.addPipeline(p)
+	return p
+}
+
+func (p *Vagrant) serialize() osbuild.Pipeline {
+	pipeline := p.Base.serialize()
+
+	inputName := "image-tree"
+	// the box of the virtualbox provider contains the whole tree of the OVF
+	// pipeline, i.e. the VMDK with its OVF descriptor and manifest
+	copyPath := osbuild.CopyStagePath{
+		From: fmt.Sprintf("input://%s/", inputName),
+		To:   "tree:///",
+	}
+	if p.Provider == VagrantProviderLibvirt {
+		copyPath = osbuild.CopyStagePath{
+			From: fmt.Sprintf("input://%s/%s", inputName, p.imgPipeline.Export().Filename()),
+			To:   fmt.Sprintf("tree:///%s", vagrantLibvirtImage),
+		}
+	}
+	pipeline.AddStage(osbuild.NewCopyStageSimple(
+		&osbuild.CopyStageOptions{
+			Paths: []osbuild.CopyStagePath{copyPath},
+		},
+		osbuild.NewPipelineTreeInputs(inputName, p.imgPipeline.Name()),
+	))
+
+	pipeline.AddStages(osbuild.GenFileNodesStages(p.boxFiles())...)
+
+	return pipeline
+}
+
+// vagrantMetadata is the metadata.json of a box
+type vagrantMetadata struct {
+	Provider VagrantProvider `json:"provider"`
+
+	// libvirt only: format and virtual size of the image in GiB
+	Format      string `json:"format,omitempty"`
+	VirtualSize uint64 `json:"virtual_size,omitempty"`
+}
+
+const vagrantfileLibvirt = `Vagrant.configure("2") do |config|
+  config.vm.synced_folder ".", "/vagrant", type: "rsync"
+  config.vm.provider :libvirt do |libvirt|
+    libvirt.driver = "kvm"
+  end
+end
+`
+
+const vagrantfileVirtualBox = `Vagrant.configure("2") do |config|
+  config.vm.synced_folder ".", "/vagrant", type: "rsync"
+end
+`
+
+// boxFiles returns the metadata.json and the Vagrantfile of the box
+func (p *Vagrant) boxFiles() []*fsnode.File {
+	metadata := vagrantMetadata{Provider: p.Provider}
+	vagrantfile := vagrantfileVirtualBox
+	if p.Provider == VagrantProviderLibvirt {
+		if p.VirtualSize == 0 {
+			panic("the vagrant libvirt provider requires the virtual size of the image")
+		}
+		metadata.Format = "qcow2"
+		metadata.VirtualSize = (p.VirtualSize + common.GibiByte - 1) / common.GibiByte
+		vagrantfile = vagrantfileLibvirt
+	}
+
+	metadataJSON, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal the vagrant box metadata: %v", err))
+	}
+
+	metadataFile, err := fsnode.NewFile("/metadata.json", nil, nil, nil, append(metadataJSON, '\n'))
+	if err != nil {
+		panic(fmt.Sprintf("failed to create the vagrant box metadata: %v", err))
+	}
+	vagrantfileFile, err := fsnode.NewFile("/Vagrantfile", nil, nil, nil, []byte(vagrantfile))
+	if err != nil {
+		panic(fmt.Sprintf("failed to create the vagrant box Vagrantfile: %v", err))
+	}
+	return []*fsnode.File{metadataFile, vagrantfileFile}
+}
+
+func (p *Vagrant) getInline() []string {
+	inlineData := []string{}
+	for _, file := range p.boxFiles() {
+		inlineData = append(inlineData, string(file.Data()))
+	}
+	return inlineData
+}
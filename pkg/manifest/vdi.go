@@ -0,0 +1,53 @@
+package manifest
+
+import (
+	"github.com/osbuild/images/pkg/artifact"
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+// A VDI turns a raw image file into a vdi image, as used by VirtualBox.
+type VDI struct {
+	Base
+	Filename string
+
+	imgPipeline *RawImage
+}
+
+// NewVDI creates a new VDI pipeline. imgPipeline is the pipeline producing the
+// raw image. Filename is the name of the produced vdi image.
+func NewVDI(m *Manifest,
+	buildPipeline *Build,
+	imgPipeline *RawImage) *VDI {
+	p := &VDI{
+		Base:        NewBase(m, "vdi", buildPipeline),
+		imgPipeline: imgPipeline,
+		Filename:    "image.vdi",
+	}
+	if imgPipeline.Base.manifest != m {
+		panic("live image pipeline from different manifest")
+	}
+	buildPipeline.addDependent(p)
+	m.addPipeline(p)
+	return p
+}
+
+func (p *VDI) serialize() osbuild.Pipeline {
+	pipeline := p.Base.serialize()
+
+	pipeline.AddStage(osbuild.NewQEMUStage(
+		osbuild.NewQEMUStageOptions(p.Filename, osbuild.QEMUFormatVDI, nil),
+		osbuild.NewQemuStagePipelineFilesInputs(p.imgPipeline.Name(), p.imgPipeline.Filename),
+	))
+
+	return pipeline
+}
+
+func (p *VDI) getBuildPackages(Distro) []string {
+	return []string{"qemu-img"}
+}
+
+func (p *VDI) Export() *artifact.Artifact {
+	p.Base.export = true
+	mimeType := "application/x-virtualbox-vdi"
+	return artifact.New(p.Name(), p.Filename, &mimeType)
+}
@@ -1,10 +1,25 @@
 package osbuild
 
-func NewBtrfsMount(name, source, target string) *Mount {
-	return &Mount{
+type BtrfsMountOptions struct {
+	Subvol   string `json:"subvol,omitempty"`
+	Compress string `json:"compress,omitempty"`
+}
+
+func (BtrfsMountOptions) isMountOptions() {}
+
+func NewBtrfsMount(name, source, target, subvol, compress string) *Mount {
+	m := &Mount{
 		Type:   "org.osbuild.btrfs",
 		Name:   name,
 		Source: source,
 		Target: target,
 	}
+
+	if subvol != "" || compress != "" {
+		m.Options = BtrfsMountOptions{
+			Subvol:   subvol,
+			Compress: compress,
+		}
+	}
+	return m
 }
@@ -0,0 +1,36 @@
+package osbuild
+
+import "fmt"
+
+// Create btrfs subvolumes on a mounted btrfs filesystem
+
+type BtrfsSubVolOptions struct {
+	Subvolumes []BtrfsSubVol `json:"subvolumes"`
+}
+
+func (BtrfsSubVolOptions) isStageOptions() {}
+
+func (o BtrfsSubVolOptions) validate() error {
+	if len(o.Subvolumes) == 0 {
+		return fmt.Errorf("at least one subvolume is required")
+	}
+	return nil
+}
+
+type BtrfsSubVol struct {
+	// Path of the subvolume, relative to the root of the filesystem
+	Name string `json:"name"`
+}
+
+func NewBtrfsSubVol(options *BtrfsSubVolOptions, devices map[string]Device, mounts []Mount) *Stage {
+	if err := options.validate(); err != nil {
+		panic(err)
+	}
+
+	return &Stage{
+		Type:    "org.osbuild.btrfs.subvol",
+		Options: options,
+		Devices: devices,
+		Mounts:  mounts,
+	}
+}
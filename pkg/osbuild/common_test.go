@@ -325,14 +325,18 @@ var testPartitionTables = map[string]disk.PartitionTable{
 					Label: "rootfs",
 					Subvolumes: []disk.BtrfsSubvolume{
 						{
+							Name:       "root",
 							Size:       0,
 							Mountpoint: "/",
 							GroupID:    0,
+							MntOps:     "compress=zstd:1",
 						},
 						{
+							Name:       "var",
 							Size:       5 * 1024 * 1024 * 1024,
 							Mountpoint: "/var",
 							GroupID:    0,
+							MntOps:     "compress=zstd:1",
 						},
 					},
 				},
@@ -3,6 +3,7 @@ package osbuild
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/osbuild/images/pkg/disk"
 )
@@ -78,7 +79,13 @@ func GenCopyFSTreeOptions(inputName, inputPipeline, filename string, pt *disk.Pa
 		case "ext4":
 			mount = NewExt4Mount(name, name, mountpoint)
 		case "btrfs":
-			mount = NewBtrfsMount(name, name, mountpoint)
+			if subvol, isSubvol := mnt.(*disk.BtrfsSubvolume); isSubvol {
+				// all subvolumes share the same device, so the mount needs
+				// its own name
+				mount = NewBtrfsMount(deviceName(subvol), name, mountpoint, subvol.Name, btrfsCompression(subvol.MntOps))
+			} else {
+				mount = NewBtrfsMount(name, name, mountpoint, "", "")
+			}
 		default:
 			panic("unknown fs type " + t)
 		}
@@ -115,3 +122,14 @@ func GenCopyFSTreeOptions(inputName, inputPipeline, filename string, pt *disk.Pa
 
 	return &options, &stageDevices, &stageMounts
 }
+
+// btrfsCompression returns the compression algorithm set in the btrfs mount
+// options, if any
+func btrfsCompression(mntOps string) string {
+	for _, opt := range strings.Split(mntOps, ",") {
+		if strings.HasPrefix(opt, "compress=") {
+			return strings.TrimPrefix(opt, "compress=")
+		}
+	}
+	return ""
+}
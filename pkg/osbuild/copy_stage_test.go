@@ -2,9 +2,14 @@ package osbuild
 
 import (
 	"fmt"
+	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/disk"
 )
 
 func TestNewCopyStage(t *testing.T) {
@@ -27,7 +32,7 @@ func TestNewCopyStage(t *testing.T) {
 	}
 
 	mounts := []Mount{
-		*NewBtrfsMount("root", "root", "/"),
+		*NewBtrfsMount("root", "root", "/", "", ""),
 	}
 
 	treeInput := NewTreeInput("name:input-pipeline")
@@ -69,3 +74,27 @@ func TestNewCopyStageSimpleSourcesInputs(t *testing.T) {
 	actualStage := NewCopyStageSimple(&CopyStageOptions{paths}, &filesInputs)
 	assert.Equal(t, expectedStage, actualStage)
 }
+
+func TestGenCopyFSTreeOptionsBtrfs(t *testing.T) {
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+
+	btrfs := testPartitionTables["btrfs"]
+	pt, err := disk.NewPartitionTable(&btrfs, nil, 0, blueprint.RawPartitioningMode, nil, nil, make(map[string]uint64), rng)
+	assert.NoError(t, err)
+
+	_, devices, mounts := GenCopyFSTreeOptions("root-tree", "os", "disk.img", pt)
+
+	var volume string
+	for name := range *devices {
+		if strings.HasPrefix(name, "btrfs-") {
+			volume = name
+		}
+	}
+	assert.NotEmpty(t, volume, "no device for the btrfs volume")
+
+	// each subvolume gets its own mount of the same device
+	assert.Contains(t, *mounts, *NewBtrfsMount("root", volume, "/", "root", "zstd:1"))
+	assert.Contains(t, *mounts, *NewBtrfsMount("var", volume, "/var", "var", "zstd:1"))
+}
@@ -160,6 +160,10 @@ func deviceName(p disk.Entity) string {
 		return payload.Name + "vg"
 	case *disk.LVMLogicalVolume:
 		return payload.Name
+	case *disk.Btrfs:
+		return "btrfs-" + payload.UUID[:4]
+	case *disk.Swap:
+		return "swap"
 	}
 	panic(fmt.Sprintf("unsupported device type in deviceName: '%T'", p))
 }
@@ -117,6 +117,11 @@ func GenImageKernelOptions(pt *disk.PartitionTable) []string {
 		case *disk.LUKSContainer:
 			karg := "luks.uuid=" + ent.UUID
 			cmdline = append(cmdline, karg)
+		case *disk.BtrfsSubvolume:
+			if ent.Mountpoint == "/" {
+				karg := "rootflags=subvol=" + ent.Name
+				cmdline = append(cmdline, karg)
+			}
 		}
 		return nil
 	}
@@ -124,3 +129,34 @@ func GenImageKernelOptions(pt *disk.PartitionTable) []string {
 	_ = pt.ForEachEntity(genOptions)
 	return cmdline
 }
+
+// GenFIPSKernelOptions returns the kernel options that enable FIPS mode. When
+// /boot is on a separate file system, it must be passed to the kernel, so
+// that the integrity of the kernel can be verified in the initramfs.
+func GenFIPSKernelOptions(pt *disk.PartitionTable) []string {
+	cmdline := []string{"fips=1"}
+
+	if pt != nil {
+		if boot := pt.FindMountable("/boot"); boot != nil {
+			cmdline = append(cmdline, "boot=UUID="+boot.GetFSSpec().UUID)
+		}
+	}
+
+	return cmdline
+}
+
+// GenResumeKernelOptions returns the kernel options that resume from
+// hibernation on the swap area of the partition table. Returns nil if there
+// is no swap area.
+func GenResumeKernelOptions(pt *disk.PartitionTable) []string {
+	if pt == nil {
+		return nil
+	}
+
+	swap := pt.FindSwap()
+	if swap == nil {
+		return nil
+	}
+
+	return []string{"resume=UUID=" + swap.UUID}
+}
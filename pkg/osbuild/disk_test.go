@@ -18,7 +18,7 @@ func TestGenImageKernelOptions(t *testing.T) {
 
 	luks_lvm := testPartitionTables["luks+lvm"]
 
-	pt, err := disk.NewPartitionTable(&luks_lvm, []blueprint.FilesystemCustomization{}, 0, false, make(map[string]uint64), rng)
+	pt, err := disk.NewPartitionTable(&luks_lvm, []blueprint.FilesystemCustomization{}, 0, blueprint.RawPartitioningMode, nil, nil, make(map[string]uint64), rng)
 	assert.NoError(err)
 
 	var uuid string
@@ -38,3 +38,53 @@ func TestGenImageKernelOptions(t *testing.T) {
 
 	assert.Subset(cmdline, []string{"luks.uuid=" + uuid})
 }
+
+func TestGenImageKernelOptionsBtrfs(t *testing.T) {
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+
+	btrfs := testPartitionTables["btrfs"]
+
+	pt, err := disk.NewPartitionTable(&btrfs, []blueprint.FilesystemCustomization{}, 0, blueprint.RawPartitioningMode, nil, nil, make(map[string]uint64), rng)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rootflags=subvol=root"}, GenImageKernelOptions(pt))
+}
+
+func TestGenFIPSKernelOptions(t *testing.T) {
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+
+	plain := testPartitionTables["plain"]
+	pt, err := disk.NewPartitionTable(&plain, []blueprint.FilesystemCustomization{}, 0, blueprint.RawPartitioningMode, nil, nil, make(map[string]uint64), rng)
+	assert.NoError(t, err)
+
+	boot := pt.FindMountable("/boot")
+	assert.NotNil(t, boot)
+	assert.Equal(t, []string{"fips=1", "boot=UUID=" + boot.GetFSSpec().UUID}, GenFIPSKernelOptions(pt))
+
+	// no boot option without a partition table
+	assert.Equal(t, []string{"fips=1"}, GenFIPSKernelOptions(nil))
+}
+
+func TestGenResumeKernelOptions(t *testing.T) {
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+
+	plain := testPartitionTables["plain"]
+	pt, err := disk.NewPartitionTable(&plain, []blueprint.FilesystemCustomization{}, 0, blueprint.RawPartitioningMode, nil, nil, make(map[string]uint64), rng)
+	assert.NoError(t, err)
+	assert.Nil(t, GenResumeKernelOptions(pt))
+
+	plain = testPartitionTables["plain"]
+	pt, err = disk.NewPartitionTable(&plain, []blueprint.FilesystemCustomization{}, 0, blueprint.AutoLVMPartitioningMode, nil, &blueprint.SwapCustomization{Size: 1024 * 1024 * 1024, Type: blueprint.SwapLogicalVolume}, make(map[string]uint64), rng)
+	assert.NoError(t, err)
+
+	swap := pt.FindSwap()
+	assert.NotNil(t, swap)
+	assert.Equal(t, []string{"resume=UUID=" + swap.UUID}, GenResumeKernelOptions(pt))
+
+	assert.Nil(t, GenResumeKernelOptions(nil))
+}
@@ -70,6 +70,9 @@ func NewFSTabStageOptions(pt *disk.PartitionTable) *FSTabStageOptions {
 	}
 
 	_ = pt.ForEachMountable(genOption) // genOption always returns nil
+	if swap := pt.FindSwap(); swap != nil {
+		options.AddFilesystem(swap.UUID, "swap", "none", swap.FSTabOptions, 0, 0)
+	}
 	// sort the entries by PassNo to maintain backward compatibility
 	sort.Slice(options.FileSystems, func(i, j int) bool {
 		return key(options.FileSystems[i]) < key(options.FileSystems[j])
@@ -1,6 +1,14 @@
 package osbuild
 
-import "github.com/osbuild/images/internal/users"
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/users"
+	"github.com/osbuild/images/pkg/blueprint"
+)
 
 type KickstartStageOptions struct {
 	// Where to place the kickstart file
@@ -13,6 +21,78 @@ type KickstartStageOptions struct {
 	Users map[string]UsersStageOptionsUser `json:"users,omitempty"`
 
 	Groups map[string]GroupsStageOptionsGroup `json:"groups,omitempty"`
+
+	Network []KickstartNetworkOptions `json:"network,omitempty"`
+
+	Bootloader *KickstartBootloaderOptions `json:"bootloader,omitempty"`
+
+	Lang string `json:"lang,omitempty"`
+
+	Keyboard string `json:"keyboard,omitempty"`
+
+	Timezone string `json:"timezone,omitempty"`
+
+	// Installer user interface: graphical, text or cmdline
+	DisplayMode string `json:"display_mode,omitempty"`
+
+	Reboot *KickstartRebootOptions `json:"reboot,omitempty"`
+
+	RootPassword *KickstartRootPasswordOptions `json:"rootpw,omitempty"`
+
+	ZeroMBR bool `json:"zerombr,omitempty"`
+
+	ClearPart *KickstartClearPartOptions `json:"clearpart,omitempty"`
+
+	AutoPart *KickstartAutoPartOptions `json:"autopart,omitempty"`
+}
+
+// KickstartRebootOptions reboots the system after the installation
+type KickstartRebootOptions struct {
+	Eject bool `json:"eject,omitempty"`
+	KExec bool `json:"kexec,omitempty"`
+}
+
+// KickstartRootPasswordOptions sets the password of the root account
+type KickstartRootPasswordOptions struct {
+	Lock bool `json:"lock,omitempty"`
+}
+
+// KickstartClearPartOptions removes partitions before the installation
+type KickstartClearPartOptions struct {
+	All       bool     `json:"all,omitempty"`
+	Linux     bool     `json:"linux,omitempty"`
+	InitLabel bool     `json:"initlabel,omitempty"`
+	Drives    []string `json:"drives,omitempty"`
+}
+
+// KickstartAutoPartOptions partitions the disks automatically
+type KickstartAutoPartOptions struct {
+	// Partitioning scheme: plain, lvm, btrfs or thinp
+	Type   string `json:"type,omitempty"`
+	FSType string `json:"fstype,omitempty"`
+	NoHome bool   `json:"nohome,omitempty"`
+}
+
+// KickstartBootloaderOptions configures the boot loader of the installed
+// system using the kickstart bootloader command
+type KickstartBootloaderOptions struct {
+	// Kernel command line arguments added to the installed system
+	Append string `json:"append,omitempty"`
+}
+
+// KickstartNetworkOptions configures a network device of the installed
+// system using the kickstart network command
+type KickstartNetworkOptions struct {
+	Device      string   `json:"device"`
+	Activate    *bool    `json:"activate,omitempty"`
+	OnBoot      *bool    `json:"onboot,omitempty"`
+	BootProto   string   `json:"bootproto,omitempty"`
+	IP          string   `json:"ip,omitempty"`
+	Netmask     string   `json:"netmask,omitempty"`
+	Gateway     string   `json:"gateway,omitempty"`
+	IPv6        string   `json:"ipv6,omitempty"`
+	IPv6Gateway string   `json:"ipv6gateway,omitempty"`
+	Nameservers []string `json:"nameservers,omitempty"`
 }
 
 type LiveIMG struct {
@@ -81,3 +161,75 @@ func NewKickstartStageOptions(
 		Groups:  groups,
 	}, nil
 }
+
+// NewKickstartBootloaderOptions returns the bootloader options that add the
+// given kernel options to the installed system, or nil if there are none.
+func NewKickstartBootloaderOptions(kernelOptions []string) *KickstartBootloaderOptions {
+	if len(kernelOptions) == 0 {
+		return nil
+	}
+	return &KickstartBootloaderOptions{
+		Append: strings.Join(kernelOptions, " "),
+	}
+}
+
+// NewKickstartNetworkOptions converts network connection customizations to
+// kickstart network commands. The kickstart network command can only
+// describe plain ethernet devices with at most one static address per
+// family, so any other connection results in an error.
+func NewKickstartNetworkOptions(conns []blueprint.NetworkConnectionCustomization) ([]KickstartNetworkOptions, error) {
+	if len(conns) == 0 {
+		return nil, nil
+	}
+
+	networks := make([]KickstartNetworkOptions, 0, len(conns))
+	for _, conn := range conns {
+		if (conn.Type != "" && conn.Type != "ethernet") || conn.Controller != "" {
+			return nil, fmt.Errorf("network connection %q cannot be configured by a kickstart file: only standalone ethernet connections are supported", conn.ID)
+		}
+		if conn.InterfaceName == "" {
+			return nil, fmt.Errorf("network connection %q cannot be configured by a kickstart file: an interface name is required", conn.ID)
+		}
+
+		onBoot := true
+		if conn.Autoconnect != nil {
+			onBoot = *conn.Autoconnect
+		}
+		network := KickstartNetworkOptions{
+			Device:    conn.InterfaceName,
+			Activate:  common.ToPtr(true),
+			OnBoot:    &onBoot,
+			BootProto: "dhcp",
+		}
+
+		for _, ipc := range []*blueprint.IPConfigCustomization{conn.IPv4, conn.IPv6} {
+			if ipc == nil {
+				continue
+			}
+			if ipc.Method == "disabled" || len(ipc.Addresses) > 1 || len(ipc.DNSSearch) > 0 || len(ipc.Routes) > 0 {
+				return nil, fmt.Errorf("network connection %q cannot be configured by a kickstart file: only a single address, a gateway and DNS servers are supported", conn.ID)
+			}
+			network.Nameservers = append(network.Nameservers, ipc.DNS...)
+		}
+
+		if conn.IPv4 != nil && conn.IPv4.Method == "manual" && len(conn.IPv4.Addresses) > 0 {
+			ip, ipNet, err := net.ParseCIDR(conn.IPv4.Addresses[0])
+			if err != nil {
+				return nil, err
+			}
+			network.BootProto = "static"
+			network.IP = ip.String()
+			network.Netmask = net.IP(ipNet.Mask).String()
+			network.Gateway = conn.IPv4.Gateway
+		}
+
+		if conn.IPv6 != nil && conn.IPv6.Method == "manual" && len(conn.IPv6.Addresses) > 0 {
+			network.IPv6 = conn.IPv6.Addresses[0]
+			network.IPv6Gateway = conn.IPv6.Gateway
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
@@ -0,0 +1,84 @@
+package osbuild
+
+import (
+	"testing"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKickstartStage(t *testing.T) {
+	expectedStage := &Stage{
+		Type:    "org.osbuild.kickstart",
+		Options: &KickstartStageOptions{},
+	}
+	actualStage := NewKickstartStage(&KickstartStageOptions{})
+	assert.Equal(t, expectedStage, actualStage)
+}
+
+func TestNewKickstartNetworkOptions(t *testing.T) {
+	conns := []blueprint.NetworkConnectionCustomization{
+		{
+			ID:            "eth0",
+			InterfaceName: "eth0",
+			IPv4: &blueprint.IPConfigCustomization{
+				Method:    "manual",
+				Addresses: []string{"192.168.1.10/24"},
+				Gateway:   "192.168.1.1",
+				DNS:       []string{"192.168.1.2"},
+			},
+			IPv6: &blueprint.IPConfigCustomization{
+				Method:    "manual",
+				Addresses: []string{"2001:db8::10/64"},
+				Gateway:   "2001:db8::1",
+			},
+		},
+		{
+			ID:            "eth1",
+			InterfaceName: "eth1",
+			Autoconnect:   common.ToPtr(false),
+		},
+	}
+
+	networks, err := NewKickstartNetworkOptions(conns)
+	assert.NoError(t, err)
+	assert.Equal(t, []KickstartNetworkOptions{
+		{
+			Device:      "eth0",
+			Activate:    common.ToPtr(true),
+			OnBoot:      common.ToPtr(true),
+			BootProto:   "static",
+			IP:          "192.168.1.10",
+			Netmask:     "255.255.255.0",
+			Gateway:     "192.168.1.1",
+			IPv6:        "2001:db8::10/64",
+			IPv6Gateway: "2001:db8::1",
+			Nameservers: []string{"192.168.1.2"},
+		},
+		{
+			Device:    "eth1",
+			Activate:  common.ToPtr(true),
+			OnBoot:    common.ToPtr(false),
+			BootProto: "dhcp",
+		},
+	}, networks)
+
+	_, err = NewKickstartNetworkOptions([]blueprint.NetworkConnectionCustomization{{ID: "bond0", Type: "bond", InterfaceName: "bond0"}})
+	assert.EqualError(t, err, `network connection "bond0" cannot be configured by a kickstart file: only standalone ethernet connections are supported`)
+
+	_, err = NewKickstartNetworkOptions([]blueprint.NetworkConnectionCustomization{{ID: "eth0"}})
+	assert.EqualError(t, err, `network connection "eth0" cannot be configured by a kickstart file: an interface name is required`)
+
+	_, err = NewKickstartNetworkOptions([]blueprint.NetworkConnectionCustomization{{
+		ID:            "eth0",
+		InterfaceName: "eth0",
+		IPv4:          &blueprint.IPConfigCustomization{Routes: []blueprint.RouteCustomization{{Destination: "10.0.0.0/8"}}},
+	}})
+	assert.EqualError(t, err, `network connection "eth0" cannot be configured by a kickstart file: only a single address, a gateway and DNS servers are supported`)
+}
+
+func TestNewKickstartBootloaderOptions(t *testing.T) {
+	assert.Nil(t, NewKickstartBootloaderOptions(nil))
+	assert.Equal(t, &KickstartBootloaderOptions{Append: "fips=1 debug"}, NewKickstartBootloaderOptions([]string{"fips=1", "debug"}))
+}
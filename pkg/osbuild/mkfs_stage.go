@@ -18,7 +18,27 @@ func GenMkfsStages(pt *disk.PartitionTable, device *Device) []*Stage {
 		panic("GenMkfsStages: failed to convert device options to loopback options")
 	}
 
-	genStage := func(mnt disk.Mountable, path []disk.Entity) error {
+	genStage := func(ent disk.Entity, path []disk.Entity) error {
+		if btrfs, ok := ent.(*disk.Btrfs); ok {
+			stages = append(stages, genBtrfsStages(btrfs, path, devOptions.Filename)...)
+			return nil
+		}
+
+		if swap, ok := ent.(*disk.Swap); ok {
+			stages = append(stages, genMkswapStage(swap, path, devOptions.Filename))
+			return nil
+		}
+
+		mnt, ok := ent.(disk.Mountable)
+		if !ok {
+			return nil
+		}
+
+		if _, ok := mnt.(*disk.BtrfsSubvolume); ok {
+			// subvolumes are created together with the btrfs filesystem
+			return nil
+		}
+
 		t := mnt.GetFSType()
 		var stage *Stage
 
@@ -40,6 +60,7 @@ func GenMkfsStages(pt *disk.PartitionTable, device *Device) []*Stage {
 		case "vfat":
 			options := &MkfsFATStageOptions{
 				VolID: strings.Replace(fsSpec.UUID, "-", "", -1),
+				Label: fsSpec.Label,
 			}
 			stage = NewMkfsFATStage(options, stageDevices)
 		case "btrfs":
@@ -62,6 +83,52 @@ func GenMkfsStages(pt *disk.PartitionTable, device *Device) []*Stage {
 		return nil
 	}
 
-	_ = pt.ForEachMountable(genStage) // genStage always returns nil
+	_ = pt.ForEachEntity(genStage) // genStage always returns nil
+	return stages
+}
+
+// genMkswapStage generates the org.osbuild.mkswap stage for a swap area
+func genMkswapStage(swap *disk.Swap, path []disk.Entity, filename string) *Stage {
+	stageDevices, lastName := getDevices(path, filename, true)
+
+	// the last device on the PartitionTable must be named "device"
+	lastDevice := stageDevices[lastName]
+	delete(stageDevices, lastName)
+	stageDevices["device"] = lastDevice
+
+	return NewMkswapStage(&MkswapStageOptions{
+		UUID:  swap.UUID,
+		Label: swap.Label,
+	}, stageDevices)
+}
+
+// genBtrfsStages generates the org.osbuild.mkfs.btrfs stage for a btrfs
+// filesystem and the org.osbuild.btrfs.subvol stage for its subvolumes
+func genBtrfsStages(btrfs *disk.Btrfs, path []disk.Entity, filename string) []*Stage {
+	stageDevices, lastName := getDevices(path, filename, true)
+
+	// the last device on the PartitionTable must be named "device"
+	lastDevice := stageDevices[lastName]
+	delete(stageDevices, lastName)
+	stageDevices["device"] = lastDevice
+
+	stages := []*Stage{
+		NewMkfsBtrfsStage(&MkfsBtrfsStageOptions{
+			UUID:  btrfs.UUID,
+			Label: btrfs.Label,
+		}, stageDevices),
+	}
+
+	if len(btrfs.Subvolumes) == 0 {
+		return stages
+	}
+
+	subvolumes := make([]BtrfsSubVol, 0, len(btrfs.Subvolumes))
+	for _, sv := range btrfs.Subvolumes {
+		subvolumes = append(subvolumes, BtrfsSubVol{Name: "/" + sv.Name})
+	}
+	mounts := []Mount{*NewBtrfsMount("volume", "device", "/", "", "")}
+	stages = append(stages, NewBtrfsSubVol(&BtrfsSubVolOptions{subvolumes}, stageDevices, mounts))
+
 	return stages
 }
@@ -1,12 +1,15 @@
 package osbuild
 
 import (
+	"math/rand"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/disk"
 )
 
 func TestNewMkfsStage(t *testing.T) {
@@ -59,6 +62,18 @@ func TestNewMkfsStage(t *testing.T) {
 	}
 	assert.Equal(t, mkfatExpected, mkfat)
 
+	swapOptions := &MkswapStageOptions{
+		UUID:  uuid.New().String(),
+		Label: "test",
+	}
+	mkswap := NewMkswapStage(swapOptions, devices)
+	mkswapExpected := &Stage{
+		Type:    "org.osbuild.mkswap",
+		Options: swapOptions,
+		Devices: Devices{"device": *device},
+	}
+	assert.Equal(t, mkswapExpected, mkswap)
+
 	xfsOptions := &MkfsXfsStageOptions{
 		UUID:  uuid.New().String(),
 		Label: "test",
@@ -71,3 +86,61 @@ func TestNewMkfsStage(t *testing.T) {
 	}
 	assert.Equal(t, mkxfsExpected, mkxfs)
 }
+
+func TestGenMkfsStagesBtrfs(t *testing.T) {
+	assert := assert.New(t)
+
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+
+	btrfs := testPartitionTables["btrfs"]
+	pt, err := disk.NewPartitionTable(&btrfs, []blueprint.FilesystemCustomization{{Mountpoint: "/home"}}, 0, blueprint.RawPartitioningMode, nil, nil, make(map[string]uint64), rng)
+	assert.NoError(err)
+
+	stages := GenMkfsStages(pt, NewLoopbackDevice(&LoopbackDeviceOptions{Filename: "file.img"}))
+
+	stageTypes := []string{}
+	for _, stage := range stages {
+		stageTypes = append(stageTypes, stage.Type)
+	}
+	// one filesystem for all the subvolumes
+	assert.Equal([]string{"org.osbuild.mkfs.fat", "org.osbuild.mkfs.xfs", "org.osbuild.mkfs.btrfs", "org.osbuild.btrfs.subvol"}, stageTypes)
+
+	subvolStage := stages[3]
+	assert.Equal(&BtrfsSubVolOptions{
+		Subvolumes: []BtrfsSubVol{{Name: "/root"}, {Name: "/var"}, {Name: "/home"}},
+	}, subvolStage.Options)
+	assert.Equal(Mounts{*NewBtrfsMount("volume", "device", "/", "", "")}, subvolStage.Mounts)
+	assert.Equal(stages[2].Devices, subvolStage.Devices)
+}
+
+func TestGenMkfsStagesSwap(t *testing.T) {
+	assert := assert.New(t)
+
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+
+	plain := testPartitionTables["plain"]
+	pt, err := disk.NewPartitionTable(&plain, nil, 0, blueprint.AutoLVMPartitioningMode, nil, &blueprint.SwapCustomization{Size: 1024 * 1024 * 1024, Type: blueprint.SwapLogicalVolume}, make(map[string]uint64), rng)
+	assert.NoError(err)
+
+	stages := GenMkfsStages(pt, NewLoopbackDevice(&LoopbackDeviceOptions{Filename: "file.img"}))
+
+	var mkswap *Stage
+	for _, stage := range stages {
+		if stage.Type == "org.osbuild.mkswap" {
+			mkswap = stage
+		}
+	}
+	assert.NotNil(mkswap)
+	assert.Equal(&MkswapStageOptions{UUID: pt.FindSwap().UUID}, mkswap.Options)
+
+	device, ok := mkswap.Devices["device"]
+	assert.True(ok)
+	assert.Equal(&LVM2LVDeviceOptions{Volume: "swaplv"}, device.Options)
+
+	fstab := NewFSTabStageOptions(pt)
+	assert.Contains(fstab.FileSystems, &FSTabEntry{UUID: pt.FindSwap().UUID, VFSType: "swap", Path: "none", Options: "defaults"})
+}
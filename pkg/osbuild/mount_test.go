@@ -10,7 +10,7 @@ func TestNewMounts(t *testing.T) {
 	assert := assert.New(t)
 
 	{ // btrfs
-		actual := NewBtrfsMount("btrfs", "/dev/sda1", "/mnt/btrfs")
+		actual := NewBtrfsMount("btrfs", "/dev/sda1", "/mnt/btrfs", "", "")
 		expected := &Mount{
 			Name:   "btrfs",
 			Type:   "org.osbuild.btrfs",
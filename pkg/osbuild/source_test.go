@@ -5,6 +5,10 @@ import (
 	"encoding/json"
 	"reflect"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/osbuild/images/pkg/rpmmd"
 )
 
 func TestSource_UnmarshalJSON(t *testing.T) {
@@ -113,3 +117,24 @@ func TestSource_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestGenSourcesRepositorySecrets(t *testing.T) {
+	packages := []rpmmd.PackageSpec{
+		{
+			RemoteLocation: "https://example.com/repo/pkg1.rpm",
+			Checksum:       "sha256:1",
+		},
+		{
+			RemoteLocation: "https://cdn.example.com/repo/pkg2.rpm",
+			Checksum:       "sha256:2",
+			Secrets:        "org.osbuild.rhsm",
+		},
+	}
+
+	sources := GenSources(packages, nil, nil, nil)
+	curl := sources["org.osbuild.curl"].(*CurlSource)
+	assert.Equal(t, map[string]CurlSourceItem{
+		"sha256:1": &CurlSourceOptions{URL: "https://example.com/repo/pkg1.rpm"},
+		"sha256:2": &CurlSourceOptions{URL: "https://cdn.example.com/repo/pkg2.rpm", Secrets: &URLSecrets{Name: "org.osbuild.rhsm"}},
+	}, curl.Items)
+}
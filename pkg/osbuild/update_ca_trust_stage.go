@@ -0,0 +1,11 @@
+package osbuild
+
+// NewUpdateCATrustStage creates a new stage that regenerates the consolidated
+// system trust store from the anchors in /etc/pki/ca-trust/source and
+// /usr/share/pki/ca-trust-source by running update-ca-trust. The stage has no
+// options.
+func NewUpdateCATrustStage() *Stage {
+	return &Stage{
+		Type: "org.osbuild.pki.update-ca-trust",
+	}
+}
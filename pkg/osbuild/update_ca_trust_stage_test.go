@@ -0,0 +1,21 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUpdateCATrustStage(t *testing.T) {
+	expectedStage := &Stage{
+		Type: "org.osbuild.pki.update-ca-trust",
+	}
+	actualStage := NewUpdateCATrustStage()
+	assert.Equal(t, expectedStage, actualStage)
+
+	data, err := json.Marshal(actualStage)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "org.osbuild.pki.update-ca-trust"}`, string(data))
+}
@@ -0,0 +1,17 @@
+package osbuild
+
+type UpdateCryptoPoliciesStageOptions struct {
+	// The system-wide cryptographic policy to set, e.g. "FIPS"
+	Policy string `json:"policy"`
+}
+
+func (UpdateCryptoPoliciesStageOptions) isStageOptions() {}
+
+// NewUpdateCryptoPoliciesStage creates a new stage that sets the system-wide
+// cryptographic policy by running update-crypto-policies --set.
+func NewUpdateCryptoPoliciesStage(options *UpdateCryptoPoliciesStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.update-crypto-policies",
+		Options: options,
+	}
+}
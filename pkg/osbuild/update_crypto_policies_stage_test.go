@@ -0,0 +1,23 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUpdateCryptoPoliciesStage(t *testing.T) {
+	options := &UpdateCryptoPoliciesStageOptions{Policy: "FIPS"}
+	expectedStage := &Stage{
+		Type:    "org.osbuild.update-crypto-policies",
+		Options: options,
+	}
+	actualStage := NewUpdateCryptoPoliciesStage(options)
+	assert.Equal(t, expectedStage, actualStage)
+
+	data, err := json.Marshal(actualStage)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "org.osbuild.update-crypto-policies", "options": {"policy": "FIPS"}}`, string(data))
+}
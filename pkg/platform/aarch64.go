@@ -3,6 +3,7 @@ package platform
 type Aarch64 struct {
 	BasePlatform
 	UEFIVendor string
+	Bootloader Bootloader
 }
 
 func (p *Aarch64) GetArch() Arch {
@@ -13,9 +14,23 @@ func (p *Aarch64) GetUEFIVendor() string {
 	return p.UEFIVendor
 }
 
+func (p *Aarch64) GetBootloader() Bootloader {
+	return p.Bootloader
+}
+
 func (p *Aarch64) GetPackages() []string {
 	packages := p.BasePlatform.FirmwarePackages
 
+	if p.Bootloader == BOOTLOADER_UKI {
+		// dracut builds the UKIs from the kernel, the generic initramfs
+		// and the EFI stub of systemd-boot, it needs objcopy from binutils
+		return append(packages,
+			"binutils",
+			"dracut-config-generic",
+			"efibootmgr",
+			"systemd-boot-unsigned")
+	}
+
 	if p.UEFIVendor != "" {
 		packages = append(packages,
 			"dracut-config-generic",
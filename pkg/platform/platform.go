@@ -2,6 +2,7 @@ package platform
 
 type Arch uint64
 type ImageFormat uint64
+type Bootloader uint64
 
 const ( // architecture enum
 	ARCH_AARCH64 Arch = iota
@@ -19,6 +20,18 @@ const ( // image format enum
 	FORMAT_VHD
 	FORMAT_GCE
 	FORMAT_OVA
+	FORMAT_VHDX
+	FORMAT_VDI
+	FORMAT_VAGRANT_LIBVIRT
+	FORMAT_VAGRANT_VIRTUALBOX
+	FORMAT_CONTAINERDISK
+)
+
+const ( // bootloader enum
+	// grub2, or zipl on s390x
+	BOOTLOADER_GRUB2 Bootloader = iota
+	// unified kernel images (UKI) booted by systemd-boot
+	BOOTLOADER_UKI
 )
 
 func (a Arch) String() string {
@@ -52,6 +65,16 @@ func (f ImageFormat) String() string {
 		return "gce"
 	case FORMAT_OVA:
 		return "ova"
+	case FORMAT_VHDX:
+		return "vhdx"
+	case FORMAT_VDI:
+		return "vdi"
+	case FORMAT_VAGRANT_LIBVIRT:
+		return "vagrant-libvirt"
+	case FORMAT_VAGRANT_VIRTUALBOX:
+		return "vagrant-virtualbox"
+	case FORMAT_CONTAINERDISK:
+		return "containerdisk"
 	default:
 		panic("invalid image format")
 	}
@@ -64,6 +87,7 @@ type Platform interface {
 	GetBIOSPlatform() string
 	GetUEFIVendor() string
 	GetZiplSupport() bool
+	GetBootloader() Bootloader
 	GetPackages() []string
 	GetBuildPackages() []string
 	GetBootFiles() [][2]string
@@ -95,6 +119,10 @@ func (p BasePlatform) GetZiplSupport() bool {
 	return false
 }
 
+func (p BasePlatform) GetBootloader() Bootloader {
+	return BOOTLOADER_GRUB2
+}
+
 func (p BasePlatform) GetPackages() []string {
 	return p.FirmwarePackages
 }
@@ -6,6 +6,7 @@ type X86 struct {
 	BasePlatform
 	BIOS       bool
 	UEFIVendor string
+	Bootloader Bootloader
 }
 
 func (p *X86) GetArch() Arch {
@@ -23,9 +24,23 @@ func (p *X86) GetUEFIVendor() string {
 	return p.UEFIVendor
 }
 
+func (p *X86) GetBootloader() Bootloader {
+	return p.Bootloader
+}
+
 func (p *X86) GetPackages() []string {
 	packages := p.BasePlatform.FirmwarePackages
 
+	if p.Bootloader == BOOTLOADER_UKI {
+		// dracut builds the UKIs from the kernel, the generic initramfs
+		// and the EFI stub of systemd-boot, it needs objcopy from binutils
+		return append(packages,
+			"binutils",
+			"dracut-config-generic",
+			"efibootmgr",
+			"systemd-boot-unsigned")
+	}
+
 	if p.BIOS {
 		packages = append(packages,
 			"dracut-config-generic",
@@ -0,0 +1,135 @@
+package rpmmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// DependencyGraph is the graph of the requirements between the packages of a
+// depsolve result. Packages are identified by their NEVRA (see
+// PackageSpec.GetNEVRA()).
+type DependencyGraph struct {
+	// Packages that were requested explicitly, i.e. the roots of the graph
+	Requested []string `json:"requested"`
+
+	// Requirements of the packages and the packages that satisfy them
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// Dependency is a requirement of a package that is provided by another
+// package
+type Dependency struct {
+	// NEVRA of the requiring package
+	Package string `json:"package"`
+
+	// The required capability, e.g. "libc.so.6()(64bit)" or "/usr/bin/sh"
+	Requires string `json:"requires"`
+
+	// NEVRA of the package providing the capability
+	Provider string `json:"provider"`
+
+	// The requirement is a weak dependency (Recommends)
+	Weak bool `json:"weak,omitempty"`
+}
+
+func (d Dependency) String() string {
+	verb := "requires"
+	if d.Weak {
+		verb = "recommends"
+	}
+	return fmt.Sprintf("%s %s %q provided by %s", d.Package, verb, d.Requires, d.Provider)
+}
+
+// IsRequested returns true if the package was requested explicitly
+func (g *DependencyGraph) IsRequested(nevra string) bool {
+	for _, requested := range g.Requested {
+		if requested == nevra {
+			return true
+		}
+	}
+	return false
+}
+
+// Why returns the shortest chain of dependencies from a requested package to
+// the given package, which explains why the package is part of the depsolve
+// result. The chain is empty if the package was requested itself. Returns
+// false if no requested package depends on the package.
+func (g *DependencyGraph) Why(nevra string) ([]Dependency, bool) {
+	if g.IsRequested(nevra) {
+		return []Dependency{}, true
+	}
+
+	edges := make(map[string][]Dependency)
+	for _, dep := range g.Dependencies {
+		edges[dep.Package] = append(edges[dep.Package], dep)
+	}
+
+	// breadth first search from all requested packages at once, the first
+	// time the package is reached is through a shortest chain
+	requested := append([]string{}, g.Requested...)
+	sort.Strings(requested)
+	via := make(map[string]Dependency)
+	visited := make(map[string]bool)
+	queue := make([]string, 0, len(requested))
+	for _, root := range requested {
+		visited[root] = true
+		queue = append(queue, root)
+	}
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		for _, dep := range edges[pkg] {
+			if visited[dep.Provider] {
+				continue
+			}
+			visited[dep.Provider] = true
+			via[dep.Provider] = dep
+			if dep.Provider == nevra {
+				return chain(via, nevra), true
+			}
+			queue = append(queue, dep.Provider)
+		}
+	}
+	return nil, false
+}
+
+// chain follows the dependencies back from the package to the requested
+// package it was reached from
+func chain(via map[string]Dependency, nevra string) []Dependency {
+	var deps []Dependency
+	for {
+		dep, ok := via[nevra]
+		if !ok {
+			break
+		}
+		deps = append([]Dependency{dep}, deps...)
+		nevra = dep.Package
+	}
+	return deps
+}
+
+// WriteDOT writes the graph in the DOT language of Graphviz. Requested
+// packages are drawn in bold, weak dependencies are dashed and the edges are
+// labeled with the required capabilities.
+func (g *DependencyGraph) WriteDOT(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "digraph dependencies {"); err != nil {
+		return err
+	}
+	for _, requested := range g.Requested {
+		if _, err := fmt.Fprintf(w, "\t%q [style=bold];\n", requested); err != nil {
+			return err
+		}
+	}
+	for _, dep := range g.Dependencies {
+		style := ""
+		if dep.Weak {
+			style = ", style=dashed"
+		}
+		if _, err := fmt.Fprintf(w, "\t%q -> %q [label=%q%s];\n", dep.Package, dep.Provider, dep.Requires, style); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
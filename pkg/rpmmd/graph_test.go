@@ -0,0 +1,77 @@
+package rpmmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testDependencyGraph() *DependencyGraph {
+	return &DependencyGraph{
+		Requested: []string{"tmux-3.3a-3.fc38.x86_64", "vim-minimal-2:9.0.1712-1.fc38.x86_64"},
+		Dependencies: []Dependency{
+			{Package: "tmux-3.3a-3.fc38.x86_64", Requires: "libevent_core-2.1.so.7()(64bit)", Provider: "libevent-2.1.12-8.fc38.x86_64"},
+			{Package: "tmux-3.3a-3.fc38.x86_64", Requires: "libc.so.6()(64bit)", Provider: "glibc-2.37-4.fc38.x86_64"},
+			{Package: "libevent-2.1.12-8.fc38.x86_64", Requires: "libssl.so.3()(64bit)", Provider: "openssl-libs-1:3.0.9-2.fc38.x86_64"},
+			{Package: "vim-minimal-2:9.0.1712-1.fc38.x86_64", Requires: "libc.so.6()(64bit)", Provider: "glibc-2.37-4.fc38.x86_64"},
+			{Package: "openssl-libs-1:3.0.9-2.fc38.x86_64", Requires: "libc.so.6()(64bit)", Provider: "glibc-2.37-4.fc38.x86_64"},
+			{Package: "glibc-2.37-4.fc38.x86_64", Requires: "/usr/bin/sh", Provider: "bash-5.2.15-3.fc38.x86_64"},
+		},
+	}
+}
+
+func TestDependencyGraphWhy(t *testing.T) {
+	g := testDependencyGraph()
+
+	deps, ok := g.Why("tmux-3.3a-3.fc38.x86_64")
+	assert.True(t, ok)
+	assert.Empty(t, deps)
+
+	// the shortest chain
+	deps, ok = g.Why("glibc-2.37-4.fc38.x86_64")
+	assert.True(t, ok)
+	assert.Equal(t, []Dependency{
+		{Package: "tmux-3.3a-3.fc38.x86_64", Requires: "libc.so.6()(64bit)", Provider: "glibc-2.37-4.fc38.x86_64"},
+	}, deps)
+
+	deps, ok = g.Why("openssl-libs-1:3.0.9-2.fc38.x86_64")
+	assert.True(t, ok)
+	assert.Equal(t, []Dependency{
+		{Package: "tmux-3.3a-3.fc38.x86_64", Requires: "libevent_core-2.1.so.7()(64bit)", Provider: "libevent-2.1.12-8.fc38.x86_64"},
+		{Package: "libevent-2.1.12-8.fc38.x86_64", Requires: "libssl.so.3()(64bit)", Provider: "openssl-libs-1:3.0.9-2.fc38.x86_64"},
+	}, deps)
+	assert.Equal(t, `libevent-2.1.12-8.fc38.x86_64 requires "libssl.so.3()(64bit)" provided by openssl-libs-1:3.0.9-2.fc38.x86_64`, deps[1].String())
+
+	deps, ok = g.Why("bash-5.2.15-3.fc38.x86_64")
+	assert.True(t, ok)
+	assert.Len(t, deps, 2)
+
+	weak := Dependency{Package: "bash-5.2.15-3.fc38.x86_64", Requires: "bash-completion", Provider: "bash-completion-1:2.11-9.fc38.noarch", Weak: true}
+	assert.Equal(t, `bash-5.2.15-3.fc38.x86_64 recommends "bash-completion" provided by bash-completion-1:2.11-9.fc38.noarch`, weak.String())
+
+	deps, ok = g.Why("zsh-5.9-5.fc38.x86_64")
+	assert.False(t, ok)
+	assert.Nil(t, deps)
+}
+
+func TestDependencyGraphWriteDOT(t *testing.T) {
+	g := &DependencyGraph{
+		Requested: []string{"tmux-3.3a-3.fc38.x86_64"},
+		Dependencies: []Dependency{
+			{Package: "tmux-3.3a-3.fc38.x86_64", Requires: "libc.so.6()(64bit)", Provider: "glibc-2.37-4.fc38.x86_64"},
+			{Package: "glibc-2.37-4.fc38.x86_64", Requires: "config(glibc) = 2.37-4.fc38", Provider: "glibc-common-2.37-4.fc38.x86_64"},
+			{Package: "glibc-2.37-4.fc38.x86_64", Requires: "glibc-gconv-extra(x86-64) = 2.37-4.fc38", Provider: "glibc-gconv-extra-2.37-4.fc38.x86_64", Weak: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, g.WriteDOT(&buf))
+	assert.Equal(t, `digraph dependencies {
+	"tmux-3.3a-3.fc38.x86_64" [style=bold];
+	"tmux-3.3a-3.fc38.x86_64" -> "glibc-2.37-4.fc38.x86_64" [label="libc.so.6()(64bit)"];
+	"glibc-2.37-4.fc38.x86_64" -> "glibc-common-2.37-4.fc38.x86_64" [label="config(glibc) = 2.37-4.fc38"];
+	"glibc-2.37-4.fc38.x86_64" -> "glibc-gconv-extra-2.37-4.fc38.x86_64" [label="glibc-gconv-extra(x86-64) = 2.37-4.fc38", style=dashed];
+}
+`, buf.String())
+}
@@ -7,7 +7,9 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,6 +27,12 @@ type repository struct {
 	RHSM           bool     `json:"rhsm,omitempty"`
 	MetadataExpire string   `json:"metadata_expire,omitempty"`
 	ImageTypeTags  []string `json:"image_type_tags,omitempty"`
+	SSLCACert      string   `json:"sslcacert,omitempty"`
+	SSLClientCert  string   `json:"sslclientcert,omitempty"`
+	SSLClientKey   string   `json:"sslclientkey,omitempty"`
+	Username       string   `json:"username,omitempty"`
+	Password       string   `json:"password,omitempty"`
+	Proxy          string   `json:"proxy,omitempty"`
 }
 
 type RepoConfig struct {
@@ -46,6 +54,56 @@ type RepoConfig struct {
 	Enabled        *bool    `json:"enabled,omitempty"`
 	ImageTypeTags  []string `json:"image_type_tags,omitempty"`
 	PackageSets    []string `json:"package_sets,omitempty"`
+
+	// Paths on the host to a PEM bundle of CA certificates that verify the
+	// repository server and to the client certificate and key used to
+	// authenticate against it. Like the credentials and the proxy below, they
+	// are only supported for depsolving (see CheckPackageDownloads).
+	SSLCACert     string `json:"sslcacert,omitempty"`
+	SSLClientCert string `json:"sslclientcert,omitempty"`
+	SSLClientKey  string `json:"sslclientkey,omitempty"`
+
+	// Secret references, see ResolveSecretRef, to the credentials used for
+	// HTTP basic authentication
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// URL of the proxy server used to access the repository
+	Proxy string `json:"proxy,omitempty"`
+}
+
+// UsesCustomSecrets returns true if the repository needs a client certificate
+// or credentials to download packages
+func (r *RepoConfig) UsesCustomSecrets() bool {
+	return r.SSLClientCert != "" || r.SSLClientKey != "" || r.Username != "" || r.Password != ""
+}
+
+// CheckPackageDownloads returns an error if osbuild cannot download the
+// packages of the repository. The CA certificate, client certificate,
+// credentials and proxy of a repository are only used for depsolving, the
+// sources of osbuild have no way to use them.
+func (r *RepoConfig) CheckPackageDownloads() error {
+	var settings []string
+	if r.SSLCACert != "" {
+		settings = append(settings, "a CA certificate")
+	}
+	if r.SSLClientCert != "" || r.SSLClientKey != "" {
+		settings = append(settings, "a client certificate")
+	}
+	if r.Username != "" || r.Password != "" {
+		settings = append(settings, "credentials")
+	}
+	if r.Proxy != "" {
+		settings = append(settings, "a proxy")
+	}
+	if len(settings) == 0 {
+		return nil
+	}
+	name := r.Name
+	if name == "" {
+		name = r.Id
+	}
+	return fmt.Errorf("packages of repository %q cannot be downloaded by osbuild with %s", name, strings.Join(settings, ", "))
 }
 
 // Hash calculates an ID string that uniquely represents a repository
@@ -69,7 +127,50 @@ func (r *RepoConfig) Hash() string {
 		bpts(r.CheckRepoGPG)+
 		bpts(r.IgnoreSSL)+
 		r.MetadataExpire+
-		bts(r.RHSM))))
+		bts(r.RHSM)+
+		r.SSLCACert+
+		r.SSLClientCert+
+		r.SSLClientKey+
+		r.Username+
+		r.Password+
+		r.Proxy)))
+}
+
+const (
+	// SecretRefEnvPrefix marks a secret reference to an environment
+	// variable, e.g. "env:REPO_PASSWORD"
+	SecretRefEnvPrefix = "env:"
+
+	// SecretRefFilePrefix marks a secret reference to a file, e.g.
+	// "file:/run/secrets/repo-password"
+	SecretRefFilePrefix = "file:"
+)
+
+// ResolveSecretRef returns the value of the secret that the reference points
+// to. Secrets are only referenced in repository configurations, so that their
+// values are read on the host that uses them and never end up in a manifest.
+func ResolveSecretRef(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, SecretRefEnvPrefix):
+		name := strings.TrimPrefix(ref, SecretRefEnvPrefix)
+		value, ok := os.LookupEnv(name)
+		if !ok || name == "" {
+			return "", fmt.Errorf("secret environment variable %q is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(ref, SecretRefFilePrefix):
+		path := strings.TrimPrefix(ref, SecretRefFilePrefix)
+		if !filepath.IsAbs(path) {
+			return "", fmt.Errorf("secret file path %q must be absolute", path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("cannot read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	default:
+		return "", fmt.Errorf("invalid secret reference %q: must start with %q or %q", ref, SecretRefEnvPrefix, SecretRefFilePrefix)
+	}
 }
 
 type DistrosRepoConfigs map[string]map[string][]RepoConfig
@@ -121,19 +222,161 @@ func (pkg Package) ToPackageInfo() PackageInfo {
 
 // The inputs to depsolve, a set of packages to include and a set of packages
 // to exclude. The Repositories are used when depsolving this package set in
-// addition to the base repositories.
+// addition to the base repositories. The Pins constrain the packages of
+// Include as well as their dependencies, in this and all following package
+// sets of a chain, and are checked against the depsolved packages.
 type PackageSet struct {
 	Include         []string
 	Exclude         []string
 	Repositories    []RepoConfig
 	InstallWeakDeps bool
+	Pins            []PackagePin
+	// Module streams enabled for the package set. Module profiles are
+	// installed by adding "@name:stream/profile" to Include.
+	EnabledModules []ModuleSpec
+}
+
+// A ModuleSpec selects a stream and, optionally, a profile of a DNF module.
+type ModuleSpec struct {
+	Name    string
+	Stream  string
+	Profile string
+}
+
+// ParseModuleSpec parses a "name:stream[/profile]" module spec
+func ParseModuleSpec(spec string) (ModuleSpec, error) {
+	var m ModuleSpec
+	name, rest, found := strings.Cut(spec, ":")
+	if !found {
+		return m, fmt.Errorf("module spec %q must have the form name:stream[/profile]", spec)
+	}
+	stream, profile, hasProfile := strings.Cut(rest, "/")
+	if name == "" || stream == "" || (hasProfile && profile == "") || strings.ContainsAny(spec, "*?[] ") || strings.ContainsAny(profile, ":/") {
+		return m, fmt.Errorf("module spec %q must have the form name:stream[/profile]", spec)
+	}
+	m.Name = name
+	m.Stream = stream
+	m.Profile = profile
+	return m, nil
+}
+
+// String returns the "name:stream[/profile]" module spec
+func (m ModuleSpec) String() string {
+	spec := m.Name + ":" + m.Stream
+	if m.Profile != "" {
+		spec += "/" + m.Profile
+	}
+	return spec
+}
+
+// ModuleState is the state of an enabled module stream after depsolving with
+// the profiles that are installed from it, as DNF records it in
+// /etc/dnf/modules.d.
+type ModuleState struct {
+	Name     string   `json:"name"`
+	Stream   string   `json:"stream"`
+	Profiles []string `json:"profiles,omitempty"`
+}
+
+// A PackagePin requires a package to be depsolved to an exact
+// epoch:version-release and, optionally, architecture.
+type PackagePin struct {
+	Name    string
+	Epoch   uint
+	Version string
+	Release string
+	Arch    string
+}
+
+// NewPackagePin returns the pin of the named package to the given
+// "[epoch:]version-release" string and architecture, which may be empty.
+func NewPackagePin(name, evr, arch string) (PackagePin, error) {
+	pin := PackagePin{Name: name, Arch: arch}
+	if name == "" || strings.ContainsAny(name+evr+arch, "*?[] ") {
+		return pin, fmt.Errorf("package pin %q must have a name and must not contain globs or spaces", name+"-"+evr)
+	}
+
+	vr := evr
+	if epoch, rest, found := strings.Cut(evr, ":"); found {
+		e, err := strconv.ParseUint(epoch, 10, 32)
+		if err != nil {
+			return pin, fmt.Errorf("package pin %q has an invalid epoch %q", name+"-"+evr, epoch)
+		}
+		pin.Epoch = uint(e)
+		vr = rest
+	}
+
+	idx := strings.LastIndex(vr, "-")
+	if idx <= 0 || idx == len(vr)-1 || !rpmVersionRegex.MatchString(vr[:idx]) || !rpmVersionRegex.MatchString(vr[idx+1:]) {
+		return pin, fmt.Errorf("package pin %q must have the form [epoch:]version-release", name+"-"+evr)
+	}
+	pin.Version = vr[:idx]
+	pin.Release = vr[idx+1:]
+
+	return pin, nil
+}
+
+// rpmVersionRegex matches the characters that rpm allows in versions and
+// releases
+var rpmVersionRegex = regexp.MustCompile(`^[A-Za-z0-9._+~^]+$`)
+
+// String returns the package spec that selects exactly the pinned package,
+// i.e. "name-[epoch:]version-release[.arch]".
+func (p PackagePin) String() string {
+	spec := p.Name + "-"
+	if p.Epoch != 0 {
+		spec += fmt.Sprintf("%d:", p.Epoch)
+	}
+	spec += p.Version + "-" + p.Release
+	if p.Arch != "" {
+		spec += "." + p.Arch
+	}
+	return spec
+}
+
+// Matches returns true if the package is the pinned one
+func (p PackagePin) Matches(pkg PackageSpec) bool {
+	return pkg.Name == p.Name &&
+		pkg.Epoch == p.Epoch &&
+		pkg.Version == p.Version &&
+		pkg.Release == p.Release &&
+		(p.Arch == "" || pkg.Arch == p.Arch)
+}
+
+// ApplyPins replaces the unversioned packages in Include that are pinned by
+// the given pins with the package specs of the pins and adds all pins to the
+// package set. Pins of packages that are not part of Include are not added
+// to it, they only constrain the depsolved packages.
+func (ps PackageSet) ApplyPins(pins []PackagePin) PackageSet {
+	if len(pins) == 0 {
+		return ps
+	}
+
+	pinned := map[string]PackagePin{}
+	for _, pin := range pins {
+		pinned[pin.Name] = pin
+	}
+
+	include := make([]string, len(ps.Include))
+	for idx, pkg := range ps.Include {
+		if pin, found := pinned[pkg]; found {
+			pkg = pin.String()
+		}
+		include[idx] = pkg
+	}
+
+	ps.Include = include
+	ps.Pins = append(ps.Pins, pins...)
+	return ps
 }
 
-// Append the Include and Exclude package list from another PackageSet and
-// return the result.
+// Append the Include and Exclude package list, the Pins and the enabled
+// modules from another PackageSet and return the result.
 func (ps PackageSet) Append(other PackageSet) PackageSet {
 	ps.Include = append(ps.Include, other.Include...)
 	ps.Exclude = append(ps.Exclude, other.Exclude...)
+	ps.Pins = append(ps.Pins, other.Pins...)
+	ps.EnabledModules = append(ps.EnabledModules, other.EnabledModules...)
 	return ps
 }
 
@@ -264,6 +507,12 @@ func loadRepositoriesFromFile(filename string) (map[string][]RepoConfig, error)
 				RHSM:           repo.RHSM,
 				MetadataExpire: repo.MetadataExpire,
 				ImageTypeTags:  repo.ImageTypeTags,
+				SSLCACert:      repo.SSLCACert,
+				SSLClientCert:  repo.SSLClientCert,
+				SSLClientKey:   repo.SSLClientKey,
+				Username:       repo.Username,
+				Password:       repo.Password,
+				Proxy:          repo.Proxy,
 			}
 
 			repoConfigs[arch] = append(repoConfigs[arch], config)
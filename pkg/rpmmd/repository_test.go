@@ -1,6 +1,9 @@
 package rpmmd
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -51,3 +54,104 @@ func TestPackageSpecGetNEVRA(t *testing.T) {
 	assert.Equal(t, "grub2-1:2.06-94.fc38.noarch", specs[1].GetNEVRA())
 
 }
+
+func TestResolveSecretRef(t *testing.T) {
+	t.Setenv("RPMMD_TEST_PASSWORD", "s3cret")
+	value, err := ResolveSecretRef("env:RPMMD_TEST_PASSWORD")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", value)
+
+	path := filepath.Join(t.TempDir(), "username")
+	assert.NoError(t, os.WriteFile(path, []byte("builder\n"), 0600))
+	value, err = ResolveSecretRef("file:" + path)
+	assert.NoError(t, err)
+	assert.Equal(t, "builder", value)
+
+	_, err = ResolveSecretRef("env:RPMMD_TEST_UNSET")
+	assert.EqualError(t, err, `secret environment variable "RPMMD_TEST_UNSET" is not set`)
+
+	_, err = ResolveSecretRef("file:username")
+	assert.EqualError(t, err, `secret file path "username" must be absolute`)
+
+	_, err = ResolveSecretRef("s3cret")
+	assert.EqualError(t, err, `invalid secret reference "s3cret": must start with "env:" or "file:"`)
+}
+
+func TestRepoConfigCheckPackageDownloads(t *testing.T) {
+	repo := RepoConfig{Name: "custom", BaseURLs: []string{"https://example.com/repo"}, RHSM: true}
+	assert.NoError(t, repo.CheckPackageDownloads())
+
+	repo.RHSM = false
+	repo.SSLCACert = "/etc/pki/repo/ca.pem"
+	assert.EqualError(t, repo.CheckPackageDownloads(), `packages of repository "custom" cannot be downloaded by osbuild with a CA certificate`)
+
+	repo.SSLClientCert = "/etc/pki/repo/client.pem"
+	repo.SSLClientKey = "/etc/pki/repo/client.key"
+	repo.Username = "env:USERNAME"
+	repo.Password = "env:PASSWORD"
+	repo.Proxy = "http://proxy.example.com:3128"
+	assert.EqualError(t, repo.CheckPackageDownloads(), `packages of repository "custom" cannot be downloaded by osbuild with a CA certificate, a client certificate, credentials, a proxy`)
+}
+
+func TestNewPackagePin(t *testing.T) {
+	pin, err := NewPackagePin("kernel", "5.14.0-362.el9", "")
+	assert.NoError(t, err)
+	assert.Equal(t, PackagePin{Name: "kernel", Version: "5.14.0", Release: "362.el9"}, pin)
+	assert.Equal(t, "kernel-5.14.0-362.el9", pin.String())
+
+	pin, err = NewPackagePin("grub2-pc", "1:2.06-94.fc38", "x86_64")
+	assert.NoError(t, err)
+	assert.Equal(t, PackagePin{Name: "grub2-pc", Epoch: 1, Version: "2.06", Release: "94.fc38", Arch: "x86_64"}, pin)
+	assert.Equal(t, "grub2-pc-1:2.06-94.fc38.x86_64", pin.String())
+
+	assert.True(t, pin.Matches(PackageSpec{Name: "grub2-pc", Epoch: 1, Version: "2.06", Release: "94.fc38", Arch: "x86_64"}))
+	assert.False(t, pin.Matches(PackageSpec{Name: "grub2-pc", Epoch: 0, Version: "2.06", Release: "94.fc38", Arch: "x86_64"}))
+	assert.False(t, pin.Matches(PackageSpec{Name: "grub2-pc", Epoch: 1, Version: "2.06", Release: "95.fc38", Arch: "x86_64"}))
+
+	_, err = NewPackagePin("kernel", "5.14.0", "")
+	assert.EqualError(t, err, `package pin "kernel-5.14.0" must have the form [epoch:]version-release`)
+
+	_, err = NewPackagePin("kernel", "x:5.14.0-362.el9", "")
+	assert.EqualError(t, err, `package pin "kernel-x:5.14.0-362.el9" has an invalid epoch "x"`)
+
+	_, err = NewPackagePin("kernel", "5.14.0-362-el9", "")
+	assert.EqualError(t, err, `package pin "kernel-5.14.0-362-el9" must have the form [epoch:]version-release`)
+
+	_, err = NewPackagePin("kernel", "5.14.0-362.el9/1", "")
+	assert.EqualError(t, err, `package pin "kernel-5.14.0-362.el9/1" must have the form [epoch:]version-release`)
+
+	_, err = NewPackagePin("kernel", "5.14.*-362.el9", "")
+	assert.EqualError(t, err, `package pin "kernel-5.14.*-362.el9" must have a name and must not contain globs or spaces`)
+}
+
+func TestPackageSetApplyPins(t *testing.T) {
+	pins := []PackagePin{
+		{Name: "kernel", Version: "5.14.0", Release: "362.el9"},
+		{Name: "tmux", Version: "3.2a", Release: "4.el9", Arch: "x86_64"},
+	}
+	ps := PackageSet{Include: []string{"@core", "kernel", "kernel-tools"}}.ApplyPins(pins)
+	assert.Equal(t, PackageSet{
+		Include: []string{"@core", "kernel-5.14.0-362.el9", "kernel-tools"},
+		Pins:    pins,
+	}, ps)
+
+	ps = PackageSet{Include: []string{"kernel"}}
+	assert.Equal(t, ps, ps.ApplyPins(nil))
+}
+
+func TestParseModuleSpec(t *testing.T) {
+	module, err := ParseModuleSpec("nodejs:18/minimal")
+	assert.NoError(t, err)
+	assert.Equal(t, ModuleSpec{Name: "nodejs", Stream: "18", Profile: "minimal"}, module)
+	assert.Equal(t, "nodejs:18/minimal", module.String())
+
+	module, err = ParseModuleSpec("postgresql:15")
+	assert.NoError(t, err)
+	assert.Equal(t, ModuleSpec{Name: "postgresql", Stream: "15"}, module)
+	assert.Equal(t, "postgresql:15", module.String())
+
+	for _, spec := range []string{"nodejs", "nodejs:", ":18", "nodejs:18/", "nodejs:1*", "nodejs:18/common/extra"} {
+		_, err = ParseModuleSpec(spec)
+		assert.EqualError(t, err, fmt.Sprintf("module spec %q must have the form name:stream[/profile]", spec))
+	}
+}